@@ -4,6 +4,9 @@
 package main
 
 import (
+	adminHandler "go-template/internal/api/handlers/admin"
+	"go-template/internal/api/handlers/authcookie"
+	oauthHandler "go-template/internal/api/handlers/oauth"
 	userHandler "go-template/internal/api/handlers/user"
 	"go-template/internal/repository"
 	"net/http"
@@ -11,10 +14,19 @@ import (
 	"github.com/google/wire"
 	"go-template/internal/api/handlers/routes"
 	"go-template/internal/configs"
+	"go-template/internal/middleware"
 	"go-template/internal/server"
+	"go-template/internal/services/oauth"
+	"go-template/internal/services/rbac"
+	"go-template/internal/services/revocation"
+	"go-template/internal/services/session"
+	"go-template/internal/services/throttle"
 	userSvc "go-template/internal/services/user"
 	"go-template/internal/utils/database"
 	"go-template/internal/utils/jwt"
+	"go-template/internal/utils/mailer"
+	"go-template/internal/utils/password"
+	"go-template/internal/validators"
 )
 
 // InitializeServer 使用 Wire 進行依賴注入，初始化 HTTP server
@@ -23,10 +35,34 @@ func InitializeServer(cfg *configs.Config) (*http.Server, func(), error) {
 		// 依序綁定各個依賴項
 		database.Start,
 		repository.NewUserRepository,
+		repository.NewRefreshTokenRepository,
+		repository.NewRoleRepository,
+		repository.NewUserTokenRepository,
+		repository.NewUserMFARepository,
+		repository.NewAPIKeyRepository,
+		repository.NewOAuthClientRepository,
+		repository.NewSessionRepository,
+		repository.NewImpersonationRepository,
 		jwt.NewService,
+		revocation.NewStore,
+		throttle.NewStore,
+		throttle.NewLimiter,
+		rbac.NewService,
+		mailer.New,
+		validators.NewPasswordPolicy,
+		validators.NewUserValidator,
+		password.New,
+		authcookie.New,
 		userSvc.NewUserService,
+		oauth.NewService,
+		session.NewTracker,
 		userHandler.NewHandler,
+		adminHandler.NewHandler,
+		oauthHandler.NewHandler,
+		middleware.NewAuth,
 		routes.NewUser,
+		routes.NewAdmin,
+		routes.NewOAuth,
 		server.Start,
 		// 將多個依賴項組合成 ServerConfig 結構體
 		wire.Struct(new(server.Config), "*"),
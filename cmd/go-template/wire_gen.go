@@ -7,14 +7,26 @@
 package main
 
 import (
+	"go-template/internal/api/handlers/admin"
+	"go-template/internal/api/handlers/authcookie"
+	oauth2 "go-template/internal/api/handlers/oauth"
 	"go-template/internal/api/handlers/routes"
 	user2 "go-template/internal/api/handlers/user"
 	"go-template/internal/configs"
+	"go-template/internal/middleware"
 	"go-template/internal/repository"
 	"go-template/internal/server"
+	"go-template/internal/services/oauth"
+	"go-template/internal/services/rbac"
+	"go-template/internal/services/revocation"
+	"go-template/internal/services/session"
+	"go-template/internal/services/throttle"
 	"go-template/internal/services/user"
 	"go-template/internal/utils/database"
 	"go-template/internal/utils/jwt"
+	"go-template/internal/utils/mailer"
+	"go-template/internal/utils/password"
+	"go-template/internal/validators"
 	"net/http"
 )
 
@@ -27,18 +39,67 @@ import (
 // InitializeServer 使用 Wire 進行依賴注入，初始化 HTTP server
 func InitializeServer(cfg *configs.Config) (*http.Server, func(), error) {
 	db := database.Start(cfg)
-	service := jwt.NewService(cfg)
+	service, cleanup, err := jwt.NewService(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
 	userRepository := repository.NewUserRepository(db)
-	userService := user.NewUserService(userRepository, service)
-	handler := user2.NewHandler(userService)
-	userRoutes := routes.NewUser(handler, service)
+	refreshTokenRepository := repository.NewRefreshTokenRepository(db)
+	store := revocation.NewStore(cfg, db)
+	roleRepository := repository.NewRoleRepository(db)
+	rbacService := rbac.NewService(roleRepository)
+	userTokenRepository := repository.NewUserTokenRepository(db)
+	userMFARepository := repository.NewUserMFARepository(db)
+	apiKeyRepository := repository.NewAPIKeyRepository(db)
+	sessionRepository := repository.NewSessionRepository(db)
+	impersonationRepository := repository.NewImpersonationRepository(db)
+	throttleStore := throttle.NewStore(cfg, db)
+	limiter := throttle.NewLimiter(cfg, throttleStore)
+	mailerMailer, err := mailer.New(cfg)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	passwordPolicy, err := validators.NewPasswordPolicy(cfg)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	userValidator := validators.NewUserValidator(userRepository)
+	hasher, err := password.New(cfg)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	userService, cleanup2 := user.NewUserService(cfg, userRepository, refreshTokenRepository, userTokenRepository, userMFARepository, apiKeyRepository, sessionRepository, impersonationRepository, limiter, store, rbacService, service, mailerMailer, passwordPolicy, userValidator, hasher)
+	manager, err := authcookie.New(cfg)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	handler := user2.NewHandler(userService, manager)
+	oAuthClientRepository := repository.NewOAuthClientRepository(db)
+	oauthService := oauth.NewService(oAuthClientRepository, refreshTokenRepository, store, rbacService, userService, service)
+	tracker, cleanup3 := session.NewTracker(cfg, sessionRepository)
+	auth := middleware.NewAuth(service, store, rbacService, userService, oauthService, tracker, manager)
+	userRoutes := routes.NewUser(handler, auth)
+	adminHandler := admin.NewHandler(userService)
+	adminRoutes := routes.NewAdmin(adminHandler, auth)
+	oauthHandler := oauth2.NewHandler(oauthService, userService)
+	oAuthRoutes := routes.NewOAuth(oauthHandler, auth)
 	config := server.Config{
 		DB:          db,
 		JwtService:  service,
 		UserService: userRoutes,
+		AdminRoutes: adminRoutes,
+		OAuthRoutes: oAuthRoutes,
 		Config:      cfg,
 	}
 	httpServer := server.Start(config)
 	return httpServer, func() {
+		cleanup3()
+		cleanup2()
+		cleanup()
 	}, nil
 }
@@ -0,0 +1,502 @@
+package admin
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-template/internal/api/handlers/exception"
+	"go-template/internal/api/handlers/response"
+	"go-template/internal/models"
+	"go-template/internal/principal"
+	"go-template/internal/repository"
+	userSvc "go-template/internal/services/user"
+	"go-template/internal/utils/logger"
+)
+
+// 分頁參數的預設值與上限
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+// listUsersRequest 使用者列表的查詢參數
+type listUsersRequest struct {
+	Offset          int        `form:"offset"            binding:"min=0"`
+	Limit           int        `form:"limit"             binding:"min=0"`
+	Cursor          string     `form:"cursor"`
+	Status          string     `form:"status"` // 帳號狀態名稱，例如 active、suspended
+	EmailDomain     string     `form:"email_domain"`
+	CreatedAfter    *time.Time `form:"created_after"     time_format:"2006-01-02T15:04:05Z07:00"`
+	CreatedBefore   *time.Time `form:"created_before"    time_format:"2006-01-02T15:04:05Z07:00"`
+	LastLoginAfter  *time.Time `form:"last_login_after"  time_format:"2006-01-02T15:04:05Z07:00"`
+	LastLoginBefore *time.Time `form:"last_login_before" time_format:"2006-01-02T15:04:05Z07:00"`
+	Sort            string     `form:"sort"` // 排序欄位，前面加上 - 代表遞減排序，例如 -created_at
+}
+
+// reasonRequest 停權、還原等操作的請求內容
+type reasonRequest struct {
+	Reason string `json:"reason"` // 選填，會寫入帳號狀態變更紀錄
+}
+
+// changeStatusRequest 變更帳號狀態的請求內容
+type changeStatusRequest struct {
+	Status models.UserStatus `json:"status" swaggertype:"string" enums:"active,pending_verification,suspended,locked,deactivated"`
+	Reason string            `json:"reason"` // 選填，會寫入帳號狀態變更紀錄
+}
+
+// Handler struct，用於處理管理者操作使用者的 HTTP 請求
+type Handler struct {
+	userService userSvc.Service
+}
+
+// NewHandler 建立一個新的 admin Handler 實例
+func NewHandler(userService userSvc.Service) *Handler {
+	return &Handler{userService: userService}
+}
+
+// ListUsers 處理取得使用者列表的請求
+// @Summary 取得使用者列表
+// @Description 管理者依照條件篩選、排序並分頁取得使用者，支援 offset 與游標 (cursor) 兩種分頁方式；
+// @Description 游標分頁只支援以 id 或 created_at 排序
+// @Tags Admin
+// @Produce  json
+// @Param offset query int false "略過的筆數" default(0)
+// @Param limit query int false "取得的筆數 (最多 100)" default(20)
+// @Param cursor query string false "上一頁回傳的 next_cursor，不可與 offset 同時使用"
+// @Param status query string false "帳號狀態" Enums(active, pending_verification, suspended, locked, deactivated)
+// @Param email_domain query string false "電子郵件網域，例如 example.com"
+// @Param created_after query string false "建立時間下限 (RFC 3339，包含)"
+// @Param created_before query string false "建立時間上限 (RFC 3339，不包含)"
+// @Param last_login_after query string false "最後登入時間下限 (RFC 3339，包含)"
+// @Param last_login_before query string false "最後登入時間上限 (RFC 3339，不包含)"
+// @Param sort query string false "排序欄位: id、created_at、username、email、last_login，前面加上 - 代表遞減" default(id)
+// @Security BearerAuth
+// @Success 200 {object} response.PagedData{Data=[]models.User} "取得成功"
+// @Failure 400 {object} response.ErrorData "錯誤的請求"
+// @Failure 403 {object} response.ErrorData "權限不足"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /admin/users [get]
+func (h *Handler) ListUsers(c *gin.Context) {
+	var input listUsersRequest
+	// 解析查詢參數到 input 變數
+	if err := c.ShouldBindQuery(&input); err != nil {
+		logger.Logger.Debugf(exception.ErrMsgInvalidRequestBody, err) // DEBUG 等級
+		response.BindError(c, err)
+		return
+	}
+	if input.Cursor != "" && input.Offset > 0 {
+		c.Error(userSvc.ErrInvalidListQuery)
+		return
+	}
+	if input.Limit == 0 {
+		input.Limit = defaultLimit
+	}
+	if input.Limit > maxLimit {
+		input.Limit = maxLimit
+	}
+
+	var status *models.UserStatus
+	if input.Status != "" {
+		parsed, err := models.ParseUserStatus(input.Status)
+		if err != nil {
+			logger.Logger.Debugf("Invalid status filter: %v", err) // DEBUG 等級
+			response.Error(c, http.StatusBadRequest, exception.ErrCodeInvalidRequest)
+			return
+		}
+		status = &parsed
+	}
+
+	query := userSvc.ListUsersQuery{
+		UserListQuery: repository.UserListQuery{
+			Status:          status,
+			EmailDomain:     input.EmailDomain,
+			CreatedAfter:    input.CreatedAfter,
+			CreatedBefore:   input.CreatedBefore,
+			LastLoginAfter:  input.LastLoginAfter,
+			LastLoginBefore: input.LastLoginBefore,
+			Offset:          input.Offset,
+			Limit:           input.Limit,
+		},
+		Cursor: input.Cursor,
+	}
+	query.SortBy, query.Desc = strings.TrimPrefix(input.Sort, "-"), strings.HasPrefix(input.Sort, "-")
+
+	page, err := h.userService.ListUsers(query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	meta := response.PageMeta{Total: page.Total, Limit: input.Limit, Offset: input.Offset, NextCursor: page.NextCursor}
+	links := response.PageLinks{Self: c.Request.URL.RequestURI()}
+	if page.HasMore {
+		// 排序方式支援 keyset 分頁時，第一頁與游標分頁的下一頁都使用游標；呼叫端已經使用 offset 分頁時下一頁沿用 offset
+		if page.NextCursor != "" && input.Offset == 0 {
+			links.Next = pageURL(c, "cursor", page.NextCursor)
+		} else {
+			links.Next = pageURL(c, "offset", strconv.Itoa(input.Offset+len(page.Users)))
+		}
+	}
+	response.Paged(c, http.StatusOK, "Users found", page.Users, meta, links)
+}
+
+// GetUser 處理取得指定使用者資訊的請求
+// @Summary 取得指定使用者資訊
+// @Description 管理者取得任意使用者的資訊 (包含已刪除的使用者)
+// @Tags Admin
+// @Produce  json
+// @Param id path int true "使用者 ID"
+// @Security BearerAuth
+// @Success 200 {object} response.SuccessData{Data=models.User} "取得成功"
+// @Failure 400 {object} response.ErrorData "錯誤的請求"
+// @Failure 403 {object} response.ErrorData "權限不足"
+// @Failure 404 {object} response.ErrorData "使用者不存在"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /admin/users/{id} [get]
+func (h *Handler) GetUser(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.userService.AdminGetUser(id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.Success(c, http.StatusOK, "User found", user)
+}
+
+// UpdateUser 處理更新指定使用者的請求
+// @Summary 更新指定使用者
+// @Description 管理者更新任意使用者的帳號名稱、電子郵件與角色，角色有變動時該使用者所有的裝置都會被登出
+// @Tags Admin
+// @Accept  json
+// @Produce  json
+// @Param id path int true "使用者 ID"
+// @Param user body userSvc.AdminUserUpdate true "要更新的欄位"
+// @Security BearerAuth
+// @Success 200 {object} response.SuccessData{Data=models.User} "更新成功"
+// @Failure 400 {object} response.ErrorData "錯誤的請求、角色不存在，或帳號名稱、電子郵件不符合規則 (列出每個欄位的錯誤)"
+// @Failure 403 {object} response.ErrorData "權限不足"
+// @Failure 404 {object} response.ErrorData "使用者不存在"
+// @Failure 409 {object} response.ErrorData "帳號名稱或電子郵件已經被使用"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /admin/users/{id} [put]
+func (h *Handler) UpdateUser(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var input userSvc.AdminUserUpdate
+	// 解析請求的 JSON 數據到 input 變數
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logger.Logger.Debugf(exception.ErrMsgInvalidRequestBody, err) // DEBUG 等級
+		response.BindError(c, err)
+		return
+	}
+
+	user, err := h.userService.AdminUpdateUser(id, input)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	logger.Logger.Infof("User %d updated by admin %d", id, principal.UserID(c)) // INFO 等級
+	response.Success(c, http.StatusOK, "User updated successfully", user)
+}
+
+// SuspendUser 處理停權使用者的請求
+// @Summary 停權使用者
+// @Description 管理者停權任意使用者，該使用者所有的裝置都會被登出且無法再登入
+// @Tags Admin
+// @Accept  json
+// @Produce  json
+// @Param id path int true "使用者 ID"
+// @Param body body reasonRequest false "停權原因 (選填)"
+// @Security BearerAuth
+// @Success 200 {object} response.SuccessData "停權成功"
+// @Failure 400 {object} response.ErrorData "錯誤的請求或無法停權自己"
+// @Failure 403 {object} response.ErrorData "權限不足"
+// @Failure 404 {object} response.ErrorData "使用者不存在"
+// @Failure 409 {object} response.ErrorData "目前的帳號狀態無法停權"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /admin/users/{id}/suspend [post]
+func (h *Handler) SuspendUser(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok || rejectSelf(c, id) {
+		return
+	}
+	input, ok := bindReason(c)
+	if !ok {
+		return
+	}
+
+	if err := h.userService.SuspendUser(id, actorID(c), input.Reason); err != nil {
+		c.Error(err)
+		return
+	}
+
+	logger.Logger.Infof("User %d suspended by admin %d", id, principal.UserID(c)) // INFO 等級
+	response.Success(c, http.StatusOK, "User suspended successfully", nil)
+}
+
+// RestoreUser 處理還原使用者的請求
+// @Summary 還原使用者
+// @Description 管理者還原被刪除、停權、鎖定或停用的使用者，還原後帳號狀態為 active
+// @Tags Admin
+// @Accept  json
+// @Produce  json
+// @Param id path int true "使用者 ID"
+// @Param body body reasonRequest false "還原原因 (選填)"
+// @Security BearerAuth
+// @Success 200 {object} response.SuccessData "還原成功"
+// @Failure 400 {object} response.ErrorData "錯誤的請求"
+// @Failure 403 {object} response.ErrorData "權限不足"
+// @Failure 404 {object} response.ErrorData "使用者不存在"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /admin/users/{id}/restore [post]
+func (h *Handler) RestoreUser(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+	input, ok := bindReason(c)
+	if !ok {
+		return
+	}
+
+	if err := h.userService.RestoreUser(id, actorID(c), input.Reason); err != nil {
+		c.Error(err)
+		return
+	}
+
+	logger.Logger.Infof("User %d restored by admin %d", id, principal.UserID(c)) // INFO 等級
+	response.Success(c, http.StatusOK, "User restored successfully", nil)
+}
+
+// ChangeStatus 處理變更帳號狀態的請求
+// @Summary 變更帳號狀態
+// @Description 管理者依照允許的轉換變更任意使用者的帳號狀態，變更成 active 以外的狀態時該使用者所有的裝置都會被登出
+// @Tags Admin
+// @Accept  json
+// @Produce  json
+// @Param id path int true "使用者 ID"
+// @Param body body changeStatusRequest true "新的狀態與原因"
+// @Security BearerAuth
+// @Success 200 {object} response.SuccessData "變更成功"
+// @Failure 400 {object} response.ErrorData "錯誤的請求或無法變更自己的狀態"
+// @Failure 403 {object} response.ErrorData "權限不足"
+// @Failure 404 {object} response.ErrorData "使用者不存在"
+// @Failure 409 {object} response.ErrorData "不允許的狀態轉換"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /admin/users/{id}/status [put]
+func (h *Handler) ChangeStatus(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok || rejectSelf(c, id) {
+		return
+	}
+
+	var input changeStatusRequest
+	// 解析請求的 JSON 數據到 input 變數
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logger.Logger.Debugf(exception.ErrMsgInvalidRequestBody, err) // DEBUG 等級
+		response.BindError(c, err)
+		return
+	}
+
+	if err := h.userService.ChangeUserStatus(id, input.Status, actorID(c), input.Reason); err != nil {
+		c.Error(err)
+		return
+	}
+
+	logger.Logger.Infof("User %d status changed to %s by admin %d", id, input.Status, principal.UserID(c)) // INFO 等級
+	response.Success(c, http.StatusOK, "User status changed successfully", nil)
+}
+
+// StatusHistory 處理取得帳號狀態變更紀錄的請求
+// @Summary 取得帳號狀態變更紀錄
+// @Description 管理者取得任意使用者的帳號狀態變更紀錄，包含執行變更的使用者與原因，最新的在前面
+// @Tags Admin
+// @Produce  json
+// @Param id path int true "使用者 ID"
+// @Security BearerAuth
+// @Success 200 {object} response.SuccessData{Data=[]models.UserStatusChange} "取得成功"
+// @Failure 400 {object} response.ErrorData "錯誤的請求"
+// @Failure 403 {object} response.ErrorData "權限不足"
+// @Failure 404 {object} response.ErrorData "使用者不存在"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /admin/users/{id}/status-history [get]
+func (h *Handler) StatusHistory(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	changes, err := h.userService.UserStatusHistory(id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.Success(c, http.StatusOK, "User status history found", changes)
+}
+
+// DeleteUser 處理永久刪除使用者的請求
+// @Summary 永久刪除使用者
+// @Description 管理者永久刪除任意使用者，刪除後無法還原
+// @Tags Admin
+// @Produce  json
+// @Param id path int true "使用者 ID"
+// @Security BearerAuth
+// @Success 200 {object} response.SuccessData "刪除成功"
+// @Failure 400 {object} response.ErrorData "錯誤的請求或無法刪除自己"
+// @Failure 403 {object} response.ErrorData "權限不足"
+// @Failure 404 {object} response.ErrorData "使用者不存在"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /admin/users/{id} [delete]
+func (h *Handler) DeleteUser(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok || rejectSelf(c, id) {
+		return
+	}
+
+	if err := h.userService.HardDeleteUser(id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	logger.Logger.Infof("User %d permanently deleted by admin %d", id, principal.UserID(c)) // INFO 等級
+	response.Success(c, http.StatusOK, "User deleted successfully", nil)
+}
+
+// ForcePasswordReset 處理要求使用者重設密碼的請求
+// @Summary 要求使用者重設密碼
+// @Description 管理者要求任意使用者重設密碼，該使用者所有的裝置都會被登出，重設密碼之前無法登入
+// @Tags Admin
+// @Produce  json
+// @Param id path int true "使用者 ID"
+// @Security BearerAuth
+// @Success 200 {object} response.SuccessData "設定成功"
+// @Failure 400 {object} response.ErrorData "錯誤的請求"
+// @Failure 403 {object} response.ErrorData "權限不足"
+// @Failure 404 {object} response.ErrorData "使用者不存在"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /admin/users/{id}/password-reset [post]
+func (h *Handler) ForcePasswordReset(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.userService.ForcePasswordReset(id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	logger.Logger.Infof("Password reset of user %d required by admin %d", id, principal.UserID(c)) // INFO 等級
+	response.Success(c, http.StatusOK, "Password reset required", nil)
+}
+
+// ListLockouts 處理列出登入鎖定的請求
+// @Summary 列出登入鎖定
+// @Description 列出目前因為登入失敗次數過多而被暫時鎖定的使用者名稱 (user:) 與來源 IP (ip:)
+// @Tags Admin
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} response.SuccessData{Data=[]throttle.Record} "取得成功"
+// @Failure 403 {object} response.ErrorData "權限不足"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /admin/lockouts [get]
+func (h *Handler) ListLockouts(c *gin.Context) {
+	records, err := h.userService.ListLoginLockouts()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Login lockouts found", records)
+}
+
+// ClearLockout 處理解除登入鎖定的請求
+// @Summary 解除登入鎖定
+// @Description 解除使用者因為登入失敗次數過多造成的暫時鎖定，並清除失敗紀錄
+// @Tags Admin
+// @Produce  json
+// @Param id path int true "使用者 ID"
+// @Security BearerAuth
+// @Success 200 {object} response.SuccessData "解除成功"
+// @Failure 400 {object} response.ErrorData "錯誤的請求"
+// @Failure 403 {object} response.ErrorData "權限不足"
+// @Failure 404 {object} response.ErrorData "使用者不存在"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /admin/users/{id}/lockout [delete]
+func (h *Handler) ClearLockout(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.userService.ClearLoginLockout(id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	logger.Logger.Infof("Login lockout of user %d cleared by admin %d", id, principal.UserID(c)) // INFO 等級
+	response.Success(c, http.StatusOK, "Login lockout cleared", nil)
+}
+
+// parseUserID 從路徑參數取得要操作的使用者 ID，格式錯誤時直接回應 400
+func parseUserID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil || id == 0 {
+		logger.Logger.Debugf("Invalid user ID in path: %s", c.Param("id")) // DEBUG 等級
+		response.Error(c, http.StatusBadRequest, exception.ErrCodeInvalidRequest)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// pageURL 以目前請求的網址為基礎，替換分頁參數後產生下一頁的連結
+func pageURL(c *gin.Context, key, value string) string {
+	next := *c.Request.URL
+	query := next.Query()
+	query.Del("offset")
+	query.Del("cursor")
+	query.Set(key, value)
+	next.RawQuery = query.Encode()
+	return next.RequestURI()
+}
+
+// bindReason 解析選填的原因，空的 body 不視為錯誤
+func bindReason(c *gin.Context) (reasonRequest, bool) {
+	var input reasonRequest
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		logger.Logger.Debugf(exception.ErrMsgInvalidRequestBody, err) // DEBUG 等級
+		response.BindError(c, err)
+		return input, false
+	}
+	return input, true
+}
+
+// actorID 取得執行操作的管理者 ID，寫入帳號狀態變更紀錄
+func actorID(c *gin.Context) *uint {
+	if caller, ok := principal.FromContext(c); ok && !caller.IsClient() {
+		return &caller.UserID
+	}
+	return nil
+}
+
+// rejectSelf 避免管理者停權或刪除自己而失去管理權限，回傳 true 代表已經回應錯誤
+func rejectSelf(c *gin.Context, id uint) bool {
+	if principal.UserID(c) == id {
+		response.Error(c, http.StatusBadRequest, exception.ErrCodeCannotActOnSelf)
+		return true
+	}
+	return false
+}
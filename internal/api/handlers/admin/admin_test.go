@@ -0,0 +1,171 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-template/internal/api/handlers/exception"
+	"go-template/internal/api/handlers/response"
+	"go-template/internal/middleware"
+	"go-template/internal/models"
+	"go-template/internal/principal"
+	userSvc "go-template/internal/services/user"
+	"go-template/internal/utils/logger"
+)
+
+func TestMain(m *testing.M) {
+	_ = logger.Init(&logger.Config{Level: "error", ConsoleOut: true, ServiceName: "admin-test"})
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+// accountActionService 只實作停權、變更狀態與永久刪除，記錄被操作的使用者
+type accountActionService struct {
+	userSvc.Service
+	acted []uint
+	err   error
+}
+
+func (s *accountActionService) SuspendUser(id uint, _ *uint, _ string) error {
+	s.acted = append(s.acted, id)
+	return s.err
+}
+
+func (s *accountActionService) ChangeUserStatus(id uint, _ models.UserStatus, _ *uint, _ string) error {
+	s.acted = append(s.acted, id)
+	return s.err
+}
+
+func (s *accountActionService) HardDeleteUser(id uint) error {
+	s.acted = append(s.acted, id)
+	return s.err
+}
+
+// newAccountActionRouter 建立以使用者 1 的身份呼叫停權、變更狀態與永久刪除的路由
+func newAccountActionRouter(svc *accountActionService) *gin.Engine {
+	handler := NewHandler(svc)
+	router := gin.New()
+	router.Use(middleware.ErrorHandler(), func(c *gin.Context) {
+		principal.Set(c, &principal.Principal{Kind: principal.KindUser, UserID: 1})
+	})
+	router.POST("/api/admin/users/:id/suspend", handler.SuspendUser)
+	router.PUT("/api/admin/users/:id/status", handler.ChangeStatus)
+	router.DELETE("/api/admin/users/:id", handler.DeleteUser)
+	return router
+}
+
+// accountActionRequests 對 id 發出停權、變更狀態與永久刪除的請求
+func accountActionRequests(id string) []*http.Request {
+	return []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/api/admin/users/"+id+"/suspend", nil),
+		httptest.NewRequest(http.MethodPut, "/api/admin/users/"+id+"/status", strings.NewReader(`{"status":"locked"}`)),
+		httptest.NewRequest(http.MethodDelete, "/api/admin/users/"+id, nil),
+	}
+}
+
+// errorMessage 解析錯誤回應的訊息
+func errorMessage(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body response.ErrorData
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return body.Message
+}
+
+// 測試管理者無法停權、變更狀態或永久刪除自己，其他使用者不受影響
+func TestRejectSelf(t *testing.T) {
+	svc := &accountActionService{}
+	router := newAccountActionRouter(svc)
+
+	for _, req := range accountActionRequests("1") {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, exception.GetErrorMessage(exception.ErrCodeCannotActOnSelf), errorMessage(t, w))
+	}
+	assert.Empty(t, svc.acted)
+
+	for _, req := range accountActionRequests("2") {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	assert.Equal(t, []uint{2, 2, 2}, svc.acted)
+}
+
+// 測試不允許的狀態轉換回應 409
+func TestInvalidStatusTransition(t *testing.T) {
+	router := newAccountActionRouter(&accountActionService{err: userSvc.ErrInvalidStatusTransition})
+
+	for _, req := range accountActionRequests("2")[:2] {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Equal(t, userSvc.ErrInvalidStatusTransition.Message, errorMessage(t, w))
+	}
+}
+
+// listUsersService 只實作 ListUsers，回傳固定的一頁
+type listUsersService struct {
+	userSvc.Service
+	page *userSvc.UserPage
+}
+
+func (s *listUsersService) ListUsers(userSvc.ListUsersQuery) (*userSvc.UserPage, error) {
+	return s.page, nil
+}
+
+// listUsers 呼叫 ListUsers 並解析回應的分頁連結
+func listUsers(t *testing.T, page *userSvc.UserPage, target string) response.PageLinks {
+	t.Helper()
+	router := gin.New()
+	router.GET("/api/admin/users", NewHandler(&listUsersService{page: page}).ListUsers)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var body response.PagedData
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return body.Links
+}
+
+// 測試下一頁連結的分頁方式
+func TestListUsersNextLink(t *testing.T) {
+	users := []models.User{{Username: "alice"}, {Username: "bob"}}
+
+	t.Run("first page of a keyset sort uses the cursor", func(t *testing.T) {
+		links := listUsers(t, &userSvc.UserPage{Users: users, HasMore: true, NextCursor: "Y3Vyc29y"},
+			"/api/admin/users?limit=2&sort=-created_at")
+		assert.Equal(t, "/api/admin/users?cursor=Y3Vyc29y&limit=2&sort=-created_at", links.Next)
+	})
+
+	t.Run("cursor page uses the next cursor", func(t *testing.T) {
+		links := listUsers(t, &userSvc.UserPage{Users: users, HasMore: true, NextCursor: "bmV4dA"},
+			"/api/admin/users?limit=2&cursor=Y3Vyc29y")
+		assert.Equal(t, "/api/admin/users?cursor=bmV4dA&limit=2", links.Next)
+	})
+
+	t.Run("offset pagination keeps using the offset", func(t *testing.T) {
+		links := listUsers(t, &userSvc.UserPage{Users: users, HasMore: true, NextCursor: "Y3Vyc29y"},
+			"/api/admin/users?limit=2&offset=2")
+		assert.Equal(t, "/api/admin/users?limit=2&offset=4", links.Next)
+	})
+
+	t.Run("sort without keyset support uses the offset", func(t *testing.T) {
+		links := listUsers(t, &userSvc.UserPage{Users: users, HasMore: true},
+			"/api/admin/users?limit=2&sort=username")
+		assert.Equal(t, "/api/admin/users?limit=2&offset=2&sort=username", links.Next)
+	})
+
+	t.Run("last page has no next link", func(t *testing.T) {
+		links := listUsers(t, &userSvc.UserPage{Users: users}, "/api/admin/users?limit=2")
+		assert.Empty(t, links.Next)
+	})
+}
@@ -0,0 +1,76 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go-template/internal/api/handlers/response"
+	"go-template/internal/principal"
+	"go-template/internal/utils/logger"
+)
+
+// Impersonate 處理代替使用者操作 (impersonation) 的請求
+// @Summary 代替使用者操作
+// @Description 客服人員取得代替使用者操作的短效期 access token，token 的 act claim 記錄實際操作的管理者 (RFC 8693)。
+// @Description token 沒有 refresh token，預設不能呼叫 DELETE 路由、變更密碼、兩步驟驗證與憑證相關的路由，
+// @Description 發行與之後每個使用該 token 的請求都會寫入稽核紀錄。不能代替自己、服務帳號或同樣可以代替他人操作的使用者
+// @Tags Admin
+// @Accept  json
+// @Produce  json
+// @Param id path int true "使用者 ID"
+// @Param body body reasonRequest false "代替操作的原因 (選填，寫入稽核紀錄)"
+// @Security BearerAuth
+// @Success 200 {object} response.SuccessData{Data=userSvc.TokenPair} "發行成功"
+// @Failure 400 {object} response.ErrorData "錯誤的請求或無法代替自己"
+// @Failure 403 {object} response.ErrorData "權限不足、使用 API key 呼叫、該使用者不能被代替操作或帳號狀態不是 active"
+// @Failure 404 {object} response.ErrorData "使用者不存在"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /admin/users/{id}/impersonate [post]
+func (h *Handler) Impersonate(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok || rejectSelf(c, id) {
+		return
+	}
+	input, ok := bindReason(c)
+	if !ok {
+		return
+	}
+
+	actorID := principal.UserID(c)
+	tokens, err := h.userService.Impersonate(actorID, id, input.Reason, c.ClientIP())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// token 一律放在回應內容中，不寫入 cookie，避免取代管理者自己的登入狀態
+	logger.Logger.Infof("User %d impersonated by admin %d", id, actorID) // INFO 等級
+	response.Success(c, http.StatusOK, "Impersonation token issued", tokens)
+}
+
+// ListImpersonations 處理取得代替操作紀錄的請求
+// @Summary 取得代替操作紀錄
+// @Description 取得使用者被代替操作的稽核紀錄 (發行的 token 與之後的每個請求)，最新的在前面，最多 200 筆
+// @Tags Admin
+// @Produce  json
+// @Param id path int true "使用者 ID"
+// @Security BearerAuth
+// @Success 200 {object} response.SuccessData{Data=[]models.ImpersonationLog} "取得成功"
+// @Failure 400 {object} response.ErrorData "錯誤的請求"
+// @Failure 403 {object} response.ErrorData "權限不足"
+// @Failure 404 {object} response.ErrorData "使用者不存在"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /admin/users/{id}/impersonations [get]
+func (h *Handler) ListImpersonations(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	logs, err := h.userService.ListImpersonations(id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.Success(c, http.StatusOK, "Impersonation logs found", logs)
+}
@@ -0,0 +1,169 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-template/internal/api/handlers/exception"
+	"go-template/internal/api/handlers/response"
+	"go-template/internal/principal"
+	userSvc "go-template/internal/services/user"
+	"go-template/internal/utils/logger"
+)
+
+// createServiceAccountRequest 建立服務帳號的請求內容
+type createServiceAccountRequest struct {
+	Username string   `json:"username" binding:"required,min=4"`
+	Email    string   `json:"email"    binding:"omitempty,email"` // 選填，不填時使用不會收到信件的地址
+	Roles    []string `json:"roles"    binding:"required,min=1"`  // 服務帳號的角色，決定 API key 可以使用的權限上限
+}
+
+// createAPIKeyRequest 建立 API key 的請求內容
+type createAPIKeyRequest struct {
+	Name      string     `json:"name"       binding:"required,max=100"`
+	Scopes    []string   `json:"scopes"     binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at" example:"2030-01-01T00:00:00Z"` // 選填，不填代表不會過期
+}
+
+// CreateServiceAccount 處理建立服務帳號的請求
+// @Summary 建立服務帳號
+// @Description 建立供批次作業與整合服務使用的服務帳號。服務帳號不能以密碼登入，只能使用管理者建立的 API key
+// @Tags Admin
+// @Accept  json
+// @Produce  json
+// @Param body body createServiceAccountRequest true "帳號名稱、電子郵件與角色"
+// @Security BearerAuth
+// @Success 201 {object} response.SuccessData{Data=models.User} "建立成功"
+// @Failure 400 {object} response.ErrorData "錯誤的請求、角色不存在，或帳號名稱、電子郵件不符合規則 (列出每個欄位的錯誤)"
+// @Failure 403 {object} response.ErrorData "權限不足或使用 API key 呼叫"
+// @Failure 409 {object} response.ErrorData "帳號名稱或電子郵件已經被使用"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /admin/service-accounts [post]
+func (h *Handler) CreateServiceAccount(c *gin.Context) {
+	var input createServiceAccountRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logger.Logger.Debugf(exception.ErrMsgInvalidRequestBody, err) // DEBUG 等級
+		response.BindError(c, err)
+		return
+	}
+
+	user, err := h.userService.CreateServiceAccount(userSvc.NewServiceAccount{
+		Username: input.Username,
+		Email:    input.Email,
+		Roles:    input.Roles,
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	logger.Logger.Infof("Service account %d created by admin %d", user.ID, principal.UserID(c)) // INFO 等級
+	response.Success(c, http.StatusCreated, "Service account created", user)
+}
+
+// ListServiceAccountAPIKeys 處理列出服務帳號 API key 的請求
+// @Summary 列出服務帳號的 API key
+// @Description 列出服務帳號尚未撤銷的 API key，只包含公開前綴，不包含完整的 key
+// @Tags Admin
+// @Produce  json
+// @Param id path int true "服務帳號 ID"
+// @Security BearerAuth
+// @Success 200 {object} response.SuccessData{Data=[]models.APIKey} "取得成功"
+// @Failure 400 {object} response.ErrorData "錯誤的請求或不是服務帳號"
+// @Failure 403 {object} response.ErrorData "權限不足或使用 API key 呼叫"
+// @Failure 404 {object} response.ErrorData "使用者不存在"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /admin/service-accounts/{id}/api-keys [get]
+func (h *Handler) ListServiceAccountAPIKeys(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	keys, err := h.userService.ListServiceAccountAPIKeys(id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "API keys found", keys)
+}
+
+// CreateServiceAccountAPIKey 處理建立服務帳號 API key 的請求
+// @Summary 建立服務帳號的 API key
+// @Description 為服務帳號建立 API key，權限必須是服務帳號目前擁有的權限；完整的 key 只會在此時回傳一次
+// @Tags Admin
+// @Accept  json
+// @Produce  json
+// @Param id path int true "服務帳號 ID"
+// @Param body body createAPIKeyRequest true "名稱、權限與過期時間"
+// @Security BearerAuth
+// @Success 201 {object} response.SuccessData{Data=userSvc.CreatedAPIKey} "建立成功"
+// @Failure 400 {object} response.ErrorData "錯誤的請求、不是服務帳號、權限不符或過期時間不在未來"
+// @Failure 403 {object} response.ErrorData "權限不足或使用 API key 呼叫"
+// @Failure 404 {object} response.ErrorData "使用者不存在"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /admin/service-accounts/{id}/api-keys [post]
+func (h *Handler) CreateServiceAccountAPIKey(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var input createAPIKeyRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logger.Logger.Debugf(exception.ErrMsgInvalidRequestBody, err) // DEBUG 等級
+		response.BindError(c, err)
+		return
+	}
+
+	key, err := h.userService.CreateServiceAccountAPIKey(id, userSvc.NewAPIKey{
+		Name:      input.Name,
+		Scopes:    input.Scopes,
+		ExpiresAt: input.ExpiresAt,
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	logger.Logger.Infof("API key %s of service account %d created by admin %d", key.Prefix, id, principal.UserID(c)) // INFO 等級
+	response.Success(c, http.StatusCreated, "API key created, store it now because it will not be shown again", key)
+}
+
+// RevokeServiceAccountAPIKey 處理撤銷服務帳號 API key 的請求
+// @Summary 撤銷服務帳號的 API key
+// @Description 撤銷服務帳號的 API key，撤銷後立即失效
+// @Tags Admin
+// @Produce  json
+// @Param id path int true "服務帳號 ID"
+// @Param keyId path int true "API key ID"
+// @Security BearerAuth
+// @Success 200 {object} response.SuccessData "撤銷成功"
+// @Failure 400 {object} response.ErrorData "錯誤的請求或不是服務帳號"
+// @Failure 403 {object} response.ErrorData "權限不足或使用 API key 呼叫"
+// @Failure 404 {object} response.ErrorData "使用者或 API key 不存在"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /admin/service-accounts/{id}/api-keys/{keyId} [delete]
+func (h *Handler) RevokeServiceAccountAPIKey(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+	keyID, err := strconv.ParseUint(c.Param("keyId"), 10, 0)
+	if err != nil || keyID == 0 {
+		logger.Logger.Debugf("Invalid API key ID in path: %s", c.Param("keyId")) // DEBUG 等級
+		response.Error(c, http.StatusBadRequest, exception.ErrCodeInvalidRequest)
+		return
+	}
+
+	if err := h.userService.RevokeServiceAccountAPIKey(id, uint(keyID)); err != nil {
+		c.Error(err)
+		return
+	}
+
+	logger.Logger.Infof("API key %d of service account %d revoked by admin %d", keyID, id, principal.UserID(c)) // INFO 等級
+	response.Success(c, http.StatusOK, "API key revoked", nil)
+}
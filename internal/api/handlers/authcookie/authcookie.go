@@ -0,0 +1,148 @@
+package authcookie
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go-template/internal/configs"
+	"go-template/internal/constants"
+	userSvc "go-template/internal/services/user"
+	"go-template/internal/utils/jwt"
+)
+
+// cookie 與 header 的名稱
+const (
+	AccessTokenCookie  = "access_token"  // HttpOnly，保存 access token
+	RefreshTokenCookie = "refresh_token" // HttpOnly，保存 refresh token，只會送到換發 token 的路徑
+	CSRFCookie         = "csrf_token"    // 前端可以讀取的 CSRF token，必須放在 CSRFHeader 中送回 (double-submit)
+	CSRFHeader         = "X-CSRF-Token"
+	ModeHeader         = "X-Auth-Mode" // 登入時值為 cookie 代表以 cookie 回傳 token
+)
+
+const (
+	modeCookie       = "cookie"
+	refreshTokenPath = "/api/user/token" // refresh token cookie 只需要送到 /api/user/token/refresh
+)
+
+// Manager 負責瀏覽器用戶端的 cookie 驗證模式：設定、讀取與清除保存 token 的 cookie
+// AUTH_COOKIE_ENABLED 沒有開啟時不會設定 cookie，也不會從 cookie 讀取 token
+type Manager struct {
+	enabled       bool
+	secure        bool
+	sameSite      http.SameSite
+	domain        string
+	refreshMaxAge int // refresh token 與 CSRF token cookie 的有效秒數
+}
+
+// New 根據設定建立 Manager，SameSite 設定無效時回傳錯誤
+func New(cfg *configs.Config) (*Manager, error) {
+	var sameSite http.SameSite
+	switch strings.ToLower(cfg.AuthCookieSameSite) {
+	case "strict":
+		sameSite = http.SameSiteStrictMode
+	case "lax":
+		sameSite = http.SameSiteLaxMode
+	case "none":
+		// 瀏覽器會拒絕沒有 Secure 屬性的 SameSite=None cookie
+		if cfg.AuthCookieEnabled && !cfg.AuthCookieSecure {
+			return nil, fmt.Errorf("AUTH_COOKIE_SAMESITE=none requires AUTH_COOKIE_SECURE=true")
+		}
+		sameSite = http.SameSiteNoneMode
+	default:
+		return nil, fmt.Errorf("unsupported AUTH_COOKIE_SAMESITE: %s", cfg.AuthCookieSameSite)
+	}
+
+	return &Manager{
+		enabled:       cfg.AuthCookieEnabled,
+		secure:        cfg.AuthCookieSecure,
+		sameSite:      sameSite,
+		domain:        cfg.AuthCookieDomain,
+		refreshMaxAge: int(cfg.RefreshTokenExpiresIn.Seconds()),
+	}, nil
+}
+
+// Enabled 是否開啟 cookie 驗證模式
+func (m *Manager) Enabled() bool {
+	return m.enabled
+}
+
+// Requested 這次請求的結果是否應該以 cookie 回傳
+// 登入時由 X-Auth-Mode: cookie 指定；已經使用 cookie 驗證的請求 (例如變更密碼) 繼續使用 cookie
+func (m *Manager) Requested(c *gin.Context) bool {
+	if !m.enabled {
+		return false
+	}
+	return c.GetBool(constants.CtxCookieAuthKey) || strings.EqualFold(c.GetHeader(ModeHeader), modeCookie)
+}
+
+// AccessToken 從 cookie 取得 access token
+func (m *Manager) AccessToken(c *gin.Context) (string, bool) {
+	return m.cookie(c, AccessTokenCookie)
+}
+
+// RefreshToken 從 cookie 取得 refresh token
+func (m *Manager) RefreshToken(c *gin.Context) (string, bool) {
+	return m.cookie(c, RefreshTokenCookie)
+}
+
+// SetTokens 將 token 組合寫入 HttpOnly cookie，並產生新的 CSRF token
+// 回傳不包含 token 的 TokenPair 作為回應內容，避免 token 被前端的 JavaScript 讀取
+func (m *Manager) SetTokens(c *gin.Context, tokens *userSvc.TokenPair) (*userSvc.TokenPair, error) {
+	csrfToken, err := jwt.NewTokenID()
+	if err != nil {
+		return nil, err
+	}
+
+	m.set(c, AccessTokenCookie, tokens.AccessToken, "/", int(tokens.ExpiresIn), true)
+	m.set(c, RefreshTokenCookie, tokens.RefreshToken, refreshTokenPath, m.refreshMaxAge, true)
+	m.set(c, CSRFCookie, csrfToken, "/", m.refreshMaxAge, false)
+
+	return &userSvc.TokenPair{TokenType: tokens.TokenType, ExpiresIn: tokens.ExpiresIn}, nil
+}
+
+// Clear 清除保存 token 與 CSRF token 的 cookie
+func (m *Manager) Clear(c *gin.Context) {
+	m.set(c, AccessTokenCookie, "", "/", -1, true)
+	m.set(c, RefreshTokenCookie, "", refreshTokenPath, -1, true)
+	m.set(c, CSRFCookie, "", "/", -1, false)
+}
+
+// VerifyCSRF 檢查 CSRFHeader 與 CSRF cookie 是否相同 (double-submit cookie)
+// 其他網站可以讓瀏覽器帶上 cookie，但無法讀取 cookie 的內容放進 header
+func VerifyCSRF(c *gin.Context) bool {
+	cookie, err := c.Cookie(CSRFCookie)
+	if err != nil || cookie == "" {
+		return false
+	}
+	header := c.GetHeader(CSRFHeader)
+	return subtle.ConstantTimeCompare([]byte(cookie), []byte(header)) == 1
+}
+
+// cookie 取得 cookie 的值，沒有開啟 cookie 驗證模式時一律視為不存在
+func (m *Manager) cookie(c *gin.Context, name string) (string, bool) {
+	if !m.enabled {
+		return "", false
+	}
+	value, err := c.Cookie(name)
+	if err != nil || value == "" {
+		return "", false
+	}
+	return value, true
+}
+
+// set 寫入 cookie，maxAge 為負數代表刪除
+func (m *Manager) set(c *gin.Context, name, value, path string, maxAge int, httpOnly bool) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   m.domain,
+		MaxAge:   maxAge,
+		Secure:   m.secure,
+		HttpOnly: httpOnly,
+		SameSite: m.sameSite,
+	})
+}
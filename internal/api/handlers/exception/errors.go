@@ -1,14 +1,20 @@
 package exception
 
-// 定義使用者相關的錯誤碼
+// 定義處理函數與中介軟體自己回應的錯誤碼
+// service 回傳的錯誤 (例如 user_not_found) 定義在各 service 的 *apperror.Error 上，由 ErrorHandler 中介軟體回應，不在此重複定義
 const (
 	_ = iota // 忽略第一個值，從 1 開始
-	ErrCodeUserNotFound
-	ErrCodeInvalidCredentials
 	ErrCodeInvalidRequest
 	ErrCodeUnknown
 	ErrCodeUserIDNotInContext
 	ErrCodeUserIDFormatInvalid
+	ErrCodeTokenRevoked
+	ErrCodeForbidden
+	ErrCodeCannotActOnSelf
+	ErrCodeAPIKeyNotAllowed
+	ErrCodeClientNotAllowed
+	ErrCodeInvalidCSRFToken
+	ErrCodeImpersonationNotAllowed
 )
 
 // 定義通用的錯誤訊息常數
@@ -18,21 +24,43 @@ const (
 	ErrMsgUserIDFormatInvalid = "Invalid user ID format"
 )
 
-// 定義錯誤碼和錯誤訊息的對應關係
-var errorMessages = map[int]string{
-	ErrCodeUserNotFound:        "user not found",
-	ErrCodeInvalidCredentials:  "invalid credentials",
-	ErrCodeInvalidRequest:      "invalid request",
-	ErrCodeUnknown:             "unknown error",
-	ErrCodeUserIDNotInContext:  "user ID not found in context",
-	ErrCodeUserIDFormatInvalid: "user ID format invalid",
+// errorDefinition 錯誤碼對應的字串代碼與錯誤訊息
+// 字串代碼會回應給用戶端，讓用戶端不需要比對錯誤訊息；代碼一旦公開就不能變更
+type errorDefinition struct {
+	code    string // 穩定的字串代碼，例如 user_not_found
+	message string // 給人看的錯誤訊息
+}
+
+// 定義錯誤碼、字串代碼和錯誤訊息的對應關係
+var errorDefinitions = map[int]errorDefinition{
+	ErrCodeInvalidRequest:          {"invalid_request", "invalid request"},
+	ErrCodeUnknown:                 {"unknown", "unknown error"},
+	ErrCodeUserIDNotInContext:      {"user_id_not_in_context", "user ID not found in context"},
+	ErrCodeUserIDFormatInvalid:     {"user_id_format_invalid", "user ID format invalid"},
+	ErrCodeTokenRevoked:            {"token_revoked", "token has been revoked"},
+	ErrCodeForbidden:               {"forbidden", "permission denied"},
+	ErrCodeCannotActOnSelf:         {"cannot_act_on_self", "administrators cannot perform this action on their own account"},
+	ErrCodeAPIKeyNotAllowed:        {"api_key_not_allowed", "this endpoint cannot be called with an API key"},
+	ErrCodeClientNotAllowed:        {"client_not_allowed", "this endpoint can only be called on behalf of a user"},
+	ErrCodeInvalidCSRFToken:        {"invalid_csrf_token", "missing or invalid CSRF token"},
+	ErrCodeImpersonationNotAllowed: {"impersonation_not_allowed", "this endpoint cannot be called while impersonating a user"},
 }
 
 // GetErrorMessage 根據錯誤碼取得對應的錯誤訊息
 func GetErrorMessage(errCode int) string {
-	message, ok := errorMessages[errCode]
+	return definition(errCode).message
+}
+
+// GetErrorCode 根據錯誤碼取得對應的字串代碼
+func GetErrorCode(errCode int) string {
+	return definition(errCode).code
+}
+
+// definition 取得錯誤碼的定義，未定義的錯誤碼視為 ErrCodeUnknown
+func definition(errCode int) errorDefinition {
+	def, ok := errorDefinitions[errCode]
 	if !ok {
-		return errorMessages[ErrCodeUnknown]
+		return errorDefinitions[ErrCodeUnknown]
 	}
-	return message
+	return def
 }
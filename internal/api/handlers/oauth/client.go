@@ -0,0 +1,107 @@
+package oauth
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go-template/internal/api/handlers/exception"
+	"go-template/internal/api/handlers/response"
+	"go-template/internal/principal"
+	oauthSvc "go-template/internal/services/oauth"
+	"go-template/internal/utils/logger"
+)
+
+// createClientRequest 註冊 OAuth2 client 的請求內容
+type createClientRequest struct {
+	Name   string   `json:"name"   binding:"required,max=100"` // 用途說明，例如 "billing-service"
+	Scopes []string `json:"scopes" binding:"required,min=1"`   // 允許取得的權限，例如 ["users:read"]
+}
+
+// CreateClient 處理註冊 OAuth2 client 的請求
+// @Summary 註冊 OAuth2 client
+// @Description 註冊供其他內部服務使用的 OAuth2 client，權限必須是自己目前擁有的權限。
+// @Description client_secret 只會在此時回傳一次，client 以 client credentials grant 向 /oauth/token 取得 access token
+// @Tags Admin
+// @Accept  json
+// @Produce  json
+// @Param body body createClientRequest true "名稱與權限"
+// @Security BearerAuth
+// @Success 201 {object} response.SuccessData{Data=oauthSvc.CreatedClient} "註冊成功"
+// @Failure 400 {object} response.ErrorData "錯誤的請求或權限不符"
+// @Failure 403 {object} response.ErrorData "權限不足，或使用 API key 或 client 的 token 呼叫"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /admin/oauth-clients [post]
+func (h *Handler) CreateClient(c *gin.Context) {
+	caller, ok := principal.FromContext(c)
+	if !ok || caller.IsClient() {
+		logger.Logger.Debugf(exception.ErrMsgUserIDNotInContext) // DEBUG 等級
+		response.Error(c, http.StatusInternalServerError, exception.ErrCodeUserIDNotInContext)
+		return
+	}
+
+	var input createClientRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logger.Logger.Debugf(exception.ErrMsgInvalidRequestBody, err) // DEBUG 等級
+		response.BindError(c, err)
+		return
+	}
+
+	client, err := h.oauthService.CreateClient(caller.UserID, oauthSvc.NewClient{Name: input.Name, Scopes: input.Scopes})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "OAuth client registered, store the secret now because it will not be shown again", client)
+}
+
+// ListClients 處理列出 OAuth2 client 的請求
+// @Summary 列出 OAuth2 client
+// @Description 列出尚未停用的 OAuth2 client，不包含 client secret
+// @Tags Admin
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} response.SuccessData{Data=[]models.OAuthClient} "取得成功"
+// @Failure 403 {object} response.ErrorData "權限不足，或使用 API key 或 client 的 token 呼叫"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /admin/oauth-clients [get]
+func (h *Handler) ListClients(c *gin.Context) {
+	clients, err := h.oauthService.ListClients()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "OAuth clients found", clients)
+}
+
+// DisableClient 處理停用 OAuth2 client 的請求
+// @Summary 停用 OAuth2 client
+// @Description 停用 OAuth2 client，之後不能再取得 token，已發行的 token 立即失效
+// @Tags Admin
+// @Produce  json
+// @Param id path int true "client 的資料 ID"
+// @Security BearerAuth
+// @Success 200 {object} response.SuccessData "停用成功"
+// @Failure 400 {object} response.ErrorData "錯誤的請求"
+// @Failure 403 {object} response.ErrorData "權限不足，或使用 API key 或 client 的 token 呼叫"
+// @Failure 404 {object} response.ErrorData "client 不存在或已停用"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /admin/oauth-clients/{id} [delete]
+func (h *Handler) DisableClient(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil || id == 0 {
+		logger.Logger.Debugf("Invalid OAuth client ID in path: %s", c.Param("id")) // DEBUG 等級
+		response.Error(c, http.StatusBadRequest, exception.ErrCodeInvalidRequest)
+		return
+	}
+
+	if err := h.oauthService.DisableClient(uint(id)); err != nil {
+		c.Error(err)
+		return
+	}
+
+	logger.Logger.Infof("OAuth client %d disabled by admin %d", id, principal.UserID(c)) // INFO 等級
+	response.Success(c, http.StatusOK, "OAuth client disabled", nil)
+}
@@ -0,0 +1,281 @@
+package oauth
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go-template/internal/apperror"
+	"go-template/internal/models"
+	oauthSvc "go-template/internal/services/oauth"
+	userSvc "go-template/internal/services/user"
+	"go-template/internal/utils/logger"
+)
+
+// 支援的 grant_type (RFC 6749)
+const (
+	grantTypeClientCredentials = "client_credentials"
+	grantTypeRefreshToken      = "refresh_token"
+)
+
+// 錯誤回應中的 error 欄位 (RFC 6749 5.2、RFC 7009 2.2.1)
+const (
+	errInvalidRequest       = "invalid_request"
+	errInvalidClient        = "invalid_client"
+	errInvalidGrant         = "invalid_grant"
+	errInvalidScope         = "invalid_scope"
+	errUnauthorizedClient   = "unauthorized_client"
+	errUnsupportedGrantType = "unsupported_grant_type"
+	errServerError          = "server_error"
+)
+
+// ErrorResponse OAuth2 標準的錯誤回應 (RFC 6749 5.2)
+// OAuth2 端點的用戶端通常是現成的函式庫，因此直接使用標準格式，不包在 response.ErrorData 中
+type ErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// Handler struct，用於處理 OAuth2 token、introspection 與 revocation 端點，以及 client 的管理
+type Handler struct {
+	oauthService oauthSvc.Service
+	userService  userSvc.Service
+}
+
+// NewHandler 建立一個新的 oauth Handler 實例
+func NewHandler(oauthService oauthSvc.Service, userService userSvc.Service) *Handler {
+	return &Handler{oauthService: oauthService, userService: userService}
+}
+
+// Token 處理發行 token 的請求
+// @Summary 取得 token
+// @Description OAuth2 token 端點 (RFC 6749)，請求使用 application/x-www-form-urlencoded。
+// @Description grant_type=client_credentials 必須提供 client 驗證 (HTTP Basic 或 client_id/client_secret)，scope 省略時取得 client 所有的權限，不會發行 refresh token。
+// @Description grant_type=refresh_token 使用登入取得的 refresh token 換發新的 token 組合，client 驗證為選填
+// @Tags OAuth
+// @Accept  x-www-form-urlencoded
+// @Produce  json
+// @Param grant_type formData string true "client_credentials 或 refresh_token"
+// @Param scope formData string false "以空白分隔的權限 (client_credentials)"
+// @Param refresh_token formData string false "refresh token (refresh_token)"
+// @Param client_id formData string false "client ID (沒有使用 HTTP Basic 時)"
+// @Param client_secret formData string false "client secret (沒有使用 HTTP Basic 時)"
+// @Success 200 {object} oauthSvc.ClientToken "發行成功 (refresh_token 時為 userSvc.TokenPair)"
+// @Failure 400 {object} ErrorResponse "錯誤的請求、不支援的 grant_type、無效的 refresh token 或權限"
+// @Failure 401 {object} ErrorResponse "client 驗證失敗"
+// @Failure 500 {object} ErrorResponse "系統錯誤"
+// @Router /oauth/token [post]
+func (h *Handler) Token(c *gin.Context) {
+	// token 回應不可以被快取 (RFC 6749 5.1)
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	client, ok := h.authenticateClient(c, false)
+	if !ok {
+		return
+	}
+
+	switch c.PostForm("grant_type") {
+	case grantTypeClientCredentials:
+		if client == nil {
+			respondInvalidClient(c, "client authentication is required")
+			return
+		}
+		token, err := h.oauthService.ClientCredentials(client, c.PostForm("scope"))
+		if err != nil {
+			if errors.Is(err, oauthSvc.ErrInvalidScope) {
+				respondError(c, http.StatusBadRequest, errInvalidScope, "requested scope exceeds the scopes of the client")
+			} else {
+				logger.Logger.Errorf("Error issuing client token: %v", err) // ERROR 等級
+				respondError(c, http.StatusInternalServerError, errServerError, "")
+			}
+			return
+		}
+		c.JSON(http.StatusOK, token)
+
+	case grantTypeRefreshToken:
+		refreshToken := c.PostForm("refresh_token")
+		if refreshToken == "" {
+			respondError(c, http.StatusBadRequest, errInvalidRequest, "refresh_token is required")
+			return
+		}
+		// 使用者的 token 沒有 scope，不能在換發時縮小權限
+		if c.PostForm("scope") != "" {
+			respondError(c, http.StatusBadRequest, errInvalidScope, "scope is not supported for refresh tokens")
+			return
+		}
+		tokens, err := h.userService.RefreshToken(refreshToken)
+		if err != nil {
+			logger.Logger.Debugf("Error refreshing token: %v", err) // DEBUG 等級
+			switch {
+			case errors.Is(err, userSvc.ErrInvalidRefreshToken):
+				respondError(c, http.StatusBadRequest, errInvalidGrant, "invalid refresh token")
+			case errors.Is(err, userSvc.ErrRefreshTokenReused):
+				respondError(c, http.StatusBadRequest, errInvalidGrant, userSvc.ErrRefreshTokenReused.Message)
+			default:
+				// 帳號不是 active 或需要重設密碼時以 invalid_grant 回應原因
+				if appErr, ok := apperror.As(err); ok && appErr.Kind == apperror.KindForbidden {
+					respondError(c, http.StatusBadRequest, errInvalidGrant, appErr.Message)
+				} else {
+					logger.Logger.Errorf("Error refreshing token: %v", err) // ERROR 等級
+					respondError(c, http.StatusInternalServerError, errServerError, "")
+				}
+			}
+			return
+		}
+		c.JSON(http.StatusOK, tokens)
+
+	case "":
+		respondError(c, http.StatusBadRequest, errInvalidRequest, "grant_type is required")
+
+	default:
+		respondError(c, http.StatusBadRequest, errUnsupportedGrantType, "")
+	}
+}
+
+// Introspect 處理檢查 token 的請求
+// @Summary 檢查 token
+// @Description OAuth2 token introspection 端點 (RFC 7662)，必須提供 client 驗證。
+// @Description token 無效、過期、已撤銷，或所屬的帳號或 client 已停用時只回傳 {"active": false}
+// @Tags OAuth
+// @Accept  x-www-form-urlencoded
+// @Produce  json
+// @Param token formData string true "要檢查的 access token 或 refresh token"
+// @Param token_type_hint formData string false "access_token 或 refresh_token"
+// @Success 200 {object} oauthSvc.Introspection "檢查結果"
+// @Failure 400 {object} ErrorResponse "錯誤的請求"
+// @Failure 401 {object} ErrorResponse "client 驗證失敗"
+// @Failure 500 {object} ErrorResponse "系統錯誤"
+// @Router /oauth/introspect [post]
+func (h *Handler) Introspect(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+
+	if _, ok := h.authenticateClient(c, true); !ok {
+		return
+	}
+	token := c.PostForm("token")
+	if token == "" {
+		respondError(c, http.StatusBadRequest, errInvalidRequest, "token is required")
+		return
+	}
+
+	result, err := h.oauthService.Introspect(token, c.PostForm("token_type_hint"))
+	if err != nil {
+		logger.Logger.Errorf("Error introspecting token: %v", err) // ERROR 等級
+		respondError(c, http.StatusInternalServerError, errServerError, "")
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// Revoke 處理撤銷 token 的請求
+// @Summary 撤銷 token
+// @Description OAuth2 token revocation 端點 (RFC 7009)。撤銷 refresh token 時同一次登入的 refresh token 都會失效。
+// @Description client 的 token 必須由同一個 client 撤銷 (需要 client 驗證)，使用者的 token 持有者即可撤銷；無效的 token 一樣回傳 200
+// @Tags OAuth
+// @Accept  x-www-form-urlencoded
+// @Produce  json
+// @Param token formData string true "要撤銷的 access token 或 refresh token"
+// @Param token_type_hint formData string false "access_token 或 refresh_token"
+// @Success 200 "撤銷成功或 token 無效"
+// @Failure 400 {object} ErrorResponse "錯誤的請求，或 token 屬於其他 client"
+// @Failure 401 {object} ErrorResponse "client 驗證失敗"
+// @Failure 500 {object} ErrorResponse "系統錯誤"
+// @Router /oauth/revoke [post]
+func (h *Handler) Revoke(c *gin.Context) {
+	client, ok := h.authenticateClient(c, false)
+	if !ok {
+		return
+	}
+	token := c.PostForm("token")
+	if token == "" {
+		respondError(c, http.StatusBadRequest, errInvalidRequest, "token is required")
+		return
+	}
+
+	if err := h.oauthService.Revoke(client, token, c.PostForm("token_type_hint")); err != nil {
+		if errors.Is(err, oauthSvc.ErrUnauthorizedClient) {
+			respondError(c, http.StatusBadRequest, errUnauthorizedClient, "the token was issued to another client")
+		} else {
+			logger.Logger.Errorf("Error revoking token: %v", err) // ERROR 等級
+			respondError(c, http.StatusInternalServerError, errServerError, "")
+		}
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// authenticateClient 依照 RFC 6749 2.3.1 驗證 client，支援 HTTP Basic 與表單中的 client_id/client_secret
+// 沒有提供 client 驗證時，required 為 false 則回傳 nil；驗證失敗時直接回應錯誤並回傳 false
+func (h *Handler) authenticateClient(c *gin.Context, required bool) (*models.OAuthClient, bool) {
+	clientID, clientSecret, found, err := clientCredentials(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, errInvalidRequest, err.Error())
+		return nil, false
+	}
+	if !found {
+		if required {
+			respondInvalidClient(c, "client authentication is required")
+			return nil, false
+		}
+		return nil, true
+	}
+
+	client, err := h.oauthService.AuthenticateClient(clientID, clientSecret)
+	if err != nil {
+		if errors.Is(err, oauthSvc.ErrInvalidClient) {
+			logger.Logger.Debugf("OAuth client authentication failed for %s", clientID) // DEBUG 等級
+			respondInvalidClient(c, "client authentication failed")
+		} else {
+			logger.Logger.Errorf("Error authenticating OAuth client: %v", err) // ERROR 等級
+			respondError(c, http.StatusInternalServerError, errServerError, "")
+		}
+		return nil, false
+	}
+	return client, true
+}
+
+// clientCredentials 從 Authorization: Basic 或表單取得 client ID 與 client secret
+// HTTP Basic 的帳號密碼需要先經過 form-urlencoded 解碼；同時使用兩種方式是錯誤的請求
+func clientCredentials(c *gin.Context) (clientID, clientSecret string, found bool, err error) {
+	formID, formSecret := c.PostForm("client_id"), c.PostForm("client_secret")
+
+	if strings.HasPrefix(c.GetHeader("Authorization"), "Basic ") {
+		basicID, basicSecret, ok := c.Request.BasicAuth()
+		if !ok {
+			return "", "", false, errors.New("malformed basic authorization header")
+		}
+		if formSecret != "" {
+			return "", "", false, errors.New("multiple client authentication methods used")
+		}
+		if clientID, err = url.QueryUnescape(basicID); err != nil {
+			return "", "", false, errors.New("malformed client ID")
+		}
+		if clientSecret, err = url.QueryUnescape(basicSecret); err != nil {
+			return "", "", false, errors.New("malformed client secret")
+		}
+		// client_id 可以同時出現在表單中，但必須與 HTTP Basic 的一致
+		if formID != "" && formID != clientID {
+			return "", "", false, errors.New("client_id does not match the authorization header")
+		}
+		return clientID, clientSecret, true, nil
+	}
+
+	if formID == "" || formSecret == "" {
+		return "", "", false, nil
+	}
+	return formID, formSecret, true, nil
+}
+
+// respondInvalidClient 回應 client 驗證失敗，依照 RFC 6749 5.2 使用 401 以及 WWW-Authenticate header
+func respondInvalidClient(c *gin.Context, description string) {
+	c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+	respondError(c, http.StatusUnauthorized, errInvalidClient, description)
+}
+
+// respondError 回應 OAuth2 標準格式的錯誤
+func respondError(c *gin.Context, statusCode int, code, description string) {
+	c.JSON(statusCode, ErrorResponse{Error: code, ErrorDescription: description})
+}
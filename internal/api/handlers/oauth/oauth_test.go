@@ -0,0 +1,236 @@
+package oauth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-template/internal/models"
+	oauthSvc "go-template/internal/services/oauth"
+	"go-template/internal/utils/logger"
+)
+
+func TestMain(m *testing.M) {
+	_ = logger.Init(&logger.Config{Level: "error", ConsoleOut: true, ServiceName: "oauth-handler-test"})
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+// tokenService 只接受一個 client，記錄 token、introspection 與 revocation 收到的 client 與參數
+type tokenService struct {
+	oauthSvc.Service
+	client       *models.OAuthClient
+	secret       string
+	scope        string
+	introspected []string
+	revokedBy    []*models.OAuthClient
+	err          error
+}
+
+func (s *tokenService) AuthenticateClient(clientID, clientSecret string) (*models.OAuthClient, error) {
+	if clientID != s.client.ClientID || clientSecret != s.secret {
+		return nil, oauthSvc.ErrInvalidClient
+	}
+	return s.client, nil
+}
+
+func (s *tokenService) ClientCredentials(client *models.OAuthClient, scope string) (*oauthSvc.ClientToken, error) {
+	s.scope = scope
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &oauthSvc.ClientToken{AccessToken: "access-token", TokenType: "Bearer", ExpiresIn: 60, Scope: scope}, nil
+}
+
+func (s *tokenService) Introspect(token, _ string) (*oauthSvc.Introspection, error) {
+	s.introspected = append(s.introspected, token)
+	return &oauthSvc.Introspection{Active: true, ClientID: s.client.ClientID}, nil
+}
+
+func (s *tokenService) Revoke(client *models.OAuthClient, _, _ string) error {
+	s.revokedBy = append(s.revokedBy, client)
+	return s.err
+}
+
+// newTokenService 建立只接受 client gtc_0123456789ab 的 tokenService
+func newTokenService() *tokenService {
+	return &tokenService{
+		client: &models.OAuthClient{ID: 1, ClientID: "gtc_0123456789ab", Scopes: []string{models.PermissionUsersRead}},
+		secret: "s3cret",
+	}
+}
+
+// newRouter 建立 token、introspection 與 revocation 端點的路由
+func newRouter(svc *tokenService) *gin.Engine {
+	handler := NewHandler(svc, nil)
+	router := gin.New()
+	router.POST("/oauth/token", handler.Token)
+	router.POST("/oauth/introspect", handler.Introspect)
+	router.POST("/oauth/revoke", handler.Revoke)
+	return router
+}
+
+// postForm 發出表單請求，basic 不是空的時以 HTTP Basic 驗證 client
+func postForm(router *gin.Engine, path string, form url.Values, basic ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if len(basic) == 2 {
+		req.SetBasicAuth(basic[0], basic[1])
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// errorCode 解析 OAuth2 錯誤回應的 error 欄位
+func errorCode(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return body.Error
+}
+
+// 測試 client credentials grant 可以使用 HTTP Basic 或表單驗證 client，並把 scope 交給服務縮小權限
+func TestTokenClientAuthentication(t *testing.T) {
+	svc := newTokenService()
+	router := newRouter(svc)
+
+	w := postForm(router, "/oauth/token", url.Values{"grant_type": {"client_credentials"}, "scope": {"users:read"}},
+		"gtc_0123456789ab", "s3cret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	assert.Equal(t, "users:read", svc.scope)
+
+	w = postForm(router, "/oauth/token", url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {"gtc_0123456789ab"},
+		"client_secret": {"s3cret"},
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "", svc.scope)
+
+	// HTTP Basic 的帳號密碼以 form-urlencoded 編碼
+	svc.secret = "s3cret/+="
+	w = postForm(router, "/oauth/token", url.Values{"grant_type": {"client_credentials"}},
+		"gtc_0123456789ab", url.QueryEscape("s3cret/+="))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+// 測試 client 驗證失敗、沒有驗證或同時使用兩種驗證方式時不發行 token
+func TestTokenClientAuthenticationRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		form   url.Values
+		basic  []string
+		status int
+		code   string
+	}{
+		{
+			name:   "wrong secret with basic",
+			form:   url.Values{"grant_type": {"client_credentials"}},
+			basic:  []string{"gtc_0123456789ab", "wrong"},
+			status: http.StatusUnauthorized,
+			code:   errInvalidClient,
+		},
+		{
+			name:   "wrong secret in form",
+			form:   url.Values{"grant_type": {"client_credentials"}, "client_id": {"gtc_0123456789ab"}, "client_secret": {"wrong"}},
+			status: http.StatusUnauthorized,
+			code:   errInvalidClient,
+		},
+		{
+			name:   "no client authentication",
+			form:   url.Values{"grant_type": {"client_credentials"}, "client_id": {"gtc_0123456789ab"}},
+			status: http.StatusUnauthorized,
+			code:   errInvalidClient,
+		},
+		{
+			name:   "both authentication methods",
+			form:   url.Values{"grant_type": {"client_credentials"}, "client_secret": {"s3cret"}},
+			basic:  []string{"gtc_0123456789ab", "s3cret"},
+			status: http.StatusBadRequest,
+			code:   errInvalidRequest,
+		},
+		{
+			name:   "client_id does not match basic",
+			form:   url.Values{"grant_type": {"client_credentials"}, "client_id": {"gtc_ba9876543210"}},
+			basic:  []string{"gtc_0123456789ab", "s3cret"},
+			status: http.StatusBadRequest,
+			code:   errInvalidRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTokenService()
+			w := postForm(newRouter(svc), "/oauth/token", tt.form, tt.basic...)
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.code, errorCode(t, w))
+			if tt.status == http.StatusUnauthorized {
+				assert.Equal(t, `Basic realm="oauth"`, w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+// 測試要求 client 沒有的權限時回應 invalid_scope
+func TestTokenInvalidScope(t *testing.T) {
+	svc := newTokenService()
+	svc.err = oauthSvc.ErrInvalidScope
+
+	w := postForm(newRouter(svc), "/oauth/token", url.Values{"grant_type": {"client_credentials"}, "scope": {"users:delete"}},
+		"gtc_0123456789ab", "s3cret")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, errInvalidScope, errorCode(t, w))
+	assert.Equal(t, "users:delete", svc.scope)
+}
+
+// 測試 introspection 必須驗證 client，沒有驗證或驗證失敗時不檢查 token
+func TestIntrospectRequiresClient(t *testing.T) {
+	svc := newTokenService()
+	router := newRouter(svc)
+
+	w := postForm(router, "/oauth/introspect", url.Values{"token": {"access-token"}})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, errInvalidClient, errorCode(t, w))
+	assert.Equal(t, `Basic realm="oauth"`, w.Header().Get("WWW-Authenticate"))
+
+	w = postForm(router, "/oauth/introspect", url.Values{"token": {"access-token"}}, "gtc_0123456789ab", "wrong")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Empty(t, svc.introspected)
+
+	w = postForm(router, "/oauth/introspect", url.Values{"token": {"access-token"}}, "gtc_0123456789ab", "s3cret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"access-token"}, svc.introspected)
+	var result oauthSvc.Introspection
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.True(t, result.Active)
+}
+
+// 測試撤銷其他 client 的 token 時回應 unauthorized_client，沒有驗證 client 時以 nil 交給服務判斷
+func TestRevokeOtherClientToken(t *testing.T) {
+	svc := newTokenService()
+	svc.err = oauthSvc.ErrUnauthorizedClient
+	router := newRouter(svc)
+
+	w := postForm(router, "/oauth/revoke", url.Values{"token": {"access-token"}}, "gtc_0123456789ab", "s3cret")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, errUnauthorizedClient, errorCode(t, w))
+
+	w = postForm(router, "/oauth/revoke", url.Values{"token": {"access-token"}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, errUnauthorizedClient, errorCode(t, w))
+	require.Len(t, svc.revokedBy, 2)
+	assert.Equal(t, svc.client, svc.revokedBy[0])
+	assert.Nil(t, svc.revokedBy[1])
+
+	svc.err = nil
+	w = postForm(router, "/oauth/revoke", url.Values{"token": {"access-token"}}, "gtc_0123456789ab", "s3cret")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,19 @@
+package response
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go-template/internal/api/handlers/exception"
+	"go-template/internal/validators"
+)
+
+// BindError 回應 ShouldBindJSON、ShouldBindQuery 等解析請求內容失敗的錯誤
+// 違反 binding tag 的欄位與型別錯誤的欄位會逐一列出，其他錯誤 (例如 JSON 格式錯誤) 只回應 invalid_request
+func BindError(c *gin.Context, err error) {
+	if fields := validators.FieldErrors(err); len(fields) > 0 {
+		ValidationError(c, http.StatusBadRequest, exception.ErrCodeInvalidRequest, fields)
+		return
+	}
+	Error(c, http.StatusBadRequest, exception.ErrCodeInvalidRequest)
+}
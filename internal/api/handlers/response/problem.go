@@ -0,0 +1,107 @@
+package response
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go-template/internal/apperror"
+	"go-template/internal/constants"
+	"go-template/internal/utils/logger"
+)
+
+// 錯誤回應的格式 (ERROR_FORMAT)
+const (
+	FormatLegacy  = "legacy"  // ErrorData：{success, message, code, request_id, details}
+	FormatProblem = "problem" // RFC 7807 application/problem+json
+)
+
+// ProblemContentType RFC 7807 錯誤回應的 Content-Type
+const ProblemContentType = "application/problem+json"
+
+// problemByDefault 沒有要求 application/problem+json 的請求是否也使用 problem 格式，由 SetDefaultFormat 設定
+var problemByDefault bool
+
+// SetDefaultFormat 設定錯誤回應的預設格式，在啟動 server 時依照 ERROR_FORMAT 呼叫一次
+// 不認得的格式視為 legacy
+func SetDefaultFormat(format string) {
+	switch format {
+	case FormatProblem:
+		problemByDefault = true
+	case FormatLegacy, "":
+		problemByDefault = false
+	default:
+		logger.Logger.Warnf("Unknown error format %q, using %s", format, FormatLegacy)
+		problemByDefault = false
+	}
+}
+
+// Problem RFC 7807 的錯誤回應，code、request_id、errors 與 details 是擴充欄位
+type Problem struct {
+	Type      string       `json:"type"`                 // 固定為 about:blank，錯誤種類以 code 區分
+	Title     string       `json:"title"`                // HTTP 狀態碼的說明，例如 Not Found
+	Status    int          `json:"status"`               // HTTP 狀態碼
+	Detail    string       `json:"detail"`               // 給人看的錯誤訊息
+	Instance  string       `json:"instance,omitempty"`   // 發生錯誤的請求路徑
+	Code      string       `json:"code"`                 // 穩定的字串代碼，例如 user_not_found
+	RequestID string       `json:"request_id,omitempty"` // 請求 ID，與回應的 X-Request-ID header 相同
+	Errors    []FieldError `json:"errors,omitempty"`     // 每個欄位的驗證錯誤
+	Details   interface{}  `json:"details,omitempty"`    // 其他詳細資訊，例如違反的密碼規則
+}
+
+// FieldError 單一欄位的驗證錯誤，與 service 回傳的 apperror.FieldError 相同
+type FieldError = apperror.FieldError
+
+// writeError 依照請求要求的格式寫入錯誤回應
+func writeError(c *gin.Context, statusCode int, code, message string, fields []FieldError, details interface{}) {
+	if wantsProblem(c) {
+		// gin 只在還沒有 Content-Type 時才寫入 application/json，所以先設定 problem+json
+		c.Header("Content-Type", ProblemContentType+"; charset=utf-8")
+		c.JSON(statusCode, Problem{
+			Type:      "about:blank",
+			Title:     http.StatusText(statusCode),
+			Status:    statusCode,
+			Detail:    message,
+			Instance:  requestPath(c),
+			Code:      code,
+			RequestID: c.GetString(constants.CtxRequestIDKey),
+			Errors:    fields,
+			Details:   details,
+		})
+		return
+	}
+
+	if fields != nil {
+		details = fields
+	}
+	c.JSON(statusCode, ErrorData{
+		Success:   false,
+		Message:   message,
+		Code:      code,
+		RequestID: c.GetString(constants.CtxRequestIDKey),
+		Details:   details,
+	})
+}
+
+// wantsProblem 判斷是否使用 problem 格式：Accept header 明確要求 application/problem+json，或 ERROR_FORMAT 為 problem
+func wantsProblem(c *gin.Context) bool {
+	if c.Request == nil {
+		return problemByDefault
+	}
+	for _, accepted := range strings.Split(c.GetHeader("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err == nil && mediaType == ProblemContentType {
+			return true
+		}
+	}
+	return problemByDefault
+}
+
+// requestPath 取得請求的路徑 (不包含查詢字串)
+func requestPath(c *gin.Context) string {
+	if c.Request == nil || c.Request.URL == nil {
+		return ""
+	}
+	return c.Request.URL.Path
+}
@@ -0,0 +1,96 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-template/internal/api/handlers/exception"
+	"go-template/internal/constants"
+	"go-template/internal/utils/logger"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	_ = logger.Init(&logger.Config{Level: "error", ConsoleOut: true, ServiceName: "response-test"})
+	os.Exit(m.Run())
+}
+
+// serveValidationError 以 accept header 請求回應欄位驗證錯誤的路由
+func serveValidationError(accept string) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.GET("/api/users", func(c *gin.Context) {
+		c.Set(constants.CtxRequestIDKey, "req-1")
+		ValidationError(c, http.StatusBadRequest, exception.ErrCodeInvalidRequest, []FieldError{
+			{Field: "email", Rule: "email", Message: "email must be a valid email address"},
+		})
+	})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/users?email=x", nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// 測試 Accept header 要求 application/problem+json 時回應 RFC 7807 格式，欄位錯誤寫入 errors
+func TestProblemRendering(t *testing.T) {
+	for _, accept := range []string{ProblemContentType, "application/json, application/problem+json;q=0.9"} {
+		w := serveValidationError(accept)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, ProblemContentType+"; charset=utf-8", w.Header().Get("Content-Type"))
+
+		var problem Problem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, "about:blank", problem.Type)
+		assert.Equal(t, "Bad Request", problem.Title)
+		assert.Equal(t, http.StatusBadRequest, problem.Status)
+		assert.Equal(t, "invalid request", problem.Detail)
+		assert.Equal(t, "/api/users", problem.Instance, "the query string is not included")
+		assert.Equal(t, "invalid_request", problem.Code)
+		assert.Equal(t, "req-1", problem.RequestID)
+		require.Len(t, problem.Errors, 1)
+		assert.Equal(t, "email", problem.Errors[0].Field)
+		assert.Nil(t, problem.Details)
+	}
+}
+
+// 測試沒有要求 problem 格式時回應 legacy 格式，欄位錯誤寫入 details
+func TestLegacyErrorRendering(t *testing.T) {
+	w := serveValidationError("application/json")
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+
+	var body struct {
+		ErrorData
+		Details []FieldError `json:"details"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.False(t, body.Success)
+	assert.Equal(t, "invalid request", body.Message)
+	assert.Equal(t, "invalid_request", body.Code)
+	assert.Equal(t, "req-1", body.RequestID)
+	require.Len(t, body.Details, 1)
+	assert.Equal(t, "email", body.Details[0].Field)
+}
+
+// 測試 ERROR_FORMAT=problem 時沒有 Accept header 的請求也使用 problem 格式
+func TestProblemByDefault(t *testing.T) {
+	SetDefaultFormat(FormatProblem)
+	t.Cleanup(func() { SetDefaultFormat(FormatLegacy) })
+
+	w := serveValidationError("")
+	assert.Equal(t, ProblemContentType+"; charset=utf-8", w.Header().Get("Content-Type"))
+
+	SetDefaultFormat("unknown")
+	w = serveValidationError("")
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"), "unknown formats fall back to legacy")
+}
@@ -3,6 +3,7 @@ package response
 import (
 	"github.com/gin-gonic/gin"
 	"go-template/internal/api/handlers/exception"
+	"go-template/internal/apperror"
 )
 
 // SuccessData 回應成功的 JSON Struct
@@ -12,10 +13,37 @@ type SuccessData struct {
 	Data    interface{} `json:"data"`
 }
 
-// ErrorData 回應錯誤的 JSON Struct
+// PageMeta 分頁資訊
+type PageMeta struct {
+	Total      int64  `json:"total"`                 // 符合條件的資料總數
+	Limit      int    `json:"limit"`                 // 每頁筆數
+	Offset     int    `json:"offset"`                // 略過的筆數 (使用游標分頁時為 0)
+	NextCursor string `json:"next_cursor,omitempty"` // 下一頁的游標
+}
+
+// PageLinks 分頁連結
+type PageLinks struct {
+	Self string `json:"self"`           // 目前這一頁
+	Next string `json:"next,omitempty"` // 下一頁，沒有下一頁時省略
+}
+
+// PagedData 回應分頁資料的 JSON Struct
+type PagedData struct {
+	Success bool        `json:"success"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data"`
+	Meta    PageMeta    `json:"meta"`
+	Links   PageLinks   `json:"links"`
+}
+
+// ErrorData 回應錯誤的 JSON Struct，預設的錯誤格式 (legacy)
+// Code 與 RequestID 是後來加入的欄位，只讀取 success 與 message 的舊用戶端不受影響
 type ErrorData struct {
-	Success bool   `json:"success"`
-	Message string `json:"message"`
+	Success   bool        `json:"success"`
+	Message   string      `json:"message"`
+	Code      string      `json:"code"`                 // 穩定的字串代碼，例如 user_not_found，用戶端應該以此判斷錯誤種類
+	RequestID string      `json:"request_id,omitempty"` // 請求 ID，與回應的 X-Request-ID header 相同
+	Details   interface{} `json:"details,omitempty"`    // 錯誤的詳細資訊，例如違反的密碼規則或每個欄位的驗證錯誤
 }
 
 // Success 回應成功的 JSON 數據
@@ -27,10 +55,34 @@ func Success(c *gin.Context, statusCode int, message string, data interface{}) {
 	})
 }
 
-// Error 回應錯誤的 JSON 數據
-func Error(c *gin.Context, statusCode int, errCode int) {
-	c.JSON(statusCode, ErrorData{
-		Success: false,
-		Message: exception.GetErrorMessage(errCode), // 使用 errors.go 中的 GetErrorMessage 函數取得錯誤訊息
+// Paged 回應分頁的 JSON 數據
+func Paged(c *gin.Context, statusCode int, message string, data interface{}, meta PageMeta, links PageLinks) {
+	c.JSON(statusCode, PagedData{
+		Success: true,
+		Message: message,
+		Data:    data,
+		Meta:    meta,
+		Links:   links,
 	})
 }
+
+// Error 回應錯誤的 JSON 數據，格式依照請求的 Accept header 與 ERROR_FORMAT 決定 (見 problem.go)
+func Error(c *gin.Context, statusCode int, errCode int) {
+	writeError(c, statusCode, exception.GetErrorCode(errCode), exception.GetErrorMessage(errCode), nil, nil)
+}
+
+// ErrorDetails 回應包含詳細資訊的錯誤 JSON 數據
+func ErrorDetails(c *gin.Context, statusCode int, errCode int, details interface{}) {
+	writeError(c, statusCode, exception.GetErrorCode(errCode), exception.GetErrorMessage(errCode), nil, details)
+}
+
+// ValidationError 回應每個欄位的驗證錯誤
+// problem 格式寫入 errors 欄位，legacy 格式寫入 details 欄位
+func ValidationError(c *gin.Context, statusCode int, errCode int, fields []FieldError) {
+	writeError(c, statusCode, exception.GetErrorCode(errCode), exception.GetErrorMessage(errCode), fields, nil)
+}
+
+// AppError 以 *apperror.Error 的字串代碼、錯誤訊息、欄位錯誤與詳細資訊回應錯誤，由 ErrorHandler 中介軟體呼叫
+func AppError(c *gin.Context, statusCode int, err *apperror.Error) {
+	writeError(c, statusCode, err.Code, err.Message, err.Fields, err.Details)
+}
@@ -0,0 +1,55 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"go-template/internal/api/handlers/admin"
+	"go-template/internal/middleware"
+	"go-template/internal/models"
+)
+
+// AdminRoutes 結構體，用於管理管理者專用的路由
+type AdminRoutes struct {
+	handler *admin.Handler
+	auth    *middleware.Auth
+}
+
+// NewAdmin 建立一個新的 AdminRoutes 實例
+func NewAdmin(handler *admin.Handler, auth *middleware.Auth) *AdminRoutes {
+	return &AdminRoutes{handler: handler, auth: auth}
+}
+
+// RegisterAdmin 註冊管理者專用的路由
+// 整個路由群組需要身份驗證以及 admin 角色，每個路由再依照操作檢查對應的權限；使用 cookie 驗證時會檢查 CSRF token
+// 代替使用者操作的 token 不能呼叫管理 API
+// OAuth2 client 沒有角色，不能呼叫管理 API
+func (r *AdminRoutes) RegisterAdmin(router *gin.Engine) {
+	adminGroup := router.Group("/api/admin")
+	adminGroup.Use(r.auth.Handle(), middleware.CSRF(), middleware.RejectImpersonation(), middleware.RequireRole(models.RoleAdmin))
+	{
+		usersGroup := adminGroup.Group("/users")
+		usersGroup.GET("", middleware.Require(models.PermissionUsersRead), r.handler.ListUsers)
+		usersGroup.GET("/:id", middleware.Require(models.PermissionUsersRead), r.handler.GetUser)
+		usersGroup.PUT("/:id", middleware.Require(models.PermissionUsersUpdate), r.handler.UpdateUser)
+		usersGroup.POST("/:id/suspend", middleware.Require(models.PermissionUsersUpdate), r.handler.SuspendUser)
+		usersGroup.POST("/:id/restore", middleware.Require(models.PermissionUsersUpdate), r.handler.RestoreUser)
+		usersGroup.PUT("/:id/status", middleware.Require(models.PermissionUsersUpdate), r.handler.ChangeStatus)
+		usersGroup.GET("/:id/status-history", middleware.Require(models.PermissionUsersRead), r.handler.StatusHistory)
+		usersGroup.POST("/:id/password-reset", middleware.Require(models.PermissionUsersUpdate), r.handler.ForcePasswordReset)
+		usersGroup.DELETE("/:id", middleware.Require(models.PermissionUsersDelete), r.handler.DeleteUser)
+		usersGroup.DELETE("/:id/lockout", middleware.Require(models.PermissionUsersUpdate), r.handler.ClearLockout)
+
+		// 代替使用者操作，發行 token 必須使用互動式登入，不能使用 API key 或 OAuth2 client 的 token
+		usersGroup.POST("/:id/impersonate", middleware.RequireUser(), middleware.RejectAPIKey(),
+			middleware.Require(models.PermissionUsersImpersonate), r.handler.Impersonate)
+		usersGroup.GET("/:id/impersonations", middleware.Require(models.PermissionUsersRead), r.handler.ListImpersonations)
+
+		adminGroup.GET("/lockouts", middleware.Require(models.PermissionUsersRead), r.handler.ListLockouts)
+
+		// 服務帳號與其 API key，建立憑證必須使用互動式登入，不能使用 API key 或 OAuth2 client 的 token
+		serviceAccountsGroup := adminGroup.Group("/service-accounts", middleware.RequireUser(), middleware.RejectAPIKey())
+		serviceAccountsGroup.POST("", middleware.Require(models.PermissionUsersUpdate), r.handler.CreateServiceAccount)
+		serviceAccountsGroup.GET("/:id/api-keys", middleware.Require(models.PermissionUsersRead), r.handler.ListServiceAccountAPIKeys)
+		serviceAccountsGroup.POST("/:id/api-keys", middleware.Require(models.PermissionUsersUpdate), r.handler.CreateServiceAccountAPIKey)
+		serviceAccountsGroup.DELETE("/:id/api-keys/:keyId", middleware.Require(models.PermissionUsersUpdate), r.handler.RevokeServiceAccountAPIKey)
+	}
+}
@@ -0,0 +1,40 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"go-template/internal/api/handlers/oauth"
+	"go-template/internal/middleware"
+	"go-template/internal/models"
+)
+
+// OAuthRoutes 結構體，用於管理 OAuth2 端點與 client 管理的路由
+type OAuthRoutes struct {
+	handler *oauth.Handler
+	auth    *middleware.Auth
+}
+
+// NewOAuth 建立一個新的 OAuthRoutes 實例
+func NewOAuth(handler *oauth.Handler, auth *middleware.Auth) *OAuthRoutes {
+	return &OAuthRoutes{handler: handler, auth: auth}
+}
+
+// RegisterOAuth 註冊 OAuth2 相關的路由
+// /oauth 底下的端點由 handler 自行驗證 client，不經過 Auth 中介軟體
+func (r *OAuthRoutes) RegisterOAuth(router *gin.Engine) {
+	oauthGroup := router.Group("/oauth")
+	{
+		oauthGroup.POST("/token", r.handler.Token)
+		oauthGroup.POST("/introspect", r.handler.Introspect)
+		oauthGroup.POST("/revoke", r.handler.Revoke)
+	}
+
+	// client 的管理，建立憑證必須使用管理者互動式登入的 access token
+	clientsGroup := router.Group("/api/admin/oauth-clients")
+	clientsGroup.Use(r.auth.Handle(), middleware.CSRF(), middleware.RequireUser(), middleware.RejectAPIKey(),
+		middleware.RejectImpersonation(), middleware.RequireRole(models.RoleAdmin))
+	{
+		clientsGroup.GET("", middleware.Require(models.PermissionClientsRead), r.handler.ListClients)
+		clientsGroup.POST("", middleware.Require(models.PermissionClientsManage), r.handler.CreateClient)
+		clientsGroup.DELETE("/:id", middleware.Require(models.PermissionClientsManage), r.handler.DisableClient)
+	}
+}
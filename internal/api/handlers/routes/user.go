@@ -4,18 +4,18 @@ import (
 	"github.com/gin-gonic/gin"
 	"go-template/internal/api/handlers/user"
 	"go-template/internal/middleware"
-	"go-template/internal/utils/jwt"
+	"go-template/internal/models"
 )
 
 // UserRoutes 結構體，用於管理使用者相關的路由
 type UserRoutes struct {
-	handler    *user.Handler
-	jwtService *jwt.Service
+	handler *user.Handler
+	auth    *middleware.Auth
 }
 
 // NewUser 建立一個新的 UserRoutes 實例
-func NewUser(handler *user.Handler, jwtService *jwt.Service) *UserRoutes {
-	return &UserRoutes{handler: handler, jwtService: jwtService}
+func NewUser(handler *user.Handler, auth *middleware.Auth) *UserRoutes {
+	return &UserRoutes{handler: handler, auth: auth}
 }
 
 // RegisterUser 註冊使用者相關的路由
@@ -26,15 +26,49 @@ func (r *UserRoutes) RegisterUser(router *gin.Engine) {
 		// 公開路由 (不需要身份驗證)
 		userGroup.POST("/register", r.handler.Register)
 		userGroup.POST("/login", r.handler.Login)
+		userGroup.POST("/login/mfa", r.handler.LoginMFA)
+		userGroup.POST("/login/magic-link", r.handler.RequestMagicLink)
+		userGroup.POST("/login/magic-link/consume", r.handler.LoginMagicLink)
+		userGroup.POST("/token/refresh", r.handler.Refresh)
+		userGroup.POST("/verify-email", r.handler.VerifyEmail)
+		userGroup.POST("/verify-email/resend", r.handler.ResendVerification)
+		userGroup.POST("/password/forgot", r.handler.ForgotPassword)
+		userGroup.POST("/password/reset", r.handler.ResetPassword)
 
 		// 受保護的路由 (需要身份驗證)
-		// 將 Auth 應用到 protectedGroup
+		// 將 Auth 應用到 protectedGroup，這些路由都代表目前的使用者操作，不接受 OAuth2 client 的 token
+		// 使用 cookie 驗證的請求由 CSRF 檢查 CSRF token
 		protectedGroup := userGroup.Group("/")
-		protectedGroup.Use(middleware.Auth(r.jwtService))
+		protectedGroup.Use(r.auth.Handle(), middleware.CSRF(), middleware.RequireUser())
 		{
-			protectedGroup.GET("/:id", r.handler.Get)
-			protectedGroup.PUT("/:id", r.handler.Update)
-			protectedGroup.DELETE("/:id", r.handler.Delete)
+			protectedGroup.POST("/logout", middleware.RejectAPIKey(), r.handler.Logout)
+			protectedGroup.POST("/logout/all", middleware.RejectAPIKey(), middleware.RejectImpersonation(), r.handler.LogoutAll)
+
+			// 自助路由，一律操作目前登入的使用者；操作其他使用者請使用 /api/admin/users
+			// 代替使用者操作的 token 不能變更帳號資料、密碼、兩步驟驗證與憑證，避免客服人員接管帳號
+			protectedGroup.GET("/me", middleware.Require(models.PermissionProfileRead), r.handler.Get)
+			protectedGroup.PUT("/me", middleware.RejectImpersonation(), middleware.Require(models.PermissionProfileUpdate), r.handler.Update)
+			protectedGroup.DELETE("/me", middleware.Require(models.PermissionProfileDelete), r.handler.Delete)
+			protectedGroup.PUT("/me/password", middleware.RejectAPIKey(), middleware.RejectImpersonation(), middleware.Require(models.PermissionProfileUpdate), r.handler.ChangePassword)
+
+			// 兩步驟驗證設定
+			mfaGroup := protectedGroup.Group("/me/mfa", middleware.RejectAPIKey(), middleware.RejectImpersonation(),
+				middleware.Require(models.PermissionProfileUpdate))
+			mfaGroup.POST("/totp", r.handler.EnrollTOTP)
+			mfaGroup.POST("/totp/confirm", r.handler.ConfirmTOTP)
+			mfaGroup.POST("/recovery-codes", r.handler.RegenerateRecoveryCodes)
+			mfaGroup.DELETE("", r.handler.DisableMFA)
+
+			// API key 管理，只能使用互動式登入的 access token，避免外洩的 API key 被用來建立新的 key
+			apiKeysGroup := protectedGroup.Group("/me/api-keys", middleware.RejectAPIKey(), middleware.RejectImpersonation())
+			apiKeysGroup.GET("", middleware.Require(models.PermissionProfileRead), r.handler.ListAPIKeys)
+			apiKeysGroup.POST("", middleware.Require(models.PermissionProfileUpdate), r.handler.CreateAPIKey)
+			apiKeysGroup.DELETE("/:id", middleware.Require(models.PermissionProfileUpdate), r.handler.RevokeAPIKey)
+
+			// 登入工作階段 (裝置) 管理，API key 沒有工作階段，因此同樣只能使用互動式登入的 access token
+			sessionsGroup := protectedGroup.Group("/me/sessions", middleware.RejectAPIKey())
+			sessionsGroup.GET("", middleware.Require(models.PermissionProfileRead), r.handler.ListSessions)
+			sessionsGroup.DELETE("/:sid", middleware.Require(models.PermissionProfileUpdate), r.handler.RevokeSession)
 		}
 	}
 }
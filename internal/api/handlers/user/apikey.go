@@ -0,0 +1,120 @@
+package user
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-template/internal/api/handlers/exception"
+	"go-template/internal/api/handlers/response"
+	userSvc "go-template/internal/services/user"
+	"go-template/internal/utils/logger"
+)
+
+// createAPIKeyRequest 建立 API key 請求的結構體
+type createAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=100"`           // 用途說明
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`           // 允許使用的權限，例如 ["profile:read"]
+	ExpiresAt *time.Time `json:"expires_at" example:"2030-01-01T00:00:00Z"` // 選填，不填代表不會過期
+}
+
+// CreateAPIKey 處理建立 API key 的請求
+// @Summary 建立 API key
+// @Description 建立供機器用戶端使用的 API key，權限必須是自己目前擁有的權限。
+// @Description 完整的 key 只會在此時回傳一次，之後只能看到公開前綴；使用時放在 X-API-Key header 或 Authorization: ApiKey <key>
+// @Tags User
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param body body createAPIKeyRequest true "名稱、權限與過期時間"
+// @Success 201 {object} response.SuccessData{Data=userSvc.CreatedAPIKey} "建立成功"
+// @Failure 400 {object} response.ErrorData "錯誤的請求、權限不符或過期時間不在未來"
+// @Failure 403 {object} response.ErrorData "不能使用 API key 呼叫"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /user/me/api-keys [post]
+func (h *Handler) CreateAPIKey(c *gin.Context) {
+	id, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var input createAPIKeyRequest
+	// 解析請求的 JSON 數據到 input 變數
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logger.Logger.Debugf(exception.ErrMsgInvalidRequestBody, err) // DEBUG 等級
+		response.BindError(c, err)
+		return
+	}
+
+	key, err := h.userService.CreateAPIKey(id, userSvc.NewAPIKey{
+		Name:      input.Name,
+		Scopes:    input.Scopes,
+		ExpiresAt: input.ExpiresAt,
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "API key created, store it now because it will not be shown again", key)
+}
+
+// ListAPIKeys 處理列出 API key 的請求
+// @Summary 列出 API key
+// @Description 列出自己尚未撤銷的 API key，只包含公開前綴，不包含完整的 key
+// @Tags User
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} response.SuccessData{Data=[]models.APIKey} "取得成功"
+// @Failure 403 {object} response.ErrorData "不能使用 API key 呼叫"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /user/me/api-keys [get]
+func (h *Handler) ListAPIKeys(c *gin.Context) {
+	id, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	keys, err := h.userService.ListAPIKeys(id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "API keys found", keys)
+}
+
+// RevokeAPIKey 處理撤銷 API key 的請求
+// @Summary 撤銷 API key
+// @Description 撤銷自己的 API key，撤銷後立即失效
+// @Tags User
+// @Produce  json
+// @Security BearerAuth
+// @Param id path int true "API key ID"
+// @Success 200 {object} response.SuccessData "撤銷成功"
+// @Failure 400 {object} response.ErrorData "錯誤的請求"
+// @Failure 403 {object} response.ErrorData "不能使用 API key 呼叫"
+// @Failure 404 {object} response.ErrorData "API key 不存在"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /user/me/api-keys/{id} [delete]
+func (h *Handler) RevokeAPIKey(c *gin.Context) {
+	id, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	keyID, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil || keyID == 0 {
+		logger.Logger.Debugf("Invalid API key ID in path: %s", c.Param("id")) // DEBUG 等級
+		response.Error(c, http.StatusBadRequest, exception.ErrCodeInvalidRequest)
+		return
+	}
+
+	if err := h.userService.RevokeAPIKey(id, uint(keyID)); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "API key revoked", nil)
+}
@@ -0,0 +1,96 @@
+package user
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go-template/internal/api/handlers/exception"
+	"go-template/internal/api/handlers/response"
+	"go-template/internal/utils/logger"
+)
+
+// magicLinkRequest 要求登入連結的請求結構體
+type magicLinkRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// magicLinkLoginRequest 使用登入連結登入的請求結構體
+type magicLinkLoginRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// RequestMagicLink 處理要求登入連結的請求
+// @Summary 要求登入連結
+// @Description 寄送不需要密碼的登入連結，舊的登入連結會失效。為了避免洩漏帳號是否存在，不論結果都回傳相同的回應；
+// @Description 在短時間內重複要求、帳號無法登入或登入被暫時鎖定時不會寄送
+// @Tags User
+// @Accept  json
+// @Produce  json
+// @Param body body magicLinkRequest true "電子郵件"
+// @Success 202 {object} response.SuccessData "已受理"
+// @Failure 400 {object} response.ErrorData "錯誤的請求"
+// @Failure 403 {object} response.ErrorData "沒有開啟登入連結功能"
+// @Router /user/login/magic-link [post]
+func (h *Handler) RequestMagicLink(c *gin.Context) {
+	var input magicLinkRequest
+	// 解析請求的 JSON 數據到 input 變數
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logger.Logger.Debugf(exception.ErrMsgInvalidRequestBody, err) // DEBUG 等級
+		response.BindError(c, err)
+		return
+	}
+
+	// 服務層只會在功能未開啟時回傳錯誤，不會回傳會洩漏帳號是否存在的錯誤
+	if err := h.userService.RequestMagicLink(input.Email, clientInfo(c)); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusAccepted,
+		"If an account with that email can sign in, a sign-in link has been sent", nil)
+}
+
+// LoginMagicLink 處理使用登入連結登入的請求
+// @Summary 使用登入連結登入
+// @Description 使用登入信中的 token 登入，回傳與 /user/login 相同的結果；啟用兩步驟驗證的使用者只會取得 mfa_token，必須再呼叫 /user/login/mfa。
+// @Description token 只能使用一次；帶上 X-Auth-Mode: cookie 時與 /user/login 一樣以 cookie 回傳 token
+// @Tags User
+// @Accept  json
+// @Produce  json
+// @Param body body magicLinkLoginRequest true "登入連結中的 token"
+// @Param X-Auth-Mode header string false "cookie 代表以 cookie 回傳 token"
+// @Success 200 {object} response.SuccessData{Data=userSvc.LoginResult} "登入成功或需要兩步驟驗證"
+// @Failure 400 {object} response.ErrorData "錯誤的請求"
+// @Failure 401 {object} response.ErrorData "登入連結無效、已使用或已過期"
+// @Failure 403 {object} response.ErrorData "沒有開啟登入連結功能、帳號不是 active 狀態或需要重設密碼"
+// @Failure 429 {object} response.ErrorData "登入失敗次數過多，Retry-After header 為需要等待的秒數"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /user/login/magic-link/consume [post]
+func (h *Handler) LoginMagicLink(c *gin.Context) {
+	var input magicLinkLoginRequest
+	// 解析請求的 JSON 數據到 input 變數
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logger.Logger.Debugf(exception.ErrMsgInvalidRequestBody, err) // DEBUG 等級
+		response.BindError(c, err)
+		return
+	}
+
+	result, err := h.userService.LoginMagicLink(input.Token, clientInfo(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// 啟用兩步驟驗證的使用者只會取得 MFA token，必須再呼叫 /user/login/mfa
+	if result.MFARequired {
+		response.Success(c, http.StatusOK, "MFA required", result)
+		return
+	}
+
+	tokens, ok := h.responseTokens(c, result.TokenPair)
+	if !ok {
+		return
+	}
+	result.TokenPair = tokens
+	response.Success(c, http.StatusOK, "Login successful", result)
+}
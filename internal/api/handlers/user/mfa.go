@@ -0,0 +1,201 @@
+package user
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go-template/internal/api/handlers/exception"
+	"go-template/internal/api/handlers/response"
+	"go-template/internal/utils/logger"
+)
+
+// loginMFARequest 完成兩步驟驗證登入請求的結構體
+type loginMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"` // TOTP 驗證碼或復原碼
+}
+
+// mfaCodeRequest 需要 TOTP 驗證碼的請求結構體
+type mfaCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// disableMFARequest 停用兩步驟驗證請求的結構體
+type disableMFARequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// recoveryCodesResponse 回傳復原碼的結構體
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// LoginMFA 處理完成兩步驟驗證登入的請求
+// @Summary 完成兩步驟驗證登入
+// @Description 使用 /user/login 回傳的 mfa_token 與 TOTP 驗證碼 (或復原碼) 換發 token 組合。
+// @Description mfa_token 只能使用一次，驗證碼錯誤時必須重新登入；帶上 X-Auth-Mode: cookie 時與 /user/login 一樣以 cookie 回傳 token
+// @Tags User
+// @Accept  json
+// @Produce  json
+// @Param body body loginMFARequest true "MFA token 與驗證碼"
+// @Param X-Auth-Mode header string false "cookie 代表以 cookie 回傳 token"
+// @Success 200 {object} response.SuccessData{Data=userSvc.TokenPair} "登入成功"
+// @Failure 400 {object} response.ErrorData "錯誤的請求"
+// @Failure 401 {object} response.ErrorData "MFA token 無效或驗證碼錯誤"
+// @Failure 403 {object} response.ErrorData "帳號不是 active 狀態或需要重設密碼"
+// @Failure 429 {object} response.ErrorData "登入失敗次數過多，Retry-After header 為需要等待的秒數"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /user/login/mfa [post]
+func (h *Handler) LoginMFA(c *gin.Context) {
+	var input loginMFARequest
+	// 解析請求的 JSON 數據到 input 變數
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logger.Logger.Debugf(exception.ErrMsgInvalidRequestBody, err) // DEBUG 等級
+		response.BindError(c, err)
+		return
+	}
+
+	tokens, err := h.userService.LoginMFA(input.MFAToken, input.Code, clientInfo(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if tokens, ok := h.responseTokens(c, tokens); ok {
+		response.Success(c, http.StatusOK, "Login successful", tokens)
+	}
+}
+
+// EnrollTOTP 處理開始設定 TOTP 的請求
+// @Summary 開始設定 TOTP 兩步驟驗證
+// @Description 產生新的共享密鑰與 provisioning URI (QR code 內容)，使用 /user/me/mfa/totp/confirm 確認後才會啟用
+// @Tags User
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} response.SuccessData{Data=userSvc.TOTPEnrollment} "已產生共享密鑰"
+// @Failure 404 {object} response.ErrorData "使用者不存在"
+// @Failure 409 {object} response.ErrorData "已經啟用兩步驟驗證"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /user/me/mfa/totp [post]
+func (h *Handler) EnrollTOTP(c *gin.Context) {
+	id, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	enrollment, err := h.userService.EnrollTOTP(id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Scan the QR code with your authenticator app and confirm with a code", enrollment)
+}
+
+// ConfirmTOTP 處理確認 TOTP 設定的請求
+// @Summary 確認 TOTP 設定
+// @Description 使用驗證器 App 產生的驗證碼確認設定並啟用兩步驟驗證，回傳的復原碼只會出現這一次
+// @Tags User
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param body body mfaCodeRequest true "TOTP 驗證碼"
+// @Success 200 {object} response.SuccessData{Data=recoveryCodesResponse} "已啟用"
+// @Failure 400 {object} response.ErrorData "錯誤的請求、驗證碼錯誤或尚未開始設定"
+// @Failure 409 {object} response.ErrorData "已經啟用兩步驟驗證"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /user/me/mfa/totp/confirm [post]
+func (h *Handler) ConfirmTOTP(c *gin.Context) {
+	id, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var input mfaCodeRequest
+	// 解析請求的 JSON 數據到 input 變數
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logger.Logger.Debugf(exception.ErrMsgInvalidRequestBody, err) // DEBUG 等級
+		response.BindError(c, err)
+		return
+	}
+
+	codes, err := h.userService.ConfirmTOTP(id, input.Code)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	logger.Logger.Infof("User enabled MFA: %d", id) // INFO 等級
+	response.Success(c, http.StatusOK, "Two-factor authentication enabled, store the recovery codes in a safe place",
+		recoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// RegenerateRecoveryCodes 處理重新產生復原碼的請求
+// @Summary 重新產生復原碼
+// @Description 驗證 TOTP 驗證碼後產生新的復原碼，之前的復原碼全部失效
+// @Tags User
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param body body mfaCodeRequest true "TOTP 驗證碼"
+// @Success 200 {object} response.SuccessData{Data=recoveryCodesResponse} "已產生新的復原碼"
+// @Failure 400 {object} response.ErrorData "錯誤的請求、驗證碼錯誤或尚未啟用兩步驟驗證"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /user/me/mfa/recovery-codes [post]
+func (h *Handler) RegenerateRecoveryCodes(c *gin.Context) {
+	id, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var input mfaCodeRequest
+	// 解析請求的 JSON 數據到 input 變數
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logger.Logger.Debugf(exception.ErrMsgInvalidRequestBody, err) // DEBUG 等級
+		response.BindError(c, err)
+		return
+	}
+
+	codes, err := h.userService.RegenerateRecoveryCodes(id, input.Code)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Recovery codes regenerated", recoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableMFA 處理停用兩步驟驗證的請求
+// @Summary 停用兩步驟驗證
+// @Description 驗證目前的密碼後停用兩步驟驗證，並刪除所有復原碼
+// @Tags User
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param body body disableMFARequest true "目前的密碼"
+// @Success 200 {object} response.SuccessData "已停用"
+// @Failure 400 {object} response.ErrorData "錯誤的請求、密碼錯誤或尚未啟用兩步驟驗證"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /user/me/mfa [delete]
+func (h *Handler) DisableMFA(c *gin.Context) {
+	id, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var input disableMFARequest
+	// 解析請求的 JSON 數據到 input 變數
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logger.Logger.Debugf(exception.ErrMsgInvalidRequestBody, err) // DEBUG 等級
+		response.BindError(c, err)
+		return
+	}
+
+	if err := h.userService.DisableMFA(id, input.Password); err != nil {
+		c.Error(err)
+		return
+	}
+
+	logger.Logger.Infof("User disabled MFA: %d", id) // INFO 等級
+	response.Success(c, http.StatusOK, "Two-factor authentication disabled", nil)
+}
@@ -0,0 +1,124 @@
+package user
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go-template/internal/api/handlers/exception"
+	"go-template/internal/api/handlers/response"
+	"go-template/internal/utils/logger"
+)
+
+// forgotPasswordRequest 忘記密碼請求的結構體
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// resetPasswordRequest 重設密碼請求的結構體
+type resetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// changePasswordRequest 變更密碼請求的結構體
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// ForgotPassword 處理忘記密碼的請求
+// @Summary 忘記密碼
+// @Description 寄送重設密碼信，舊的重設密碼 token 會失效。為了避免洩漏帳號是否存在，不論結果都回傳相同的回應；
+// @Description 在短時間內重複要求時不會重新寄送
+// @Tags User
+// @Accept  json
+// @Produce  json
+// @Param body body forgotPasswordRequest true "電子郵件"
+// @Success 202 {object} response.SuccessData "已受理"
+// @Failure 400 {object} response.ErrorData "錯誤的請求"
+// @Router /user/password/forgot [post]
+func (h *Handler) ForgotPassword(c *gin.Context) {
+	var input forgotPasswordRequest
+	// 解析請求的 JSON 數據到 input 變數
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logger.Logger.Debugf(exception.ErrMsgInvalidRequestBody, err) // DEBUG 等級
+		response.BindError(c, err)
+		return
+	}
+
+	// 服務層不會回傳會洩漏帳號是否存在的錯誤，任何錯誤都只記錄下來並回傳相同的回應
+	if err := h.userService.ForgotPassword(input.Email); err != nil {
+		logger.Logger.Errorf("Error requesting password reset: %v", err) // ERROR 等級
+	}
+
+	response.Success(c, http.StatusAccepted,
+		"If an account with that email exists, a password reset email has been sent", nil)
+}
+
+// ResetPassword 處理重設密碼的請求
+// @Summary 重設密碼
+// @Description 使用重設密碼信中的 token 設定新的密碼；每個 token 只能使用一次，重設成功後會登出所有裝置
+// @Tags User
+// @Accept  json
+// @Produce  json
+// @Param body body resetPasswordRequest true "重設密碼 token 與新的密碼"
+// @Success 200 {object} response.SuccessData "重設成功"
+// @Failure 400 {object} response.ErrorData "錯誤的請求、密碼不符合規則或 token 無效"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /user/password/reset [post]
+func (h *Handler) ResetPassword(c *gin.Context) {
+	var input resetPasswordRequest
+	// 解析請求的 JSON 數據到 input 變數
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logger.Logger.Debugf(exception.ErrMsgInvalidRequestBody, err) // DEBUG 等級
+		response.BindError(c, err)
+		return
+	}
+
+	if err := h.userService.ResetPassword(input.Token, input.NewPassword); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Password reset successfully", nil)
+}
+
+// ChangePassword 處理變更密碼的請求
+// @Summary 變更密碼
+// @Description 驗證目前的密碼後設定新的密碼，其他裝置都會被登出，並回傳目前裝置使用的新 token 組合；使用 cookie 驗證時新的 token 以 cookie 回傳
+// @Tags User
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param body body changePasswordRequest true "目前的密碼與新的密碼"
+// @Success 200 {object} response.SuccessData{Data=userSvc.TokenPair} "變更成功"
+// @Failure 400 {object} response.ErrorData "錯誤的請求、密碼不符合規則或目前的密碼錯誤"
+// @Failure 404 {object} response.ErrorData "使用者不存在"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /user/me/password [put]
+func (h *Handler) ChangePassword(c *gin.Context) {
+	id, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var input changePasswordRequest
+	// 解析請求的 JSON 數據到 input 變數
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logger.Logger.Debugf(exception.ErrMsgInvalidRequestBody, err) // DEBUG 等級
+		response.BindError(c, err)
+		return
+	}
+
+	tokens, err := h.userService.ChangePassword(id, input.CurrentPassword, input.NewPassword, clientInfo(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	logger.Logger.Infof("User changed password: %d", id) // INFO 等級
+	if tokens, ok = h.responseTokens(c, tokens); !ok {
+		return
+	}
+	response.Success(c, http.StatusOK, "Password changed successfully, other sessions have been logged out", tokens)
+}
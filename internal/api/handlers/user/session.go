@@ -0,0 +1,74 @@
+package user
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go-template/internal/api/handlers/response"
+	"go-template/internal/principal"
+)
+
+// ListSessions 處理列出登入工作階段的請求
+// @Summary 列出登入工作階段
+// @Description 列出自己目前有效的登入工作階段 (每個裝置一筆)，包含登入時的 IP、User-Agent 與最後使用時間。
+// @Description 目前請求使用的工作階段 current 為 true；最後使用時間為批次更新，可能會有數分鐘的落差
+// @Tags User
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} response.SuccessData{Data=[]models.Session} "取得成功"
+// @Failure 403 {object} response.ErrorData "不能使用 API key 呼叫"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /user/me/sessions [get]
+func (h *Handler) ListSessions(c *gin.Context) {
+	id, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := h.userService.ListSessions(id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	current := currentSessionID(c)
+	for i := range sessions {
+		sessions[i].Current = current != "" && sessions[i].ID == current
+	}
+
+	response.Success(c, http.StatusOK, "Sessions found", sessions)
+}
+
+// RevokeSession 處理撤銷登入工作階段的請求
+// @Summary 撤銷登入工作階段
+// @Description 登出指定的裝置，該工作階段的 access token 與 refresh token 立即失效；撤銷目前的工作階段等同於登出
+// @Tags User
+// @Produce  json
+// @Security BearerAuth
+// @Param sid path string true "工作階段 ID"
+// @Success 200 {object} response.SuccessData "撤銷成功"
+// @Failure 403 {object} response.ErrorData "不能使用 API key 呼叫"
+// @Failure 404 {object} response.ErrorData "工作階段不存在或已撤銷"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /user/me/sessions/{sid} [delete]
+func (h *Handler) RevokeSession(c *gin.Context) {
+	id, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.userService.RevokeSession(id, c.Param("sid")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Session revoked", nil)
+}
+
+// currentSessionID 取得目前請求使用的 access token 的 sid，沒有時回傳空字串
+func currentSessionID(c *gin.Context) string {
+	if caller, ok := principal.FromContext(c); ok {
+		return caller.SessionID
+	}
+	return ""
+}
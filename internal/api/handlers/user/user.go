@@ -1,16 +1,19 @@
 package user
 
 import (
+	"errors"
+	"io"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"go-template/internal/api/handlers/authcookie"
 	"go-template/internal/api/handlers/exception"
 	"go-template/internal/api/handlers/response"
 	"go-template/internal/constants"
 	"go-template/internal/models"
+	"go-template/internal/principal"
 	userSvc "go-template/internal/services/user"
 	"go-template/internal/utils/logger"
-	"go-template/internal/validators"
 )
 
 // loginRequest 登入請求的結構體
@@ -19,25 +22,47 @@ type loginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// refreshRequest 刷新 token 請求的結構體
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"` // cookie 驗證模式時可以省略，改用 refresh_token cookie
+}
+
+// logoutRequest 登出請求的結構體
+type logoutRequest struct {
+	RefreshToken string `json:"refresh_token"` // 選填，同一次登入的 refresh token 也會一併撤銷
+}
+
+// verifyEmailRequest 驗證電子郵件請求的結構體
+type verifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// resendVerificationRequest 重新寄送驗證信請求的結構體
+type resendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
 // Handler struct，用於處理使用者相關的 HTTP 請求
 type Handler struct {
 	userService userSvc.Service
+	cookies     *authcookie.Manager
 }
 
 // NewHandler 建立一個新的 UserHandler 實例
-func NewHandler(userService userSvc.Service) *Handler {
-	return &Handler{userService: userService}
+func NewHandler(userService userSvc.Service, cookies *authcookie.Manager) *Handler {
+	return &Handler{userService: userService, cookies: cookies}
 }
 
 // Register 處理使用者註冊的請求
 // @Summary 註冊使用者
-// @Description 註冊一個新的使用者
+// @Description 註冊一個新的使用者，註冊後會寄送驗證信，完成電子郵件驗證之前無法登入
 // @Tags User
 // @Accept  json
 // @Produce  json
 // @Param user body models.User true "使用者資料"
 // @Success 201 {object} response.SuccessData{data=models.User} "註冊成功"
-// @Failure 400 {object} response.ErrorData "錯誤的請求"
+// @Failure 400 {object} response.ErrorData "錯誤的請求、使用者資料不符合規則 (列出每個欄位的錯誤) 或密碼不符合規則 (details 列出違反的規則)"
+// @Failure 409 {object} response.ErrorData "帳號名稱或電子郵件已經被使用 (details 的 field 為重複的欄位)"
 // @Failure 500 {object} response.ErrorData "系統錯誤"
 // @Router /user/register [post]
 func (h *Handler) Register(c *gin.Context) {
@@ -49,14 +74,7 @@ func (h *Handler) Register(c *gin.Context) {
 	// 解析請求的 JSON 數據到 user 變數
 	if err := c.ShouldBindJSON(&input); err != nil {
 		logger.Logger.Debugf(exception.ErrMsgInvalidRequestBody, err) // DEBUG 等級
-		response.Error(c, http.StatusBadRequest, exception.ErrCodeInvalidRequest)
-		return
-	}
-
-	// 驗證使用者資料
-	if err := validators.ValidateNewUser(input.Username, input.Email, input.Password); err != nil {
-		logger.Logger.Debugf("Invalid user data: %v", err)
-		response.Error(c, http.StatusBadRequest, exception.ErrCodeInvalidRequest)
+		response.BindError(c, err)
 		return
 	}
 
@@ -69,26 +87,88 @@ func (h *Handler) Register(c *gin.Context) {
 
 	// 呼叫 user 建立使用者
 	if err := h.userService.CreateUser(&user); err != nil {
-		logger.Logger.Errorf("Error creating user: %v", err) // ERROR 等級
-		response.Error(c, http.StatusInternalServerError, exception.ErrCodeUnknown)
+		// 使用者資料或密碼不符合規則時由 ErrorHandler 回應 400 並列出每個欄位的錯誤或違反的密碼規則
+		c.Error(err)
 		return
 	}
 
 	// 回應註冊成功的訊息
 	logger.Logger.Infof("User created: %s", user.Username) // INFO 等級
-	response.Success(c, http.StatusCreated, "User created successfully", user)
+	response.Success(c, http.StatusCreated, "User created successfully, please verify your email address", user)
+}
+
+// VerifyEmail 處理驗證電子郵件的請求
+// @Summary 驗證電子郵件
+// @Description 使用驗證信中的 token 驗證電子郵件，驗證成功後帳號即可登入；每個 token 只能使用一次
+// @Tags User
+// @Accept  json
+// @Produce  json
+// @Param body body verifyEmailRequest true "驗證 token"
+// @Success 200 {object} response.SuccessData "驗證成功"
+// @Failure 400 {object} response.ErrorData "錯誤的請求或 token 無效"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /user/verify-email [post]
+func (h *Handler) VerifyEmail(c *gin.Context) {
+	var input verifyEmailRequest
+	// 解析請求的 JSON 數據到 input 變數
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logger.Logger.Debugf(exception.ErrMsgInvalidRequestBody, err) // DEBUG 等級
+		response.BindError(c, err)
+		return
+	}
+
+	if err := h.userService.VerifyEmail(input.Token); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Email verified successfully", nil)
+}
+
+// ResendVerification 處理重新寄送驗證信的請求
+// @Summary 重新寄送驗證信
+// @Description 重新寄送驗證信，舊的驗證 token 會失效。為了避免洩漏帳號是否存在，不論結果都回傳相同的回應；
+// @Description 在短時間內重複要求時不會重新寄送
+// @Tags User
+// @Accept  json
+// @Produce  json
+// @Param body body resendVerificationRequest true "電子郵件"
+// @Success 202 {object} response.SuccessData "已受理"
+// @Failure 400 {object} response.ErrorData "錯誤的請求"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /user/verify-email/resend [post]
+func (h *Handler) ResendVerification(c *gin.Context) {
+	var input resendVerificationRequest
+	// 解析請求的 JSON 數據到 input 變數
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logger.Logger.Debugf(exception.ErrMsgInvalidRequestBody, err) // DEBUG 等級
+		response.BindError(c, err)
+		return
+	}
+
+	if err := h.userService.ResendVerificationEmail(input.Email); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusAccepted,
+		"If an account with that email is waiting for verification, a verification email has been sent", nil)
 }
 
 // Login 處理使用者登入的請求
 // @Summary 登入使用者
-// @Description 登入一個已註冊的使用者
+// @Description 登入一個已註冊的使用者。啟用兩步驟驗證的使用者只會取得 mfa_token，必須再呼叫 /user/login/mfa 完成登入。
+// @Description 開啟 cookie 驗證模式並帶上 X-Auth-Mode: cookie 時，token 改以 HttpOnly cookie 回傳，回應內容不包含 token
 // @Tags User
 // @Accept  json
 // @Produce  json
 // @Param credentials body loginRequest true "使用者登入資訊"
-// @Success 200 {object} response.SuccessData{Data=string} "登入成功"
+// @Param X-Auth-Mode header string false "cookie 代表以 cookie 回傳 token"
+// @Success 200 {object} response.SuccessData{Data=userSvc.LoginResult} "登入成功或需要兩步驟驗證"
 // @Failure 400 {object} response.ErrorData "錯誤的請求"
 // @Failure 401 {object} response.ErrorData "使用者不存在或密碼錯誤"
+// @Failure 403 {object} response.ErrorData "帳號不是 active 狀態或需要重設密碼"
+// @Failure 429 {object} response.ErrorData "登入失敗次數過多，Retry-After header 為需要等待的秒數"
 // @Failure 500 {object} response.ErrorData "系統錯誤"
 // @Router /user/login [post]
 func (h *Handler) Login(c *gin.Context) {
@@ -96,69 +176,189 @@ func (h *Handler) Login(c *gin.Context) {
 	// 解析請求的 JSON 數據到 input 變數
 	if err := c.ShouldBindJSON(&input); err != nil {
 		logger.Logger.Debugf(exception.ErrMsgInvalidRequestBody, err) // DEBUG 等級
-		response.Error(c, http.StatusBadRequest, exception.ErrCodeInvalidRequest)
+		response.BindError(c, err)
 		return
 	}
 
 	// 呼叫 user 進行使用者登入
-	token, err := h.userService.Login(input.Username, input.Password)
+	result, err := h.userService.Login(input.Username, input.Password, clientInfo(c))
 	if err != nil {
-		logger.Logger.Errorf("Error logging in: %v", err) // ERROR 等級
-		// 根據不同的錯誤類型回覆不同的錯誤碼
-		switch err {
-		case userSvc.ErrUserNotFound:
-			response.Error(c, http.StatusUnauthorized, exception.ErrCodeUserNotFound)
-		case userSvc.ErrInvalidCredentials:
-			response.Error(c, http.StatusUnauthorized, exception.ErrCodeInvalidCredentials)
-		default:
-			response.Error(c, http.StatusInternalServerError, exception.ErrCodeUnknown)
-		}
+		// 失敗次數過多時由 ErrorHandler 回應 429 並設定 Retry-After header
+		c.Error(err)
 		return
 	}
 
-	// 回應登入成功的訊息和 JWT token
+	// 啟用兩步驟驗證的使用者只會取得 MFA token，必須再呼叫 /user/login/mfa
+	if result.MFARequired {
+		logger.Logger.Infof("User login pending MFA: %s", input.Username) // INFO 等級
+		response.Success(c, http.StatusOK, "MFA required", result)
+		return
+	}
+
+	// 回應登入成功的訊息和 token 組合
+	tokens, ok := h.responseTokens(c, result.TokenPair)
+	if !ok {
+		return
+	}
+	result.TokenPair = tokens
 	logger.Logger.Infof("User logged in: %s", input.Username) // INFO 等級
-	response.Success(c, http.StatusOK, "Login successful", gin.H{"token": token})
+	response.Success(c, http.StatusOK, "Login successful", result)
+}
+
+// Refresh 處理刷新 token 的請求
+// @Summary 刷新 token
+// @Description 使用 refresh token 換發新的 access token 與 refresh token，舊的 refresh token 會失效。
+// @Description cookie 驗證模式時可以省略 refresh_token，改用 refresh_token cookie，此時必須帶上 X-CSRF-Token header，新的 token 一樣以 cookie 回傳
+// @Tags User
+// @Accept  json
+// @Produce  json
+// @Param body body refreshRequest false "Refresh token"
+// @Param X-CSRF-Token header string false "使用 refresh_token cookie 時必須與 csrf_token cookie 相同"
+// @Success 200 {object} response.SuccessData{Data=userSvc.TokenPair} "刷新成功"
+// @Failure 400 {object} response.ErrorData "錯誤的請求"
+// @Failure 401 {object} response.ErrorData "Refresh token 無效或被重複使用"
+// @Failure 403 {object} response.ErrorData "帳號不是 active 狀態、需要重設密碼，或 CSRF token 不符"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /user/token/refresh [post]
+func (h *Handler) Refresh(c *gin.Context) {
+	var input refreshRequest
+	// 解析請求的 JSON 數據到 input 變數，使用 cookie 時可以沒有 body
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		logger.Logger.Debugf(exception.ErrMsgInvalidRequestBody, err) // DEBUG 等級
+		response.BindError(c, err)
+		return
+	}
+
+	// 沒有在 body 中提供 refresh token 時改用 cookie，瀏覽器會自動帶上 cookie，因此必須檢查 CSRF token
+	refreshToken, fromCookie := input.RefreshToken, false
+	if refreshToken == "" {
+		if refreshToken, fromCookie = h.cookies.RefreshToken(c); !fromCookie {
+			logger.Logger.Debugf("Refresh token is missing") // DEBUG 等級
+			response.Error(c, http.StatusBadRequest, exception.ErrCodeInvalidRequest)
+			return
+		}
+		if !authcookie.VerifyCSRF(c) {
+			logger.Logger.Debugf("CSRF token missing or mismatched for cookie refresh") // DEBUG 等級
+			response.Error(c, http.StatusForbidden, exception.ErrCodeInvalidCSRFToken)
+			return
+		}
+	}
+
+	// 呼叫 user 刷新 token
+	tokens, err := h.userService.RefreshToken(refreshToken)
+	if err != nil {
+		logger.Logger.Debugf("Error refreshing token: %v", err) // DEBUG 等級
+		if fromCookie {
+			// cookie 中的 refresh token 已經不能使用，清除後由前端引導使用者重新登入
+			h.cookies.Clear(c)
+		}
+		c.Error(err)
+		return
+	}
+
+	// 回應新的 token 組合，使用 cookie 換發時一樣以 cookie 回傳
+	if fromCookie {
+		if tokens, err = h.cookies.SetTokens(c, tokens); err != nil {
+			logger.Logger.Debugf("Error setting auth cookies: %v", err) // DEBUG 等級
+			c.Error(err)
+			return
+		}
+	}
+	response.Success(c, http.StatusOK, "Token refreshed", tokens)
+}
+
+// Logout 處理登出目前裝置的請求
+// @Summary 登出
+// @Description 撤銷目前使用的 access token 與所屬的登入工作階段 (包含同一次登入的 refresh token)
+// @Tags User
+// @Accept  json
+// @Produce  json
+// @Param body body logoutRequest false "Refresh token (選填)"
+// @Security BearerAuth
+// @Success 200 {object} response.SuccessData "登出成功"
+// @Failure 400 {object} response.ErrorData "錯誤的請求"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /user/logout [post]
+func (h *Handler) Logout(c *gin.Context) {
+	// 從 gin.Context 中取得目前的呼叫者
+	caller, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var input logoutRequest
+	// 請求內容是選填的，空的 body 不視為錯誤
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		logger.Logger.Debugf(exception.ErrMsgInvalidRequestBody, err) // DEBUG 等級
+		response.BindError(c, err)
+		return
+	}
+
+	// 呼叫 user 登出
+	if err := h.userService.Logout(caller.UserID, caller.SessionID, caller.TokenID, caller.ExpiresAt, input.RefreshToken); err != nil {
+		logger.Logger.Debugf("Error logging out: %v", err) // DEBUG 等級
+		c.Error(err)
+		return
+	}
+
+	// 回應登出成功的訊息，使用 cookie 驗證時一併清除 cookie
+	if c.GetBool(constants.CtxCookieAuthKey) {
+		h.cookies.Clear(c)
+	}
+	response.Success(c, http.StatusOK, "Logout successful", nil)
+}
+
+// LogoutAll 處理登出所有裝置的請求
+// @Summary 登出所有裝置
+// @Description 讓使用者目前所有的 access token、refresh token 與登入工作階段失效
+// @Tags User
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} response.SuccessData "登出成功"
+// @Failure 500 {object} response.ErrorData "系統錯誤"
+// @Router /user/logout/all [post]
+func (h *Handler) LogoutAll(c *gin.Context) {
+	// 從 gin.Context 中取得目前登入使用者的 ID
+	id, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	// 呼叫 user 登出所有裝置
+	if err := h.userService.LogoutAll(id); err != nil {
+		logger.Logger.Debugf("Error logging out all sessions: %v", err) // DEBUG 等級
+		c.Error(err)
+		return
+	}
+
+	// 回應登出成功的訊息，使用 cookie 驗證時一併清除 cookie
+	if c.GetBool(constants.CtxCookieAuthKey) {
+		h.cookies.Clear(c)
+	}
+	response.Success(c, http.StatusOK, "Logged out from all sessions", nil)
 }
 
 // Get 處理取得使用者資訊的請求
 // @Summary 取得使用者資訊
-// @Description 取得指定使用者的資訊
+// @Description 取得目前登入使用者的資訊
 // @Tags User
 // @Produce  json
-// @Param id path int true "User ID"
 // @Security BearerAuth
 // @Success 200 {object} response.SuccessData{Data=models.User} "取得成功"
 // @Failure 404 {object} response.ErrorData "使用者不存在"
 // @Failure 500 {object} response.ErrorData "系統錯誤"
-// @Router /user/{id} [get]
+// @Router /user/me [get]
 func (h *Handler) Get(c *gin.Context) {
-	// 從 gin.Context 中取得 userID
-	userID, exists := c.Get(constants.CtxUserIDKey)
-	if !exists {
-		logger.Logger.Debugf(exception.ErrMsgUserIDNotInContext) // DEBUG 等級
-		response.Error(c, http.StatusInternalServerError, exception.ErrCodeUserIDNotInContext)
-		return
-	}
-
-	// 將 userID 轉成 uint 型別
-	id, ok := userID.(uint)
+	// 從 gin.Context 中取得目前登入使用者的 ID
+	id, ok := currentUserID(c)
 	if !ok {
-		logger.Logger.Debugf(exception.ErrMsgUserIDFormatInvalid) // DEBUG 等級
-		response.Error(c, http.StatusInternalServerError, exception.ErrCodeUserIDFormatInvalid)
 		return
 	}
 
 	// 呼叫 user 取得使用者資訊
 	user, err := h.userService.GetUserByID(id)
 	if err != nil {
-		logger.Logger.Errorf("Error getting user: %v", err) // ERROR 等級
-		// 根據不同的錯誤類型回覆不同的錯誤碼
-		if err == userSvc.ErrUserNotFound {
-			response.Error(c, http.StatusNotFound, exception.ErrCodeUserNotFound)
-		} else {
-			response.Error(c, http.StatusInternalServerError, exception.ErrCodeUnknown)
-		}
+		c.Error(err)
 		return
 	}
 
@@ -169,32 +369,22 @@ func (h *Handler) Get(c *gin.Context) {
 
 // Update 處理更新使用者資訊的請求
 // @Summary 更新使用者資訊
-// @Description 更新指定使用者的資訊
+// @Description 更新目前登入使用者的帳號名稱與電子郵件；變更密碼請使用 /user/me/password
 // @Tags User
 // @Accept  json
 // @Produce  json
-// @Param id path int true "使用者 ID"
 // @Security BearerAuth
 // @Param user body models.User true "使用者資料"
 // @Success 200 {object} response.SuccessData{Data=models.User} "更新成功"
-// @Failure 400 {object} response.ErrorData "錯誤的請求"
+// @Failure 400 {object} response.ErrorData "錯誤的請求，或帳號名稱、電子郵件不符合規則或已經被使用 (列出每個欄位的錯誤)"
 // @Failure 404 {object} response.ErrorData "使用者不存在"
+// @Failure 409 {object} response.ErrorData "帳號名稱或電子郵件已經被使用"
 // @Failure 500 {object} response.ErrorData "系統錯誤"
-// @Router /user/{id} [put]
+// @Router /user/me [put]
 func (h *Handler) Update(c *gin.Context) {
-	// 從 gin.Context 中取得 userID
-	userID, exists := c.Get(constants.CtxUserIDKey)
-	if !exists {
-		logger.Logger.Debugf(exception.ErrMsgUserIDNotInContext) // DEBUG 等級
-		response.Error(c, http.StatusInternalServerError, exception.ErrCodeUserIDNotInContext)
-		return
-	}
-
-	// 將 userID 轉成 uint 型別
-	id, ok := userID.(uint)
+	// 從 gin.Context 中取得目前登入使用者的 ID
+	id, ok := currentUserID(c)
 	if !ok {
-		logger.Logger.Debugf(exception.ErrMsgUserIDFormatInvalid) // DEBUG 等級
-		response.Error(c, http.StatusInternalServerError, exception.ErrCodeUserIDFormatInvalid)
 		return
 	}
 
@@ -202,7 +392,7 @@ func (h *Handler) Update(c *gin.Context) {
 	// 解析請求的 JSON 數據到 user 變數
 	if err := c.ShouldBindJSON(&user); err != nil {
 		logger.Logger.Debugf(exception.ErrMsgInvalidRequestBody, err) // DEBUG 等級
-		response.Error(c, http.StatusBadRequest, exception.ErrCodeInvalidRequest)
+		response.BindError(c, err)
 		return
 	}
 
@@ -210,8 +400,7 @@ func (h *Handler) Update(c *gin.Context) {
 	user.ID = id
 	// 呼叫 user 更新使用者資訊
 	if err := h.userService.UpdateUser(&user); err != nil {
-		logger.Logger.Errorf("Error updating user: %v", err) // ERROR 等級
-		response.Error(c, http.StatusInternalServerError, exception.ErrCodeUnknown)
+		c.Error(err)
 		return
 	}
 
@@ -222,36 +411,24 @@ func (h *Handler) Update(c *gin.Context) {
 
 // Delete 處理刪除使用者的請求
 // @Summary 刪除使用者
-// @Description 刪除指定使用者
+// @Description 刪除目前登入的使用者
 // @Tags User
 // @Produce  json
-// @Param id path int true "使用者 ID"
 // @Security BearerAuth
 // @Success 200 {object} response.SuccessData "刪除成功"
 // @Failure 404 {object} response.ErrorData "使用者不存在"
 // @Failure 500 {object} response.ErrorData "系統錯誤"
-// @Router /user/{id} [delete]
+// @Router /user/me [delete]
 func (h *Handler) Delete(c *gin.Context) {
-	// 從 gin.Context 中取得 userID
-	userID, exists := c.Get(constants.CtxUserIDKey)
-	if !exists {
-		logger.Logger.Debugf(exception.ErrMsgUserIDNotInContext) // DEBUG 等級
-		response.Error(c, http.StatusInternalServerError, exception.ErrCodeUserIDNotInContext)
-		return
-	}
-
-	// 將 userID 轉成 uint 型別
-	id, ok := userID.(uint)
+	// 從 gin.Context 中取得目前登入使用者的 ID
+	id, ok := currentUserID(c)
 	if !ok {
-		logger.Logger.Debugf(exception.ErrMsgUserIDFormatInvalid) // DEBUG 等級
-		response.Error(c, http.StatusInternalServerError, exception.ErrCodeUserIDFormatInvalid)
 		return
 	}
 
 	// 呼叫 user 刪除使用者
 	if err := h.userService.DeleteUser(id); err != nil {
-		logger.Logger.Errorf("Error deleting user: %v", err) // ERROR 等級
-		response.Error(c, http.StatusInternalServerError, exception.ErrCodeUnknown)
+		c.Error(err)
 		return
 	}
 
@@ -259,3 +436,50 @@ func (h *Handler) Delete(c *gin.Context) {
 	logger.Logger.Info("User deleted") // INFO 等級
 	response.Success(c, http.StatusOK, "User deleted successfully", nil)
 }
+
+// currentUser 從 gin.Context 中取得目前登入的使用者，取得失敗時會直接回應錯誤
+func currentUser(c *gin.Context) (*principal.Principal, bool) {
+	caller, ok := principal.FromContext(c)
+	if !ok {
+		logger.Logger.Debugf(exception.ErrMsgUserIDNotInContext) // DEBUG 等級
+		response.Error(c, http.StatusInternalServerError, exception.ErrCodeUserIDNotInContext)
+		return nil, false
+	}
+
+	// OAuth2 client 沒有使用者 ID
+	if caller.Kind != principal.KindUser {
+		logger.Logger.Debugf(exception.ErrMsgUserIDFormatInvalid) // DEBUG 等級
+		response.Error(c, http.StatusInternalServerError, exception.ErrCodeUserIDFormatInvalid)
+		return nil, false
+	}
+	return caller, true
+}
+
+// currentUserID 從 gin.Context 中取得目前登入使用者的 ID，取得失敗時會直接回應錯誤
+func currentUserID(c *gin.Context) (uint, bool) {
+	caller, ok := currentUser(c)
+	if !ok {
+		return 0, false
+	}
+	return caller.UserID, true
+}
+
+// clientInfo 取得請求的來源 IP 與 User-Agent，記錄在登入工作階段中
+func clientInfo(c *gin.Context) userSvc.ClientInfo {
+	return userSvc.ClientInfo{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+}
+
+// responseTokens 取得要放在回應內容中的 token 組合
+// 以 cookie 驗證模式回應時將 token 寫入 HttpOnly cookie，回傳不包含 token 的 TokenPair；失敗時直接回應錯誤
+func (h *Handler) responseTokens(c *gin.Context, tokens *userSvc.TokenPair) (*userSvc.TokenPair, bool) {
+	if tokens == nil || !h.cookies.Requested(c) {
+		return tokens, true
+	}
+	stripped, err := h.cookies.SetTokens(c, tokens)
+	if err != nil {
+		logger.Logger.Debugf("Error setting auth cookies: %v", err) // DEBUG 等級
+		c.Error(err)
+		return nil, false
+	}
+	return stripped, true
+}
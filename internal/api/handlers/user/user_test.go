@@ -0,0 +1,83 @@
+package user
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-template/internal/api/handlers/authcookie"
+	"go-template/internal/api/handlers/response"
+	"go-template/internal/configs"
+	"go-template/internal/middleware"
+	"go-template/internal/principal"
+	userSvc "go-template/internal/services/user"
+	"go-template/internal/utils/logger"
+)
+
+func TestMain(m *testing.M) {
+	_ = logger.Init(&logger.Config{Level: "error", ConsoleOut: true, ServiceName: "user-handler-test"})
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+// logoutService 只實作登出與登出所有裝置，回傳指定的錯誤
+type logoutService struct {
+	userSvc.Service
+	err error
+}
+
+func (s *logoutService) Logout(uint, string, string, time.Time, string) error {
+	return s.err
+}
+
+func (s *logoutService) LogoutAll(uint) error {
+	return s.err
+}
+
+// newLogoutRouter 建立以使用者 7 的身份呼叫登出與登出所有裝置的路由
+func newLogoutRouter(t *testing.T, svc *logoutService) *gin.Engine {
+	t.Helper()
+	cookies, err := authcookie.New(&configs.Config{AuthCookieSameSite: "lax"})
+	require.NoError(t, err)
+	handler := NewHandler(svc, cookies)
+	router := gin.New()
+	router.Use(middleware.ErrorHandler(), func(c *gin.Context) {
+		principal.Set(c, &principal.Principal{Kind: principal.KindUser, UserID: 7})
+	})
+	router.POST("/api/user/logout", handler.Logout)
+	router.POST("/api/user/logout/all", handler.LogoutAll)
+	return router
+}
+
+// 測試登出失敗時錯誤交給 ErrorHandler 依照錯誤的種類回應，不會一律回應 500
+func TestLogoutErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"application error", userSvc.ErrUserNotFound, http.StatusNotFound, userSvc.ErrUserNotFound.Code},
+		{"unexpected error", errors.New("connection reset"), http.StatusInternalServerError, "unknown"},
+	}
+	for _, tt := range tests {
+		for _, path := range []string{"/api/user/logout", "/api/user/logout/all"} {
+			t.Run(tt.name+" "+path, func(t *testing.T) {
+				w := httptest.NewRecorder()
+				newLogoutRouter(t, &logoutService{err: tt.err}).ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+
+				assert.Equal(t, tt.status, w.Code)
+				var body response.ErrorData
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+				assert.Equal(t, tt.code, body.Code)
+			})
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package wellknown
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go-template/internal/utils/jwt"
+)
+
+// Handler struct，用於處理 /.well-known 底下的公開資訊
+type Handler struct {
+	jwtService *jwt.Service
+}
+
+// NewHandler 建立一個新的 wellknown Handler 實例
+func NewHandler(jwtService *jwt.Service) *Handler {
+	return &Handler{jwtService: jwtService}
+}
+
+// JWKS 回傳驗證 token 用的公鑰集合
+// @Summary 取得 JWKS
+// @Description 取得驗證 access token 用的公開金鑰 (RFC 7517)，HMAC 密鑰不會被公開
+// @Tags WellKnown
+// @Produce  json
+// @Success 200 {object} jwt.JWKSet "公開金鑰集合"
+// @Router /.well-known/jwks.json [get]
+func (h *Handler) JWKS(c *gin.Context) {
+	// JWKS 是標準格式，直接回傳不包在 response.SuccessData 中
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.jwtService.JWKS())
+}
@@ -0,0 +1,104 @@
+package apperror
+
+import "errors"
+
+// Kind 錯誤的種類，由 ErrorHandler 中介軟體轉換成 HTTP 狀態碼
+type Kind int
+
+const (
+	KindInternal        Kind = iota // 非預期的錯誤，不會把訊息回應給用戶端
+	KindInvalid                     // 請求內容不正確
+	KindUnauthorized                // 沒有通過身份驗證，或憑證無效
+	KindForbidden                   // 沒有權限，或帳號目前不能執行這個操作
+	KindNotFound                    // 資源不存在
+	KindConflict                    // 與目前的資料狀態衝突，例如重複的帳號名稱
+	KindTooManyRequests             // 嘗試次數過多，需要等待一段時間
+)
+
+// Error 應用程式的錯誤，service 與 repository 回傳這個型別讓 handler 不需要逐一判斷錯誤
+// 可以直接宣告成 sentinel error 使用 == 比較；需要附上原因或詳細資訊時以 Wrap、WithDetails 複製一份
+type Error struct {
+	Kind    Kind         // 錯誤的種類
+	Code    string       // 穩定的字串代碼，例如 user_not_found，回應給用戶端，一旦公開就不能變更
+	Message string       // 給人看的錯誤訊息，會回應給用戶端
+	Cause   error        // 造成錯誤的原因，只寫入日誌
+	Details interface{}  // 錯誤的詳細資訊，例如重複的欄位
+	Fields  []FieldError // 每個欄位的驗證錯誤，讓用戶端可以標示每個錯誤的欄位
+}
+
+// FieldError 單一欄位的驗證錯誤
+type FieldError struct {
+	Field   string `json:"field"`           // 欄位路徑，使用 JSON 欄位名稱，例如 email 或 items[0].name
+	Rule    string `json:"rule"`            // 違反的規則，例如 required、email、min
+	Param   string `json:"param,omitempty"` // 規則的參數，例如 min=4 的 4
+	Message string `json:"message"`         // 給人看的錯誤訊息
+}
+
+// New 建立一個新的 Error
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// Error 實作 error 介面，包含原因方便記錄日誌
+func (e *Error) Error() string {
+	if e.Cause != nil {
+		return e.Message + ": " + e.Cause.Error()
+	}
+	return e.Message
+}
+
+// Unwrap 讓 errors.Is、errors.As 可以判斷原因，例如 gorm.ErrRecordNotFound
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// Is 相同字串代碼的 Error 視為相同的錯誤，讓 Wrap 之後的錯誤仍然可以用 errors.Is 與原本的 sentinel error 比較
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && e.Code == t.Code && e.Kind == t.Kind
+}
+
+// Wrap 複製一份錯誤並附上原因
+func (e *Error) Wrap(cause error) *Error {
+	wrapped := *e
+	wrapped.Cause = cause
+	return &wrapped
+}
+
+// WithMessage 複製一份錯誤並替換錯誤訊息
+func (e *Error) WithMessage(message string) *Error {
+	copied := *e
+	copied.Message = message
+	return &copied
+}
+
+// WithDetails 複製一份錯誤並附上詳細資訊
+func (e *Error) WithDetails(details interface{}) *Error {
+	copied := *e
+	copied.Details = details
+	return &copied
+}
+
+// WithFields 複製一份錯誤並附上每個欄位的驗證錯誤
+func (e *Error) WithFields(fields []FieldError) *Error {
+	copied := *e
+	copied.Fields = fields
+	return &copied
+}
+
+// As 取得錯誤鏈中的第一個 *Error
+func As(err error) (*Error, bool) {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr, true
+	}
+	return nil, false
+}
+
+// KindOf 取得錯誤的種類，不是 *Error 的錯誤視為 KindInternal
+func KindOf(err error) Kind {
+	if appErr, ok := As(err); ok {
+		return appErr.Kind
+	}
+	return KindInternal
+}
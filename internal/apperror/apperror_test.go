@@ -0,0 +1,54 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errTestNotFound = New(KindNotFound, "thing_not_found", "thing not found")
+
+// 測試 Wrap 之後仍然可以與 sentinel error 及原因比較，sentinel error 本身不會被修改
+func TestWrap(t *testing.T) {
+	cause := errors.New("record not found")
+	err := fmt.Errorf("get thing: %w", errTestNotFound.Wrap(cause))
+
+	assert.ErrorIs(t, err, errTestNotFound)
+	assert.ErrorIs(t, err, cause)
+	assert.Equal(t, "get thing: thing not found: record not found", err.Error())
+	assert.Nil(t, errTestNotFound.Cause)
+
+	assert.NotErrorIs(t, err, New(KindNotFound, "other_not_found", "thing not found"))
+	assert.NotErrorIs(t, err, New(KindConflict, "thing_not_found", "thing not found"))
+}
+
+// 測試 WithMessage、WithDetails 與 WithFields 複製一份錯誤，相同字串代碼的錯誤仍然視為相同
+func TestWithCopies(t *testing.T) {
+	fields := []FieldError{{Field: "name", Rule: "required", Message: "name is required"}}
+	err := errTestNotFound.WithMessage("no such thing").WithDetails(map[string]string{"id": "7"}).WithFields(fields)
+
+	assert.ErrorIs(t, err, errTestNotFound)
+	assert.Equal(t, "no such thing", err.Message)
+	assert.Equal(t, map[string]string{"id": "7"}, err.Details)
+	assert.Equal(t, fields, err.Fields)
+	assert.Equal(t, "thing not found", errTestNotFound.Message)
+	assert.Nil(t, errTestNotFound.Details)
+	assert.Nil(t, errTestNotFound.Fields)
+}
+
+// 測試 As 與 KindOf 取得錯誤鏈中的 *Error，其他錯誤視為 KindInternal
+func TestKindOf(t *testing.T) {
+	wrapped := fmt.Errorf("handler: %w", errTestNotFound)
+	appErr, ok := As(wrapped)
+	require.True(t, ok)
+	assert.Same(t, errTestNotFound, appErr)
+	assert.Equal(t, KindNotFound, KindOf(wrapped))
+
+	_, ok = As(errors.New("boom"))
+	assert.False(t, ok)
+	assert.Equal(t, KindInternal, KindOf(errors.New("boom")))
+	assert.Equal(t, KindInternal, KindOf(nil))
+}
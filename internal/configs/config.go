@@ -3,6 +3,7 @@ package configs
 import (
 	"errors"
 	"fmt"
+	"net"
 	"os"
 	"strconv"
 	"strings"
@@ -14,16 +15,73 @@ import (
 
 // Config struct，定義了應用程式的配置
 type Config struct {
-	DBHost         string        // 資料庫主機
-	DBPort         int           // 資料庫埠號
-	DBUser         string        // 資料庫使用者名稱
-	DBPassword     string        // 資料庫密碼
-	DBName         string        // 資料庫名稱
-	JWTSecret      string        // JWT 密鑰
-	JWTOldSecrets  []string      // 舊的 JWT 密鑰，用於支援密鑰輪換
-	TokenExpiresIn time.Duration // Token 過期時間
-	AppPort        int           // 應用程式埠號
-	Logger         logger.Config // 日誌配置
+	DBHost                          string        // 資料庫主機
+	DBPort                          int           // 資料庫埠號
+	DBUser                          string        // 資料庫使用者名稱
+	DBPassword                      string        // 資料庫密碼
+	DBName                          string        // 資料庫名稱
+	JWTSecret                       string        // JWT 密鑰
+	JWTAlgorithm                    string        // JWT 簽署演算法: HS256、RS256、ES256、EdDSA
+	JWTPrivateKeyFile               string        // 非對稱演算法使用的 PEM 私鑰檔案路徑
+	JWTKeyID                        string        // JWT header 中的 kid，未設定時自動產生
+	JWTKeyringFile                  string        // JWT keyring 設定檔，設定後會取代上面的單一金鑰設定
+	JWTKeyringReloadInterval        time.Duration // 檢查 keyring 設定檔是否變動的間隔
+	JWTOldSecrets                   []string      // 舊的 JWT 密鑰，用於支援密鑰輪換
+	JWTIssuer                       string        // JWT 的發行者 (iss)，驗證時必須相符
+	JWTAudience                     string        // JWT 的接收者 (aud)，驗證時必須相符
+	JWTTenant                       string        // 寫入 token 的租戶 ID (tid)，設定後只接受相同租戶的 token
+	TokenExpiresIn                  time.Duration // Access token 過期時間
+	RefreshTokenExpiresIn           time.Duration // Refresh token 過期時間
+	TokenRevocationStore            string        // Token 撤銷清單的儲存方式: database、memory
+	SessionLastSeenFlushInterval    time.Duration // 登入工作階段最後使用時間批次寫入資料庫的間隔
+	AuthCookieEnabled               bool          // 是否允許瀏覽器用戶端以 HttpOnly cookie 保存 token
+	AuthCookieSecure                bool          // cookie 是否只透過 HTTPS 傳送
+	AuthCookieSameSite              string        // cookie 的 SameSite 屬性: strict、lax、none
+	AuthCookieDomain                string        // cookie 的 Domain 屬性，未設定時只送回設定 cookie 的主機
+	AdminUsernames                  []string      // 執行 migration 時會被指派 admin 角色的使用者名稱
+	TrustedProxies                  []string      // 信任的反向代理 IP 或 CIDR，只採用這些位址送來的 X-Forwarded-For，未設定時一律使用連線的來源 IP
+	ErrorFormat                     string        // 錯誤回應的預設格式: legacy (ErrorData)、problem (RFC 7807 application/problem+json)
+	AppBaseURL                      string        // 寄給使用者的信件中連結使用的網址
+	MailerDriver                    string        // 寄信方式: smtp、outbox
+	SMTPHost                        string        // SMTP 伺服器主機
+	SMTPPort                        int           // SMTP 伺服器埠號
+	SMTPUsername                    string        // SMTP 帳號，未設定時不進行驗證
+	SMTPPassword                    string        // SMTP 密碼
+	MailFrom                        string        // 寄件者
+	MailOutboxDir                   string        // outbox 寫入信件檔案的目錄，未設定時只保存在記憶體
+	EmailVerificationExpiresIn      time.Duration // 電子郵件驗證 token 的有效時間
+	EmailVerificationResendInterval time.Duration // 重新寄送驗證信的最短間隔
+	PasswordResetExpiresIn          time.Duration // 重設密碼 token 的有效時間
+	PasswordResetResendInterval     time.Duration // 重新寄送重設密碼信的最短間隔
+	MagicLinkEnabled                bool          // 是否允許以寄到電子郵件的登入連結登入 (不需要密碼)
+	MagicLinkExpiresIn              time.Duration // 登入連結的有效時間
+	MagicLinkResendInterval         time.Duration // 重新寄送登入連結的最短間隔
+	ImpersonationExpiresIn          time.Duration // 管理者代替使用者操作的 access token 的有效時間
+	MFAIssuer                       string        // 兩步驟驗證在驗證器 App 中顯示的服務名稱
+	MFAPendingExpiresIn             time.Duration // 密碼驗證成功後，完成兩步驟驗證的期限
+	LoginThrottleStore              string        // 登入失敗計數的儲存方式: database、memory
+	LoginMaxAttempts                int           // 同一個使用者名稱連續登入失敗幾次後暫時鎖定，0 代表不鎖定
+	LoginIPMaxAttempts              int           // 同一個 IP 連續登入失敗幾次後暫時鎖定，0 代表不鎖定
+	LoginAttemptWindow              time.Duration // 登入失敗的計數區間，最後一次失敗超過這段時間後重新計數
+	LoginBackoffBase                time.Duration // 第一次登入失敗後需要等待的時間，之後每次失敗加倍
+	LoginBackoffMax                 time.Duration // 登入失敗後等待時間的上限
+	LoginLockoutDuration            time.Duration // 暫時鎖定的時間
+	PasswordMinLength               int           // 密碼最短長度 (字元數)
+	PasswordMaxLength               int           // 密碼最長長度 (字元數)，0 代表不限制
+	PasswordRequireUpper            bool          // 密碼必須包含大寫字母
+	PasswordRequireLower            bool          // 密碼必須包含小寫字母
+	PasswordRequireDigit            bool          // 密碼必須包含數字
+	PasswordRequireSymbol           bool          // 密碼必須包含符號
+	PasswordDisallowPersonalInfo    bool          // 密碼不得包含帳號名稱或電子郵件
+	PasswordMinStrength             int           // 密碼最低的強度分數 (0 ~ 4)，0 代表不檢查
+	PasswordBreachedListDir         string        // 外洩密碼雜湊清單的目錄，未設定時不檢查
+	PasswordHashAlgorithm           string        // 新密碼使用的雜湊演算法 (argon2id 或 bcrypt)
+	PasswordBcryptCost              int           // bcrypt 的 cost
+	PasswordArgon2Memory            int           // Argon2id 使用的記憶體 (KiB)
+	PasswordArgon2Iterations        int           // Argon2id 的迭代次數
+	PasswordArgon2Parallelism       int           // Argon2id 的平行度
+	AppPort                         int           // 應用程式埠號
+	Logger                          logger.Config // 日誌配置
 }
 
 // LoadConfig 載入配置
@@ -49,12 +107,159 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("invalid PORT: %w", err)
 	}
 
-	// 讀取 TOKEN_EXPIRES_IN 環境變數，如果不存在則預設為 24 (小時)
-	tokenExpiresIn, err := strconv.ParseInt(getEnv("TOKEN_EXPIRES_IN", "24"), 10, 64)
+	// 讀取 TOKEN_EXPIRES_IN 環境變數 (access token)，如果不存在則預設為 15 分鐘
+	// 為了相容舊設定，純數字仍視為小時
+	tokenExpiresIn, err := getDurationEnv("TOKEN_EXPIRES_IN", "15m", time.Hour)
 	if err != nil {
 		return nil, fmt.Errorf("invalid TOKEN_EXPIRES_IN: %w", err)
 	}
 
+	// 讀取 REFRESH_TOKEN_EXPIRES_IN 環境變數，如果不存在則預設為 720 小時 (30 天)
+	refreshTokenExpiresIn, err := getDurationEnv("REFRESH_TOKEN_EXPIRES_IN", "720h", time.Hour)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REFRESH_TOKEN_EXPIRES_IN: %w", err)
+	}
+
+	// 讀取 SESSION_LAST_SEEN_FLUSH_INTERVAL 環境變數，如果不存在則預設為 1 分鐘
+	sessionLastSeenFlushInterval, err := getDurationEnv("SESSION_LAST_SEEN_FLUSH_INTERVAL", "1m", time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SESSION_LAST_SEEN_FLUSH_INTERVAL: %w", err)
+	}
+
+	// 讀取 JWT_KEYRING_RELOAD_INTERVAL 環境變數，如果不存在則預設為 1 分鐘
+	keyringReloadInterval, err := getDurationEnv("JWT_KEYRING_RELOAD_INTERVAL", "1m", time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT_KEYRING_RELOAD_INTERVAL: %w", err)
+	}
+
+	// 讀取 SMTP_PORT 環境變數，如果不存在則預設為 587
+	smtpPort, err := strconv.Atoi(getEnv("SMTP_PORT", "587"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP_PORT: %w", err)
+	}
+
+	// 讀取 EMAIL_VERIFICATION_EXPIRES_IN 環境變數，如果不存在則預設為 24 小時
+	emailVerificationExpiresIn, err := getDurationEnv("EMAIL_VERIFICATION_EXPIRES_IN", "24h", time.Hour)
+	if err != nil {
+		return nil, fmt.Errorf("invalid EMAIL_VERIFICATION_EXPIRES_IN: %w", err)
+	}
+
+	// 讀取 EMAIL_VERIFICATION_RESEND_INTERVAL 環境變數，如果不存在則預設為 1 分鐘
+	emailVerificationResendInterval, err := getDurationEnv("EMAIL_VERIFICATION_RESEND_INTERVAL", "1m", time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("invalid EMAIL_VERIFICATION_RESEND_INTERVAL: %w", err)
+	}
+
+	// 讀取 PASSWORD_RESET_EXPIRES_IN 環境變數，如果不存在則預設為 1 小時
+	passwordResetExpiresIn, err := getDurationEnv("PASSWORD_RESET_EXPIRES_IN", "1h", time.Hour)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PASSWORD_RESET_EXPIRES_IN: %w", err)
+	}
+
+	// 讀取 PASSWORD_RESET_RESEND_INTERVAL 環境變數，如果不存在則預設為 1 分鐘
+	passwordResetResendInterval, err := getDurationEnv("PASSWORD_RESET_RESEND_INTERVAL", "1m", time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PASSWORD_RESET_RESEND_INTERVAL: %w", err)
+	}
+
+	// 讀取 MAGIC_LINK_EXPIRES_IN 環境變數，如果不存在則預設為 15 分鐘
+	magicLinkExpiresIn, err := getDurationEnv("MAGIC_LINK_EXPIRES_IN", "15m", time.Minute)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MAGIC_LINK_EXPIRES_IN: %w", err)
+	}
+
+	// 讀取 MAGIC_LINK_RESEND_INTERVAL 環境變數，如果不存在則預設為 1 分鐘
+	magicLinkResendInterval, err := getDurationEnv("MAGIC_LINK_RESEND_INTERVAL", "1m", time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MAGIC_LINK_RESEND_INTERVAL: %w", err)
+	}
+
+	// 讀取 IMPERSONATION_EXPIRES_IN 環境變數，如果不存在則預設為 15 分鐘
+	impersonationExpiresIn, err := getDurationEnv("IMPERSONATION_EXPIRES_IN", "15m", time.Minute)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IMPERSONATION_EXPIRES_IN: %w", err)
+	}
+
+	// 讀取 MFA_PENDING_EXPIRES_IN 環境變數，如果不存在則預設為 5 分鐘
+	mfaPendingExpiresIn, err := getDurationEnv("MFA_PENDING_EXPIRES_IN", "5m", time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MFA_PENDING_EXPIRES_IN: %w", err)
+	}
+
+	// 讀取 LOGIN_MAX_ATTEMPTS 環境變數，如果不存在則預設為 5 次
+	loginMaxAttempts, err := strconv.Atoi(getEnv("LOGIN_MAX_ATTEMPTS", "5"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOGIN_MAX_ATTEMPTS: %w", err)
+	}
+
+	// 讀取 LOGIN_IP_MAX_ATTEMPTS 環境變數，如果不存在則預設為 20 次
+	loginIPMaxAttempts, err := strconv.Atoi(getEnv("LOGIN_IP_MAX_ATTEMPTS", "20"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOGIN_IP_MAX_ATTEMPTS: %w", err)
+	}
+
+	// 讀取 LOGIN_ATTEMPT_WINDOW 環境變數，如果不存在則預設為 15 分鐘
+	loginAttemptWindow, err := getDurationEnv("LOGIN_ATTEMPT_WINDOW", "15m", time.Minute)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOGIN_ATTEMPT_WINDOW: %w", err)
+	}
+
+	// 讀取 LOGIN_BACKOFF_BASE 環境變數，如果不存在則預設為 1 秒
+	loginBackoffBase, err := getDurationEnv("LOGIN_BACKOFF_BASE", "1s", time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOGIN_BACKOFF_BASE: %w", err)
+	}
+
+	// 讀取 LOGIN_BACKOFF_MAX 環境變數，如果不存在則預設為 30 秒
+	loginBackoffMax, err := getDurationEnv("LOGIN_BACKOFF_MAX", "30s", time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOGIN_BACKOFF_MAX: %w", err)
+	}
+
+	// 讀取 LOGIN_LOCKOUT_DURATION 環境變數，如果不存在則預設為 15 分鐘
+	loginLockoutDuration, err := getDurationEnv("LOGIN_LOCKOUT_DURATION", "15m", time.Minute)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOGIN_LOCKOUT_DURATION: %w", err)
+	}
+
+	// 讀取 PASSWORD_MIN_LENGTH 環境變數，如果不存在則預設為 8 個字元
+	passwordMinLength, err := strconv.Atoi(getEnv("PASSWORD_MIN_LENGTH", "8"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid PASSWORD_MIN_LENGTH: %w", err)
+	}
+
+	// 讀取 PASSWORD_MAX_LENGTH 環境變數，如果不存在則預設為 64 個字元
+	passwordMaxLength, err := strconv.Atoi(getEnv("PASSWORD_MAX_LENGTH", "64"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid PASSWORD_MAX_LENGTH: %w", err)
+	}
+
+	// 讀取 PASSWORD_MIN_STRENGTH 環境變數，如果不存在則預設為 2
+	passwordMinStrength, err := strconv.Atoi(getEnv("PASSWORD_MIN_STRENGTH", "2"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid PASSWORD_MIN_STRENGTH: %w", err)
+	}
+
+	// 讀取 PASSWORD_BCRYPT_COST 環境變數，如果不存在則預設為 12
+	passwordBcryptCost, err := strconv.Atoi(getEnv("PASSWORD_BCRYPT_COST", "12"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid PASSWORD_BCRYPT_COST: %w", err)
+	}
+
+	// 讀取 Argon2id 的參數，預設值為 OWASP 建議的 m=19 MiB, t=2, p=1
+	passwordArgon2Memory, err := strconv.Atoi(getEnv("PASSWORD_ARGON2_MEMORY", "19456"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid PASSWORD_ARGON2_MEMORY: %w", err)
+	}
+	passwordArgon2Iterations, err := strconv.Atoi(getEnv("PASSWORD_ARGON2_ITERATIONS", "2"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid PASSWORD_ARGON2_ITERATIONS: %w", err)
+	}
+	passwordArgon2Parallelism, err := strconv.Atoi(getEnv("PASSWORD_ARGON2_PARALLELISM", "1"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid PASSWORD_ARGON2_PARALLELISM: %w", err)
+	}
+
 	// 讀取 JWT_SECRET
 	jwtSecret := getEnv("JWT_SECRET", "")
 
@@ -64,17 +269,87 @@ func LoadConfig() (*Config, error) {
 		jwtOldSecrets = strings.Split(oldSecretsStr, ",")
 	}
 
+	// 讀取 ADMIN_USERNAMES (用逗號分隔的多個使用者名稱)
+	var adminUsernames []string
+	if adminUsernamesStr := getEnv("ADMIN_USERNAMES", ""); adminUsernamesStr != "" {
+		adminUsernames = strings.Split(adminUsernamesStr, ",")
+	}
+
+	// 讀取 TRUSTED_PROXIES (用逗號分隔的多個 IP 或 CIDR)，預設不信任任何代理
+	// 信任所有代理時任何人都可以用 X-Forwarded-For 偽造來源 IP，繞過以 IP 計算的登入失敗限制
+	trustedProxies, err := parseTrustedProxies(getEnv("TRUSTED_PROXIES", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+
 	// 建立 Config 結構體並返回
 	return &Config{
-		DBHost:         getEnv("DB_HOST", "localhost"), // 預設為 localhost
-		DBPort:         dbPort,
-		DBUser:         getEnv("DB_USERNAME", "postgres"), // 預設為 postgres
-		DBPassword:     getEnv("DB_PASSWORD", ""),         // 預設為空
-		DBName:         getEnv("DB_DATABASE", "mydb"),     // 預設為 mydb
-		JWTSecret:      jwtSecret,
-		JWTOldSecrets:  jwtOldSecrets,
-		TokenExpiresIn: time.Duration(tokenExpiresIn) * time.Hour, // 將小時轉換成 time.Duration
-		AppPort:        appPort,
+		DBHost:                          getEnv("DB_HOST", "localhost"), // 預設為 localhost
+		DBPort:                          dbPort,
+		DBUser:                          getEnv("DB_USERNAME", "postgres"), // 預設為 postgres
+		DBPassword:                      getEnv("DB_PASSWORD", ""),         // 預設為空
+		DBName:                          getEnv("DB_DATABASE", "mydb"),     // 預設為 mydb
+		JWTSecret:                       jwtSecret,
+		JWTAlgorithm:                    getEnv("JWT_ALGORITHM", "HS256"), // 預設為 HS256
+		JWTPrivateKeyFile:               getEnv("JWT_PRIVATE_KEY_FILE", ""),
+		JWTKeyID:                        getEnv("JWT_KEY_ID", ""),
+		JWTKeyringFile:                  getEnv("JWT_KEYRING_FILE", ""),
+		JWTKeyringReloadInterval:        keyringReloadInterval,
+		JWTOldSecrets:                   jwtOldSecrets,
+		JWTIssuer:                       getEnv("JWT_ISSUER", "go-template"),   // 預設為 go-template
+		JWTAudience:                     getEnv("JWT_AUDIENCE", "go-template"), // 預設為 go-template
+		JWTTenant:                       getEnv("JWT_TENANT", ""),
+		TokenExpiresIn:                  tokenExpiresIn,
+		RefreshTokenExpiresIn:           refreshTokenExpiresIn,
+		TokenRevocationStore:            getEnv("TOKEN_REVOCATION_STORE", "database"), // 預設為 database
+		SessionLastSeenFlushInterval:    sessionLastSeenFlushInterval,
+		AuthCookieEnabled:               getBoolEnv("AUTH_COOKIE_ENABLED", false),
+		AuthCookieSecure:                getBoolEnv("AUTH_COOKIE_SECURE", true),
+		AuthCookieSameSite:              getEnv("AUTH_COOKIE_SAMESITE", "strict"), // 預設為 strict
+		AuthCookieDomain:                getEnv("AUTH_COOKIE_DOMAIN", ""),
+		AdminUsernames:                  adminUsernames,
+		TrustedProxies:                  trustedProxies,
+		ErrorFormat:                     getEnv("ERROR_FORMAT", "legacy"), // 預設為 legacy，維持原本的回應格式
+		AppBaseURL:                      strings.TrimSuffix(getEnv("APP_BASE_URL", "http://localhost:8080"), "/"),
+		MailerDriver:                    getEnv("MAILER", "outbox"), // 預設為 outbox
+		SMTPHost:                        getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:                        smtpPort,
+		SMTPUsername:                    getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:                    getEnv("SMTP_PASSWORD", ""),
+		MailFrom:                        getEnv("MAIL_FROM", "no-reply@localhost"),
+		MailOutboxDir:                   getEnv("MAIL_OUTBOX_DIR", ""),
+		EmailVerificationExpiresIn:      emailVerificationExpiresIn,
+		EmailVerificationResendInterval: emailVerificationResendInterval,
+		PasswordResetExpiresIn:          passwordResetExpiresIn,
+		PasswordResetResendInterval:     passwordResetResendInterval,
+		MagicLinkEnabled:                getBoolEnv("MAGIC_LINK_ENABLED", false), // 預設不開啟
+		MagicLinkExpiresIn:              magicLinkExpiresIn,
+		MagicLinkResendInterval:         magicLinkResendInterval,
+		ImpersonationExpiresIn:          impersonationExpiresIn,
+		MFAIssuer:                       getEnv("MFA_ISSUER", "go-template"), // 預設為 go-template
+		MFAPendingExpiresIn:             mfaPendingExpiresIn,
+		LoginThrottleStore:              getEnv("LOGIN_THROTTLE_STORE", "database"), // 預設為 database
+		LoginMaxAttempts:                loginMaxAttempts,
+		LoginIPMaxAttempts:              loginIPMaxAttempts,
+		LoginAttemptWindow:              loginAttemptWindow,
+		LoginBackoffBase:                loginBackoffBase,
+		LoginBackoffMax:                 loginBackoffMax,
+		LoginLockoutDuration:            loginLockoutDuration,
+		PasswordMinLength:               passwordMinLength,
+		PasswordMaxLength:               passwordMaxLength,
+		PasswordRequireUpper:            getBoolEnv("PASSWORD_REQUIRE_UPPER", false),
+		PasswordRequireLower:            getBoolEnv("PASSWORD_REQUIRE_LOWER", false),
+		PasswordRequireDigit:            getBoolEnv("PASSWORD_REQUIRE_DIGIT", false),
+		PasswordRequireSymbol:           getBoolEnv("PASSWORD_REQUIRE_SYMBOL", false),
+		PasswordDisallowPersonalInfo:    getBoolEnv("PASSWORD_DISALLOW_PERSONAL_INFO", true),
+		PasswordMinStrength:             passwordMinStrength,
+		PasswordBreachedListDir:         getEnv("PASSWORD_BREACHED_LIST_DIR", ""),
+		PasswordHashAlgorithm:           getEnv("PASSWORD_HASH_ALGORITHM", "argon2id"),
+		PasswordBcryptCost:              passwordBcryptCost,
+		PasswordArgon2Memory:            passwordArgon2Memory,
+		PasswordArgon2Iterations:        passwordArgon2Iterations,
+		PasswordArgon2Parallelism:       passwordArgon2Parallelism,
+		AppPort:                         appPort,
 		Logger: logger.Config{
 			Level:       getEnv("LOG_LEVEL", "info"),
 			Filename:    getEnv("LOG_FILENAME", "logs/app"),
@@ -96,6 +371,16 @@ func getEnv(key, defaultValue string) string {
 	return value
 }
 
+// getDurationEnv 是一個輔助函數，用於取得環境變數 (時間長度)，並在環境變數不存在時提供預設值
+// 值可以是 time.ParseDuration 支援的格式 (例如 15m、720h)，純數字則以 unit 為單位
+func getDurationEnv(key, defaultValue string, unit time.Duration) (time.Duration, error) {
+	valueStr := getEnv(key, defaultValue)
+	if value, err := strconv.ParseInt(valueStr, 10, 64); err == nil {
+		return time.Duration(value) * unit, nil
+	}
+	return time.ParseDuration(valueStr)
+}
+
 //// getIntEnv 是一個輔助函數，用於取得環境變數 (整數)，並在環境變數不存在時提供預設值
 //func getIntEnv(key string, defaultValue int) int {
 //	valueStr := getEnv(key, "")
@@ -113,3 +398,19 @@ func getBoolEnv(key string, defaultValue bool) bool {
 	}
 	return defaultValue
 }
+
+// parseTrustedProxies 解析用逗號分隔的 IP 或 CIDR，空字串代表不信任任何代理
+func parseTrustedProxies(value string) ([]string, error) {
+	var proxies []string
+	for _, proxy := range strings.Split(value, ",") {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return nil, fmt.Errorf("%q is not an IP address or CIDR", proxy)
+		}
+		proxies = append(proxies, proxy)
+	}
+	return proxies, nil
+}
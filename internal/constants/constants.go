@@ -2,5 +2,8 @@ package constants
 
 // 定義整個應用程式中使用的常數
 const (
-	CtxUserIDKey = "userID" // 在 gin.Context 中儲存使用者 ID 的 key
+	CtxPrincipalKey   = "principal"   // 在 gin.Context 中儲存通過驗證的呼叫者 (*principal.Principal) 的 key，請使用 principal.FromContext 取得
+	CtxPermissionsKey = "permissions" // 在 gin.Context 中儲存權限 (map[string]bool) 的 key
+	CtxCookieAuthKey  = "cookieAuth"  // access token 來自 cookie 而不是 Authorization header 時，在 gin.Context 中儲存 true 的 key
+	CtxRequestIDKey   = "requestID"   // 在 gin.Context 中儲存請求 ID (string) 的 key，由 RequestID 中介軟體設定
 )
@@ -1,46 +1,421 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"go-template/internal/api/handlers/authcookie"
 	"go-template/internal/api/handlers/exception"
 	"go-template/internal/api/handlers/response"
+	"go-template/internal/apperror"
 	"go-template/internal/constants"
+	"go-template/internal/models"
+	"go-template/internal/principal"
+	"go-template/internal/services/oauth"
+	"go-template/internal/services/rbac"
+	"go-template/internal/services/revocation"
+	"go-template/internal/services/session"
+	userSvc "go-template/internal/services/user"
 	"go-template/internal/utils/jwt"
 	"go-template/internal/utils/logger"
 )
 
-// Auth 驗證 JWT token 的中介軟體
-func Auth(jwtService *jwt.Service) gin.HandlerFunc {
+// Auth 驗證 JWT access token 或 API key 的中介軟體
+// access token 可以屬於使用者，也可以屬於以 client credentials 取得 token 的 OAuth2 client
+// 管理者代替使用者操作 (impersonation) 的 token 預設不能呼叫 DELETE 路由，每個請求都會寫入稽核紀錄
+type Auth struct {
+	jwtService      *jwt.Service
+	revocationStore revocation.Store
+	rbacService     rbac.Service
+	userService     userSvc.Service
+	oauthService    oauth.Service
+	sessionTracker  *session.Tracker
+	cookies         *authcookie.Manager
+}
+
+// NewAuth 建立一個新的 Auth 中介軟體實例
+func NewAuth(jwtService *jwt.Service, revocationStore revocation.Store, rbacService rbac.Service,
+	userService userSvc.Service, oauthService oauth.Service, sessionTracker *session.Tracker,
+	cookies *authcookie.Manager) *Auth {
+	if jwtService == nil {
+		logger.Logger.Error("jwtService is nil in Auth") // 新增日誌
+		panic("jwtService is nil")                       // 或者返回錯誤，避免 panic
+	}
+	return &Auth{
+		jwtService:      jwtService,
+		revocationStore: revocationStore,
+		rbacService:     rbacService,
+		userService:     userService,
+		oauthService:    oauthService,
+		sessionTracker:  sessionTracker,
+		cookies:         cookies,
+	}
+}
+
+// API key 的傳送方式：X-API-Key header，或 Authorization: ApiKey <key>
+const (
+	apiKeyHeader     = "X-API-Key"
+	apiKeyAuthScheme = "ApiKey "
+)
+
+// identity 通過驗證的呼叫者
+type identity struct {
+	userID       uint
+	roles        []string
+	claims       *jwt.Claims          // 使用 access token 時的 token 資訊
+	apiKey       *models.APIKey       // 使用 API key 時的 API key
+	clientScopes []string             // OAuth2 client 目前被允許的權限，呼叫者為使用者時為 nil
+	actor        *principal.Principal // 代替使用者操作時實際操作的管理者
+}
+
+// Handle 回傳驗證 JWT access token 或 API key 的 gin.HandlerFunc
+func (m *Auth) Handle() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if jwtService == nil {
-			logger.Logger.Error("jwtService is nil in Auth") // 新增日誌
-			panic("jwtService is nil")                       // 或者返回錯誤，避免 panic
-		}
-		// 從 Authorization header 中取得 token
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			logger.Logger.Debugf("Authorization header is missing")
-			response.Error(c, http.StatusUnauthorized, exception.ErrCodeInvalidRequest)
+		var caller *identity
+		var ok bool
+		if key, found := apiKeyFromRequest(c); found {
+			caller, ok = m.authenticateAPIKey(c, key)
+		} else {
+			caller, ok = m.authenticateToken(c)
+		}
+		if !ok {
+			c.Abort() // 中止後續的處理函數
+			return
+		}
+
+		if caller.claims != nil && caller.claims.IsClient() {
+			m.setClient(c, caller)
+			c.Next()
+			return
+		}
+
+		// 檢查帳號目前的狀態，停權、鎖定或停用的帳號不能繼續使用已發行的 token 或 API key
+		// 帳號狀態的錯誤由 ErrorHandler 回應 403 與對應的錯誤碼
+		if err := m.userService.CheckAccountActive(caller.userID); err != nil {
+			if errors.Is(err, userSvc.ErrUserNotFound) {
+				response.Error(c, http.StatusUnauthorized, exception.ErrCodeTokenRevoked)
+			} else {
+				c.Error(err)
+			}
 			c.Abort() // 中止後續的處理函數
 			return
 		}
 
-		// 解析 Bearer token
-		tokenString := authHeader[len("Bearer "):]
-		userID, err := jwtService.ValidateToken(tokenString)
+		// 將角色展開成權限，供 Require 中介軟體檢查
+		permissions, err := m.rbacService.PermissionsForRoles(caller.roles)
 		if err != nil {
-			logger.Logger.Debugf("Invalid token: %v", err)
-			response.Error(c, http.StatusUnauthorized, exception.ErrCodeInvalidCredentials)
+			logger.Logger.Errorf("Error resolving permissions: %v", err)
+			response.Error(c, http.StatusInternalServerError, exception.ErrCodeUnknown)
 			c.Abort() // 中止後續的處理函數
 			return
 		}
+		if caller.apiKey != nil {
+			// API key 只能使用建立時指定、而且擁有者目前仍然擁有的權限
+			permissions = restrictToScopes(permissions, caller.apiKey.Scopes)
+		}
 
-		// 將 userID 儲存到 gin.Context 中，方便後續的處理函數使用
-		c.Set(constants.CtxUserIDKey, userID)
+		// 將呼叫者與權限儲存到 gin.Context 中，方便後續的處理函數使用
+		p := caller.principal()
+		principal.Set(c, p)
+		c.Set(constants.CtxPermissionsKey, permissions)
+
+		// 記錄登入工作階段的最後使用時間，由 Tracker 批次寫入資料庫
+		if caller.claims != nil {
+			m.sessionTracker.Touch(caller.claims.SessionID)
+		}
+
+		if p.IsImpersonated() {
+			// 請求處理完成後寫入稽核紀錄，被拒絕的請求也會記錄
+			defer m.auditImpersonation(c, p)
+			if c.Request.Method == http.MethodDelete {
+				logger.Logger.Debugf("Impersonated DELETE %s refused for admin %d", c.FullPath(), p.Actor.UserID)
+				response.Error(c, http.StatusForbidden, exception.ErrCodeImpersonationNotAllowed)
+				c.Abort() // 中止後續的處理函數
+				return
+			}
+		}
 
 		// 呼叫下一個處理函數
 		c.Next()
 	}
 }
+
+// authenticateToken 驗證 Authorization header 中的 Bearer access token，失敗時直接回應錯誤
+// 開啟 cookie 驗證模式時，沒有 Authorization header 的請求改用 cookie 中的 access token
+func (m *Auth) authenticateToken(c *gin.Context) (*identity, bool) {
+	tokenString, ok := m.accessToken(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, exception.ErrCodeInvalidRequest)
+		return nil, false
+	}
+	claims, err := m.jwtService.ValidateToken(tokenString)
+	if err != nil {
+		logger.Logger.Debugf("Invalid token: %v", err)
+		c.Error(userSvc.ErrInvalidCredentials)
+		return nil, false
+	}
+
+	// 檢查 token 是否已被撤銷
+	if err := m.checkRevocation(claims); err != nil {
+		if errors.Is(err, errTokenRevoked) || errors.Is(err, revocation.ErrSubjectNotFound) {
+			logger.Logger.Debugf("Revoked token used by user %d (client %q): %v", claims.UserID, claims.ClientID, err)
+			response.Error(c, http.StatusUnauthorized, exception.ErrCodeTokenRevoked)
+		} else {
+			logger.Logger.Errorf("Error checking token revocation: %v", err)
+			response.Error(c, http.StatusInternalServerError, exception.ErrCodeUnknown)
+		}
+		return nil, false
+	}
+
+	if claims.IsClient() {
+		// 停用的 client 已發行的 token 立即失效
+		scopes, err := m.oauthService.ActiveClientScopes(claims.ClientID)
+		if err != nil {
+			if errors.Is(err, oauth.ErrInvalidClient) {
+				logger.Logger.Debugf("Token of disabled OAuth client %s used", claims.ClientID)
+				response.Error(c, http.StatusUnauthorized, exception.ErrCodeTokenRevoked)
+			} else {
+				logger.Logger.Errorf("Error checking OAuth client: %v", err)
+				response.Error(c, http.StatusInternalServerError, exception.ErrCodeUnknown)
+			}
+			return nil, false
+		}
+		return &identity{claims: claims, clientScopes: scopes}, true
+	}
+
+	caller := &identity{userID: claims.UserID, roles: claims.Roles, claims: claims}
+	if claims.IsImpersonated() {
+		if caller.actor, ok = m.authenticateActor(c, claims); !ok {
+			return nil, false
+		}
+	}
+	return caller, true
+}
+
+// authenticateActor 檢查代替使用者操作的管理者目前仍然可以代替操作，失敗時直接回應錯誤
+// 管理者的帳號不是 active 或已經失去代替操作的權限時，已發行的代替操作 token 立即失效
+func (m *Auth) authenticateActor(c *gin.Context, claims *jwt.Claims) (*principal.Principal, bool) {
+	if err := m.userService.CheckAccountActive(claims.ActorID); err != nil {
+		if apperror.KindOf(err) == apperror.KindForbidden || errors.Is(err, userSvc.ErrUserNotFound) {
+			logger.Logger.Debugf("Impersonation token of inactive admin %d refused: %v", claims.ActorID, err)
+			response.Error(c, http.StatusUnauthorized, exception.ErrCodeTokenRevoked)
+		} else {
+			logger.Logger.Errorf("Error checking account status: %v", err)
+			response.Error(c, http.StatusInternalServerError, exception.ErrCodeUnknown)
+		}
+		return nil, false
+	}
+
+	roles, err := m.rbacService.RolesForUser(claims.ActorID)
+	if err != nil {
+		logger.Logger.Errorf("Error resolving roles of impersonating admin: %v", err)
+		response.Error(c, http.StatusInternalServerError, exception.ErrCodeUnknown)
+		return nil, false
+	}
+	permissions, err := m.rbacService.PermissionsForRoles(roles)
+	if err != nil {
+		logger.Logger.Errorf("Error resolving permissions: %v", err)
+		response.Error(c, http.StatusInternalServerError, exception.ErrCodeUnknown)
+		return nil, false
+	}
+	if !permissions[models.PermissionUsersImpersonate] {
+		logger.Logger.Debugf("Impersonation token of admin %d without permission refused", claims.ActorID)
+		response.Error(c, http.StatusUnauthorized, exception.ErrCodeTokenRevoked)
+		return nil, false
+	}
+
+	return &principal.Principal{
+		Subject: strconv.FormatUint(uint64(claims.ActorID), 10),
+		Kind:    principal.KindUser,
+		UserID:  claims.ActorID,
+		Roles:   roles,
+		Tenant:  claims.Tenant,
+	}, true
+}
+
+// auditImpersonation 將代替使用者操作的請求寫入稽核紀錄，在請求處理完成後呼叫
+// 只記錄路徑不記錄查詢字串，避免把查詢字串中的敏感資料寫入稽核紀錄
+func (m *Auth) auditImpersonation(c *gin.Context, caller *principal.Principal) {
+	err := m.userService.RecordImpersonatedRequest(&models.ImpersonationLog{
+		ActorID:    caller.Actor.UserID,
+		UserID:     caller.UserID,
+		TokenID:    caller.TokenID,
+		Method:     c.Request.Method,
+		Path:       c.Request.URL.Path,
+		StatusCode: c.Writer.Status(),
+		IP:         c.ClientIP(),
+	})
+	if err != nil {
+		logger.Logger.Errorf("Error recording impersonated request: %v", err)
+	}
+}
+
+// accessToken 從 Authorization header 或 cookie 取得 access token
+// 使用 cookie 時在 gin.Context 中記錄 constants.CtxCookieAuthKey，讓 CSRF 中介軟體檢查 CSRF token
+func (m *Auth) accessToken(c *gin.Context) (string, bool) {
+	// 從 Authorization header 中取得 token
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		if token, found := m.cookies.AccessToken(c); found {
+			c.Set(constants.CtxCookieAuthKey, true)
+			return token, true
+		}
+		logger.Logger.Debugf("Authorization header is missing")
+		return "", false
+	}
+
+	// 解析 Bearer token
+	tokenString, found := strings.CutPrefix(authHeader, "Bearer ")
+	if !found {
+		logger.Logger.Debugf("Authorization header is not a Bearer token")
+		return "", false
+	}
+	return tokenString, true
+}
+
+// setClient 將 OAuth2 client 的資訊儲存到 gin.Context 中
+// client 沒有使用者 ID 與角色，權限為 token 的 scope 與 client 目前被允許的權限的交集
+func (m *Auth) setClient(c *gin.Context, caller *identity) {
+	allowed := make(map[string]bool, len(caller.clientScopes))
+	for _, scope := range caller.clientScopes {
+		allowed[scope] = true
+	}
+	principal.Set(c, caller.principal())
+	c.Set(constants.CtxPermissionsKey, restrictToScopes(allowed, caller.claims.Scopes))
+}
+
+// principal 將通過驗證的呼叫者轉換成 principal.Principal
+func (caller *identity) principal() *principal.Principal {
+	claims := caller.claims
+	if claims != nil && claims.IsClient() {
+		return &principal.Principal{
+			Subject:   claims.ClientID,
+			Kind:      principal.KindClient,
+			ClientID:  claims.ClientID,
+			Scopes:    claims.Scopes,
+			Tenant:    claims.Tenant,
+			TokenID:   claims.TokenID,
+			ExpiresAt: claims.ExpiresAt,
+		}
+	}
+
+	p := &principal.Principal{
+		Subject: strconv.FormatUint(uint64(caller.userID), 10),
+		Kind:    principal.KindUser,
+		UserID:  caller.userID,
+		Roles:   caller.roles,
+	}
+	if claims != nil {
+		p.SessionID = claims.SessionID
+		p.Tenant = claims.Tenant
+		p.AuthTime = claims.AuthTime
+		p.MFA = claims.MFA()
+		p.TokenID = claims.TokenID
+		p.ExpiresAt = claims.ExpiresAt
+	}
+	p.Actor = caller.actor
+	if caller.apiKey != nil {
+		p.APIKeyID = caller.apiKey.ID
+		p.Scopes = caller.apiKey.Scopes
+	}
+	return p
+}
+
+// authenticateAPIKey 驗證 API key，失敗時直接回應錯誤
+// API key 不帶角色，每次都使用擁有者目前的角色，角色的變更會立即生效
+func (m *Auth) authenticateAPIKey(c *gin.Context, key string) (*identity, bool) {
+	apiKey, err := m.userService.AuthenticateAPIKey(key)
+	if err != nil {
+		// API key 無效時由 ErrorHandler 回應 401
+		c.Error(err)
+		return nil, false
+	}
+
+	roles, err := m.rbacService.RolesForUser(apiKey.UserID)
+	if err != nil {
+		logger.Logger.Errorf("Error resolving roles of API key owner: %v", err)
+		response.Error(c, http.StatusInternalServerError, exception.ErrCodeUnknown)
+		return nil, false
+	}
+
+	return &identity{userID: apiKey.UserID, roles: roles, apiKey: apiKey}, true
+}
+
+// apiKeyFromRequest 從 X-API-Key header 或 Authorization: ApiKey <key> 取得 API key
+func apiKeyFromRequest(c *gin.Context) (string, bool) {
+	if key, found := strings.CutPrefix(c.GetHeader("Authorization"), apiKeyAuthScheme); found {
+		return strings.TrimSpace(key), true
+	}
+	if c.GetHeader("Authorization") == "" {
+		if key := strings.TrimSpace(c.GetHeader(apiKeyHeader)); key != "" {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// restrictToScopes 只保留 API key 或 OAuth2 client 的 token 允許使用的權限
+func restrictToScopes(permissions map[string]bool, scopes []string) map[string]bool {
+	restricted := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		if permissions[scope] {
+			restricted[scope] = true
+		}
+	}
+	return restricted
+}
+
+// errTokenRevoked token 已被撤銷
+var errTokenRevoked = errors.New("token revoked")
+
+// checkRevocation 檢查 token 或所屬的登入工作階段是否被單獨撤銷，或是發行時間早於使用者的 "tokens valid after" 時間
+// OAuth2 client 的 token 只檢查單獨撤銷，停用 client 則由 ActiveClientScopes 檢查
+func (m *Auth) checkRevocation(claims *jwt.Claims) error {
+	for _, key := range revocationKeys(claims) {
+		revoked, err := m.revocationStore.IsRevoked(key)
+		if err != nil {
+			return err
+		}
+		if revoked {
+			return errTokenRevoked
+		}
+	}
+	if claims.IsClient() {
+		return nil
+	}
+
+	validAfter, err := m.revocationStore.TokensValidAfter(claims.UserID)
+	if err != nil {
+		return err
+	}
+	if revocation.IssuedBefore(claims.IssuedAt, validAfter) {
+		return errTokenRevoked
+	}
+
+	// 代替使用者操作的 token 同時受管理者的 "tokens valid after" 時間限制，管理者登出所有裝置時一併失效
+	if claims.IsImpersonated() {
+		actorValidAfter, err := m.revocationStore.TokensValidAfter(claims.ActorID)
+		if err != nil {
+			return err
+		}
+		if revocation.IssuedBefore(claims.IssuedAt, actorValidAfter) {
+			return errTokenRevoked
+		}
+	}
+	return nil
+}
+
+// revocationKeys 撤銷清單中與 token 有關的 key：token 本身的 jti，以及所屬工作階段的 sid
+func revocationKeys(claims *jwt.Claims) []string {
+	keys := make([]string, 0, 2)
+	if claims.TokenID != "" {
+		keys = append(keys, claims.TokenID)
+	}
+	if claims.SessionID != "" {
+		keys = append(keys, revocation.SessionKey(claims.SessionID))
+	}
+	return keys
+}
@@ -0,0 +1,245 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-template/internal/api/handlers/authcookie"
+	"go-template/internal/configs"
+	"go-template/internal/models"
+	"go-template/internal/services/rbac"
+	"go-template/internal/services/revocation"
+	userSvc "go-template/internal/services/user"
+	"go-template/internal/utils/jwt"
+	"go-template/internal/utils/logger"
+)
+
+func TestMain(m *testing.M) {
+	_ = logger.Init(&logger.Config{Level: "error", ConsoleOut: true, ServiceName: "middleware-test"})
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+// authUserService 只實作 Auth 使用的方法：API key 驗證、帳號狀態檢查與代替操作的稽核紀錄
+type authUserService struct {
+	userSvc.Service
+	apiKeys map[string]*models.APIKey // 明文 key 對應的 API key
+	logs    []*models.ImpersonationLog
+}
+
+func (s *authUserService) AuthenticateAPIKey(key string) (*models.APIKey, error) {
+	apiKey, ok := s.apiKeys[key]
+	if !ok {
+		return nil, userSvc.ErrInvalidAPIKey
+	}
+	return apiKey, nil
+}
+
+func (s *authUserService) CheckAccountActive(uint) error {
+	return nil
+}
+
+func (s *authUserService) RecordImpersonatedRequest(log *models.ImpersonationLog) error {
+	s.logs = append(s.logs, log)
+	return nil
+}
+
+// authRBAC 使用 models.DefaultRolePermissions 的 rbac.Service，使用者的角色由 roles 指定
+type authRBAC struct {
+	rbac.Service
+	roles map[uint][]string
+}
+
+func (r *authRBAC) RolesForUser(userID uint) ([]string, error) {
+	return r.roles[userID], nil
+}
+
+func (r *authRBAC) PermissionsForRoles(roles []string) (map[string]bool, error) {
+	permissions := make(map[string]bool)
+	for _, role := range roles {
+		for _, permission := range models.DefaultRolePermissions[role] {
+			permissions[permission] = true
+		}
+	}
+	return permissions, nil
+}
+
+// existingUsers 回傳只認得 ids 中的使用者的 revocation.SubjectExists
+func existingUsers(ids ...uint) revocation.SubjectExists {
+	return func(userID uint) (bool, error) {
+		for _, id := range ids {
+			if id == userID {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+// newTestAuth 建立使用 HS256 金鑰與記憶體撤銷清單的 Auth，預設不開啟 cookie 驗證模式
+func newTestAuth(t *testing.T, users *authUserService, roles map[uint][]string) *Auth {
+	t.Helper()
+	jwtService, _, err := jwt.NewService(&configs.Config{JWTSecret: "secret", TokenExpiresIn: time.Minute})
+	require.NoError(t, err)
+	cookies, err := authcookie.New(&configs.Config{AuthCookieSameSite: "lax"})
+	require.NoError(t, err)
+
+	ids := make([]uint, 0, len(roles))
+	for id := range roles {
+		ids = append(ids, id)
+	}
+	return NewAuth(jwtService, revocation.NewMemoryStore(existingUsers(ids...)), &authRBAC{roles: roles}, users, nil, nil, cookies)
+}
+
+// serveAuth 以 Auth 與 handler 處理請求，回傳狀態碼
+func serveAuth(auth *Auth, method string, header http.Header, handler gin.HandlerFunc) int {
+	router := gin.New()
+	router.Use(ErrorHandler())
+	router.Handle(method, "/test", auth.Handle(), handler, func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, "/test", nil)
+	req.Header = header
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+// 測試 API key 可以從 X-API-Key 或 Authorization: ApiKey 傳送，無效的 key 回應 401
+func TestAuthAPIKey(t *testing.T) {
+	users := &authUserService{apiKeys: map[string]*models.APIKey{
+		"valid-key": {ID: 1, UserID: 1, Scopes: []string{models.PermissionUsersRead}},
+	}}
+	auth := newTestAuth(t, users, map[uint][]string{1: {models.RoleAdmin}})
+	read := Require(models.PermissionUsersRead)
+
+	assert.Equal(t, http.StatusNoContent, serveAuth(auth, http.MethodGet, http.Header{"X-Api-Key": {"valid-key"}}, read))
+	assert.Equal(t, http.StatusNoContent, serveAuth(auth, http.MethodGet, http.Header{"Authorization": {"ApiKey valid-key"}}, read))
+	assert.Equal(t, http.StatusUnauthorized, serveAuth(auth, http.MethodGet, http.Header{"X-Api-Key": {"revoked-key"}}, read))
+	assert.Equal(t, http.StatusUnauthorized, serveAuth(auth, http.MethodGet, http.Header{"Authorization": {"ApiKey revoked-key"}}, read))
+}
+
+// 測試 API key 的權限為 scope 與擁有者目前權限的交集
+func TestAuthAPIKeyScopes(t *testing.T) {
+	users := &authUserService{apiKeys: map[string]*models.APIKey{
+		"admin-key": {ID: 1, UserID: 1, Scopes: []string{models.PermissionUsersRead}},
+		"user-key":  {ID: 2, UserID: 2, Scopes: []string{models.PermissionProfileRead, models.PermissionUsersRead}},
+	}}
+	auth := newTestAuth(t, users, map[uint][]string{1: {models.RoleAdmin}, 2: {models.RoleUser}})
+	adminKey := http.Header{"X-Api-Key": {"admin-key"}}
+	userKey := http.Header{"X-Api-Key": {"user-key"}}
+
+	// 擁有者有權限，但不在 scope 中
+	assert.Equal(t, http.StatusNoContent, serveAuth(auth, http.MethodGet, adminKey, Require(models.PermissionUsersRead)))
+	assert.Equal(t, http.StatusForbidden, serveAuth(auth, http.MethodGet, adminKey, Require(models.PermissionUsersUpdate)))
+
+	// 在 scope 中，但擁有者目前沒有權限 (例如建立 key 之後被移除角色)
+	assert.Equal(t, http.StatusNoContent, serveAuth(auth, http.MethodGet, userKey, Require(models.PermissionProfileRead)))
+	assert.Equal(t, http.StatusForbidden, serveAuth(auth, http.MethodGet, userKey, Require(models.PermissionUsersRead)))
+}
+
+// 測試使用 API key 的請求被 RejectAPIKey 拒絕
+func TestRejectAPIKey(t *testing.T) {
+	users := &authUserService{apiKeys: map[string]*models.APIKey{
+		"valid-key": {ID: 1, UserID: 1, Scopes: []string{models.PermissionProfileUpdate}},
+	}}
+	auth := newTestAuth(t, users, map[uint][]string{1: {models.RoleUser}})
+
+	assert.Equal(t, http.StatusForbidden, serveAuth(auth, http.MethodPut, http.Header{"X-Api-Key": {"valid-key"}}, RejectAPIKey()))
+}
+
+// 測試單獨撤銷的 token 與工作階段會被拒絕
+func TestCheckRevocationRevokedTokenAndSession(t *testing.T) {
+	store := revocation.NewMemoryStore(existingUsers(1))
+	auth := &Auth{revocationStore: store}
+	issuedAt := time.Now().Truncate(time.Millisecond)
+
+	require.NoError(t, store.Revoke(1, "jti-1", time.Now().Add(time.Hour)))
+	require.NoError(t, store.Revoke(1, revocation.SessionKey("session-1"), time.Now().Add(time.Hour)))
+
+	assert.ErrorIs(t, auth.checkRevocation(&jwt.Claims{UserID: 1, TokenID: "jti-1", SessionID: "session-2", IssuedAt: issuedAt}), errTokenRevoked)
+	assert.ErrorIs(t, auth.checkRevocation(&jwt.Claims{UserID: 1, TokenID: "jti-2", SessionID: "session-1", IssuedAt: issuedAt}), errTokenRevoked)
+	assert.NoError(t, auth.checkRevocation(&jwt.Claims{UserID: 1, TokenID: "jti-2", SessionID: "session-2", IssuedAt: issuedAt}))
+}
+
+// 測試登出所有裝置時，同一秒內較早發行的 token 失效，之後發行的 token 仍然有效
+func TestCheckRevocationTokensValidAfter(t *testing.T) {
+	store := revocation.NewMemoryStore(existingUsers(1))
+	auth := &Auth{revocationStore: store}
+
+	revokedAt := time.Date(2026, 1, 2, 3, 4, 5, 500_000_000, time.UTC)
+	require.NoError(t, store.RevokeAllForUser(1, revokedAt))
+
+	sameSecondBefore := &jwt.Claims{UserID: 1, TokenID: "old", IssuedAt: revokedAt.Add(-400 * time.Millisecond)}
+	assert.ErrorIs(t, auth.checkRevocation(sameSecondBefore), errTokenRevoked)
+
+	sameMillisecond := &jwt.Claims{UserID: 1, TokenID: "tie", IssuedAt: revokedAt}
+	assert.ErrorIs(t, auth.checkRevocation(sameMillisecond), errTokenRevoked)
+
+	sameSecondAfter := &jwt.Claims{UserID: 1, TokenID: "new", IssuedAt: revokedAt.Add(time.Millisecond)}
+	assert.NoError(t, auth.checkRevocation(sameSecondAfter))
+}
+
+// 測試代替使用者操作的 token 同時受管理者的撤銷時間限制，已刪除的使用者的 token 立即失效
+func TestCheckRevocationActorAndDeletedUser(t *testing.T) {
+	store := revocation.NewMemoryStore(existingUsers(1, 2))
+	auth := &Auth{revocationStore: store}
+	issuedAt := time.Now().Truncate(time.Millisecond)
+
+	require.NoError(t, store.RevokeAllForUser(2, issuedAt.Add(time.Second)))
+	impersonated := &jwt.Claims{UserID: 1, ActorID: 2, TokenID: "act", IssuedAt: issuedAt}
+	assert.ErrorIs(t, auth.checkRevocation(impersonated), errTokenRevoked)
+
+	deleted := &jwt.Claims{UserID: 3, TokenID: "deleted", IssuedAt: issuedAt}
+	assert.ErrorIs(t, auth.checkRevocation(deleted), revocation.ErrSubjectNotFound)
+
+	// OAuth2 client 的 token 沒有使用者，只檢查單獨撤銷
+	client := &jwt.Claims{ClientID: "client-1", TokenID: "client", IssuedAt: issuedAt}
+	assert.NoError(t, auth.checkRevocation(client))
+}
+
+// 測試代替使用者操作的 token 不能呼叫 DELETE 路由，所有請求 (包含被拒絕的請求) 都會寫入稽核紀錄
+func TestAuthImpersonation(t *testing.T) {
+	users := &authUserService{}
+	auth := newTestAuth(t, users, map[uint][]string{1: {models.RoleUser}, 2: {models.RoleAdmin}})
+
+	token, err := auth.jwtService.GenerateToken(jwt.UserToken{UserID: 1, Roles: []string{models.RoleUser}, ActorID: 2, TokenID: "imp-1"})
+	require.NoError(t, err)
+	bearer := http.Header{"Authorization": {"Bearer " + token}}
+
+	assert.Equal(t, http.StatusNoContent, serveAuth(auth, http.MethodGet, bearer, Require(models.PermissionProfileRead)))
+	assert.Equal(t, http.StatusForbidden, serveAuth(auth, http.MethodDelete, bearer, Require(models.PermissionProfileDelete)))
+
+	require.Len(t, users.logs, 2)
+	for i, expected := range []struct {
+		method string
+		status int
+	}{{http.MethodGet, http.StatusNoContent}, {http.MethodDelete, http.StatusForbidden}} {
+		log := users.logs[i]
+		assert.Equal(t, uint(2), log.ActorID)
+		assert.Equal(t, uint(1), log.UserID)
+		assert.Equal(t, "imp-1", log.TokenID)
+		assert.Equal(t, expected.method, log.Method)
+		assert.Equal(t, "/test", log.Path)
+		assert.Equal(t, expected.status, log.StatusCode)
+	}
+}
+
+// 測試管理者失去代替操作的權限後，已發行的代替操作 token 立即失效
+func TestAuthImpersonationActorWithoutPermission(t *testing.T) {
+	users := &authUserService{}
+	auth := newTestAuth(t, users, map[uint][]string{1: {models.RoleUser}, 2: {models.RoleUser}})
+
+	token, err := auth.jwtService.GenerateToken(jwt.UserToken{UserID: 1, Roles: []string{models.RoleUser}, ActorID: 2})
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusUnauthorized,
+		serveAuth(auth, http.MethodGet, http.Header{"Authorization": {"Bearer " + token}}, Require(models.PermissionProfileRead)))
+	assert.Empty(t, users.logs)
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go-template/internal/api/handlers/authcookie"
+	"go-template/internal/api/handlers/exception"
+	"go-template/internal/api/handlers/response"
+	"go-template/internal/constants"
+	"go-template/internal/utils/logger"
+)
+
+// CSRF 回傳以 double-submit cookie 保護 cookie 驗證的中介軟體，必須放在 Auth 之後
+// 只檢查 access token 來自 cookie 的請求，使用 Authorization header 或 API key 的請求不會自動被瀏覽器帶上，不需要檢查
+func CSRF() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !c.GetBool(constants.CtxCookieAuthKey) || isSafeMethod(c.Request.Method) {
+			c.Next()
+			return
+		}
+		if !authcookie.VerifyCSRF(c) {
+			logger.Logger.Debugf("CSRF token missing or mismatched for %s %s", c.Request.Method, c.FullPath())
+			response.Error(c, http.StatusForbidden, exception.ErrCodeInvalidCSRFToken)
+			c.Abort() // 中止後續的處理函數
+			return
+		}
+		c.Next()
+	}
+}
+
+// isSafeMethod 不會改變狀態的 HTTP 方法 (RFC 9110 9.2.1)
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-template/internal/api/handlers/authcookie"
+	"go-template/internal/configs"
+	"go-template/internal/models"
+	"go-template/internal/utils/jwt"
+)
+
+// 測試只有以 cookie 驗證、會改變狀態的請求需要 CSRF token
+func TestCSRF(t *testing.T) {
+	auth := newTestAuth(t, &authUserService{}, map[uint][]string{1: {models.RoleUser}})
+	cookies, err := authcookie.New(&configs.Config{AuthCookieEnabled: true, AuthCookieSameSite: "lax"})
+	require.NoError(t, err)
+	auth.cookies = cookies
+
+	token, err := auth.jwtService.GenerateToken(jwt.UserToken{UserID: 1, Roles: []string{models.RoleUser}})
+	require.NoError(t, err)
+	accessCookie := authcookie.AccessTokenCookie + "=" + token
+	csrfCookie := authcookie.CSRFCookie + "=csrf-1"
+
+	tests := []struct {
+		name   string
+		method string
+		header http.Header
+		status int
+	}{
+		{"bearer token without CSRF token", http.MethodPost,
+			http.Header{"Authorization": {"Bearer " + token}}, http.StatusNoContent},
+		{"bearer token takes precedence over cookies", http.MethodDelete,
+			http.Header{"Authorization": {"Bearer " + token}, "Cookie": {accessCookie}}, http.StatusNoContent},
+		{"cookie with safe method", http.MethodGet,
+			http.Header{"Cookie": {accessCookie}}, http.StatusNoContent},
+		{"cookie without CSRF token", http.MethodPost,
+			http.Header{"Cookie": {accessCookie}}, http.StatusForbidden},
+		{"cookie with CSRF cookie but no header", http.MethodPut,
+			http.Header{"Cookie": {accessCookie + "; " + csrfCookie}}, http.StatusForbidden},
+		{"cookie with mismatched CSRF header", http.MethodPost,
+			http.Header{"Cookie": {accessCookie + "; " + csrfCookie}, "X-Csrf-Token": {"csrf-2"}}, http.StatusForbidden},
+		{"cookie with matching CSRF header", http.MethodPost,
+			http.Header{"Cookie": {accessCookie + "; " + csrfCookie}, "X-Csrf-Token": {"csrf-1"}}, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.status, serveAuth(auth, tt.method, tt.header, CSRF()))
+		})
+	}
+}
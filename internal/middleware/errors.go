@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go-template/internal/api/handlers/exception"
+	"go-template/internal/api/handlers/response"
+	"go-template/internal/apperror"
+	"go-template/internal/services/throttle"
+	"go-template/internal/utils/logger"
+)
+
+// statusByKind 錯誤種類對應的 HTTP 狀態碼
+var statusByKind = map[apperror.Kind]int{
+	apperror.KindInvalid:         http.StatusBadRequest,
+	apperror.KindUnauthorized:    http.StatusUnauthorized,
+	apperror.KindForbidden:       http.StatusForbidden,
+	apperror.KindNotFound:        http.StatusNotFound,
+	apperror.KindConflict:        http.StatusConflict,
+	apperror.KindTooManyRequests: http.StatusTooManyRequests,
+}
+
+// ErrorHandler 回應處理函數以 c.Error 記錄的錯誤，應該套用在整個 router 上
+// *apperror.Error 依照 Kind 決定 HTTP 狀態碼，並回應錯誤的字串代碼、訊息與詳細資訊；
+// 其他錯誤 (以及 KindInternal) 記錄到日誌後一律回應 500，不把內部的錯誤訊息交給用戶端
+// 處理函數已經寫入回應時不會再回應
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		err := c.Errors.Last().Err
+		appErr, ok := apperror.As(err)
+		status, known := statusByKind[apperror.KindOf(err)]
+		if !ok || !known {
+			logger.Logger.Errorf("Error handling %s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+			response.Error(c, http.StatusInternalServerError, exception.ErrCodeUnknown)
+			return
+		}
+
+		logger.Logger.Debugf("Error handling %s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+		// 失敗次數過多時在 Retry-After header 告知需要等待的秒數
+		var throttled *throttle.ThrottledError
+		if errors.As(err, &throttled) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		}
+		response.AppError(c, status, appErr)
+	}
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-template/internal/api/handlers/response"
+	"go-template/internal/apperror"
+	"go-template/internal/services/throttle"
+	"go-template/internal/validators"
+)
+
+// serveError 以 ErrorHandler 處理回傳 err 的處理函數，回傳回應
+func serveError(t *testing.T, err error) *httptest.ResponseRecorder {
+	t.Helper()
+	router := gin.New()
+	router.Use(ErrorHandler())
+	router.GET("/test", func(c *gin.Context) {
+		c.Error(err)
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+	return w
+}
+
+// decodeError 解析 legacy 格式的錯誤回應
+func decodeError(t *testing.T, w *httptest.ResponseRecorder) response.ErrorData {
+	t.Helper()
+	var body response.ErrorData
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return body
+}
+
+// 測試每個錯誤種類對應的 HTTP 狀態碼，以及回應的字串代碼與訊息
+func TestErrorHandlerKindToStatus(t *testing.T) {
+	tests := []struct {
+		kind   apperror.Kind
+		status int
+	}{
+		{apperror.KindInvalid, http.StatusBadRequest},
+		{apperror.KindUnauthorized, http.StatusUnauthorized},
+		{apperror.KindForbidden, http.StatusForbidden},
+		{apperror.KindNotFound, http.StatusNotFound},
+		{apperror.KindConflict, http.StatusConflict},
+		{apperror.KindTooManyRequests, http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		w := serveError(t, apperror.New(tt.kind, "some_code", "some message").Wrap(errors.New("internal cause")))
+		assert.Equal(t, tt.status, w.Code, "kind %d", tt.kind)
+		body := decodeError(t, w)
+		assert.Equal(t, "some_code", body.Code)
+		assert.Equal(t, "some message", body.Message, "the cause must not be sent to the client")
+	}
+}
+
+// 測試 KindInternal 與不是 *apperror.Error 的錯誤一律回應 500，不洩漏內部的錯誤訊息
+func TestErrorHandlerInternal(t *testing.T) {
+	for _, err := range []error{
+		errors.New("connection refused"),
+		apperror.New(apperror.KindInternal, "db_down", "database is down"),
+	} {
+		w := serveError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		body := decodeError(t, w)
+		assert.Equal(t, "unknown", body.Code)
+		assert.NotContains(t, w.Body.String(), err.Error())
+	}
+}
+
+// 測試包裝在其他錯誤中的 *apperror.Error 也會依照種類回應，並附上詳細資訊
+func TestErrorHandlerWrappedErrors(t *testing.T) {
+	w := serveError(t, &throttle.ThrottledError{RetryAfter: 1500 * time.Millisecond})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"), "Retry-After is rounded up to whole seconds")
+	assert.Equal(t, "too_many_login_attempts", decodeError(t, w).Code)
+
+	policyErr := &validators.PasswordPolicyError{Violations: []validators.PasswordViolation{
+		{Rule: validators.PasswordRuleMinLength, Message: "too short"},
+	}}
+	w = serveError(t, policyErr)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	body := decodeError(t, w)
+	assert.Equal(t, "password_policy", body.Code)
+	assert.Contains(t, w.Body.String(), validators.PasswordRuleMinLength)
+	assert.NotNil(t, body.Details)
+}
+
+// 測試處理函數已經寫入回應時，ErrorHandler 不會再回應
+func TestErrorHandlerSkipsWrittenResponse(t *testing.T) {
+	router := gin.New()
+	router.Use(ErrorHandler())
+	router.GET("/test", func(c *gin.Context) {
+		c.Error(errors.New("logged only"))
+		c.String(http.StatusAccepted, "done")
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, "done", w.Body.String())
+}
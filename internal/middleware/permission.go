@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go-template/internal/api/handlers/exception"
+	"go-template/internal/api/handlers/response"
+	"go-template/internal/constants"
+	"go-template/internal/principal"
+	"go-template/internal/utils/logger"
+)
+
+// Require 檢查呼叫者是否擁有所有指定權限的中介軟體
+// 必須放在 Auth 之後，可以套用在單一路由或整個路由群組，例如：
+//
+//	group.DELETE("/:id", middleware.Require(models.PermissionUsersDelete), handler.Delete)
+func Require(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted, _ := c.Get(constants.CtxPermissionsKey)
+		grantedPermissions, _ := granted.(map[string]bool)
+
+		for _, permission := range permissions {
+			if !grantedPermissions[permission] {
+				logger.Logger.Debugf("Permission %s denied for user %d", permission, principal.UserID(c))
+				response.Error(c, http.StatusForbidden, exception.ErrCodeForbidden)
+				c.Abort() // 中止後續的處理函數
+				return
+			}
+		}
+
+		// 呼叫下一個處理函數
+		c.Next()
+	}
+}
+
+// RequireRole 檢查呼叫者是否擁有任一指定角色的中介軟體
+// 必須放在 Auth 之後，通常套用在整個路由群組，例如管理者專用的路由
+// OAuth2 client 沒有角色，一律拒絕，不能以 client 被授與的權限呼叫需要角色的路由
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		caller, _ := principal.FromContext(c)
+		if caller != nil && caller.IsClient() {
+			logger.Logger.Debugf("Roles %v required, OAuth client %s refused for %s", roles, caller.ClientID, c.FullPath())
+			response.Error(c, http.StatusForbidden, exception.ErrCodeClientNotAllowed)
+			c.Abort() // 中止後續的處理函數
+			return
+		}
+
+		var grantedRoles []string
+		if caller != nil {
+			grantedRoles = caller.Roles
+		}
+
+		for _, grantedRole := range grantedRoles {
+			for _, role := range roles {
+				if grantedRole == role {
+					// 呼叫下一個處理函數
+					c.Next()
+					return
+				}
+			}
+		}
+
+		logger.Logger.Debugf("Roles %v required for user %d", roles, principal.UserID(c))
+		response.Error(c, http.StatusForbidden, exception.ErrCodeForbidden)
+		c.Abort() // 中止後續的處理函數
+	}
+}
+
+// RejectAPIKey 拒絕使用 API key 驗證的請求，只允許使用 access token (也就是互動式登入) 的呼叫者
+// 必須放在 Auth 之後，用於管理 API key、變更密碼等不應該交給機器用戶端的操作，
+// 避免外洩的 API key 被用來建立新的 key 或接管帳號
+func RejectAPIKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if caller, ok := principal.FromContext(c); ok && caller.UsingAPIKey() {
+			logger.Logger.Debugf("API key refused for %s by user %d", c.FullPath(), caller.UserID)
+			response.Error(c, http.StatusForbidden, exception.ErrCodeAPIKeyNotAllowed)
+			c.Abort() // 中止後續的處理函數
+			return
+		}
+
+		// 呼叫下一個處理函數
+		c.Next()
+	}
+}
+
+// RequireUser 拒絕 OAuth2 client 的 token，只允許代表使用者的呼叫者
+// 必須放在 Auth 之後，用於 /me 這類操作目前使用者的路由，以及建立憑證等不應該交給其他服務的操作
+func RequireUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if caller, ok := principal.FromContext(c); ok && caller.IsClient() {
+			logger.Logger.Debugf("OAuth client %s refused for %s", caller.ClientID, c.FullPath())
+			response.Error(c, http.StatusForbidden, exception.ErrCodeClientNotAllowed)
+			c.Abort() // 中止後續的處理函數
+			return
+		}
+
+		// 呼叫下一個處理函數
+		c.Next()
+	}
+}
+
+// RejectImpersonation 拒絕管理者代替使用者操作 (impersonation) 的請求
+// 必須放在 Auth 之後，用於變更密碼、兩步驟驗證、建立憑證等可以接管帳號的操作，以及管理 API；
+// DELETE 路由已經由 Auth 預設拒絕，不需要另外套用
+func RejectImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if caller, ok := principal.FromContext(c); ok && caller.IsImpersonated() {
+			logger.Logger.Debugf("Impersonation refused for %s by admin %d", c.FullPath(), caller.Actor.UserID)
+			response.Error(c, http.StatusForbidden, exception.ErrCodeImpersonationNotAllowed)
+			c.Abort() // 中止後續的處理函數
+			return
+		}
+
+		// 呼叫下一個處理函數
+		c.Next()
+	}
+}
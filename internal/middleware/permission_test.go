@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go-template/internal/constants"
+	"go-template/internal/models"
+	"go-template/internal/principal"
+)
+
+// serveAs 以 caller 與 permissions 作為 Auth 的結果執行 handler，回傳狀態碼
+// caller 為 nil 時模擬沒有經過 Auth 的請求
+func serveAs(caller *principal.Principal, permissions map[string]bool, handler gin.HandlerFunc) int {
+	router := gin.New()
+	router.GET("/test", func(c *gin.Context) {
+		if caller != nil {
+			principal.Set(c, caller)
+			c.Set(constants.CtxPermissionsKey, permissions)
+		}
+		c.Next()
+	}, handler, func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+	return w.Code
+}
+
+// 測試 Require 必須擁有所有指定的權限
+func TestRequire(t *testing.T) {
+	user := &principal.Principal{Kind: principal.KindUser, UserID: 1, Roles: []string{models.RoleUser}}
+	granted := map[string]bool{models.PermissionUsersRead: true, models.PermissionUsersUpdate: true}
+
+	assert.Equal(t, http.StatusNoContent, serveAs(user, granted, Require(models.PermissionUsersRead)))
+	assert.Equal(t, http.StatusNoContent, serveAs(user, granted, Require(models.PermissionUsersRead, models.PermissionUsersUpdate)))
+	assert.Equal(t, http.StatusForbidden, serveAs(user, granted, Require(models.PermissionUsersRead, models.PermissionUsersDelete)))
+	assert.Equal(t, http.StatusForbidden, serveAs(user, nil, Require(models.PermissionUsersRead)))
+	assert.Equal(t, http.StatusForbidden, serveAs(nil, nil, Require(models.PermissionUsersRead)), "requests without Auth are denied")
+}
+
+// 測試 RequireRole 擁有任一指定角色即可通過，OAuth2 client 即使被授與權限也會被拒絕
+func TestRequireRole(t *testing.T) {
+	admin := &principal.Principal{Kind: principal.KindUser, UserID: 1, Roles: []string{models.RoleUser, models.RoleAdmin}}
+	user := &principal.Principal{Kind: principal.KindUser, UserID: 2, Roles: []string{models.RoleUser}}
+	client := &principal.Principal{Kind: principal.KindClient, ClientID: "client-1"}
+
+	assert.Equal(t, http.StatusNoContent, serveAs(admin, nil, RequireRole(models.RoleAdmin)))
+	assert.Equal(t, http.StatusNoContent, serveAs(user, nil, RequireRole(models.RoleAdmin, models.RoleUser)))
+	assert.Equal(t, http.StatusForbidden, serveAs(user, nil, RequireRole(models.RoleAdmin)))
+	assert.Equal(t, http.StatusForbidden, serveAs(nil, nil, RequireRole(models.RoleAdmin)), "requests without Auth are denied")
+	assert.Equal(t, http.StatusForbidden, serveAs(client, map[string]bool{models.PermissionUsersRead: true}, RequireRole(models.RoleAdmin)))
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"go-template/internal/constants"
+	"go-template/internal/utils/jwt"
+	"go-template/internal/utils/logger"
+)
+
+// RequestIDHeader 傳遞請求 ID 的 header
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength 接受用戶端或反向代理傳入的請求 ID 的最大長度
+const maxRequestIDLength = 128
+
+// RequestID 為每個請求指定請求 ID 的中介軟體，應該套用在整個 router 上
+// 沿用反向代理或用戶端傳入的 X-Request-ID (格式不符時重新產生)，
+// 儲存到 gin.Context 的 constants.CtxRequestIDKey 中並寫入回應的 X-Request-ID header，
+// 錯誤回應會帶上同一個 ID，方便對照伺服器日誌
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			generated, err := jwt.NewTokenID()
+			if err != nil {
+				logger.Logger.Errorf("Error generating request ID: %v", err)
+			}
+			requestID = generated
+		}
+
+		c.Set(constants.CtxRequestIDKey, requestID)
+		c.Header(RequestIDHeader, requestID)
+
+		// 呼叫下一個處理函數
+		c.Next()
+	}
+}
+
+// validRequestID 只接受長度合理、由英數字與 -_.: 組成的請求 ID，避免把任意內容寫入日誌與回應
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
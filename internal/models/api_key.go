@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// APIKey 定義 API key 資料 Struct，供批次作業與整合服務等機器用戶端長期使用
+// 資料庫只儲存 key 的雜湊值與可以公開的前綴，明文 key 只會在建立時交給使用者一次
+type APIKey struct {
+	ID         uint       `json:"id"           gorm:"primarykey"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"-"`
+	UserID     uint       `json:"user_id"      gorm:"index;not null"`            // 所屬的使用者 (或服務帳號) ID
+	Name       string     `json:"name"         gorm:"not null"`                  // 用途說明，例如 "nightly-export"
+	Prefix     string     `json:"prefix"       gorm:"uniqueIndex;not null"`      // key 開頭的公開部分，用於在列表與日誌中辨識
+	KeyHash    string     `json:"-"            gorm:"uniqueIndex;not null"`      // 完整 key 的雜湊值
+	Scopes     []string   `json:"scopes"       gorm:"serializer:json;type:text"` // 允許使用的權限，實際權限為與擁有者目前權限的交集
+	ExpiresAt  *time.Time `json:"expires_at"`                                    // 過期時間，空值代表不會過期
+	LastUsedAt *time.Time `json:"last_used_at"`                                  // 最後使用時間 (精確度約為一分鐘)
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`                          // 撤銷時間，不為空代表此 key 不可再使用
+}
+
+// TableName 表名可以自定義
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// Active 判斷 key 在指定時間是否仍然可以使用 (尚未撤銷且尚未過期)
+func (k *APIKey) Active(at time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || at.Before(*k.ExpiresAt))
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// 代替操作紀錄的事件種類 (ImpersonationLog.Event)
+const (
+	ImpersonationEventIssued  = "issued"  // 管理者取得代替使用者操作的 token
+	ImpersonationEventRequest = "request" // 使用代替操作的 token 發出的請求
+)
+
+// ImpersonationLog 管理者代替使用者操作 (impersonation) 的稽核紀錄
+// 發行 token 時寫入一筆 issued，之後每個使用該 token 的請求都寫入一筆 request
+type ImpersonationLog struct {
+	ID         uint      `json:"id"                    gorm:"primaryKey"`
+	ActorID    uint      `json:"actor_id"              gorm:"index;not null"` // 實際操作的管理者 ID
+	UserID     uint      `json:"user_id"               gorm:"index;not null"` // 被代替操作的使用者 ID
+	TokenID    string    `json:"token_id"              gorm:"index;not null"` // 代替操作的 access token 的 jti
+	Event      string    `json:"event"                 gorm:"not null"`       // 事件種類
+	Method     string    `json:"method,omitempty"`                            // 請求的 HTTP 方法，只有 request 事件才有
+	Path       string    `json:"path,omitempty"`                              // 請求的路徑，只有 request 事件才有
+	StatusCode int       `json:"status_code,omitempty"`                       // 回應的 HTTP 狀態碼，只有 request 事件才有
+	IP         string    `json:"ip"`                                          // 來源 IP
+	Reason     string    `json:"reason,omitempty"`                            // 管理者取得 token 時填寫的原因
+	CreatedAt  time.Time `json:"created_at"`                                  // 紀錄時間
+}
+
+// TableName 表名可以自定義
+func (ImpersonationLog) TableName() string {
+	return "impersonation_logs"
+}
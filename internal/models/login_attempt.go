@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// LoginAttempt 定義登入失敗次數的計數資料 Struct
+// Key 為計數的對象，例如 user:<使用者名稱> 或 ip:<IP 位址>
+type LoginAttempt struct {
+	Key           string     `gorm:"primaryKey"` // 計數的對象
+	Failures      int        `gorm:"not null"`   // 目前計數區間內連續失敗的次數
+	LastFailureAt time.Time  `gorm:"not null"`   // 最後一次失敗的時間
+	LockedUntil   *time.Time `gorm:"index"`      // 暫時鎖定的到期時間，為空代表沒有被鎖定
+	UpdatedAt     time.Time  // 更新時間
+}
+
+// TableName 表名可以自定義
+func (LoginAttempt) TableName() string {
+	return "login_attempts"
+}
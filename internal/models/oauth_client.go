@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// OAuthClient 定義 OAuth2 client 資料 Struct，供其他內部服務以 client credentials grant 取得 access token
+// 資料庫只儲存 client secret 的雜湊值，明文 secret 只會在註冊時交給管理者一次
+type OAuthClient struct {
+	ID         uint       `json:"id"          gorm:"primarykey"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"-"`
+	ClientID   string     `json:"client_id"   gorm:"uniqueIndex;not null"`      // 公開的 client ID，同時是 token 的 sub
+	SecretHash string     `json:"-"           gorm:"not null"`                  // client secret 的雜湊值
+	Name       string     `json:"name"        gorm:"not null"`                  // 用途說明，例如 "billing-service"
+	Scopes     []string   `json:"scopes"      gorm:"serializer:json;type:text"` // 允許取得的權限
+	CreatedBy  *uint      `json:"created_by"`                                   // 註冊此 client 的管理者 ID
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`                        // 停用時間，不為空代表不能再取得或使用 token
+}
+
+// TableName 表名可以自定義
+func (OAuthClient) TableName() string {
+	return "oauth_clients"
+}
+
+// Active 判斷 client 是否仍然可以使用 (尚未停用)
+func (c *OAuthClient) Active() bool {
+	return c.DisabledAt == nil
+}
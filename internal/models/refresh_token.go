@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RefreshToken 定義 refresh token 資料 Struct
+// 資料庫只儲存 token 的雜湊值，明文 token 只會在發行時交給使用者端一次
+type RefreshToken struct {
+	gorm.Model
+	UserID    uint       `gorm:"index;not null"`       // 所屬的使用者 ID
+	FamilyID  string     `gorm:"index;not null"`       // Token family，同一次登入輪換出來的 token 共用同一個 family
+	TokenHash string     `gorm:"uniqueIndex;not null"` // Token 的雜湊值
+	ExpiresAt time.Time  `gorm:"not null"`             // 過期時間
+	UsedAt    *time.Time // 已被輪換 (使用) 的時間，不為空代表此 token 不可再使用
+	RevokedAt *time.Time // 被撤銷的時間
+}
+
+// TableName 表名可以自定義
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// RevokedToken 定義被撤銷的 access token 資料 Struct
+// 只需要保存到 token 原本的過期時間，過期後就可以刪除
+type RevokedToken struct {
+	TokenID   string    `gorm:"primaryKey"`     // Token ID (jti)，撤銷整個登入工作階段時為 sid:<工作階段 ID>
+	UserID    uint      `gorm:"index;not null"` // 所屬的使用者 ID，OAuth2 client 的 token 為 0
+	ExpiresAt time.Time `gorm:"index;not null"` // Token 原本的過期時間
+	CreatedAt time.Time // 撤銷時間
+}
+
+// TableName 表名可以自定義
+func (RevokedToken) TableName() string {
+	return "revoked_tokens"
+}
@@ -0,0 +1,59 @@
+package models
+
+import "gorm.io/gorm"
+
+// 預設的角色名稱
+const (
+	RoleAdmin = "admin" // 管理員，擁有所有權限
+	RoleUser  = "user"  // 一般使用者，註冊時自動指派
+)
+
+// 權限名稱，格式為 "資源:動作"
+const (
+	PermissionProfileRead      = "profile:read"      // 讀取自己的帳號資料
+	PermissionProfileUpdate    = "profile:update"    // 更新自己的帳號資料
+	PermissionProfileDelete    = "profile:delete"    // 刪除自己的帳號
+	PermissionUsersRead        = "users:read"        // 讀取任意使用者資料
+	PermissionUsersUpdate      = "users:update"      // 更新任意使用者資料
+	PermissionUsersDelete      = "users:delete"      // 刪除任意使用者
+	PermissionClientsRead      = "clients:read"      // 讀取 OAuth2 client
+	PermissionClientsManage    = "clients:manage"    // 註冊與停用 OAuth2 client
+	PermissionUsersImpersonate = "users:impersonate" // 代替任意使用者操作 (客服人員協助使用者時使用)
+)
+
+// DefaultRolePermissions 預設角色與權限的對應關係，執行 migration 時會寫入資料庫
+var DefaultRolePermissions = map[string][]string{
+	RoleAdmin: {
+		PermissionProfileRead, PermissionProfileUpdate, PermissionProfileDelete,
+		PermissionUsersRead, PermissionUsersUpdate, PermissionUsersDelete, PermissionUsersImpersonate,
+		PermissionClientsRead, PermissionClientsManage,
+	},
+	RoleUser: {
+		PermissionProfileRead, PermissionProfileUpdate, PermissionProfileDelete,
+	},
+}
+
+// Role 定義角色資料 Struct
+type Role struct {
+	gorm.Model
+	Name        string       `json:"name"        gorm:"uniqueIndex;not null"`        // 角色名稱
+	Description string       `json:"description"`                                    // 角色說明
+	Permissions []Permission `json:"permissions" gorm:"many2many:role_permissions;"` // 角色擁有的權限
+}
+
+// TableName 表名可以自定義
+func (Role) TableName() string {
+	return "roles"
+}
+
+// Permission 定義權限資料 Struct
+type Permission struct {
+	gorm.Model
+	Name        string `json:"name"        gorm:"uniqueIndex;not null"` // 權限名稱，例如 users:delete
+	Description string `json:"description"`                             // 權限說明
+}
+
+// TableName 表名可以自定義
+func (Permission) TableName() string {
+	return "permissions"
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Session 定義登入工作階段資料 Struct，每次登入 (每個裝置) 一筆
+// ID 同時是 access token 的 sid claim 與 refresh token 的 FamilyID，同一次登入換發的 token 都屬於同一個工作階段
+type Session struct {
+	ID          string     `json:"id"           gorm:"primaryKey"`                // 工作階段 ID (sid)
+	UserID      uint       `json:"-"            gorm:"index;not null"`            // 所屬的使用者 ID
+	UserAgent   string     `json:"user_agent"`                                    // 登入時的 User-Agent
+	IP          string     `json:"ip"`                                            // 登入時的來源 IP
+	AuthMethods []string   `json:"auth_methods" gorm:"serializer:json;type:text"` // 登入時使用的驗證方式 (amr)，換發的 access token 沿用
+	CreatedAt   time.Time  `json:"created_at"`                                    // 登入時間，同時是 access token 的 auth_time
+	LastSeenAt  time.Time  `json:"last_seen_at"`                                  // 最後使用時間 (批次更新，精確度約為數分鐘)
+	ExpiresAt   time.Time  `json:"expires_at"   gorm:"not null"`                  // 最新的 refresh token 過期的時間，之後必須重新登入
+	RevokedAt   *time.Time `json:"-"`                                             // 登出或被撤銷的時間
+	Current     bool       `json:"current"      gorm:"-"`                         // 是否為目前請求使用的工作階段，不儲存在資料庫
+}
+
+// TableName 表名可以自定義
+func (Session) TableName() string {
+	return "sessions"
+}
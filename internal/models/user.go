@@ -1,21 +1,37 @@
 package models
 
 import (
-	"gorm.io/gorm"
+	"strings"
 	"time"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
 )
 
 // User 定義使用者資料 Struct
 type User struct {
-	gorm.Model           // gorm.Model 包含了ID、CreatedAt、UpdatedAt字段
-	Username   string    `json:"username"    validate:"required"       gorm:"unique;not null"` // 帳號名稱
-	Email      string    `json:"email"       validate:"required,email" gorm:"unique;not null"` // 電子郵件
-	Password   string    `json:"-"           validate:"required"       gorm:"not null"`        // 密碼
-	LastLogin  time.Time `json:"last_login"`                                                   // 最後登入時間
-	Status     int       `json:"status"`                                                       // 帳號狀態
+	gorm.Model                       // gorm.Model 包含了ID、CreatedAt、UpdatedAt字段
+	Username              string     `json:"username"    validate:"required,min=4,max=64,not_reserved" gorm:"unique;not null"` // 帳號名稱，由 validators.UserValidator 驗證
+	UsernameKey           string     `json:"-"           gorm:"uniqueIndex"`                                                   // 比較帳號名稱時使用的形式 (UsernameKey)，由 repository.UserRepository 寫入
+	Email                 string     `json:"email"       validate:"required,email,max=254"             gorm:"unique;not null"` // 電子郵件
+	Password              string     `json:"-"           validate:"required"                           gorm:"not null"`        // 密碼
+	LastLogin             time.Time  `json:"last_login"`                                                                       // 最後登入時間
+	Status                UserStatus `json:"status"      gorm:"not null;default:0"`                                            // 帳號狀態，只能透過 user service 依照允許的轉換變更
+	PasswordResetRequired bool       `json:"password_reset_required"`                                                          // 管理者要求重設密碼，重設之前無法登入
+	TokensValidAfter      *time.Time `json:"-"`                                                                                // 在這個時間之前發行的 access token 一律視為無效 (用於登出所有裝置)
+	ServiceAccount        bool       `json:"service_account" gorm:"not null;default:false"`                                    // 服務帳號，只能使用 API key，不能以密碼登入
+	Roles                 []Role     `json:"roles,omitempty" gorm:"many2many:user_roles;"`                                     // 使用者擁有的角色
 }
 
 // TableName 表名可以自定義
 func (User) TableName() string {
 	return "users"
 }
+
+// UsernameKey 比較帳號名稱是否相同時使用的形式：NFKC 正規化並去掉前後的空白後再 case folding
+// 全形與半形、合成與分解、大小寫不同的帳號名稱會得到相同的結果
+func UsernameKey(username string) string {
+	// cases.Caser 不能在多個 goroutine 之間共用，每次建立新的
+	return cases.Fold().String(strings.TrimSpace(norm.NFKC.String(username)))
+}
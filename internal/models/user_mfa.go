@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// UserMFA 定義使用者的 TOTP 兩步驟驗證設定 Struct
+// 使用者開始設定時建立，輸入驗證碼確認之後 (ConfirmedAt 不為空) 登入才需要兩步驟驗證
+type UserMFA struct {
+	ID           uint       `gorm:"primaryKey"`
+	UserID       uint       `gorm:"uniqueIndex;not null"` // 所屬的使用者 ID，每個使用者只有一組設定
+	Secret       string     `gorm:"not null" json:"-"`    // TOTP 共享密鑰 (base32)，驗證時需要原始值因此無法雜湊
+	ConfirmedAt  *time.Time // 使用者確認設定的時間，為空代表尚未啟用
+	LastUsedStep int64      `gorm:"not null;default:0" json:"-"` // 最後一次成功使用的 time step，用來防止驗證碼被重複使用
+	CreatedAt    time.Time  // 建立時間
+	UpdatedAt    time.Time  // 更新時間
+}
+
+// TableName 表名可以自定義
+func (UserMFA) TableName() string {
+	return "user_mfa"
+}
+
+// Enabled 判斷兩步驟驗證是否已經啟用
+func (m *UserMFA) Enabled() bool {
+	return m != nil && m.ConfirmedAt != nil
+}
+
+// MFARecoveryCode 定義兩步驟驗證的復原碼 Struct
+// 資料庫只儲存復原碼的雜湊值，明文復原碼只會在產生時回傳給使用者一次
+type MFARecoveryCode struct {
+	ID        uint       `gorm:"primaryKey"`
+	UserID    uint       `gorm:"index;not null"`       // 所屬的使用者 ID
+	CodeHash  string     `gorm:"uniqueIndex;not null"` // 正規化後復原碼的雜湊值
+	UsedAt    *time.Time // 已使用的時間，不為空代表此復原碼不可再使用
+	CreatedAt time.Time  // 建立時間
+}
+
+// TableName 表名可以自定義
+func (MFARecoveryCode) TableName() string {
+	return "mfa_recovery_codes"
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+// UserStatus 帳號狀態，資料庫中儲存為整數，JSON 中以名稱表示
+type UserStatus int
+
+// 帳號狀態 (User.Status)
+// 既有資料的預設值 0 代表 active，新增狀態時只能往後加，不能改變既有的數值
+const (
+	UserStatusActive              UserStatus = 0 // 正常
+	UserStatusPendingVerification UserStatus = 1 // 等待驗證電子郵件
+	UserStatusSuspended           UserStatus = 2 // 被管理者停權
+	UserStatusLocked              UserStatus = 3 // 因安全因素被鎖定 (例如多次登入失敗)
+	UserStatusDeactivated         UserStatus = 4 // 已停用 (例如使用者刪除自己的帳號)
+)
+
+// userStatusNames 狀態與名稱的對應
+var userStatusNames = map[UserStatus]string{
+	UserStatusActive:              "active",
+	UserStatusPendingVerification: "pending_verification",
+	UserStatusSuspended:           "suspended",
+	UserStatusLocked:              "locked",
+	UserStatusDeactivated:         "deactivated",
+}
+
+// String 取得狀態名稱
+func (s UserStatus) String() string {
+	if name, ok := userStatusNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", int(s))
+}
+
+// MarshalText 將狀態轉換成名稱，讓 JSON 中顯示為字串
+func (s UserStatus) MarshalText() ([]byte, error) {
+	if _, ok := userStatusNames[s]; !ok {
+		return nil, fmt.Errorf("unknown user status: %d", int(s))
+	}
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText 將名稱轉換成狀態
+func (s *UserStatus) UnmarshalText(text []byte) error {
+	status, err := ParseUserStatus(string(text))
+	if err != nil {
+		return err
+	}
+	*s = status
+	return nil
+}
+
+// Value 實作 driver.Valuer，資料庫中儲存為整數
+// 有實作 MarshalText，明確指定儲存方式避免資料庫驅動改用文字格式
+func (s UserStatus) Value() (driver.Value, error) {
+	return int64(s), nil
+}
+
+// Scan 實作 sql.Scanner，從資料庫的整數讀取狀態
+func (s *UserStatus) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case int64:
+		*s = UserStatus(v)
+	case int32:
+		*s = UserStatus(v)
+	case nil:
+		*s = UserStatusActive
+	default:
+		return fmt.Errorf("cannot scan %T into UserStatus", value)
+	}
+	return nil
+}
+
+// ParseUserStatus 將狀態名稱轉換成 UserStatus
+func ParseUserStatus(name string) (UserStatus, error) {
+	for status, statusName := range userStatusNames {
+		if statusName == name {
+			return status, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown user status: %s", name)
+}
+
+// UserStatusChange 帳號狀態的變更紀錄
+type UserStatusChange struct {
+	ID         uint       `json:"id"          gorm:"primaryKey"`
+	UserID     uint       `json:"user_id"     gorm:"index;not null"` // 狀態被變更的使用者
+	FromStatus UserStatus `json:"from_status" gorm:"not null"`       // 變更前的狀態
+	ToStatus   UserStatus `json:"to_status"   gorm:"not null"`       // 變更後的狀態
+	ActorID    *uint      `json:"actor_id"`                          // 執行變更的使用者，nil 代表由系統自動變更
+	Reason     string     `json:"reason"`                            // 變更原因
+	CreatedAt  time.Time  `json:"created_at"`                        // 變更時間
+}
+
+// TableName 表名可以自定義
+func (UserStatusChange) TableName() string {
+	return "user_status_changes"
+}
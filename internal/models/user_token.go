@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// 一次性 token 的用途
+const (
+	UserTokenPurposeEmailVerification = "email_verification" // 驗證電子郵件
+	UserTokenPurposePasswordReset     = "password_reset"     // 重設密碼
+	UserTokenPurposeMFAPending        = "mfa_pending"        // 密碼驗證成功、等待兩步驟驗證的登入
+	UserTokenPurposeMagicLink         = "magic_link"         // 不需要密碼的登入連結
+)
+
+// UserToken 定義寄送給使用者的一次性 token 資料 Struct
+// 資料庫只儲存 token 的雜湊值，明文 token 只會出現在寄給使用者的信件中
+type UserToken struct {
+	ID        uint       `gorm:"primaryKey"`
+	UserID    uint       `gorm:"index;not null"`       // 所屬的使用者 ID
+	Purpose   string     `gorm:"index;not null"`       // Token 用途，不同用途的 token 不能互相使用
+	TokenHash string     `gorm:"uniqueIndex;not null"` // Token 的雜湊值
+	ExpiresAt time.Time  `gorm:"not null"`             // 過期時間
+	UsedAt    *time.Time // 已使用的時間，不為空代表此 token 不可再使用
+	CreatedAt time.Time  // 建立時間，也用來限制重新寄送的頻率
+
+	// 等待兩步驟驗證的登入已經通過的驗證方式 (例如密碼或登入連結)，完成登入後寫入 access token 的 amr claim
+	AuthMethods []string `gorm:"serializer:json;type:text"`
+}
+
+// TableName 表名可以自定義
+func (UserToken) TableName() string {
+	return "user_tokens"
+}
@@ -0,0 +1,104 @@
+package principal
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-template/internal/constants"
+)
+
+// Kind 呼叫者的種類
+type Kind string
+
+const (
+	KindUser   Kind = "user"   // 使用者，使用 access token 或 API key 驗證
+	KindClient Kind = "client" // OAuth2 client，使用 client credentials 取得的 access token 驗證
+)
+
+// Principal 通過驗證的呼叫者，由 Auth 中介軟體建立，後續的中介軟體與處理函數以 FromContext 取得
+type Principal struct {
+	Subject   string     // 呼叫者的識別 (token 的 sub)：使用者 ID 或 OAuth2 client ID
+	Kind      Kind       // 呼叫者的種類
+	UserID    uint       // 使用者 ID，呼叫者為 OAuth2 client 時為 0
+	ClientID  string     // OAuth2 client ID，呼叫者為使用者時為空字串
+	Roles     []string   // 使用者擁有的角色，OAuth2 client 沒有角色
+	Scopes    []string   // API key 或 OAuth2 client 的 token 被限制的權限，使用者的 access token 為 nil
+	SessionID string     // 登入工作階段 ID (sid)，使用 API key 或 OAuth2 client 時為空字串
+	Tenant    string     // 租戶 ID (tid)
+	AuthTime  time.Time  // 使用者完成登入的時間，未知時為零值
+	MFA       bool       // 登入時是否通過兩步驟驗證
+	TokenID   string     // access token 的 jti，使用 API key 時為空字串
+	ExpiresAt time.Time  // access token 的過期時間，使用 API key 時為零值
+	APIKeyID  uint       // 使用 API key 驗證時的 API key ID，其他情況為 0
+	Actor     *Principal // 管理者代替使用者操作 (impersonation) 時實際操作的管理者，其他情況為 nil
+}
+
+// IsClient 判斷呼叫者是否為 OAuth2 client
+func (p *Principal) IsClient() bool {
+	return p.Kind == KindClient
+}
+
+// UsingAPIKey 判斷呼叫者是否使用 API key 驗證
+func (p *Principal) UsingAPIKey() bool {
+	return p.APIKeyID != 0
+}
+
+// IsImpersonated 判斷請求是否由管理者代替使用者操作
+// 這時 Principal 本身代表被代替操作的使用者 (有效的呼叫者)，Actor 代表實際操作的管理者
+func (p *Principal) IsImpersonated() bool {
+	return p.Actor != nil
+}
+
+// Real 取得實際發出請求的呼叫者：代替使用者操作時為管理者，其他情況為呼叫者本身
+func (p *Principal) Real() *Principal {
+	if p.Actor != nil {
+		return p.Actor
+	}
+	return p
+}
+
+// contextKey 在 context.Context 中儲存 *Principal 的 key，使用私有型別避免與其他套件衝突
+type contextKey struct{}
+
+// NewContext 回傳帶有呼叫者的 context.Context，供 service 等不依賴 gin 的程式使用
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// Set 將呼叫者同時儲存到 gin.Context 與請求的 context.Context 中
+func Set(c *gin.Context, p *Principal) {
+	c.Set(constants.CtxPrincipalKey, p)
+	if c.Request != nil {
+		c.Request = c.Request.WithContext(NewContext(c.Request.Context(), p))
+	}
+}
+
+// FromContext 取得通過驗證的呼叫者，可以傳入 *gin.Context 或由請求延伸的 context.Context
+// 請求沒有經過 Auth 中介軟體時回傳 false
+func FromContext(ctx context.Context) (*Principal, bool) {
+	if c, ok := ctx.(*gin.Context); ok {
+		p, ok := c.Value(constants.CtxPrincipalKey).(*Principal)
+		return p, ok && p != nil
+	}
+	p, ok := ctx.Value(contextKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// RealFromContext 取得實際發出請求的呼叫者，用於稽核與記錄日誌
+// 代替使用者操作時回傳管理者，其他情況與 FromContext 相同
+func RealFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := FromContext(ctx)
+	if !ok {
+		return nil, false
+	}
+	return p.Real(), true
+}
+
+// UserID 取得呼叫者的使用者 ID，用於記錄日誌；沒有通過驗證或呼叫者不是使用者時回傳 0
+func UserID(ctx context.Context) uint {
+	if p, ok := FromContext(ctx); ok {
+		return p.UserID
+	}
+	return 0
+}
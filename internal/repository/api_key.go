@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"time"
+
+	"go-template/internal/models"
+	"go-template/internal/utils/logger"
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository struct {
+	db *gorm.DB
+}
+
+// NewAPIKeyRepository 建立一個新的 APIKeyRepository 實例
+func NewAPIKeyRepository(db *gorm.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+// Create 新增一個 API key
+// @Param key body models.APIKey true "新增的 API key 資料"
+// @return error "錯誤訊息"
+func (repo *APIKeyRepository) Create(key *models.APIKey) error {
+	result := repo.db.Create(key)
+	if result.Error != nil {
+		logger.Logger.Errorf("Error creating API key in database: %v", result.Error) // 記錄資料庫錯誤
+		return result.Error
+	}
+	logger.Logger.Debugf("API key %s created in database for user: %d", key.Prefix, key.UserID) // 記錄 API key 已建立
+	return nil
+}
+
+// GetByHash 根據雜湊值取得 API key
+// @param keyHash path string true "key 雜湊值"
+// @return models.APIKey "API key"
+// @return error "錯誤訊息"
+func (repo *APIKeyRepository) GetByHash(keyHash string) (*models.APIKey, error) {
+	var key models.APIKey
+	result := repo.db.Where("key_hash = ?", keyHash).First(&key)
+	if result.Error != nil {
+		logger.Logger.Debugf("Error getting API key by hash from database: %v", result.Error) // 記錄資料庫錯誤
+		return nil, result.Error
+	}
+	return &key, nil
+}
+
+// ListByUser 取得使用者所有尚未撤銷的 API key (包含已過期的)，依照建立時間排序
+// @param userID path uint true "使用者 ID"
+// @return []models.APIKey "API key 列表"
+// @return error "錯誤訊息"
+func (repo *APIKeyRepository) ListByUser(userID uint) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	result := repo.db.Where("user_id = ? AND revoked_at IS NULL", userID).Order("created_at, id").Find(&keys)
+	if result.Error != nil {
+		logger.Logger.Errorf("Error listing API keys from database: %v", result.Error) // 記錄資料庫錯誤
+		return nil, result.Error
+	}
+	return keys, nil
+}
+
+// Revoke 撤銷使用者的 API key，回傳值代表是否有 key 被撤銷 (不存在、屬於其他使用者或已撤銷時為 false)
+// @param userID path uint true "使用者 ID"
+// @param id path uint true "API key ID"
+// @return bool "是否成功撤銷"
+// @return error "錯誤訊息"
+func (repo *APIKeyRepository) Revoke(userID, id uint) (bool, error) {
+	result := repo.db.Model(&models.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		logger.Logger.Errorf("Error revoking API key in database: %v", result.Error) // 記錄資料庫錯誤
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// TouchLastUsed 更新 API key 的最後使用時間
+// 只有在上次記錄的時間早於 before 時才會更新，避免每個請求都寫入資料庫
+// @param id path uint true "API key ID"
+// @param usedAt path time.Time true "使用時間"
+// @param before path time.Time true "上次記錄的時間早於此時間才更新"
+// @return error "錯誤訊息"
+func (repo *APIKeyRepository) TouchLastUsed(id uint, usedAt, before time.Time) error {
+	result := repo.db.Model(&models.APIKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, before).
+		UpdateColumn("last_used_at", usedAt)
+	if result.Error != nil {
+		logger.Logger.Errorf("Error updating API key last used time in database: %v", result.Error) // 記錄資料庫錯誤
+		return result.Error
+	}
+	return nil
+}
+
+// deleteUserAPIKeys 刪除使用者所有的 API key，供永久刪除使用者時在同一個 transaction 中使用
+func deleteUserAPIKeys(tx *gorm.DB, userID uint) error {
+	return tx.Where("user_id = ?", userID).Delete(&models.APIKey{}).Error
+}
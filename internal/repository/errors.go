@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"go-template/internal/apperror"
+	"gorm.io/gorm"
+)
+
+// 資料庫錯誤轉換後的錯誤，原本的錯誤保留在 Cause 中，仍然可以使用 errors.Is(err, gorm.ErrRecordNotFound) 判斷
+var (
+	// ErrNotFound 查詢的資料不存在
+	ErrNotFound = apperror.New(apperror.KindNotFound, "not_found", "resource not found")
+	// ErrAlreadyExists 違反唯一性限制，Details 的 field 為重複的欄位
+	ErrAlreadyExists = apperror.New(apperror.KindConflict, "already_exists", "resource already exists")
+)
+
+// pgUniqueViolation Postgres 違反唯一性限制的錯誤代碼
+const pgUniqueViolation = "23505"
+
+// duplicateKeyPattern 從 Postgres 的錯誤詳細資訊取得重複的欄位，例如 Key (username)=(alice) already exists.
+var duplicateKeyPattern = regexp.MustCompile(`^Key \(([^)]+)\)=`)
+
+// derivedColumns 由其他欄位推導出來的欄位，違反唯一性限制時回報原本的欄位
+var derivedColumns = map[string]string{
+	"username_key": "username",
+}
+
+// translateError 將 GORM 與 Postgres 的錯誤轉換成 *apperror.Error，其他錯誤原樣回傳
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound.Wrap(err)
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+		field := duplicateField(pgErr)
+		if original, ok := derivedColumns[field]; ok {
+			field = original
+		}
+		if field == "" {
+			return ErrAlreadyExists.Wrap(err)
+		}
+		return ErrAlreadyExists.Wrap(err).
+			WithMessage(field + " already exists").
+			WithDetails(map[string]string{"field": field})
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrAlreadyExists.Wrap(err)
+	}
+	return err
+}
+
+// duplicateField 取得違反唯一性限制的欄位名稱
+// 優先使用錯誤詳細資訊中的欄位；沒有時從 GORM 產生的限制名稱 (uni_<table>_<column>) 推算
+func duplicateField(pgErr *pgconn.PgError) string {
+	if match := duplicateKeyPattern.FindStringSubmatch(pgErr.Detail); match != nil {
+		return match[1]
+	}
+	if pgErr.TableName != "" {
+		if field, ok := strings.CutPrefix(pgErr.ConstraintName, "uni_"+pgErr.TableName+"_"); ok {
+			return field
+		}
+	}
+	return ""
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-template/internal/apperror"
+	"gorm.io/gorm"
+)
+
+// 測試 Postgres 的唯一性錯誤 (23505) 轉換成 ErrAlreadyExists，並取得重複的欄位
+func TestTranslateErrorUniqueViolation(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		field string
+	}{
+		{"field from detail", &pgconn.PgError{Code: "23505", Detail: "Key (username)=(alice) already exists."}, "username"},
+		{"field from constraint name", &pgconn.PgError{Code: "23505", TableName: "users", ConstraintName: "uni_users_email"}, "email"},
+		{"wrapped error", fmt.Errorf("insert user: %w", &pgconn.PgError{Code: "23505", Detail: "Key (email)=(a@example.com) already exists."}), "email"},
+		{"derived column reported as its source", &pgconn.PgError{Code: "23505", Detail: "Key (username_key)=(alice) already exists."}, "username"},
+		{"unknown field", &pgconn.PgError{Code: "23505", ConstraintName: "idx_custom"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := translateError(tt.err)
+			require.ErrorIs(t, err, ErrAlreadyExists)
+			assert.Equal(t, apperror.KindConflict, apperror.KindOf(err))
+
+			var pgErr *pgconn.PgError
+			assert.True(t, errors.As(err, &pgErr), "the original error is kept as the cause")
+
+			appErr, ok := apperror.As(err)
+			require.True(t, ok)
+			if tt.field == "" {
+				assert.Equal(t, ErrAlreadyExists.Message, appErr.Message)
+				assert.Nil(t, appErr.Details)
+				return
+			}
+			assert.Equal(t, tt.field+" already exists", appErr.Message)
+			assert.Equal(t, map[string]string{"field": tt.field}, appErr.Details)
+		})
+	}
+}
+
+// 測試其他錯誤的轉換
+func TestTranslateErrorOthers(t *testing.T) {
+	assert.NoError(t, translateError(nil))
+
+	notFound := translateError(fmt.Errorf("get user: %w", gorm.ErrRecordNotFound))
+	assert.ErrorIs(t, notFound, ErrNotFound)
+	assert.ErrorIs(t, notFound, gorm.ErrRecordNotFound)
+
+	assert.ErrorIs(t, translateError(gorm.ErrDuplicatedKey), ErrAlreadyExists)
+
+	// 其他 Postgres 錯誤 (例如違反外鍵限制) 原樣回傳
+	foreignKey := &pgconn.PgError{Code: "23503"}
+	assert.Same(t, foreignKey, translateError(foreignKey))
+}
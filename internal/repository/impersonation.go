@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"go-template/internal/models"
+	"go-template/internal/utils/logger"
+	"gorm.io/gorm"
+)
+
+type ImpersonationRepository struct {
+	db *gorm.DB
+}
+
+// NewImpersonationRepository 建立一個新的 ImpersonationRepository 實例
+func NewImpersonationRepository(db *gorm.DB) *ImpersonationRepository {
+	return &ImpersonationRepository{db: db}
+}
+
+// Create 新增一筆代替操作的稽核紀錄
+// @Param log body models.ImpersonationLog true "新增的稽核紀錄"
+// @return error "錯誤訊息"
+func (repo *ImpersonationRepository) Create(log *models.ImpersonationLog) error {
+	result := repo.db.Create(log)
+	if result.Error != nil {
+		logger.Logger.Errorf("Error creating impersonation log in database: %v", result.Error) // 記錄資料庫錯誤
+		return result.Error
+	}
+	return nil
+}
+
+// ListByUser 取得使用者被代替操作的稽核紀錄，最新的在前面，最多回傳 limit 筆
+// @param userID path uint true "被代替操作的使用者 ID"
+// @param limit query int true "最多回傳的筆數"
+// @return []models.ImpersonationLog "稽核紀錄"
+// @return error "錯誤訊息"
+func (repo *ImpersonationRepository) ListByUser(userID uint, limit int) ([]models.ImpersonationLog, error) {
+	var logs []models.ImpersonationLog
+	result := repo.db.Where("user_id = ?", userID).Order("created_at DESC, id DESC").Limit(limit).Find(&logs)
+	if result.Error != nil {
+		logger.Logger.Errorf("Error listing impersonation logs from database: %v", result.Error) // 記錄資料庫錯誤
+		return nil, result.Error
+	}
+	return logs, nil
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"time"
+
+	"go-template/internal/models"
+	"go-template/internal/utils/logger"
+	"gorm.io/gorm"
+)
+
+type OAuthClientRepository struct {
+	db *gorm.DB
+}
+
+// NewOAuthClientRepository 建立一個新的 OAuthClientRepository 實例
+func NewOAuthClientRepository(db *gorm.DB) *OAuthClientRepository {
+	return &OAuthClientRepository{db: db}
+}
+
+// Create 新增一個 OAuth2 client
+// @Param client body models.OAuthClient true "新增的 client 資料"
+// @return error "錯誤訊息"
+func (repo *OAuthClientRepository) Create(client *models.OAuthClient) error {
+	result := repo.db.Create(client)
+	if result.Error != nil {
+		logger.Logger.Errorf("Error creating OAuth client in database: %v", result.Error) // 記錄資料庫錯誤
+		return result.Error
+	}
+	logger.Logger.Debugf("OAuth client created in database: %s", client.ClientID) // 記錄 client 已建立
+	return nil
+}
+
+// GetByClientID 根據 client ID 取得 OAuth2 client (包含已停用的 client)
+// @param clientID path string true "client ID"
+// @return models.OAuthClient "OAuth2 client"
+// @return error "錯誤訊息"
+func (repo *OAuthClientRepository) GetByClientID(clientID string) (*models.OAuthClient, error) {
+	var client models.OAuthClient
+	result := repo.db.Where("client_id = ?", clientID).First(&client)
+	if result.Error != nil {
+		logger.Logger.Debugf("Error getting OAuth client from database: %v", result.Error) // 記錄資料庫錯誤
+		return nil, result.Error
+	}
+	return &client, nil
+}
+
+// List 取得所有尚未停用的 OAuth2 client，依照建立時間排序
+// @return []models.OAuthClient "client 列表"
+// @return error "錯誤訊息"
+func (repo *OAuthClientRepository) List() ([]models.OAuthClient, error) {
+	var clients []models.OAuthClient
+	result := repo.db.Where("disabled_at IS NULL").Order("created_at, id").Find(&clients)
+	if result.Error != nil {
+		logger.Logger.Errorf("Error listing OAuth clients from database: %v", result.Error) // 記錄資料庫錯誤
+		return nil, result.Error
+	}
+	return clients, nil
+}
+
+// Disable 停用 OAuth2 client，回傳值代表是否有 client 被停用 (不存在或已停用時為 false)
+// @param id path uint true "client 的資料 ID"
+// @return bool "是否成功停用"
+// @return error "錯誤訊息"
+func (repo *OAuthClientRepository) Disable(id uint) (bool, error) {
+	result := repo.db.Model(&models.OAuthClient{}).
+		Where("id = ? AND disabled_at IS NULL", id).
+		Update("disabled_at", time.Now())
+	if result.Error != nil {
+		logger.Logger.Errorf("Error disabling OAuth client in database: %v", result.Error) // 記錄資料庫錯誤
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"time"
+
+	"go-template/internal/models"
+	"go-template/internal/utils/logger"
+	"gorm.io/gorm"
+)
+
+type RefreshTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewRefreshTokenRepository 建立一個新的 RefreshTokenRepository 實例
+func NewRefreshTokenRepository(db *gorm.DB) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: db}
+}
+
+// Create 新增一個 refresh token
+// @Param token body models.RefreshToken true "新增的 refresh token 資料"
+// @return error "錯誤訊息"
+func (repo *RefreshTokenRepository) Create(token *models.RefreshToken) error {
+	result := repo.db.Create(token)
+	if result.Error != nil {
+		logger.Logger.Errorf("Error creating refresh token in database: %v", result.Error) // 記錄資料庫錯誤
+		return result.Error
+	}
+	logger.Logger.Debugf("Refresh token created in database for user: %d", token.UserID) // 記錄 token 已建立
+	return nil
+}
+
+// GetByHash 根據雜湊值取得 refresh token
+// @param tokenHash path string true "token 雜湊值"
+// @return models.RefreshToken "refresh token"
+// @return error "錯誤訊息"
+func (repo *RefreshTokenRepository) GetByHash(tokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	result := repo.db.Where("token_hash = ?", tokenHash).First(&token)
+	if result.Error != nil {
+		logger.Logger.Debugf("Error getting refresh token by hash from database: %v", result.Error) // 記錄資料庫錯誤
+		return nil, result.Error
+	}
+	return &token, nil
+}
+
+// MarkUsed 將 refresh token 標記為已使用
+// 只有在 token 尚未被使用且未被撤銷時才會更新，回傳值代表是否成功標記，用來避免同一個 token 被同時輪換兩次
+// @param id path uint true "refresh token ID"
+// @return bool "是否成功標記"
+// @return error "錯誤訊息"
+func (repo *RefreshTokenRepository) MarkUsed(id uint) (bool, error) {
+	result := repo.db.Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		logger.Logger.Errorf("Error marking refresh token as used in database: %v", result.Error) // 記錄資料庫錯誤
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RevokeFamily 撤銷同一個 family 底下所有尚未撤銷的 refresh token
+// @param familyID path string true "token family ID"
+// @return error "錯誤訊息"
+func (repo *RefreshTokenRepository) RevokeFamily(familyID string) error {
+	result := repo.db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		logger.Logger.Errorf("Error revoking refresh token family in database: %v", result.Error) // 記錄資料庫錯誤
+		return result.Error
+	}
+	logger.Logger.Debugf("Refresh token family revoked in database: %s", familyID) // 記錄 family 已撤銷
+	return nil
+}
+
+// RevokeByUser 撤銷使用者所有尚未撤銷的 refresh token
+// @param userID path uint true "使用者 ID"
+// @return error "錯誤訊息"
+func (repo *RefreshTokenRepository) RevokeByUser(userID uint) error {
+	result := repo.db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		logger.Logger.Errorf("Error revoking refresh tokens of user in database: %v", result.Error) // 記錄資料庫錯誤
+		return result.Error
+	}
+	logger.Logger.Debugf("Refresh tokens revoked in database for user: %d", userID) // 記錄使用者 token 已撤銷
+	return nil
+}
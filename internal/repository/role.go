@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"errors"
+
+	"go-template/internal/models"
+	"go-template/internal/utils/logger"
+	"gorm.io/gorm"
+)
+
+// ErrUnknownRole 指定的角色不存在
+var ErrUnknownRole = errors.New("unknown role")
+
+type RoleRepository struct {
+	db *gorm.DB
+}
+
+// NewRoleRepository 建立一個新的 RoleRepository 實例
+func NewRoleRepository(db *gorm.DB) *RoleRepository {
+	return &RoleRepository{db: db}
+}
+
+// ListWithPermissions 取得所有角色以及角色擁有的權限
+// @return []models.Role "角色列表"
+// @return error "錯誤訊息"
+func (repo *RoleRepository) ListWithPermissions() ([]models.Role, error) {
+	var roles []models.Role
+	result := repo.db.Preload("Permissions").Find(&roles)
+	if result.Error != nil {
+		logger.Logger.Errorf("Error listing roles from database: %v", result.Error) // 記錄資料庫錯誤
+		return nil, result.Error
+	}
+	return roles, nil
+}
+
+// GetRoleNamesByUserID 取得使用者擁有的角色名稱
+// @param userID path uint true "使用者 ID"
+// @return []string "角色名稱"
+// @return error "錯誤訊息"
+func (repo *RoleRepository) GetRoleNamesByUserID(userID uint) ([]string, error) {
+	var names []string
+	result := repo.db.Model(&models.Role{}).
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", userID).
+		Order("roles.name").
+		Pluck("roles.name", &names)
+	if result.Error != nil {
+		logger.Logger.Errorf("Error getting roles of user from database: %v", result.Error) // 記錄資料庫錯誤
+		return nil, result.Error
+	}
+	return names, nil
+}
+
+// AssignRoles 將角色指派給使用者 (已擁有的角色不會重複指派)
+// 任何一個角色不存在時不會指派任何角色，並回傳 ErrUnknownRole
+// @param userID path uint true "使用者 ID"
+// @param roleNames body []string true "角色名稱"
+// @return error "錯誤訊息"
+func (repo *RoleRepository) AssignRoles(userID uint, roleNames ...string) error {
+	if err := assignRoles(repo.db, userID, roleNames); err != nil {
+		if !errors.Is(err, ErrUnknownRole) {
+			logger.Logger.Errorf("Error assigning roles in database: %v", err) // 記錄資料庫錯誤
+		}
+		return err
+	}
+	logger.Logger.Debugf("Roles %v assigned to user in database: %d", roleNames, userID) // 記錄角色已指派
+	return nil
+}
+
+// SeedDefaults 寫入預設的角色與權限 (models.DefaultRolePermissions)，並將預設角色指派給沒有任何角色的使用者
+// 可以重複執行，已存在的資料不會被覆蓋
+// @return error "錯誤訊息"
+func (repo *RoleRepository) SeedDefaults() error {
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		for roleName, permissionNames := range models.DefaultRolePermissions {
+			role := models.Role{Name: roleName}
+			if err := tx.Where(models.Role{Name: roleName}).FirstOrCreate(&role).Error; err != nil {
+				return err
+			}
+
+			permissions := make([]models.Permission, 0, len(permissionNames))
+			for _, name := range permissionNames {
+				permission := models.Permission{Name: name}
+				if err := tx.Where(models.Permission{Name: name}).FirstOrCreate(&permission).Error; err != nil {
+					return err
+				}
+				permissions = append(permissions, permission)
+			}
+			if err := tx.Model(&role).Association("Permissions").Append(permissions); err != nil {
+				return err
+			}
+		}
+
+		// 既有的使用者在導入角色之前沒有任何角色，補上預設角色避免失去原本的權限
+		return tx.Exec(`INSERT INTO user_roles (user_id, role_id)
+			SELECT users.id, roles.id FROM users, roles
+			WHERE roles.name = ? AND NOT EXISTS (SELECT 1 FROM user_roles WHERE user_roles.user_id = users.id)`,
+			models.RoleUser).Error
+	})
+}
+
+// ReplaceRoles 以指定的角色取代使用者目前所有的角色
+// @param userID path uint true "使用者 ID"
+// @param roleNames body []string true "角色名稱"
+// @return error "錯誤訊息"
+func (repo *RoleRepository) ReplaceRoles(userID uint, roleNames ...string) error {
+	roles, err := findRoles(repo.db, roleNames)
+	if errors.Is(err, ErrUnknownRole) {
+		return err
+	}
+	if err != nil {
+		logger.Logger.Errorf("Error getting roles from database: %v", err) // 記錄資料庫錯誤
+		return err
+	}
+
+	user := models.User{Model: gorm.Model{ID: userID}}
+	if err := repo.db.Model(&user).Association("Roles").Replace(roles); err != nil {
+		logger.Logger.Errorf("Error replacing roles in database: %v", err) // 記錄資料庫錯誤
+		return err
+	}
+	logger.Logger.Debugf("Roles of user replaced in database: %d -> %v", userID, roleNames) // 記錄角色已更新
+	return nil
+}
+
+// assignRoles 在 tx 中將角色指派給使用者，任何一個角色不存在時回傳 ErrUnknownRole
+func assignRoles(tx *gorm.DB, userID uint, roleNames []string) error {
+	roles, err := findRoles(tx, roleNames)
+	if err != nil {
+		return err
+	}
+	user := models.User{Model: gorm.Model{ID: userID}}
+	return tx.Model(&user).Association("Roles").Append(roles)
+}
+
+// findRoles 取得指定名稱的角色，任何一個角色不存在時回傳 ErrUnknownRole
+func findRoles(tx *gorm.DB, roleNames []string) ([]models.Role, error) {
+	var roles []models.Role
+	if err := tx.Where("name IN ?", roleNames).Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	unique := make(map[string]bool, len(roleNames))
+	for _, name := range roleNames {
+		unique[name] = true
+	}
+	if len(roles) != len(unique) {
+		return nil, ErrUnknownRole
+	}
+	return roles, nil
+}
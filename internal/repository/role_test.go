@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"os"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-template/internal/models"
+	"go-template/internal/utils/logger"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormLog "gorm.io/gorm/logger"
+)
+
+func TestMain(m *testing.M) {
+	_ = logger.Init(&logger.Config{Level: "error", ConsoleOut: true, ServiceName: "repository-test"})
+	os.Exit(m.Run())
+}
+
+// roleColumns roles 查詢回傳的欄位
+var roleColumns = []string{"id", "name"}
+
+// newMockDB 建立使用 sqlmock 的 gorm 資料庫，測試結束時檢查所有預期的查詢都已執行
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	conn, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, mock.ExpectationsWereMet())
+		_ = conn.Close()
+	})
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{Logger: gormLog.Default.LogMode(gormLog.Silent)})
+	require.NoError(t, err)
+	return db, mock
+}
+
+// expectAppendRole 預期將角色 1 指派給使用者 7 的查詢
+func expectAppendRole(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(`UPDATE "users" SET "updated_at"=\$1 WHERE "users"."deleted_at" IS NULL AND "id" = \$2`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO "roles"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectExec(`INSERT INTO "user_roles" \("user_id","role_id"\) VALUES \(\$1,\$2\) ON CONFLICT DO NOTHING`).
+		WithArgs(7, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+// 測試任何一個角色不存在時 AssignRoles 回傳 ErrUnknownRole，且不會指派存在的角色
+func TestAssignRolesUnknownRole(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewRoleRepository(db)
+
+	mock.ExpectQuery(`SELECT \* FROM "roles" WHERE name IN \(\$1,\$2\)`).WithArgs(models.RoleUser, "no-such-role").
+		WillReturnRows(sqlmock.NewRows(roleColumns).AddRow(1, models.RoleUser))
+
+	assert.ErrorIs(t, repo.AssignRoles(7, models.RoleUser, "no-such-role"), ErrUnknownRole)
+}
+
+// 測試重複的角色名稱不會被視為不存在的角色
+func TestAssignRolesDuplicateNames(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewRoleRepository(db)
+
+	mock.ExpectQuery(`SELECT \* FROM "roles" WHERE name IN`).
+		WillReturnRows(sqlmock.NewRows(roleColumns).AddRow(1, models.RoleUser))
+	mock.ExpectBegin()
+	expectAppendRole(mock)
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.AssignRoles(7, models.RoleUser, models.RoleUser))
+}
+
+// 測試建立使用者與指派角色在同一個交易中，角色不存在時回復交易，不會留下沒有角色的使用者
+func TestCreateWithRolesRollsBackOnUnknownRole(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewUserRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "users"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectQuery(`SELECT \* FROM "roles" WHERE name IN \(\$1\)`).WithArgs("no-such-role").
+		WillReturnRows(sqlmock.NewRows(roleColumns))
+	mock.ExpectRollback()
+
+	user := &models.User{Username: "alice", Email: "alice@example.com", Password: "hash"}
+	assert.ErrorIs(t, repo.CreateWithRoles(user, "no-such-role"), ErrUnknownRole)
+	assert.Zero(t, user.ID, "the user was rolled back")
+}
+
+// 測試建立使用者與指派角色成功時在同一個交易中提交
+func TestCreateWithRoles(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewUserRepository(db)
+
+	// 角色指派在同一個交易中，不會另外開始交易
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "users"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectQuery(`SELECT \* FROM "roles" WHERE name IN \(\$1\)`).WithArgs(models.RoleUser).
+		WillReturnRows(sqlmock.NewRows(roleColumns).AddRow(1, models.RoleUser))
+	expectAppendRole(mock)
+	mock.ExpectCommit()
+
+	user := &models.User{Username: "alice", Email: "alice@example.com", Password: "hash"}
+	require.NoError(t, repo.CreateWithRoles(user, models.RoleUser))
+	assert.Equal(t, uint(7), user.ID)
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"time"
+
+	"go-template/internal/models"
+	"go-template/internal/utils/logger"
+	"gorm.io/gorm"
+)
+
+type SessionRepository struct {
+	db *gorm.DB
+}
+
+// NewSessionRepository 建立一個新的 SessionRepository 實例
+func NewSessionRepository(db *gorm.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+// Create 新增一個登入工作階段
+// @Param session body models.Session true "新增的工作階段資料"
+// @return error "錯誤訊息"
+func (repo *SessionRepository) Create(session *models.Session) error {
+	result := repo.db.Create(session)
+	if result.Error != nil {
+		logger.Logger.Errorf("Error creating session in database: %v", result.Error) // 記錄資料庫錯誤
+		return result.Error
+	}
+	logger.Logger.Debugf("Session created in database for user: %d", session.UserID) // 記錄工作階段已建立
+	return nil
+}
+
+// GetByID 根據 ID 取得工作階段 (包含已撤銷與已過期的工作階段)
+// @param id path string true "工作階段 ID"
+// @return models.Session "工作階段"
+// @return error "錯誤訊息"
+func (repo *SessionRepository) GetByID(id string) (*models.Session, error) {
+	var session models.Session
+	result := repo.db.Where("id = ?", id).First(&session)
+	if result.Error != nil {
+		logger.Logger.Debugf("Error getting session from database: %v", result.Error) // 記錄資料庫錯誤
+		return nil, result.Error
+	}
+	return &session, nil
+}
+
+// ListActiveByUser 取得使用者所有尚未撤銷且尚未過期的工作階段，依照最後使用時間排序 (最近的在前)
+// @param userID path uint true "使用者 ID"
+// @return []models.Session "工作階段列表"
+// @return error "錯誤訊息"
+func (repo *SessionRepository) ListActiveByUser(userID uint) ([]models.Session, error) {
+	var sessions []models.Session
+	result := repo.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC, created_at DESC").Find(&sessions)
+	if result.Error != nil {
+		logger.Logger.Errorf("Error listing sessions from database: %v", result.Error) // 記錄資料庫錯誤
+		return nil, result.Error
+	}
+	return sessions, nil
+}
+
+// Extend 換發 token 時延長工作階段的過期時間並更新最後使用時間
+// 已撤銷的工作階段不會被更新，回傳值代表工作階段是否仍然有效
+// @param id path string true "工作階段 ID"
+// @param seenAt path time.Time true "使用時間"
+// @param expiresAt path time.Time true "新的過期時間"
+// @return bool "工作階段是否仍然有效"
+// @return error "錯誤訊息"
+func (repo *SessionRepository) Extend(id string, seenAt, expiresAt time.Time) (bool, error) {
+	result := repo.db.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		UpdateColumns(map[string]interface{}{"last_seen_at": seenAt, "expires_at": expiresAt})
+	if result.Error != nil {
+		logger.Logger.Errorf("Error extending session in database: %v", result.Error) // 記錄資料庫錯誤
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// TouchLastSeen 批次更新工作階段的最後使用時間，只會讓時間往後移動
+// @param seen path map[string]time.Time true "工作階段 ID 與最後使用時間"
+// @return error "錯誤訊息"
+func (repo *SessionRepository) TouchLastSeen(seen map[string]time.Time) error {
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		for id, seenAt := range seen {
+			err := tx.Model(&models.Session{}).
+				Where("id = ? AND last_seen_at < ?", id, seenAt).
+				UpdateColumn("last_seen_at", seenAt).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Logger.Errorf("Error updating session last seen time in database: %v", err) // 記錄資料庫錯誤
+		return err
+	}
+	return nil
+}
+
+// Revoke 撤銷使用者的工作階段，回傳值代表是否有工作階段被撤銷 (不存在、屬於其他使用者或已撤銷時為 false)
+// @param userID path uint true "使用者 ID"
+// @param id path string true "工作階段 ID"
+// @return bool "是否成功撤銷"
+// @return error "錯誤訊息"
+func (repo *SessionRepository) Revoke(userID uint, id string) (bool, error) {
+	result := repo.db.Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		logger.Logger.Errorf("Error revoking session in database: %v", result.Error) // 記錄資料庫錯誤
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// RevokeByUser 撤銷使用者所有尚未撤銷的工作階段
+// @param userID path uint true "使用者 ID"
+// @return error "錯誤訊息"
+func (repo *SessionRepository) RevokeByUser(userID uint) error {
+	result := repo.db.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		logger.Logger.Errorf("Error revoking sessions of user in database: %v", result.Error) // 記錄資料庫錯誤
+		return result.Error
+	}
+	logger.Logger.Debugf("Sessions revoked in database for user: %d", userID) // 記錄使用者工作階段已撤銷
+	return nil
+}
+
+// deleteUserSessions 刪除使用者所有的工作階段，供永久刪除使用者時在同一個 transaction 中使用
+func deleteUserSessions(tx *gorm.DB, userID uint) error {
+	return tx.Where("user_id = ?", userID).Delete(&models.Session{}).Error
+}
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"errors"
+
 	"go-template/internal/models"
 	"go-template/internal/utils/logger"
 	"gorm.io/gorm"
@@ -16,18 +18,45 @@ func NewUserRepository(db *gorm.DB) *UserRepository {
 }
 
 // Create 新增一個使用者
+// 帳號名稱或電子郵件重複時回傳 ErrAlreadyExists，Details 的 field 為重複的欄位
 // @Param user body models.User true "新增的使用者資料"
 // @return error "錯誤訊息"
 func (repo *UserRepository) Create(user *models.User) error {
+	user.UsernameKey = models.UsernameKey(user.Username)
 	result := repo.db.Create(user)
 	if result.Error != nil {
 		logger.Logger.Errorf("Error creating user in database: %v", result.Error) // 記錄資料庫錯誤
-		return result.Error
+		return translateError(result.Error)
 	}
 	logger.Logger.Debugf("User created in database: %s", user.Username) // 記錄使用者已建立
 	return nil
 }
 
+// CreateWithRoles 在同一個交易中建立使用者並指派角色
+// 任何一個角色不存在或指派失敗時不會留下使用者，角色不存在時回傳 ErrUnknownRole
+// @param user body models.User true "使用者"
+// @param roleNames body []string true "角色名稱"
+// @return error "錯誤訊息"
+func (repo *UserRepository) CreateWithRoles(user *models.User, roleNames ...string) error {
+	user.UsernameKey = models.UsernameKey(user.Username)
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		return assignRoles(tx, user.ID, roleNames)
+	})
+	if err != nil {
+		user.ID = 0 // 交易已經回復，使用者沒有被建立
+		if errors.Is(err, ErrUnknownRole) {
+			return err
+		}
+		logger.Logger.Errorf("Error creating user with roles in database: %v", err) // 記錄資料庫錯誤
+		return translateError(err)
+	}
+	logger.Logger.Debugf("User created in database with roles %v: %s", roleNames, user.Username) // 記錄使用者已建立
+	return nil
+}
+
 // GetByID 根據 ID 取得使用者
 // @param id path uint true "使用者 ID"
 // @return models.User "使用者"
@@ -37,35 +66,80 @@ func (repo *UserRepository) GetByID(id uint) (*models.User, error) {
 	result := repo.db.First(&user, id)
 	if result.Error != nil {
 		logger.Logger.Errorf("Error getting user by ID from database: %v", result.Error) // 記錄資料庫錯誤
-		return nil, result.Error
+		return nil, translateError(result.Error)
 	}
 	logger.Logger.Debugf("User found by ID in database: %d", id) // 記錄使用者已找到
 	return &user, nil
 }
 
-// GetByUsername 根據使用者名稱取得使用者
+// GetByUsername 根據使用者名稱取得使用者，以 models.UsernameKey 比較 (全形半形與大小寫不同也視為相同)
 // @param username path string true "使用者名稱"
 // @return models.User "使用者"
 // @return error "錯誤訊息"
 func (repo *UserRepository) GetByUsername(username string) (*models.User, error) {
 	var user models.User
-	result := repo.db.Where("username = ?", username).First(&user)
+	result := repo.db.Where("username_key = ?", models.UsernameKey(username)).First(&user)
 	if result.Error != nil {
 		logger.Logger.Errorf("Error getting user by username from database: %v", result.Error) // 記錄資料庫錯誤
-		return nil, result.Error
+		return nil, translateError(result.Error)
 	}
 	logger.Logger.Debugf("User found by username in database: %s", username) // 記錄使用者已找到
 	return &user, nil
 }
 
+// GetByEmail 根據電子郵件取得使用者 (不區分大小寫)
+// @param email path string true "電子郵件"
+// @return models.User "使用者"
+// @return error "錯誤訊息"
+func (repo *UserRepository) GetByEmail(email string) (*models.User, error) {
+	var user models.User
+	result := repo.db.Where("LOWER(email) = LOWER(?)", email).First(&user)
+	if result.Error != nil {
+		logger.Logger.Debugf("Error getting user by email from database: %v", result.Error) // 記錄資料庫錯誤
+		return nil, translateError(result.Error)
+	}
+	return &user, nil
+}
+
+// UsernameTaken 檢查帳號名稱是否已經被其他使用者使用 (以 models.UsernameKey 比較，包含已刪除的使用者)
+// @param username path string true "帳號名稱"
+// @param excludeID path uint false "要排除的使用者 ID，更新時為要更新的使用者"
+// @return bool "是否已經被使用"
+// @return error "錯誤訊息"
+func (repo *UserRepository) UsernameTaken(username string, excludeID uint) (bool, error) {
+	return repo.taken("username_key = ?", models.UsernameKey(username), excludeID)
+}
+
+// EmailTaken 檢查電子郵件是否已經被其他使用者使用 (不區分大小寫，包含已刪除的使用者)
+// @param email path string true "電子郵件"
+// @param excludeID path uint false "要排除的使用者 ID，更新時為要更新的使用者"
+// @return bool "是否已經被使用"
+// @return error "錯誤訊息"
+func (repo *UserRepository) EmailTaken(email string, excludeID uint) (bool, error) {
+	return repo.taken("LOWER(email) = LOWER(?)", email, excludeID)
+}
+
+// taken 檢查是否有 excludeID 以外的使用者符合條件；已刪除的使用者仍然佔用唯一性限制，所以一併檢查
+func (repo *UserRepository) taken(condition string, value string, excludeID uint) (bool, error) {
+	var count int64
+	result := repo.db.Unscoped().Model(&models.User{}).Where(condition, value).Where("id <> ?", excludeID).Count(&count)
+	if result.Error != nil {
+		logger.Logger.Errorf("Error checking user uniqueness in database: %v", result.Error) // 記錄資料庫錯誤
+		return false, translateError(result.Error)
+	}
+	return count > 0, nil
+}
+
 // Update 更新使用者資訊
+// 密碼、帳號狀態與 token 撤銷時間不會被更新，必須透過 UpdateColumns、ChangeStatus 與 revocation.Store 變更
 // @Param user body models.User true "修改的使用者資料"
 // @return error "錯誤訊息"
 func (repo *UserRepository) Update(user *models.User) error {
-	result := repo.db.Save(user)
+	user.UsernameKey = models.UsernameKey(user.Username)
+	result := repo.db.Omit("password", "status", "tokens_valid_after").Save(user)
 	if result.Error != nil {
 		logger.Logger.Errorf("Error updating user in database: %v", result.Error) // 記錄資料庫錯誤
-		return result.Error
+		return translateError(result.Error)
 	}
 	logger.Logger.Debugf("User updated in database: %s", user.Username) // 記錄使用者已更新
 	return nil
@@ -83,3 +157,197 @@ func (repo *UserRepository) Delete(id uint) error {
 	logger.Logger.Debugf("User deleted from database with ID: %d", id) // 記錄使用者已刪除
 	return nil
 }
+
+// List 依照查詢條件取得使用者列表 (包含使用者的角色)
+// 有設定 query.After 時使用 keyset 分頁，否則使用 offset 分頁；總數只套用篩選條件，不受分頁影響
+// @param query query UserListQuery true "篩選、排序與分頁條件"
+// @return []models.User "使用者列表"
+// @return int64 "符合篩選條件的使用者總數"
+// @return error "錯誤訊息"
+func (repo *UserRepository) List(query UserListQuery) ([]models.User, int64, error) {
+	filtered := query.applyFilters(repo.db.Model(&models.User{}))
+
+	var total int64
+	if err := filtered.Count(&total).Error; err != nil {
+		logger.Logger.Errorf("Error counting users in database: %v", err) // 記錄資料庫錯誤
+		return nil, 0, err
+	}
+
+	page, err := query.applyPagination(query.applyFilters(repo.db.Model(&models.User{})))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var users []models.User
+	result := page.Preload("Roles").Find(&users)
+	if result.Error != nil {
+		logger.Logger.Errorf("Error listing users from database: %v", result.Error) // 記錄資料庫錯誤
+		return nil, 0, result.Error
+	}
+	return users, total, nil
+}
+
+// GetByIDUnscoped 根據 ID 取得使用者 (包含已刪除的使用者與使用者的角色)
+// @param id path uint true "使用者 ID"
+// @return models.User "使用者"
+// @return error "錯誤訊息"
+func (repo *UserRepository) GetByIDUnscoped(id uint) (*models.User, error) {
+	var user models.User
+	result := repo.db.Unscoped().Preload("Roles").First(&user, id)
+	if result.Error != nil {
+		logger.Logger.Errorf("Error getting user by ID from database: %v", result.Error) // 記錄資料庫錯誤
+		return nil, translateError(result.Error)
+	}
+	return &user, nil
+}
+
+// UpdateColumns 只更新指定的欄位，避免覆蓋其他欄位 (例如密碼)；更新 username 時一併更新 username_key
+// @param id path uint true "使用者 ID"
+// @param columns body map[string]interface{} true "欄位名稱與新的值"
+// @return error "錯誤訊息"
+func (repo *UserRepository) UpdateColumns(id uint, columns map[string]interface{}) error {
+	if username, ok := columns["username"].(string); ok {
+		columns["username_key"] = models.UsernameKey(username)
+	}
+	result := repo.db.Model(&models.User{}).Where("id = ?", id).Updates(columns)
+	if result.Error != nil {
+		logger.Logger.Errorf("Error updating user columns in database: %v", result.Error) // 記錄資料庫錯誤
+		return translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return translateError(gorm.ErrRecordNotFound)
+	}
+	logger.Logger.Debugf("User columns updated in database: %d", id) // 記錄使用者已更新
+	return nil
+}
+
+// Restore 還原已被軟刪除的使用者
+// @param id path uint true "使用者 ID"
+// @return error "錯誤訊息"
+func (repo *UserRepository) Restore(id uint) error {
+	result := repo.db.Unscoped().Model(&models.User{}).Where("id = ?", id).Update("deleted_at", nil)
+	if result.Error != nil {
+		logger.Logger.Errorf("Error restoring user in database: %v", result.Error) // 記錄資料庫錯誤
+		return translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return translateError(gorm.ErrRecordNotFound)
+	}
+	logger.Logger.Debugf("User restored in database with ID: %d", id) // 記錄使用者已還原
+	return nil
+}
+
+// BackfillUsernameKeys 為還沒有 username_key 的使用者 (包含已刪除的使用者) 寫入 models.UsernameKey，供資料庫遷移使用
+// 既有的帳號名稱正規化後重複時違反唯一性限制，回傳 ErrAlreadyExists，必須先手動更名
+// @return int64 "寫入的使用者數量"
+// @return error "錯誤訊息"
+func (repo *UserRepository) BackfillUsernameKeys() (int64, error) {
+	var users []models.User
+	result := repo.db.Unscoped().Select("id", "username").
+		Where("username_key IS NULL OR username_key = ''").Find(&users)
+	if result.Error != nil {
+		logger.Logger.Errorf("Error listing users without username key from database: %v", result.Error) // 記錄資料庫錯誤
+		return 0, translateError(result.Error)
+	}
+	for _, user := range users {
+		err := repo.db.Unscoped().Model(&models.User{}).Where("id = ?", user.ID).
+			UpdateColumn("username_key", models.UsernameKey(user.Username)).Error
+		if err != nil {
+			logger.Logger.Errorf("Error writing username key of user %d to database: %v", user.ID, err) // 記錄資料庫錯誤
+			return 0, translateError(err)
+		}
+	}
+	return int64(len(users)), nil
+}
+
+// HardDelete 永久刪除使用者，以及使用者的角色、refresh token、登入工作階段、狀態變更紀錄、被代替操作的紀錄、一次性 token、兩步驟驗證設定與 API key
+// @param id path uint true "使用者 ID"
+// @return error "錯誤訊息"
+func (repo *UserRepository) HardDelete(id uint) error {
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		user := models.User{Model: gorm.Model{ID: id}}
+		if err := tx.Model(&user).Association("Roles").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("user_id = ?", id).Delete(&models.RefreshToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&models.UserStatusChange{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&models.ImpersonationLog{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&models.UserToken{}).Error; err != nil {
+			return err
+		}
+		if err := deleteUserMFA(tx, id); err != nil {
+			return err
+		}
+		if err := deleteUserAPIKeys(tx, id); err != nil {
+			return err
+		}
+		if err := deleteUserSessions(tx, id); err != nil {
+			return err
+		}
+		result := tx.Unscoped().Delete(&models.User{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Logger.Errorf("Error hard deleting user from database: %v", err) // 記錄資料庫錯誤
+		return translateError(err)
+	}
+	logger.Logger.Debugf("User permanently deleted from database with ID: %d", id) // 記錄使用者已永久刪除
+	return nil
+}
+
+// ChangeStatus 變更使用者的帳號狀態並寫入變更紀錄
+// 只有在目前狀態仍然是 change.FromStatus 時才會變更，避免同時變更時覆蓋其他請求的結果
+// @param change body models.UserStatusChange true "狀態變更紀錄"
+// @return bool "是否成功變更"
+// @return error "錯誤訊息"
+func (repo *UserRepository) ChangeStatus(change *models.UserStatusChange) (bool, error) {
+	changed := false
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Model(&models.User{}).
+			Where("id = ? AND status = ?", change.UserID, change.FromStatus).
+			Update("status", change.ToStatus)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		changed = true
+		return tx.Create(change).Error
+	})
+	if err != nil {
+		logger.Logger.Errorf("Error changing user status in database: %v", err) // 記錄資料庫錯誤
+		return false, err
+	}
+	if changed {
+		logger.Logger.Debugf("User status changed in database: %d %s -> %s",
+			change.UserID, change.FromStatus, change.ToStatus) // 記錄狀態已變更
+	}
+	return changed, nil
+}
+
+// ListStatusChanges 取得使用者的帳號狀態變更紀錄，最新的在前面
+// @param userID path uint true "使用者 ID"
+// @return []models.UserStatusChange "狀態變更紀錄"
+// @return error "錯誤訊息"
+func (repo *UserRepository) ListStatusChanges(userID uint) ([]models.UserStatusChange, error) {
+	var changes []models.UserStatusChange
+	result := repo.db.Where("user_id = ?", userID).Order("created_at DESC, id DESC").Find(&changes)
+	if result.Error != nil {
+		logger.Logger.Errorf("Error listing user status changes from database: %v", result.Error) // 記錄資料庫錯誤
+		return nil, result.Error
+	}
+	return changes, nil
+}
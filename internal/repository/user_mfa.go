@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"time"
+
+	"go-template/internal/models"
+	"go-template/internal/utils/logger"
+	"gorm.io/gorm"
+)
+
+type UserMFARepository struct {
+	db *gorm.DB
+}
+
+// NewUserMFARepository 建立一個新的 UserMFARepository 實例
+func NewUserMFARepository(db *gorm.DB) *UserMFARepository {
+	return &UserMFARepository{db: db}
+}
+
+// GetByUserID 取得使用者的兩步驟驗證設定
+// @param userID path uint true "使用者 ID"
+// @return models.UserMFA "兩步驟驗證設定"
+// @return error "錯誤訊息，沒有設定時回傳 gorm.ErrRecordNotFound"
+func (repo *UserMFARepository) GetByUserID(userID uint) (*models.UserMFA, error) {
+	var mfa models.UserMFA
+	result := repo.db.Where("user_id = ?", userID).First(&mfa)
+	if result.Error != nil {
+		logger.Logger.Debugf("Error getting user MFA from database: %v", result.Error) // 記錄資料庫錯誤
+		return nil, result.Error
+	}
+	return &mfa, nil
+}
+
+// ReplacePending 建立尚未確認的兩步驟驗證設定，取代使用者之前尚未確認的設定
+// 已經確認的設定不會被取代，回傳值代表是否成功建立
+// @param mfa body models.UserMFA true "兩步驟驗證設定"
+// @return bool "是否成功建立"
+// @return error "錯誤訊息"
+func (repo *UserMFARepository) ReplacePending(mfa *models.UserMFA) (bool, error) {
+	created := false
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ? AND confirmed_at IS NULL", mfa.UserID).Delete(&models.UserMFA{})
+		if result.Error != nil {
+			return result.Error
+		}
+		var count int64
+		if err := tx.Model(&models.UserMFA{}).Where("user_id = ?", mfa.UserID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+		if err := tx.Create(mfa).Error; err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
+	if err != nil {
+		logger.Logger.Errorf("Error creating user MFA in database: %v", err) // 記錄資料庫錯誤
+		return false, err
+	}
+	return created, nil
+}
+
+// Confirm 啟用兩步驟驗證並設定復原碼，使用者之前的復原碼會被刪除
+// 只有尚未確認的設定會被更新，回傳值代表是否成功啟用
+// @param userID path uint true "使用者 ID"
+// @param step path int64 true "確認時使用的 time step"
+// @param codeHashes body []string true "復原碼的雜湊值"
+// @return bool "是否成功啟用"
+// @return error "錯誤訊息"
+func (repo *UserMFARepository) Confirm(userID uint, step int64, codeHashes []string) (bool, error) {
+	confirmed := false
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.UserMFA{}).
+			Where("user_id = ? AND confirmed_at IS NULL", userID).
+			Updates(map[string]interface{}{"confirmed_at": time.Now(), "last_used_step": step})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		if err := replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+			return err
+		}
+		confirmed = true
+		return nil
+	})
+	if err != nil {
+		logger.Logger.Errorf("Error confirming user MFA in database: %v", err) // 記錄資料庫錯誤
+		return false, err
+	}
+	return confirmed, nil
+}
+
+// UseStep 記錄最後一次成功使用的 time step
+// 只有在 step 比之前使用過的更新時才會更新，回傳值代表是否成功記錄，用來避免同一個驗證碼被使用兩次
+// @param userID path uint true "使用者 ID"
+// @param step path int64 true "time step"
+// @return bool "是否成功記錄"
+// @return error "錯誤訊息"
+func (repo *UserMFARepository) UseStep(userID uint, step int64) (bool, error) {
+	result := repo.db.Model(&models.UserMFA{}).
+		Where("user_id = ? AND confirmed_at IS NOT NULL AND last_used_step < ?", userID, step).
+		Update("last_used_step", step)
+	if result.Error != nil {
+		logger.Logger.Errorf("Error updating user MFA step in database: %v", result.Error) // 記錄資料庫錯誤
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ReplaceRecoveryCodes 以新的復原碼取代使用者所有的復原碼
+// @param userID path uint true "使用者 ID"
+// @param codeHashes body []string true "復原碼的雜湊值"
+// @return error "錯誤訊息"
+func (repo *UserMFARepository) ReplaceRecoveryCodes(userID uint, codeHashes []string) error {
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	})
+	if err != nil {
+		logger.Logger.Errorf("Error replacing recovery codes in database: %v", err) // 記錄資料庫錯誤
+		return err
+	}
+	return nil
+}
+
+// UseRecoveryCode 使用一組復原碼
+// 只有尚未使用的復原碼會被標記，回傳值代表是否成功使用
+// @param userID path uint true "使用者 ID"
+// @param codeHash path string true "復原碼的雜湊值"
+// @return bool "是否成功使用"
+// @return error "錯誤訊息"
+func (repo *UserMFARepository) UseRecoveryCode(userID uint, codeHash string) (bool, error) {
+	result := repo.db.Model(&models.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		logger.Logger.Errorf("Error using recovery code in database: %v", result.Error) // 記錄資料庫錯誤
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// CountRemainingRecoveryCodes 取得使用者尚未使用的復原碼數量
+// @param userID path uint true "使用者 ID"
+// @return int64 "尚未使用的復原碼數量"
+// @return error "錯誤訊息"
+func (repo *UserMFARepository) CountRemainingRecoveryCodes(userID uint) (int64, error) {
+	var count int64
+	result := repo.db.Model(&models.MFARecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&count)
+	if result.Error != nil {
+		logger.Logger.Errorf("Error counting recovery codes in database: %v", result.Error) // 記錄資料庫錯誤
+		return 0, result.Error
+	}
+	return count, nil
+}
+
+// DeleteByUser 停用兩步驟驗證，刪除使用者的設定與所有復原碼
+// @param userID path uint true "使用者 ID"
+// @return error "錯誤訊息"
+func (repo *UserMFARepository) DeleteByUser(userID uint) error {
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		return deleteUserMFA(tx, userID)
+	})
+	if err != nil {
+		logger.Logger.Errorf("Error deleting user MFA from database: %v", err) // 記錄資料庫錯誤
+		return err
+	}
+	logger.Logger.Debugf("User MFA deleted from database for user: %d", userID) // 記錄設定已刪除
+	return nil
+}
+
+// replaceRecoveryCodes 在交易中以新的復原碼取代使用者所有的復原碼
+func replaceRecoveryCodes(tx *gorm.DB, userID uint, codeHashes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+		return err
+	}
+	codes := make([]models.MFARecoveryCode, 0, len(codeHashes))
+	for _, hash := range codeHashes {
+		codes = append(codes, models.MFARecoveryCode{UserID: userID, CodeHash: hash})
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+	return tx.Create(&codes).Error
+}
+
+// deleteUserMFA 在交易中刪除使用者的兩步驟驗證設定與所有復原碼
+func deleteUserMFA(tx *gorm.DB, userID uint) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("user_id = ?", userID).Delete(&models.UserMFA{}).Error
+}
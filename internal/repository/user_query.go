@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-template/internal/models"
+	"gorm.io/gorm"
+)
+
+// 使用者列表可以排序的欄位
+const (
+	UserSortID        = "id"
+	UserSortCreatedAt = "created_at"
+	UserSortUsername  = "username"
+	UserSortEmail     = "email"
+	UserSortLastLogin = "last_login"
+)
+
+// userSortColumns 排序欄位與資料庫欄位的對應，只有列在這裡的欄位可以排序，避免 SQL injection
+var userSortColumns = map[string]string{
+	UserSortID:        "id",
+	UserSortCreatedAt: "created_at",
+	UserSortUsername:  "username",
+	UserSortEmail:     "email",
+	UserSortLastLogin: "last_login",
+}
+
+var (
+	// ErrInvalidSortField 不支援的排序欄位
+	ErrInvalidSortField = errors.New("invalid sort field")
+	// ErrKeysetNotSupported keyset 分頁只支援以 id 或 created_at 排序
+	ErrKeysetNotSupported = errors.New("keyset pagination requires sorting by id or created_at")
+)
+
+// UserListQuery 使用者列表的篩選、排序與分頁條件，零值代表不篩選
+type UserListQuery struct {
+	Status          *models.UserStatus // 帳號狀態
+	EmailDomain     string             // 電子郵件的網域，例如 example.com
+	CreatedAfter    *time.Time         // 建立時間 (包含)
+	CreatedBefore   *time.Time         // 建立時間 (不包含)
+	LastLoginAfter  *time.Time         // 最後登入時間 (包含)
+	LastLoginBefore *time.Time         // 最後登入時間 (不包含)
+
+	SortBy string // 排序欄位，預設為 id
+	Desc   bool   // 是否遞減排序
+
+	Offset int         // 略過的筆數 (offset 分頁)
+	Limit  int         // 取得的筆數
+	After  *UserKeyset // 上一頁最後一筆資料的排序鍵 (keyset 分頁)，設定後忽略 Offset
+}
+
+// UserKeyset keyset 分頁使用的排序鍵
+// 以 created_at 排序時，相同的建立時間再以 id 排序，確保順序固定
+type UserKeyset struct {
+	ID        uint
+	CreatedAt time.Time
+}
+
+// SupportsKeyset 判斷目前的排序欄位是否可以使用 keyset 分頁
+func (q UserListQuery) SupportsKeyset() bool {
+	sortBy := q.SortBy
+	return sortBy == "" || sortBy == UserSortID || sortBy == UserSortCreatedAt
+}
+
+// applyFilters 套用篩選條件
+func (q UserListQuery) applyFilters(db *gorm.DB) *gorm.DB {
+	if q.Status != nil {
+		db = db.Where("status = ?", *q.Status)
+	}
+	if q.EmailDomain != "" {
+		db = db.Where("LOWER(email) LIKE ?", "%@"+escapeLike(strings.ToLower(q.EmailDomain)))
+	}
+	if q.CreatedAfter != nil {
+		db = db.Where("created_at >= ?", *q.CreatedAfter)
+	}
+	if q.CreatedBefore != nil {
+		db = db.Where("created_at < ?", *q.CreatedBefore)
+	}
+	if q.LastLoginAfter != nil {
+		db = db.Where("last_login >= ?", *q.LastLoginAfter)
+	}
+	if q.LastLoginBefore != nil {
+		db = db.Where("last_login < ?", *q.LastLoginBefore)
+	}
+	return db
+}
+
+// applyPagination 套用排序與分頁條件
+func (q UserListQuery) applyPagination(db *gorm.DB) (*gorm.DB, error) {
+	sortBy := q.SortBy
+	if sortBy == "" {
+		sortBy = UserSortID
+	}
+	column, ok := userSortColumns[sortBy]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSortField, sortBy)
+	}
+
+	direction, comparison := "ASC", ">"
+	if q.Desc {
+		direction, comparison = "DESC", "<"
+	}
+	// 以 id 作為第二排序鍵，讓排序欄位相同的資料順序固定
+	db = db.Order(column + " " + direction)
+	if column != "id" {
+		db = db.Order("id " + direction)
+	}
+
+	if q.After != nil {
+		switch sortBy {
+		case UserSortID:
+			db = db.Where("id "+comparison+" ?", q.After.ID)
+		case UserSortCreatedAt:
+			db = db.Where("(created_at "+comparison+" ? OR (created_at = ? AND id "+comparison+" ?))",
+				q.After.CreatedAt, q.After.CreatedAt, q.After.ID)
+		default:
+			return nil, ErrKeysetNotSupported
+		}
+	} else if q.Offset > 0 {
+		db = db.Offset(q.Offset)
+	}
+
+	if q.Limit > 0 {
+		db = db.Limit(q.Limit)
+	}
+	return db, nil
+}
+
+// escapeLike 跳脫 LIKE 語法中的特殊字元
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"time"
+
+	"go-template/internal/models"
+	"go-template/internal/utils/logger"
+	"gorm.io/gorm"
+)
+
+type UserTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewUserTokenRepository 建立一個新的 UserTokenRepository 實例
+func NewUserTokenRepository(db *gorm.DB) *UserTokenRepository {
+	return &UserTokenRepository{db: db}
+}
+
+// Create 新增一個一次性 token
+// @Param token body models.UserToken true "新增的 token 資料"
+// @return error "錯誤訊息"
+func (repo *UserTokenRepository) Create(token *models.UserToken) error {
+	result := repo.db.Create(token)
+	if result.Error != nil {
+		logger.Logger.Errorf("Error creating user token in database: %v", result.Error) // 記錄資料庫錯誤
+		return result.Error
+	}
+	logger.Logger.Debugf("User token (%s) created in database for user: %d", token.Purpose, token.UserID) // 記錄 token 已建立
+	return nil
+}
+
+// GetByHash 根據用途與雜湊值取得 token
+// @param purpose path string true "token 用途"
+// @param tokenHash path string true "token 雜湊值"
+// @return models.UserToken "token"
+// @return error "錯誤訊息"
+func (repo *UserTokenRepository) GetByHash(purpose, tokenHash string) (*models.UserToken, error) {
+	var token models.UserToken
+	result := repo.db.Where("purpose = ? AND token_hash = ?", purpose, tokenHash).First(&token)
+	if result.Error != nil {
+		logger.Logger.Debugf("Error getting user token by hash from database: %v", result.Error) // 記錄資料庫錯誤
+		return nil, result.Error
+	}
+	return &token, nil
+}
+
+// MarkUsed 將 token 標記為已使用
+// 只有在 token 尚未被使用且尚未過期時才會更新，回傳值代表是否成功標記，用來避免同一個 token 被使用兩次
+// @param id path uint true "token ID"
+// @return bool "是否成功標記"
+// @return error "錯誤訊息"
+func (repo *UserTokenRepository) MarkUsed(id uint) (bool, error) {
+	now := time.Now()
+	result := repo.db.Model(&models.UserToken{}).
+		Where("id = ? AND used_at IS NULL AND expires_at > ?", id, now).
+		Update("used_at", now)
+	if result.Error != nil {
+		logger.Logger.Errorf("Error marking user token as used in database: %v", result.Error) // 記錄資料庫錯誤
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// InvalidateByUser 讓使用者某個用途所有尚未使用的 token 失效，例如重新寄送驗證信時讓舊的 token 失效
+// @param userID path uint true "使用者 ID"
+// @param purpose path string true "token 用途"
+// @return error "錯誤訊息"
+func (repo *UserTokenRepository) InvalidateByUser(userID uint, purpose string) error {
+	result := repo.db.Model(&models.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		logger.Logger.Errorf("Error invalidating user tokens in database: %v", result.Error) // 記錄資料庫錯誤
+		return result.Error
+	}
+	return nil
+}
+
+// LatestCreatedAt 取得使用者某個用途最近一次建立 token 的時間，沒有任何 token 時回傳零值
+// @param userID path uint true "使用者 ID"
+// @param purpose path string true "token 用途"
+// @return time.Time "最近一次建立的時間"
+// @return error "錯誤訊息"
+func (repo *UserTokenRepository) LatestCreatedAt(userID uint, purpose string) (time.Time, error) {
+	var token models.UserToken
+	result := repo.db.Where("user_id = ? AND purpose = ?", userID, purpose).Order("created_at DESC").Limit(1).Find(&token)
+	if result.Error != nil {
+		logger.Logger.Errorf("Error getting latest user token from database: %v", result.Error) // 記錄資料庫錯誤
+		return time.Time{}, result.Error
+	}
+	return token.CreatedAt, nil
+}
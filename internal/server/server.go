@@ -10,8 +10,13 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go-template/internal/api/handlers/response"
 	"go-template/internal/api/handlers/routes"
+	"go-template/internal/api/handlers/wellknown"
+	"go-template/internal/middleware"
 	"go-template/internal/utils/jwt"
+	"go-template/internal/utils/logger"
+	"go-template/internal/validators"
 )
 
 // Config Struct，用於設定 server
@@ -19,19 +24,41 @@ type Config struct {
 	DB          *gorm.DB
 	JwtService  *jwt.Service
 	UserService *routes.UserRoutes
+	AdminRoutes *routes.AdminRoutes
+	OAuthRoutes *routes.OAuthRoutes
 	Config      *configs.Config // 這是通用的配置，例如 AppPort
 }
 
 // Start 建立一個新的 HTTP server 實例
 func Start(cfg Config) *http.Server {
 	router := gin.Default()
+	// 只採用信任的反向代理送來的 X-Forwarded-For，c.ClientIP() 才不會被用戶端偽造
+	trustProxies(router, cfg.Config.TrustedProxies)
+
+	// 錯誤回應的預設格式，以及驗證錯誤的欄位路徑使用 JSON 欄位名稱
+	response.SetDefaultFormat(cfg.Config.ErrorFormat)
+	validators.RegisterJSONFieldNames()
+
+	// 每個請求都有請求 ID，錯誤回應與 X-Request-ID header 會帶上同一個 ID
+	router.Use(middleware.RequestID())
+	// 處理函數以 c.Error 記錄的錯誤統一在這裡依照錯誤種類回應
+	router.Use(middleware.ErrorHandler())
 
 	// 註冊 swagger 相關的路由
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	// 註冊公開的 JWKS 路由，讓其他服務不需要持有簽署金鑰也能驗證 token
+	router.GET("/.well-known/jwks.json", wellknown.NewHandler(cfg.JwtService).JWKS)
+
 	// 註冊使用者相關的路由
 	cfg.UserService.RegisterUser(router)
 
+	// 註冊管理者專用的路由
+	cfg.AdminRoutes.RegisterAdmin(router)
+
+	// 註冊 OAuth2 端點與 client 管理的路由
+	cfg.OAuthRoutes.RegisterOAuth(router)
+
 	// 建立 HTTP server 實例
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.Config.AppPort), // 使用配置中的 AppPort
@@ -43,3 +70,12 @@ func Start(cfg Config) *http.Server {
 
 	return server
 }
+
+// trustProxies 設定信任的反向代理，proxies 為空時不信任任何代理，c.ClientIP() 一律使用連線的來源 IP
+// 登入失敗限制、登入工作階段與代替操作的稽核紀錄都使用 c.ClientIP()，不可以信任用戶端自己送來的 X-Forwarded-For
+func trustProxies(router *gin.Engine, proxies []string) {
+	if err := router.SetTrustedProxies(proxies); err != nil {
+		// LoadConfig 已經檢查過格式，這裡不應該失敗
+		logger.Logger.Fatalf("Invalid trusted proxies %v: %v", proxies, err)
+	}
+}
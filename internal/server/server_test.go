@@ -0,0 +1,46 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go-template/internal/services/throttle"
+)
+
+// ipKey 以 trustProxies 設定 router 後，取得請求在登入失敗限制中使用的來源 IP 計數對象
+func ipKey(t *testing.T, proxies []string, remoteAddr string, headers map[string]string) string {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	trustProxies(router, proxies)
+	router.GET("/ip", func(c *gin.Context) {
+		c.String(http.StatusOK, throttle.IPKey(c.ClientIP()))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+	req.RemoteAddr = remoteAddr
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Body.String()
+}
+
+// 測試沒有設定信任的代理時，偽造的 X-Forwarded-For 與 X-Real-IP 不會改變來源 IP 的計數對象
+func TestSpoofedForwardedForIgnored(t *testing.T) {
+	spoofed := map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Real-IP": "198.51.100.2"}
+	assert.Equal(t, "ip:203.0.113.7", ipKey(t, nil, "203.0.113.7:51234", spoofed))
+}
+
+// 測試只有來自信任的代理的請求才會採用 X-Forwarded-For
+func TestTrustedProxyForwardedFor(t *testing.T) {
+	proxies := []string{"10.0.0.0/8"}
+	headers := map[string]string{"X-Forwarded-For": "198.51.100.1"}
+	assert.Equal(t, "ip:198.51.100.1", ipKey(t, proxies, "10.1.2.3:51234", headers))
+	assert.Equal(t, "ip:203.0.113.7", ipKey(t, proxies, "203.0.113.7:51234", headers),
+		"a client outside the trusted range must not be able to choose its own IP")
+}
@@ -0,0 +1,87 @@
+package oauth
+
+import (
+	"go-template/internal/apperror"
+	"go-template/internal/models"
+)
+
+// 支援的 token_type_hint (RFC 7009、RFC 7662)
+const (
+	TokenTypeHintAccessToken  = "access_token"
+	TokenTypeHintRefreshToken = "refresh_token"
+)
+
+// 可能的錯誤代碼，Kind 決定 ErrorHandler 中介軟體回應的 HTTP 狀態碼
+// token、introspection 與 revocation 端點依照 RFC 6749 的格式回應，不使用 ErrorHandler
+var (
+	// ErrInvalidClient client 不存在、已停用或 client secret 錯誤
+	ErrInvalidClient = apperror.New(apperror.KindUnauthorized, "invalid_client", "invalid client")
+	// ErrClientNotFound 要停用的 client 不存在或已停用
+	ErrClientNotFound = apperror.New(apperror.KindNotFound, "oauth_client_not_found", "OAuth client not found")
+	// ErrInvalidScope 要求的權限是空的，或不在 client (或註冊者) 擁有的權限之內
+	ErrInvalidScope = apperror.New(apperror.KindInvalid, "invalid_client_scope", "OAuth client scopes must be permissions you currently have")
+	// ErrUnauthorizedClient client 嘗試撤銷發行給其他 client 的 token
+	ErrUnauthorizedClient = apperror.New(apperror.KindForbidden, "unauthorized_client", "unauthorized client")
+)
+
+// NewClient 註冊 OAuth2 client 時的輸入
+type NewClient struct {
+	Name   string   // 用途說明
+	Scopes []string // 允許取得的權限，必須是註冊者目前擁有的權限
+}
+
+// CreatedClient 註冊成功的 client，ClientSecret 只會在這裡出現一次
+type CreatedClient struct {
+	*models.OAuthClient
+	ClientSecret string `json:"client_secret"` // 明文的 client secret，請立即保存
+}
+
+// ClientToken client credentials grant 發行的 access token (RFC 6749 5.1)，不包含 refresh token
+type ClientToken struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"` // 固定為 Bearer
+	ExpiresIn   int64  `json:"expires_in"` // Access token 的有效秒數
+	Scope       string `json:"scope"`      // 以空白分隔的權限
+}
+
+// Introspection token 檢查的結果 (RFC 7662 2.2)
+// token 無效時只有 Active 為 false，不回傳其他資訊
+type Introspection struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`      // client 的 token 被授與的權限
+	ClientID  string `json:"client_id,omitempty"`  // client 的 token 所屬的 client
+	Username  string `json:"username,omitempty"`   // 使用者的 token 所屬的使用者名稱
+	TokenType string `json:"token_type,omitempty"` // access token 為 Bearer，refresh token 為 refresh_token
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Iss       string `json:"iss,omitempty"`
+	Aud       string `json:"aud,omitempty"`
+	Jti       string `json:"jti,omitempty"`
+	Act       *Actor `json:"act,omitempty"` // 管理者代替使用者操作的 token 中實際操作的管理者 (RFC 8693)
+}
+
+// Actor 代替使用者操作的一方
+type Actor struct {
+	Sub string `json:"sub"` // 管理者的使用者 ID
+}
+
+// Service 介面，定義 OAuth2 client 與 token 端點相關的方法
+type Service interface {
+	// CreateClient 註冊 OAuth2 client，scopes 必須是註冊者目前擁有的權限
+	CreateClient(actorID uint, input NewClient) (*CreatedClient, error)
+	// ListClients 列出尚未停用的 client
+	ListClients() ([]models.OAuthClient, error)
+	// DisableClient 停用 client，已發行的 token 立即失效
+	DisableClient(id uint) error
+	// AuthenticateClient 以 client ID 與 client secret 驗證 client
+	AuthenticateClient(clientID, clientSecret string) (*models.OAuthClient, error)
+	// ActiveClientScopes 取得仍在使用中的 client 目前被允許的權限，client 不存在或已停用時回傳 ErrInvalidClient
+	ActiveClientScopes(clientID string) ([]string, error)
+	// ClientCredentials 為已驗證的 client 發行 access token (client credentials grant)
+	ClientCredentials(client *models.OAuthClient, scope string) (*ClientToken, error)
+	// Introspect 檢查 access token 或 refresh token 目前是否有效 (RFC 7662)
+	Introspect(token, tokenTypeHint string) (*Introspection, error)
+	// Revoke 撤銷 access token 或 refresh token (RFC 7009)，client 為 nil 代表呼叫者沒有提供 client 驗證
+	Revoke(client *models.OAuthClient, token, tokenTypeHint string) error
+}
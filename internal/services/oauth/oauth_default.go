@@ -0,0 +1,423 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-template/internal/models"
+	"go-template/internal/repository"
+	"go-template/internal/services/rbac"
+	"go-template/internal/services/revocation"
+	userSvc "go-template/internal/services/user"
+	"go-template/internal/utils/jwt"
+	"go-template/internal/utils/logger"
+	"gorm.io/gorm"
+)
+
+// clientIDPrefix 所有 client ID 共用的開頭，也讓 client ID 不會與使用者 ID (純數字) 混淆
+const clientIDPrefix = "gtc_"
+
+// clientIDBytes client ID 的隨機位元組長度
+const clientIDBytes = 8
+
+// ServiceDefault Struct，實作 oauth.Service 介面
+type ServiceDefault struct {
+	clientRepo       *repository.OAuthClientRepository
+	refreshTokenRepo *repository.RefreshTokenRepository
+	revocationStore  revocation.Store
+	rbacService      rbac.Service
+	userService      userSvc.Service
+	jwtService       *jwt.Service
+}
+
+// NewService 建立一個新的 oauth 實例
+func NewService(clientRepo *repository.OAuthClientRepository, refreshTokenRepo *repository.RefreshTokenRepository,
+	revocationStore revocation.Store, rbacService rbac.Service, userService userSvc.Service,
+	jwtService *jwt.Service) Service {
+	return &ServiceDefault{
+		clientRepo:       clientRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		revocationStore:  revocationStore,
+		rbacService:      rbacService,
+		userService:      userService,
+		jwtService:       jwtService,
+	}
+}
+
+// CreateClient 註冊 OAuth2 client
+// client secret 只儲存雜湊值，明文只會在這裡回傳一次
+// @param actorID path uint true "註冊者 (管理者) ID"
+// @param input body NewClient true "client 的名稱與權限"
+// @return client 新的 client
+// @return error 錯誤訊息
+func (svc *ServiceDefault) CreateClient(actorID uint, input NewClient) (*CreatedClient, error) {
+	scopes, err := svc.validateClientScopes(actorID, input.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	clientID, err := generateClientID()
+	if err != nil {
+		return nil, err
+	}
+	secret, secretHash, err := jwt.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	client := &models.OAuthClient{
+		ClientID:   clientID,
+		SecretHash: secretHash,
+		Name:       input.Name,
+		Scopes:     scopes,
+		CreatedBy:  &actorID,
+	}
+	if err := svc.clientRepo.Create(client); err != nil {
+		return nil, err
+	}
+
+	logger.Logger.Infof("OAuth client %s registered by user %d with scopes %v", clientID, actorID, scopes) // 記錄 client 已註冊
+	return &CreatedClient{OAuthClient: client, ClientSecret: secret}, nil
+}
+
+// ListClients 列出尚未停用的 client (不包含 client secret)
+// @return clients client 列表
+// @return error 錯誤訊息
+func (svc *ServiceDefault) ListClients() ([]models.OAuthClient, error) {
+	return svc.clientRepo.List()
+}
+
+// DisableClient 停用 client
+// 停用後不能再取得 token，已發行的 token 在下一次使用或檢查時就會被拒絕
+// @param id path uint true "client 的資料 ID"
+// @return error 錯誤訊息
+func (svc *ServiceDefault) DisableClient(id uint) error {
+	disabled, err := svc.clientRepo.Disable(id)
+	if err != nil {
+		return err
+	}
+	if !disabled {
+		return ErrClientNotFound
+	}
+	logger.Logger.Infof("OAuth client %d disabled", id) // 記錄 client 已停用
+	return nil
+}
+
+// AuthenticateClient 以 client ID 與 client secret 驗證 client
+// @param clientID body string true "client ID"
+// @param clientSecret body string true "client secret"
+// @return client 通過驗證的 client
+// @return error 錯誤訊息，驗證失敗時為 ErrInvalidClient
+func (svc *ServiceDefault) AuthenticateClient(clientID, clientSecret string) (*models.OAuthClient, error) {
+	client, err := svc.activeClient(clientID)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(jwt.HashOpaqueToken(clientSecret)), []byte(client.SecretHash)) != 1 {
+		logger.Logger.Debugf("Invalid secret for OAuth client %s", clientID) // 記錄錯誤
+		return nil, ErrInvalidClient
+	}
+	return client, nil
+}
+
+// ActiveClientScopes 取得仍在使用中的 client 目前被允許的權限
+// @param clientID path string true "client ID"
+// @return scopes 權限
+// @return error 錯誤訊息，client 不存在或已停用時為 ErrInvalidClient
+func (svc *ServiceDefault) ActiveClientScopes(clientID string) ([]string, error) {
+	client, err := svc.activeClient(clientID)
+	if err != nil {
+		return nil, err
+	}
+	return client.Scopes, nil
+}
+
+// ClientCredentials 為已驗證的 client 發行 access token
+// scope 為空白分隔的權限，省略時使用 client 所有的權限；依照 RFC 6749 4.4.3 不發行 refresh token
+// @param client body models.OAuthClient true "已驗證的 client"
+// @param scope body string false "要求的權限"
+// @return token access token
+// @return error 錯誤訊息
+func (svc *ServiceDefault) ClientCredentials(client *models.OAuthClient, scope string) (*ClientToken, error) {
+	scopes := client.Scopes
+	if requested := strings.Fields(scope); len(requested) > 0 {
+		allowed := make(map[string]bool, len(client.Scopes))
+		for _, s := range client.Scopes {
+			allowed[s] = true
+		}
+		var err error
+		if scopes, err = normalizeScopes(requested, allowed); err != nil {
+			logger.Logger.Debugf("OAuth client %s requested scopes %v outside of %v", client.ClientID, requested, client.Scopes) // 記錄錯誤
+			return nil, err
+		}
+	}
+
+	accessToken, err := svc.jwtService.GenerateClientToken(client.ClientID, scopes)
+	if err != nil {
+		logger.Logger.Errorf("Error generating client token: %v", err) // 記錄錯誤
+		return nil, err
+	}
+
+	logger.Logger.Debugf("Access token issued to OAuth client %s", client.ClientID) // 記錄 token 已發行
+	return &ClientToken{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(svc.jwtService.AccessTokenExpiresIn().Seconds()),
+		Scope:       strings.Join(scopes, " "),
+	}, nil
+}
+
+// Introspect 檢查 token 目前是否有效
+// 依照 token_type_hint 決定先檢查 access token 或 refresh token，找不到時再檢查另一種
+// token 無效、過期、已撤銷，或所屬的帳號或 client 已停用時只回傳 active: false
+// @param token body string true "要檢查的 token"
+// @param tokenTypeHint body string false "access_token 或 refresh_token"
+// @return result 檢查結果
+// @return error 錯誤訊息
+func (svc *ServiceDefault) Introspect(token, tokenTypeHint string) (*Introspection, error) {
+	lookups := []func(string) (*Introspection, error){svc.introspectAccessToken, svc.introspectRefreshToken}
+	if tokenTypeHint == TokenTypeHintRefreshToken {
+		lookups[0], lookups[1] = lookups[1], lookups[0]
+	}
+	for _, lookup := range lookups {
+		result, err := lookup(token)
+		if err != nil || result != nil {
+			return result, err
+		}
+	}
+	return &Introspection{Active: false}, nil
+}
+
+// Revoke 撤銷 token
+// 撤銷 access token 時將 jti 加入撤銷清單，撤銷 refresh token 時撤銷同一次登入的整個 token family；
+// client 的 token 只能由同一個 client 撤銷，使用者的 token 持有者即可撤銷 (與登出相同)。
+// 依照 RFC 7009 2.2，無效或不存在的 token 不視為錯誤
+// @param client body models.OAuthClient false "已驗證的 client，沒有提供 client 驗證時為 nil"
+// @param token body string true "要撤銷的 token"
+// @param tokenTypeHint body string false "access_token 或 refresh_token"
+// @return error 錯誤訊息
+func (svc *ServiceDefault) Revoke(client *models.OAuthClient, token, tokenTypeHint string) error {
+	if tokenTypeHint == TokenTypeHintRefreshToken {
+		if revoked, err := svc.revokeRefreshToken(token); revoked || err != nil {
+			return err
+		}
+		_, err := svc.revokeAccessToken(client, token)
+		return err
+	}
+	if revoked, err := svc.revokeAccessToken(client, token); revoked || err != nil {
+		return err
+	}
+	_, err := svc.revokeRefreshToken(token)
+	return err
+}
+
+// introspectAccessToken 檢查 access token，不是有效的 JWT 時回傳 nil 讓呼叫端繼續檢查 refresh token
+func (svc *ServiceDefault) introspectAccessToken(token string) (*Introspection, error) {
+	claims, err := svc.jwtService.ValidateToken(token)
+	if err != nil {
+		return nil, nil
+	}
+	inactive := &Introspection{Active: false}
+
+	// token 本身或所屬的登入工作階段被撤銷
+	keys := []string{claims.TokenID}
+	if claims.SessionID != "" {
+		keys = append(keys, revocation.SessionKey(claims.SessionID))
+	}
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		revoked, err := svc.revocationStore.IsRevoked(key)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return inactive, nil
+		}
+	}
+
+	result := &Introspection{
+		Active:    true,
+		TokenType: "Bearer",
+		Exp:       claims.ExpiresAt.Unix(),
+		Iat:       claims.IssuedAt.Unix(),
+		Iss:       svc.jwtService.Issuer(),
+		Aud:       svc.jwtService.Audience(),
+		Jti:       claims.TokenID,
+	}
+
+	if claims.IsClient() {
+		if _, err := svc.activeClient(claims.ClientID); err != nil {
+			if errors.Is(err, ErrInvalidClient) {
+				return inactive, nil
+			}
+			return nil, err
+		}
+		result.Sub = claims.ClientID
+		result.ClientID = claims.ClientID
+		result.Scope = strings.Join(claims.Scopes, " ")
+		return result, nil
+	}
+
+	validAfter, err := svc.revocationStore.TokensValidAfter(claims.UserID)
+	if errors.Is(err, revocation.ErrSubjectNotFound) {
+		return inactive, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if revocation.IssuedBefore(claims.IssuedAt, validAfter) {
+		return inactive, nil
+	}
+	user, ok, err := svc.activeUser(claims.UserID)
+	if err != nil || !ok {
+		return inactive, err
+	}
+	result.Sub = strconv.FormatUint(uint64(user.ID), 10)
+	result.Username = user.Username
+	if claims.IsImpersonated() {
+		result.Act = &Actor{Sub: strconv.FormatUint(uint64(claims.ActorID), 10)}
+	}
+	return result, nil
+}
+
+// introspectRefreshToken 檢查 refresh token，找不到時回傳 nil
+func (svc *ServiceDefault) introspectRefreshToken(token string) (*Introspection, error) {
+	stored, err := svc.refreshTokenRepo.GetByHash(jwt.HashOpaqueToken(token))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	inactive := &Introspection{Active: false}
+	if stored.UsedAt != nil || stored.RevokedAt != nil || !time.Now().Before(stored.ExpiresAt) {
+		return inactive, nil
+	}
+	user, ok, err := svc.activeUser(stored.UserID)
+	if err != nil || !ok {
+		return inactive, err
+	}
+	return &Introspection{
+		Active:    true,
+		TokenType: TokenTypeHintRefreshToken,
+		Exp:       stored.ExpiresAt.Unix(),
+		Iat:       stored.CreatedAt.Unix(),
+		Sub:       strconv.FormatUint(uint64(user.ID), 10),
+		Iss:       svc.jwtService.Issuer(),
+		Username:  user.Username,
+	}, nil
+}
+
+// revokeAccessToken 撤銷 access token，回傳值代表 token 是否為有效的 access token
+func (svc *ServiceDefault) revokeAccessToken(client *models.OAuthClient, token string) (bool, error) {
+	claims, err := svc.jwtService.ValidateToken(token)
+	if err != nil || claims.TokenID == "" {
+		return false, nil
+	}
+	if claims.IsClient() && (client == nil || client.ClientID != claims.ClientID) {
+		logger.Logger.Debugf("Token of OAuth client %s cannot be revoked by another client", claims.ClientID) // 記錄錯誤
+		return true, ErrUnauthorizedClient
+	}
+
+	if err := svc.revocationStore.Revoke(claims.UserID, claims.TokenID, claims.ExpiresAt); err != nil {
+		logger.Logger.Errorf("Error revoking access token: %v", err) // 記錄錯誤
+		return true, err
+	}
+	logger.Logger.Debugf("Access token %s revoked through the revocation endpoint", claims.TokenID) // 記錄 token 已撤銷
+	return true, nil
+}
+
+// revokeRefreshToken 撤銷 refresh token 所屬的整個 token family，回傳值代表 token 是否存在
+func (svc *ServiceDefault) revokeRefreshToken(token string) (bool, error) {
+	stored, err := svc.refreshTokenRepo.GetByHash(jwt.HashOpaqueToken(token))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := svc.refreshTokenRepo.RevokeFamily(stored.FamilyID); err != nil {
+		return true, err
+	}
+	logger.Logger.Debugf("Refresh token family of user %d revoked through the revocation endpoint", stored.UserID) // 記錄 token 已撤銷
+	return true, nil
+}
+
+// activeClient 取得尚未停用的 client，不存在或已停用時回傳 ErrInvalidClient
+func (svc *ServiceDefault) activeClient(clientID string) (*models.OAuthClient, error) {
+	client, err := svc.clientRepo.GetByClientID(clientID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidClient
+		}
+		return nil, err
+	}
+	if !client.Active() {
+		logger.Logger.Debugf("Disabled OAuth client %s used", clientID) // 記錄錯誤
+		return nil, ErrInvalidClient
+	}
+	return client, nil
+}
+
+// activeUser 取得使用者，並判斷帳號是否為 active 狀態；使用者不存在時視為不是 active
+func (svc *ServiceDefault) activeUser(userID uint) (*models.User, bool, error) {
+	user, err := svc.userService.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, userSvc.ErrUserNotFound) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return user, user.Status == models.UserStatusActive, nil
+}
+
+// validateClientScopes 檢查 client 的權限都是註冊者目前擁有的權限，避免管理者透過 client 取得自己沒有的權限
+func (svc *ServiceDefault) validateClientScopes(actorID uint, scopes []string) ([]string, error) {
+	roles, err := svc.rbacService.RolesForUser(actorID)
+	if err != nil {
+		return nil, err
+	}
+	granted, err := svc.rbacService.PermissionsForRoles(roles)
+	if err != nil {
+		return nil, err
+	}
+	return normalizeScopes(scopes, granted)
+}
+
+// normalizeScopes 確認權限都在 allowed 之內，回傳排序並移除重複後的權限
+func normalizeScopes(scopes []string, allowed map[string]bool) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, ErrInvalidScope
+	}
+	seen := make(map[string]bool, len(scopes))
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !allowed[scope] {
+			return nil, ErrInvalidScope
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// generateClientID 產生 client ID，例如 gtc_3f9a0c1b2d4e5f60
+func generateClientID() (string, error) {
+	buf := make([]byte, clientIDBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return clientIDPrefix + hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,164 @@
+package oauth
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-template/internal/configs"
+	"go-template/internal/models"
+	"go-template/internal/repository"
+	"go-template/internal/services/revocation"
+	"go-template/internal/utils/jwt"
+	"go-template/internal/utils/logger"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormLog "gorm.io/gorm/logger"
+)
+
+func TestMain(m *testing.M) {
+	_ = logger.Init(&logger.Config{Level: "error", ConsoleOut: true, ServiceName: "oauth-test"})
+	os.Exit(m.Run())
+}
+
+// newTestService 建立使用 sqlmock 資料庫與記憶體撤銷清單的 oauth 服務，測試結束時檢查所有預期的查詢都已執行
+func newTestService(t *testing.T) (*ServiceDefault, sqlmock.Sqlmock) {
+	t.Helper()
+	conn, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, mock.ExpectationsWereMet())
+		_ = conn.Close()
+	})
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{Logger: gormLog.Default.LogMode(gormLog.Silent)})
+	require.NoError(t, err)
+
+	jwtService, _, err := jwt.NewService(&configs.Config{JWTSecret: "secret", TokenExpiresIn: time.Minute})
+	require.NoError(t, err)
+	svc := NewService(repository.NewOAuthClientRepository(db), repository.NewRefreshTokenRepository(db),
+		revocation.NewMemoryStore(func(uint) (bool, error) { return true, nil }), nil, nil, jwtService)
+	return svc.(*ServiceDefault), mock
+}
+
+// testClient 建立測試使用的 client，secret 為明文的 client secret
+func testClient(clientID, secret string, scopes ...string) *models.OAuthClient {
+	return &models.OAuthClient{ID: 1, ClientID: clientID, SecretHash: jwt.HashOpaqueToken(secret), Name: "billing", Scopes: scopes}
+}
+
+// expectClient 預期以 client ID 查詢 client，client 為 nil 時代表找不到
+func expectClient(mock sqlmock.Sqlmock, clientID string, client *models.OAuthClient) {
+	rows := sqlmock.NewRows([]string{"id", "client_id", "secret_hash", "name", "scopes", "disabled_at"})
+	if client != nil {
+		scopes, _ := json.Marshal(client.Scopes)
+		rows.AddRow(client.ID, client.ClientID, client.SecretHash, client.Name, string(scopes), client.DisabledAt)
+	}
+	mock.ExpectQuery(`SELECT \* FROM "oauth_clients" WHERE client_id = \$1`).WithArgs(clientID, 1).WillReturnRows(rows)
+}
+
+// 測試以 client secret 驗證 client，secret 錯誤、client 不存在或已停用時回傳 ErrInvalidClient
+func TestAuthenticateClient(t *testing.T) {
+	svc, mock := newTestService(t)
+	client := testClient("gtc_0123456789ab", "s3cret", models.PermissionUsersRead)
+
+	expectClient(mock, client.ClientID, client)
+	authenticated, err := svc.AuthenticateClient(client.ClientID, "s3cret")
+	require.NoError(t, err)
+	assert.Equal(t, client.ClientID, authenticated.ClientID)
+	assert.Equal(t, []string{models.PermissionUsersRead}, authenticated.Scopes)
+
+	expectClient(mock, client.ClientID, client)
+	_, err = svc.AuthenticateClient(client.ClientID, "wrong-secret")
+	assert.ErrorIs(t, err, ErrInvalidClient)
+
+	expectClient(mock, "gtc_unknown", nil)
+	_, err = svc.AuthenticateClient("gtc_unknown", "s3cret")
+	assert.ErrorIs(t, err, ErrInvalidClient)
+
+	disabledAt := time.Now()
+	client.DisabledAt = &disabledAt
+	expectClient(mock, client.ClientID, client)
+	_, err = svc.AuthenticateClient(client.ClientID, "s3cret")
+	assert.ErrorIs(t, err, ErrInvalidClient)
+}
+
+// 測試 client credentials grant 可以縮小權限，但不能要求 client 沒有的權限
+func TestClientCredentialsScopes(t *testing.T) {
+	svc, _ := newTestService(t)
+	client := testClient("gtc_0123456789ab", "s3cret", models.PermissionUsersRead, models.PermissionUsersUpdate)
+
+	tests := []struct {
+		name   string
+		scope  string
+		scopes []string
+	}{
+		{"omitted scope grants every scope of the client", "", []string{models.PermissionUsersRead, models.PermissionUsersUpdate}},
+		{"narrowed scope", models.PermissionUsersRead, []string{models.PermissionUsersRead}},
+		{"duplicated scopes are removed", "users:update  users:read users:update", []string{models.PermissionUsersRead, models.PermissionUsersUpdate}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := svc.ClientCredentials(client, tt.scope)
+			require.NoError(t, err)
+			assert.Equal(t, "Bearer", token.TokenType)
+			assert.Equal(t, int64(60), token.ExpiresIn)
+
+			claims, err := svc.jwtService.ValidateToken(token.AccessToken)
+			require.NoError(t, err)
+			assert.True(t, claims.IsClient())
+			assert.Equal(t, client.ClientID, claims.ClientID)
+			assert.Equal(t, tt.scopes, claims.Scopes)
+		})
+	}
+
+	_, err := svc.ClientCredentials(client, "users:read users:delete")
+	assert.ErrorIs(t, err, ErrInvalidScope)
+}
+
+// 測試 introspection 回傳 client token 的權限，client 停用後 token 不再有效
+func TestIntrospectClientToken(t *testing.T) {
+	svc, mock := newTestService(t)
+	client := testClient("gtc_0123456789ab", "s3cret", models.PermissionUsersRead)
+	token, err := svc.ClientCredentials(client, "")
+	require.NoError(t, err)
+
+	expectClient(mock, client.ClientID, client)
+	result, err := svc.Introspect(token.AccessToken, "")
+	require.NoError(t, err)
+	assert.True(t, result.Active)
+	assert.Equal(t, client.ClientID, result.ClientID)
+	assert.Equal(t, client.ClientID, result.Sub)
+	assert.Equal(t, models.PermissionUsersRead, result.Scope)
+
+	disabledAt := time.Now()
+	client.DisabledAt = &disabledAt
+	expectClient(mock, client.ClientID, client)
+	result, err = svc.Introspect(token.AccessToken, TokenTypeHintAccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, &Introspection{Active: false}, result)
+}
+
+// 測試 client 的 token 只能由同一個 client 撤銷
+func TestRevokeClientToken(t *testing.T) {
+	svc, _ := newTestService(t)
+	owner := testClient("gtc_0123456789ab", "s3cret", models.PermissionUsersRead)
+	other := testClient("gtc_ba9876543210", "other", models.PermissionUsersRead)
+	token, err := svc.ClientCredentials(owner, "")
+	require.NoError(t, err)
+	claims, err := svc.jwtService.ValidateToken(token.AccessToken)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, svc.Revoke(other, token.AccessToken, ""), ErrUnauthorizedClient)
+	assert.ErrorIs(t, svc.Revoke(nil, token.AccessToken, TokenTypeHintAccessToken), ErrUnauthorizedClient)
+	revoked, err := svc.revocationStore.IsRevoked(claims.TokenID)
+	require.NoError(t, err)
+	assert.False(t, revoked, "another client cannot revoke the token")
+
+	require.NoError(t, svc.Revoke(owner, token.AccessToken, ""))
+	revoked, err = svc.revocationStore.IsRevoked(claims.TokenID)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+}
@@ -0,0 +1,13 @@
+package rbac
+
+// Service 介面，定義角色與權限相關的方法
+type Service interface {
+	// PermissionsForRoles 取得多個角色擁有的權限聯集
+	PermissionsForRoles(roles []string) (map[string]bool, error)
+	// RolesForUser 取得使用者擁有的角色名稱
+	RolesForUser(userID uint) ([]string, error)
+	// AssignRoles 將角色指派給使用者，任何一個角色不存在時回傳 repository.ErrUnknownRole
+	AssignRoles(userID uint, roles ...string) error
+	// ReplaceRoles 以指定的角色取代使用者目前所有的角色
+	ReplaceRoles(userID uint, roles ...string) error
+}
@@ -0,0 +1,97 @@
+package rbac
+
+import (
+	"sync"
+	"time"
+
+	"go-template/internal/repository"
+	"go-template/internal/utils/logger"
+)
+
+// cacheTTL 角色與權限對應關係的快取時間，避免每個請求都查詢資料庫
+const cacheTTL = time.Minute
+
+// ServiceDefault Struct，實作 rbac.Service 介面
+type ServiceDefault struct {
+	roleRepo *repository.RoleRepository
+
+	mu       sync.RWMutex
+	cache    map[string][]string // 角色名稱 -> 權限名稱
+	loadedAt time.Time
+}
+
+// NewService 建立一個新的 rbac 實例
+func NewService(roleRepo *repository.RoleRepository) Service {
+	return &ServiceDefault{roleRepo: roleRepo}
+}
+
+// PermissionsForRoles 取得多個角色擁有的權限聯集
+// @param roles body []string true "角色名稱"
+// @return permissions 權限集合
+// @return error 錯誤訊息
+func (svc *ServiceDefault) PermissionsForRoles(roles []string) (map[string]bool, error) {
+	rolePermissions, err := svc.rolePermissions()
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := make(map[string]bool)
+	for _, role := range roles {
+		for _, permission := range rolePermissions[role] {
+			permissions[permission] = true
+		}
+	}
+	return permissions, nil
+}
+
+// RolesForUser 取得使用者擁有的角色名稱
+// @param userID path uint true "使用者 ID"
+// @return roles 角色名稱
+// @return error 錯誤訊息
+func (svc *ServiceDefault) RolesForUser(userID uint) ([]string, error) {
+	return svc.roleRepo.GetRoleNamesByUserID(userID)
+}
+
+// AssignRoles 將角色指派給使用者
+// @param userID path uint true "使用者 ID"
+// @param roles body []string true "角色名稱"
+// @return error 錯誤訊息
+func (svc *ServiceDefault) AssignRoles(userID uint, roles ...string) error {
+	return svc.roleRepo.AssignRoles(userID, roles...)
+}
+
+// ReplaceRoles 以指定的角色取代使用者目前所有的角色
+// @param userID path uint true "使用者 ID"
+// @param roles body []string true "角色名稱"
+// @return error 錯誤訊息
+func (svc *ServiceDefault) ReplaceRoles(userID uint, roles ...string) error {
+	return svc.roleRepo.ReplaceRoles(userID, roles...)
+}
+
+// rolePermissions 取得角色與權限的對應關係，快取過期時才重新查詢資料庫
+func (svc *ServiceDefault) rolePermissions() (map[string][]string, error) {
+	svc.mu.RLock()
+	if svc.cache != nil && time.Since(svc.loadedAt) < cacheTTL {
+		defer svc.mu.RUnlock()
+		return svc.cache, nil
+	}
+	svc.mu.RUnlock()
+
+	roles, err := svc.roleRepo.ListWithPermissions()
+	if err != nil {
+		logger.Logger.Errorf("Error loading role permissions: %v", err) // 記錄錯誤
+		return nil, err
+	}
+
+	cache := make(map[string][]string, len(roles))
+	for _, role := range roles {
+		for _, permission := range role.Permissions {
+			cache[role.Name] = append(cache[role.Name], permission.Name)
+		}
+	}
+
+	svc.mu.Lock()
+	svc.cache, svc.loadedAt = cache, time.Now()
+	svc.mu.Unlock()
+	return cache, nil
+}
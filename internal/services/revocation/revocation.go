@@ -0,0 +1,83 @@
+package revocation
+
+import (
+	"errors"
+	"time"
+
+	"go-template/internal/configs"
+	"go-template/internal/models"
+	"go-template/internal/utils/logger"
+	"gorm.io/gorm"
+)
+
+// 支援的撤銷清單儲存方式
+const (
+	StoreMemory   = "memory"   // 儲存在記憶體，只適用於單一實例
+	StoreDatabase = "database" // 儲存在資料庫，多個實例共用
+)
+
+// 可能的錯誤代碼
+var (
+	ErrSubjectNotFound = errors.New("token subject not found")
+)
+
+// Store 介面，定義 token 撤銷清單的方法
+type Store interface {
+	// Revoke 撤銷單一 token，expiresAt 為 token 原本的過期時間，超過後即可從清單中移除
+	Revoke(userID uint, tokenID string, expiresAt time.Time) error
+	// IsRevoked 檢查 token 是否已被撤銷
+	IsRevoked(tokenID string) (bool, error)
+	// RevokeAllForUser 撤銷使用者在 at 之前發行的所有 token
+	RevokeAllForUser(userID uint, at time.Time) error
+	// TokensValidAfter 取得使用者 token 的最早有效發行時間，零值代表沒有限制
+	// 使用者不存在 (或已被刪除) 時回傳 ErrSubjectNotFound
+	TokensValidAfter(userID uint) (time.Time, error)
+}
+
+// SubjectExists 檢查使用者是否存在，供記憶體版本的 Store 判斷 token 的主體是否已被刪除
+type SubjectExists func(userID uint) (bool, error)
+
+// NewStore 根據設定建立撤銷清單的儲存實例
+func NewStore(cfg *configs.Config, db *gorm.DB) Store {
+	switch cfg.TokenRevocationStore {
+	case StoreMemory:
+		logger.Logger.Info("Using in-memory token revocation store")
+		return NewMemoryStore(UserExists(db))
+	default:
+		return NewDatabaseStore(db)
+	}
+}
+
+// SessionKey 撤銷整個登入工作階段時，存入撤銷清單的 key
+// 與 jti 放在同一個清單中，帶有這個 sid 的 access token 在過期之前都會被拒絕
+func SessionKey(sessionID string) string {
+	return "sid:" + sessionID
+}
+
+// UserExists 回傳以資料庫的 users 檢查使用者是否存在的 SubjectExists
+func UserExists(db *gorm.DB) SubjectExists {
+	return func(userID uint) (bool, error) {
+		var count int64
+		if err := db.Model(&models.User{}).Where("id = ?", userID).Count(&count).Error; err != nil {
+			logger.Logger.Errorf("Error checking user existence in database: %v", err) // 記錄資料庫錯誤
+			return false, err
+		}
+		return count > 0, nil
+	}
+}
+
+// IssuedBefore 判斷發行時間為 issuedAt 的 token 是否因為使用者的 "tokens valid after" 時間而失效
+// 與撤銷時間在同一毫秒內發行的 token 無法分辨先後，一律視為已撤銷
+func IssuedBefore(issuedAt, validAfter time.Time) bool {
+	return !validAfter.IsZero() && !issuedAt.After(validAfter)
+}
+
+// ValidFrom 撤銷時間為 at 時，之後發行的 token 不會被 IssuedBefore 拒絕的最早發行時間 (撤銷時間的下一毫秒)
+func ValidFrom(at time.Time) time.Time {
+	return cutoff(at).Add(time.Millisecond)
+}
+
+// cutoff 將時間無條件捨去到毫秒，與 JWT 的 iat 精確度相同
+func cutoff(at time.Time) time.Time {
+	return at.Truncate(time.Millisecond)
+}
@@ -0,0 +1,80 @@
+package revocation
+
+import (
+	"errors"
+	"time"
+
+	"go-template/internal/models"
+	"go-template/internal/utils/logger"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DatabaseStore Struct，將撤銷清單儲存在資料庫中，實作 Store 介面
+// 單一 token 的撤銷紀錄存放在 revoked_tokens，使用者層級的撤銷時間存放在 users.tokens_valid_after
+type DatabaseStore struct {
+	db *gorm.DB
+}
+
+// NewDatabaseStore 建立一個新的 DatabaseStore 實例
+func NewDatabaseStore(db *gorm.DB) *DatabaseStore {
+	return &DatabaseStore{db: db}
+}
+
+// Revoke 撤銷單一 token，同時清除已經過期的紀錄
+func (s *DatabaseStore) Revoke(userID uint, tokenID string, expiresAt time.Time) error {
+	err := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.RevokedToken{
+		TokenID:   tokenID,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+	}).Error
+	if err != nil {
+		logger.Logger.Errorf("Error revoking token in database: %v", err) // 記錄資料庫錯誤
+		return err
+	}
+
+	// 已過期的 token 本來就無法通過驗證，不需要繼續保留
+	if err := s.db.Where("expires_at < ?", time.Now()).Delete(&models.RevokedToken{}).Error; err != nil {
+		logger.Logger.Warnf("Error deleting expired revoked tokens: %v", err) // 清除失敗不影響撤銷結果
+	}
+	return nil
+}
+
+// IsRevoked 檢查 token 是否已被撤銷
+func (s *DatabaseStore) IsRevoked(tokenID string) (bool, error) {
+	var count int64
+	err := s.db.Model(&models.RevokedToken{}).Where("token_id = ?", tokenID).Count(&count).Error
+	if err != nil {
+		logger.Logger.Errorf("Error checking revoked token in database: %v", err) // 記錄資料庫錯誤
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// RevokeAllForUser 撤銷使用者在 at 之前發行的所有 token
+func (s *DatabaseStore) RevokeAllForUser(userID uint, at time.Time) error {
+	err := s.db.Model(&models.User{}).Where("id = ?", userID).Update("tokens_valid_after", cutoff(at)).Error
+	if err != nil {
+		logger.Logger.Errorf("Error revoking tokens of user in database: %v", err) // 記錄資料庫錯誤
+		return err
+	}
+	return nil
+}
+
+// TokensValidAfter 取得使用者 token 的最早有效發行時間
+// 使用者不存在 (或已被刪除) 時回傳 ErrSubjectNotFound，讓已刪除使用者的 token 立即失效
+func (s *DatabaseStore) TokensValidAfter(userID uint) (time.Time, error) {
+	var user models.User
+	err := s.db.Select("id", "tokens_valid_after").First(&user, userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return time.Time{}, ErrSubjectNotFound
+	}
+	if err != nil {
+		logger.Logger.Errorf("Error getting tokens valid after from database: %v", err) // 記錄資料庫錯誤
+		return time.Time{}, err
+	}
+	if user.TokensValidAfter == nil {
+		return time.Time{}, nil
+	}
+	return *user.TokensValidAfter, nil
+}
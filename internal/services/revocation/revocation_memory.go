@@ -0,0 +1,74 @@
+package revocation
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryStore Struct，將撤銷清單儲存在記憶體中，實作 Store 介面
+// 使用者是否存在仍由 exists 檢查，與 DatabaseStore 一樣讓已刪除使用者的 token 立即失效
+type MemoryStore struct {
+	mu          sync.RWMutex
+	revoked     map[string]time.Time // jti -> token 原本的過期時間
+	validAfters map[uint]time.Time   // userID -> token 最早有效發行時間
+	exists      SubjectExists
+}
+
+// NewMemoryStore 建立一個新的 MemoryStore 實例
+func NewMemoryStore(exists SubjectExists) *MemoryStore {
+	return &MemoryStore{
+		revoked:     make(map[string]time.Time),
+		validAfters: make(map[uint]time.Time),
+		exists:      exists,
+	}
+}
+
+// Revoke 撤銷單一 token，同時清除已經過期的紀錄
+func (s *MemoryStore) Revoke(_ uint, tokenID string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for id, exp := range s.revoked {
+		if now.After(exp) {
+			delete(s.revoked, id)
+		}
+	}
+	s.revoked[tokenID] = expiresAt
+	return nil
+}
+
+// IsRevoked 檢查 token 是否已被撤銷
+func (s *MemoryStore) IsRevoked(tokenID string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.revoked[tokenID]
+	return ok, nil
+}
+
+// RevokeAllForUser 撤銷使用者在 at 之前發行的所有 token
+func (s *MemoryStore) RevokeAllForUser(userID uint, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.validAfters[userID] = cutoff(at)
+	return nil
+}
+
+// TokensValidAfter 取得使用者 token 的最早有效發行時間
+// 使用者不存在 (或已被刪除) 時回傳 ErrSubjectNotFound
+func (s *MemoryStore) TokensValidAfter(userID uint) (time.Time, error) {
+	exists, err := s.exists(userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !exists {
+		return time.Time{}, ErrSubjectNotFound
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.validAfters[userID], nil
+}
@@ -0,0 +1,114 @@
+package revocation
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-template/internal/utils/logger"
+)
+
+func TestMain(m *testing.M) {
+	_ = logger.Init(&logger.Config{Level: "error", ConsoleOut: true, ServiceName: "revocation-test"})
+	os.Exit(m.Run())
+}
+
+// existingUsers 回傳只認得 ids 中的使用者的 SubjectExists
+func existingUsers(ids ...uint) SubjectExists {
+	return func(userID uint) (bool, error) {
+		for _, id := range ids {
+			if id == userID {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+// 測試撤銷單一 token 與工作階段，已經過期的紀錄在下一次撤銷時清除
+func TestMemoryStoreRevoke(t *testing.T) {
+	store := NewMemoryStore(existingUsers(1))
+
+	require.NoError(t, store.Revoke(1, "expired", time.Now().Add(-time.Minute)))
+	require.NoError(t, store.Revoke(1, SessionKey("session-1"), time.Now().Add(time.Hour)))
+
+	revoked, err := store.IsRevoked(SessionKey("session-1"))
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, err = store.IsRevoked("expired")
+	require.NoError(t, err)
+	assert.False(t, revoked, "expired entries are removed on the next revoke")
+
+	revoked, err = store.IsRevoked("other")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+// 測試撤銷使用者所有 token 的時間保留到毫秒
+func TestMemoryStoreRevokeAllForUser(t *testing.T) {
+	store := NewMemoryStore(existingUsers(1, 2))
+
+	validAfter, err := store.TokensValidAfter(1)
+	require.NoError(t, err)
+	assert.True(t, validAfter.IsZero(), "no cutoff before the first revoke")
+
+	at := time.Date(2026, 1, 2, 3, 4, 5, 678_900_000, time.UTC)
+	require.NoError(t, store.RevokeAllForUser(1, at))
+
+	validAfter, err = store.TokensValidAfter(1)
+	require.NoError(t, err)
+	assert.Equal(t, at.Truncate(time.Millisecond), validAfter)
+
+	validAfter, err = store.TokensValidAfter(2)
+	require.NoError(t, err)
+	assert.True(t, validAfter.IsZero(), "other users are not affected")
+}
+
+// 測試使用者不存在時與 DatabaseStore 一樣回傳 ErrSubjectNotFound，檢查失敗時回傳原本的錯誤
+func TestMemoryStoreUnknownSubject(t *testing.T) {
+	store := NewMemoryStore(existingUsers(1))
+	require.NoError(t, store.RevokeAllForUser(2, time.Now()))
+
+	_, err := store.TokensValidAfter(2)
+	assert.ErrorIs(t, err, ErrSubjectNotFound)
+
+	dbErr := errors.New("connection refused")
+	store = NewMemoryStore(func(uint) (bool, error) { return false, dbErr })
+	_, err = store.TokensValidAfter(1)
+	assert.ErrorIs(t, err, dbErr)
+}
+
+// 測試與撤銷時間在同一秒 (以及同一毫秒) 內發行的 token 也會失效，之後發行的 token 不受影響
+func TestIssuedBefore(t *testing.T) {
+	revokedAt := cutoff(time.Date(2026, 1, 2, 3, 4, 5, 500_000_000, time.UTC))
+
+	tests := []struct {
+		name     string
+		issuedAt time.Time
+		revoked  bool
+	}{
+		{"earlier second", revokedAt.Add(-time.Second), true},
+		{"same second before cutoff", revokedAt.Add(-300 * time.Millisecond), true},
+		{"same millisecond", revokedAt, true},
+		{"same second after cutoff", revokedAt.Add(time.Millisecond), false},
+		{"later second", revokedAt.Add(time.Second), false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.revoked, IssuedBefore(tt.issuedAt, revokedAt), tt.name)
+	}
+	assert.False(t, IssuedBefore(revokedAt, time.Time{}), "zero cutoff means no limit")
+}
+
+// 測試 ValidFrom 是撤銷時間的下一毫秒，在這之後發行的 token 都不會被拒絕
+func TestValidFrom(t *testing.T) {
+	at := time.Date(2026, 1, 2, 3, 4, 5, 678_900_000, time.UTC)
+	validFrom := ValidFrom(at)
+
+	assert.Equal(t, time.Date(2026, 1, 2, 3, 4, 5, 679_000_000, time.UTC), validFrom)
+	assert.False(t, IssuedBefore(validFrom, cutoff(at)))
+	assert.True(t, IssuedBefore(validFrom.Add(-time.Millisecond), cutoff(at)))
+}
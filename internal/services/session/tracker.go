@@ -0,0 +1,96 @@
+package session
+
+import (
+	"sync"
+	"time"
+
+	"go-template/internal/configs"
+	"go-template/internal/repository"
+	"go-template/internal/utils/logger"
+)
+
+// Tracker 記錄登入工作階段的最後使用時間
+// 每個請求只更新記憶體中的時間，再定期批次寫入資料庫，避免 Auth 中介軟體在每個請求都寫入資料庫
+type Tracker struct {
+	repo *repository.SessionRepository
+
+	mu   sync.Mutex
+	seen map[string]time.Time // 尚未寫入資料庫的工作階段 ID 與最後使用時間
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewTracker 建立 Tracker 並開始定期寫入資料庫，回傳的 cleanup 會停止排程並寫入剩下的資料
+func NewTracker(cfg *configs.Config, repo *repository.SessionRepository) (*Tracker, func()) {
+	t := &Tracker{
+		repo: repo,
+		seen: make(map[string]time.Time),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	interval := cfg.SessionLastSeenFlushInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	go t.run(interval)
+
+	return t, func() {
+		close(t.stop)
+		<-t.done
+	}
+}
+
+// Touch 記錄工作階段在此時被使用
+func (t *Tracker) Touch(sessionID string) {
+	if sessionID == "" {
+		return
+	}
+	t.mu.Lock()
+	t.seen[sessionID] = time.Now()
+	t.mu.Unlock()
+}
+
+// Flush 將記憶體中的最後使用時間寫入資料庫，寫入失敗時保留資料等待下一次寫入
+func (t *Tracker) Flush() error {
+	t.mu.Lock()
+	if len(t.seen) == 0 {
+		t.mu.Unlock()
+		return nil
+	}
+	batch := t.seen
+	t.seen = make(map[string]time.Time)
+	t.mu.Unlock()
+
+	if err := t.repo.TouchLastSeen(batch); err != nil {
+		t.mu.Lock()
+		for id, seenAt := range batch {
+			// 寫入失敗期間有較新的時間時保留較新的
+			if current, ok := t.seen[id]; !ok || current.Before(seenAt) {
+				t.seen[id] = seenAt
+			}
+		}
+		t.mu.Unlock()
+		return err
+	}
+	logger.Logger.Debugf("Session last seen time flushed for %d sessions", len(batch)) // 記錄已寫入資料庫
+	return nil
+}
+
+// run 定期寫入資料庫，收到停止訊號時寫入剩下的資料後結束
+func (t *Tracker) run(interval time.Duration) {
+	defer close(t.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			_ = t.Flush() // 錯誤已在 repository 記錄
+		case <-t.stop:
+			_ = t.Flush()
+			return
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package session
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+	"go-template/internal/configs"
+	"go-template/internal/repository"
+	"go-template/internal/utils/logger"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormLog "gorm.io/gorm/logger"
+)
+
+func TestMain(m *testing.M) {
+	_ = logger.Init(&logger.Config{Level: "error", ConsoleOut: true, ServiceName: "session-test"})
+	os.Exit(m.Run())
+}
+
+// touchLastSeen 更新最後使用時間的語句，只更新比較舊的時間
+const touchLastSeen = `UPDATE "sessions" SET "last_seen_at"=\$1 WHERE id = \$2 AND last_seen_at < \$3`
+
+// newTestTracker 建立不會自動寫入資料庫的 Tracker，由測試呼叫 Flush 或 stop
+func newTestTracker(t *testing.T) (*Tracker, func(), sqlmock.Sqlmock) {
+	conn, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, mock.ExpectationsWereMet())
+		_ = conn.Close()
+	})
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{Logger: gormLog.Default.LogMode(gormLog.Silent)})
+	require.NoError(t, err)
+
+	tracker, stop := NewTracker(&configs.Config{SessionLastSeenFlushInterval: time.Hour}, repository.NewSessionRepository(db))
+	return tracker, stop, mock
+}
+
+// expectTouch 預期在同一個交易中更新工作階段的最後使用時間
+func expectTouch(mock sqlmock.Sqlmock, sessionID string) {
+	mock.ExpectBegin()
+	mock.ExpectExec(touchLastSeen).WithArgs(sqlmock.AnyArg(), sessionID, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+// 測試 Touch 只記錄在記憶體，Flush 時才在同一個交易中寫入資料庫
+func TestTrackerTouchAndFlush(t *testing.T) {
+	tracker, stop, mock := newTestTracker(t)
+	defer stop()
+
+	tracker.Touch("session-1")
+	tracker.Touch("session-1")
+	tracker.Touch("") // 沒有工作階段的 token (例如舊的 token) 不記錄
+
+	expectTouch(mock, "session-1")
+	require.NoError(t, tracker.Flush())
+
+	// 沒有新的使用紀錄時不寫入資料庫
+	require.NoError(t, tracker.Flush())
+}
+
+// 測試寫入失敗時保留使用紀錄，下一次 Flush 再寫入
+func TestTrackerFlushFailureKeepsSessions(t *testing.T) {
+	tracker, stop, mock := newTestTracker(t)
+	defer stop()
+
+	tracker.Touch("session-1")
+	mock.ExpectBegin()
+	mock.ExpectExec(touchLastSeen).WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+	require.Error(t, tracker.Flush())
+
+	expectTouch(mock, "session-1")
+	require.NoError(t, tracker.Flush())
+}
+
+// 測試停止 Tracker 時寫入剩下的使用紀錄
+func TestTrackerStopFlushes(t *testing.T) {
+	tracker, stop, mock := newTestTracker(t)
+
+	tracker.Touch("session-1")
+	expectTouch(mock, "session-1")
+	stop()
+}
@@ -0,0 +1,300 @@
+package throttle
+
+import (
+	"strings"
+	"time"
+
+	"go-template/internal/apperror"
+	"go-template/internal/configs"
+	"go-template/internal/models"
+	"go-template/internal/utils/logger"
+	"gorm.io/gorm"
+)
+
+// 支援的計數儲存方式
+const (
+	StoreMemory   = "memory"   // 儲存在記憶體，只適用於單一實例
+	StoreDatabase = "database" // 儲存在資料庫，多個實例共用
+)
+
+// 計數對象的前綴
+const (
+	KeyPrefixUsername = "user:" // 以使用者名稱計數
+	KeyPrefixIP       = "ip:"   // 以來源 IP 計數
+)
+
+// maxBackoffShift 計算退避時間時位移的上限，避免溢位
+const maxBackoffShift = 30
+
+// ErrThrottled 失敗次數過多，需要等待一段時間才能再嘗試
+var ErrThrottled = apperror.New(apperror.KindTooManyRequests, "too_many_login_attempts",
+	"too many failed login attempts, please try again later")
+
+// ThrottledError 失敗次數過多時回傳的錯誤，帶有可以再次嘗試前需要等待的時間
+type ThrottledError struct {
+	RetryAfter time.Duration // 需要等待的時間
+	Locked     bool          // 是否因為達到上限而被暫時鎖定 (否則只是退避中)
+}
+
+// Error 實作 error 介面
+func (e *ThrottledError) Error() string {
+	return ErrThrottled.Error()
+}
+
+// Unwrap 讓 errors.Is(err, ErrThrottled) 成立
+func (e *ThrottledError) Unwrap() error {
+	return ErrThrottled
+}
+
+// Record 計數對象目前的狀態
+type Record struct {
+	Key           string     `json:"key"`                    // 計數的對象
+	Failures      int        `json:"failures"`               // 目前計數區間內連續失敗的次數，包含還在驗證中的嘗試
+	LastFailureAt time.Time  `json:"last_failure_at"`        // 最後一次失敗 (或開始嘗試) 的時間
+	LockedUntil   *time.Time `json:"locked_until,omitempty"` // 暫時鎖定的到期時間
+}
+
+// Store 介面，定義登入失敗計數的儲存方法
+type Store interface {
+	// Get 取得計數對象目前的狀態，沒有紀錄時回傳 nil
+	Get(key string) (*Record, error)
+	// Update 讀取計數對象目前的狀態交給 update，並儲存 update 回傳的新狀態；回傳 nil 時不變更
+	// 同一個計數對象的 Update 會依序執行，多個實例同時更新時也不會互相覆蓋
+	// 沒有紀錄時 update 收到 Failures 為 0 的狀態；最後一次失敗早於 windowStart 且沒有鎖定的紀錄會順便清除
+	Update(key string, windowStart time.Time, update func(current Record) *Record) error
+	// Reset 清除計數對象的紀錄
+	Reset(key string) error
+	// ListLocked 列出在 now 時仍被鎖定的計數對象
+	ListLocked(now time.Time) ([]Record, error)
+}
+
+// NewStore 根據設定建立登入失敗計數的儲存實例
+func NewStore(cfg *configs.Config, db *gorm.DB) Store {
+	switch cfg.LoginThrottleStore {
+	case StoreMemory:
+		logger.Logger.Info("Using in-memory login throttle store")
+		return NewMemoryStore()
+	default:
+		return NewDatabaseStore(db)
+	}
+}
+
+// Limiter Struct，依照設定的規則限制登入失敗的次數
+// 同時以使用者名稱與來源 IP 計數：每次失敗後需要等待指數成長的退避時間才能再嘗試，
+// 連續失敗達到上限後暫時鎖定一段時間
+type Limiter struct {
+	store           Store
+	maxAttempts     int           // 同一個使用者名稱連續失敗幾次後鎖定
+	ipMaxAttempts   int           // 同一個 IP 連續失敗幾次後鎖定
+	window          time.Duration // 計數區間，最後一次失敗超過這段時間後重新計數
+	backoffBase     time.Duration // 第一次失敗後的退避時間，之後每次失敗加倍
+	backoffMax      time.Duration // 退避時間的上限
+	lockoutDuration time.Duration // 鎖定的時間
+	now             func() time.Time
+}
+
+// NewLimiter 建立一個新的 Limiter 實例
+func NewLimiter(cfg *configs.Config, store Store) *Limiter {
+	return &Limiter{
+		store:           store,
+		maxAttempts:     cfg.LoginMaxAttempts,
+		ipMaxAttempts:   cfg.LoginIPMaxAttempts,
+		window:          cfg.LoginAttemptWindow,
+		backoffBase:     cfg.LoginBackoffBase,
+		backoffMax:      cfg.LoginBackoffMax,
+		lockoutDuration: cfg.LoginLockoutDuration,
+		now:             time.Now,
+	}
+}
+
+// UsernameKey 取得使用者名稱的計數對象，與登入時一樣以 models.UsernameKey 比較，登入同一個帳號的不同寫法共用計數
+func UsernameKey(username string) string {
+	return KeyPrefixUsername + models.UsernameKey(username)
+}
+
+// IPKey 取得來源 IP 的計數對象
+func IPKey(ip string) string {
+	return KeyPrefixIP + ip
+}
+
+// Check 檢查使用者名稱與來源 IP 目前是否可以嘗試登入，不會記錄這次嘗試
+// 被鎖定或仍在退避時間內時回傳 *ThrottledError；驗證密碼的登入必須改用 Attempt
+func (l *Limiter) Check(username, ip string) error {
+	now := l.now()
+	var wait time.Duration
+	locked := false
+	for _, key := range l.keys(username, ip) {
+		record, err := l.store.Get(key)
+		if err != nil {
+			return err
+		}
+		if record == nil {
+			continue
+		}
+		if record.LockedUntil != nil && record.LockedUntil.After(now) {
+			locked = true
+			wait = maxDuration(wait, record.LockedUntil.Sub(now))
+			continue
+		}
+		if record.LastFailureAt.Before(now.Add(-l.window)) {
+			continue
+		}
+		wait = maxDuration(wait, record.LastFailureAt.Add(l.backoff(record.Failures)).Sub(now))
+	}
+	if wait > 0 {
+		return &ThrottledError{RetryAfter: wait, Locked: locked}
+	}
+	return nil
+}
+
+// Attempt 在驗證密碼之前呼叫，檢查使用者名稱與來源 IP 目前是否可以嘗試登入，可以時先把這次嘗試記為一次失敗
+// 被鎖定或仍在退避時間內時回傳 *ThrottledError，不會記錄這次嘗試；達到上限時暫時鎖定並記錄鎖定事件
+// 先記錄再驗證，同時送出的請求才不會在任何一次失敗被記錄之前全部通過檢查；驗證成功後必須呼叫 RecordSuccess
+func (l *Limiter) Attempt(username, ip string) error {
+	now := l.now()
+	reserved := make([]string, 0, 2)
+	for _, key := range l.keys(username, ip) {
+		wait, locked, err := l.reserve(key, now)
+		if err == nil && wait <= 0 {
+			reserved = append(reserved, key)
+			continue
+		}
+		// 這次嘗試沒有進行，歸還已經記錄在其他計數對象上的嘗試
+		for _, k := range reserved {
+			if releaseErr := l.release(k); releaseErr != nil {
+				logger.Logger.Errorf("Error releasing login attempt for %s: %v", k, releaseErr) // 記錄錯誤
+			}
+		}
+		if err != nil {
+			return err
+		}
+		return &ThrottledError{RetryAfter: wait, Locked: locked}
+	}
+	return nil
+}
+
+// RecordSuccess 登入成功後清除使用者名稱的失敗紀錄，並歸還 Attempt 在來源 IP 上記錄的這次嘗試
+// 來源 IP 之前的失敗紀錄不會清除，避免攻擊者穿插登入自己的帳號來重置計數；沒有經過 Attempt 的登入傳入空的 ip
+func (l *Limiter) RecordSuccess(username, ip string) error {
+	if err := l.store.Reset(UsernameKey(username)); err != nil {
+		return err
+	}
+	if ip == "" {
+		return nil
+	}
+	return l.release(IPKey(ip))
+}
+
+// Release 歸還 Attempt 記錄的這次嘗試，使用者名稱與來源 IP 之前的失敗紀錄都保持不變
+// 用於密碼正確但登入還沒完成 (例如還需要兩步驟驗證) 的情況，完成登入之後才呼叫 RecordSuccess
+func (l *Limiter) Release(username, ip string) error {
+	for _, key := range l.keys(username, ip) {
+		if err := l.release(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Locked 列出目前被鎖定的使用者名稱與來源 IP
+func (l *Limiter) Locked() ([]Record, error) {
+	return l.store.ListLocked(l.now())
+}
+
+// Unlock 解除使用者名稱的鎖定並清除失敗紀錄
+func (l *Limiter) Unlock(username string) error {
+	key := UsernameKey(username)
+	if err := l.store.Reset(key); err != nil {
+		return err
+	}
+	logger.Logger.Infof("Login lockout cleared for %s", key) // 記錄解除鎖定
+	return nil
+}
+
+// reserve 在鎖定與退避時間都已經過去時把一次嘗試記為失敗，否則回傳還需要等待的時間
+func (l *Limiter) reserve(key string, now time.Time) (wait time.Duration, locked bool, err error) {
+	windowStart := now.Add(-l.window)
+	var lockedUntil *time.Time
+	failures := 0
+	err = l.store.Update(key, windowStart, func(current Record) *Record {
+		if current.LockedUntil != nil && current.LockedUntil.After(now) {
+			wait, locked = current.LockedUntil.Sub(now), true
+			return nil
+		}
+		if current.LastFailureAt.Before(windowStart) {
+			current.Failures = 0
+		}
+		if delay := current.LastFailureAt.Add(l.backoff(current.Failures)).Sub(now); current.Failures > 0 && delay > 0 {
+			wait = delay
+			return nil
+		}
+
+		next := Record{Key: key, Failures: current.Failures + 1, LastFailureAt: now}
+		if limit := l.limit(key); limit > 0 && next.Failures >= limit {
+			until := now.Add(l.lockoutDuration)
+			next.LockedUntil = &until
+		}
+		lockedUntil, failures = next.LockedUntil, next.Failures
+		return &next
+	})
+	if err == nil && lockedUntil != nil {
+		logger.Logger.Warnf("Login locked out for %s after %d failed attempts until %s",
+			key, failures, lockedUntil.Format(time.RFC3339)) // 記錄鎖定事件
+	}
+	return wait, locked, err
+}
+
+// release 歸還 Attempt 記錄的一次嘗試，之前的失敗紀錄保持不變；歸還後低於上限時一併解除鎖定
+func (l *Limiter) release(key string) error {
+	return l.store.Update(key, l.now().Add(-l.window), func(current Record) *Record {
+		if current.Failures == 0 {
+			return nil
+		}
+		current.Failures--
+		if limit := l.limit(key); limit <= 0 || current.Failures < limit {
+			current.LockedUntil = nil
+		}
+		return &current
+	})
+}
+
+// limit 取得計數對象的失敗上限，0 代表不鎖定
+func (l *Limiter) limit(key string) int {
+	if strings.HasPrefix(key, KeyPrefixIP) {
+		return l.ipMaxAttempts
+	}
+	return l.maxAttempts
+}
+
+// keys 取得這次登入需要檢查的計數對象，沒有來源 IP 時只檢查使用者名稱
+func (l *Limiter) keys(username, ip string) []string {
+	keys := []string{UsernameKey(username)}
+	if ip != "" {
+		keys = append(keys, IPKey(ip))
+	}
+	return keys
+}
+
+// backoff 計算連續失敗 failures 次之後的退避時間: base * 2^(failures-1)，不超過上限
+func (l *Limiter) backoff(failures int) time.Duration {
+	if failures <= 0 || l.backoffBase <= 0 {
+		return 0
+	}
+	shift := failures - 1
+	if shift > maxBackoffShift {
+		shift = maxBackoffShift
+	}
+	delay := l.backoffBase << shift
+	if l.backoffMax > 0 && (delay > l.backoffMax || delay <= 0) {
+		delay = l.backoffMax
+	}
+	return delay
+}
+
+// maxDuration 回傳兩個時間長度中較大的一個
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,109 @@
+package throttle
+
+import (
+	"errors"
+	"time"
+
+	"go-template/internal/models"
+	"go-template/internal/utils/logger"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DatabaseStore Struct，將登入失敗計數儲存在資料庫的 login_attempts 中，實作 Store 介面
+type DatabaseStore struct {
+	db *gorm.DB
+}
+
+// NewDatabaseStore 建立一個新的 DatabaseStore 實例
+func NewDatabaseStore(db *gorm.DB) *DatabaseStore {
+	return &DatabaseStore{db: db}
+}
+
+// Get 取得計數對象目前的狀態
+func (s *DatabaseStore) Get(key string) (*Record, error) {
+	var attempt models.LoginAttempt
+	err := s.db.Where("key = ?", key).First(&attempt).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		logger.Logger.Errorf("Error getting login attempts from database: %v", err) // 記錄資料庫錯誤
+		return nil, err
+	}
+	return toRecord(&attempt), nil
+}
+
+// Update 在交易中以 SELECT ... FOR UPDATE 鎖定計數對象的資料列後更新狀態
+// 多個實例同時更新同一個計數對象時會依序執行，檢查與記錄之間不會有其他請求插入
+func (s *DatabaseStore) Update(key string, windowStart time.Time, update func(current Record) *Record) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// 先確保資料列存在才能鎖定，同時建立的資料列由 ON CONFLICT 忽略
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.LoginAttempt{Key: key}).Error; err != nil {
+			return err
+		}
+		var attempt models.LoginAttempt
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("key = ?", key).First(&attempt).Error; err != nil {
+			return err
+		}
+
+		next := update(*toRecord(&attempt))
+		if next == nil {
+			return nil
+		}
+		return tx.Model(&models.LoginAttempt{}).Where("key = ?", key).Updates(map[string]interface{}{
+			"failures":        next.Failures,
+			"last_failure_at": next.LastFailureAt,
+			"locked_until":    next.LockedUntil,
+			"updated_at":      time.Now(),
+		}).Error
+	})
+	if err != nil {
+		logger.Logger.Errorf("Error updating login attempts in database: %v", err) // 記錄資料庫錯誤
+		return err
+	}
+
+	// 順便清除已經過期的紀錄
+	if err := s.db.Where("last_failure_at < ? AND (locked_until IS NULL OR locked_until < ?)", windowStart, time.Now()).
+		Delete(&models.LoginAttempt{}).Error; err != nil {
+		logger.Logger.Warnf("Error deleting expired login attempts: %v", err) // 清除失敗不影響計數結果
+	}
+	return nil
+}
+
+// Reset 清除計數對象的紀錄
+func (s *DatabaseStore) Reset(key string) error {
+	err := s.db.Where("key = ?", key).Delete(&models.LoginAttempt{}).Error
+	if err != nil {
+		logger.Logger.Errorf("Error resetting login attempts in database: %v", err) // 記錄資料庫錯誤
+		return err
+	}
+	return nil
+}
+
+// ListLocked 列出在 now 時仍被鎖定的計數對象，依照鎖定到期時間排序
+func (s *DatabaseStore) ListLocked(now time.Time) ([]Record, error) {
+	var attempts []models.LoginAttempt
+	err := s.db.Where("locked_until > ?", now).Order("locked_until").Find(&attempts).Error
+	if err != nil {
+		logger.Logger.Errorf("Error listing locked login attempts from database: %v", err) // 記錄資料庫錯誤
+		return nil, err
+	}
+	records := make([]Record, 0, len(attempts))
+	for i := range attempts {
+		records = append(records, *toRecord(&attempts[i]))
+	}
+	return records, nil
+}
+
+// toRecord 將資料庫的紀錄轉換成 Record
+func toRecord(attempt *models.LoginAttempt) *Record {
+	return &Record{
+		Key:           attempt.Key,
+		Failures:      attempt.Failures,
+		LastFailureAt: attempt.LastFailureAt,
+		LockedUntil:   attempt.LockedUntil,
+	}
+}
@@ -0,0 +1,81 @@
+package throttle
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore Struct，將登入失敗計數儲存在記憶體中，實作 Store 介面
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]*Record
+}
+
+// NewMemoryStore 建立一個新的 MemoryStore 實例
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]*Record)}
+}
+
+// Get 取得計數對象目前的狀態
+func (s *MemoryStore) Get(key string) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[key]
+	if !ok {
+		return nil, nil
+	}
+	copied := *record
+	return &copied, nil
+}
+
+// Update 在互斥鎖中讀取並更新計數對象的狀態，同時清除已經過期的紀錄
+func (s *MemoryStore) Update(key string, windowStart time.Time, update func(current Record) *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, record := range s.records {
+		if record.LastFailureAt.Before(windowStart) && (record.LockedUntil == nil || record.LockedUntil.Before(now)) {
+			delete(s.records, k)
+		}
+	}
+
+	current := Record{Key: key}
+	if record, ok := s.records[key]; ok {
+		current = *record
+	}
+	if next := update(current); next != nil {
+		copied := *next
+		copied.Key = key
+		s.records[key] = &copied
+	}
+	return nil
+}
+
+// Reset 清除計數對象的紀錄
+func (s *MemoryStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+	return nil
+}
+
+// ListLocked 列出在 now 時仍被鎖定的計數對象，依照鎖定到期時間排序
+func (s *MemoryStore) ListLocked(now time.Time) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	locked := make([]Record, 0)
+	for _, record := range s.records {
+		if record.LockedUntil != nil && record.LockedUntil.After(now) {
+			locked = append(locked, *record)
+		}
+	}
+	sort.Slice(locked, func(i, j int) bool {
+		return locked[i].LockedUntil.Before(*locked[j].LockedUntil)
+	})
+	return locked, nil
+}
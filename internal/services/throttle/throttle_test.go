@@ -0,0 +1,215 @@
+package throttle
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-template/internal/utils/logger"
+)
+
+func TestMain(m *testing.M) {
+	_ = logger.Init(&logger.Config{Level: "error", ConsoleOut: true, ServiceName: "throttle-test"})
+	os.Exit(m.Run())
+}
+
+// testClock 可以手動前進的時間，取代 Limiter 使用的 time.Now
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// newTestLimiter 建立使用記憶體儲存與可控時間的 Limiter
+func newTestLimiter(maxAttempts, ipMaxAttempts int) (*Limiter, *MemoryStore, *testClock) {
+	store := NewMemoryStore()
+	clock := &testClock{now: time.Now()}
+	return &Limiter{
+		store:           store,
+		maxAttempts:     maxAttempts,
+		ipMaxAttempts:   ipMaxAttempts,
+		window:          time.Hour,
+		backoffBase:     time.Second,
+		backoffMax:      10 * time.Second,
+		lockoutDuration: 15 * time.Minute,
+		now:             clock.Now,
+	}, store, clock
+}
+
+// requireThrottled 確認 err 為 *ThrottledError 並回傳
+func requireThrottled(t *testing.T, err error) *ThrottledError {
+	t.Helper()
+	var throttled *ThrottledError
+	require.True(t, errors.As(err, &throttled), "expected *ThrottledError, got %v", err)
+	return throttled
+}
+
+// failures 取得計數對象目前的失敗次數
+func failures(t *testing.T, store Store, key string) int {
+	t.Helper()
+	record, err := store.Get(key)
+	require.NoError(t, err)
+	if record == nil {
+		return 0
+	}
+	return record.Failures
+}
+
+// 測試每次失敗後的退避時間以指數成長，並且不超過上限
+func TestLimiterBackoffGrowth(t *testing.T) {
+	limiter, _, clock := newTestLimiter(0, 0)
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	for _, wait := range expected {
+		require.NoError(t, limiter.Attempt("alice", "203.0.113.7"))
+
+		throttled := requireThrottled(t, limiter.Attempt("alice", "203.0.113.7"))
+		assert.Equal(t, wait, throttled.RetryAfter)
+		assert.False(t, throttled.Locked)
+		assert.Equal(t, wait, requireThrottled(t, limiter.Check("alice", "")).RetryAfter)
+
+		clock.Advance(wait)
+	}
+}
+
+// 測試被退避拒絕的嘗試不會計數，也不會延長等待時間
+func TestLimiterThrottledAttemptNotCounted(t *testing.T) {
+	limiter, store, clock := newTestLimiter(0, 0)
+
+	require.NoError(t, limiter.Attempt("alice", "203.0.113.7"))
+	clock.Advance(500 * time.Millisecond)
+	throttled := requireThrottled(t, limiter.Attempt("alice", "203.0.113.7"))
+	assert.Equal(t, 500*time.Millisecond, throttled.RetryAfter)
+	assert.Equal(t, 1, failures(t, store, UsernameKey("alice")))
+	assert.Equal(t, 1, failures(t, store, IPKey("203.0.113.7")))
+}
+
+// 測試連續失敗達到上限時鎖定，鎖定期間即使退避時間已過也無法嘗試
+func TestLimiterLockoutAtLimit(t *testing.T) {
+	limiter, _, clock := newTestLimiter(3, 0)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, limiter.Attempt("Alice", "203.0.113.7"), "attempt %d", i+1)
+		clock.Advance(time.Minute)
+	}
+
+	throttled := requireThrottled(t, limiter.Attempt("alice", "198.51.100.1"))
+	assert.True(t, throttled.Locked)
+	assert.Equal(t, 14*time.Minute, throttled.RetryAfter, "locked until 15 minutes after the third failure")
+
+	locked, err := limiter.Locked()
+	require.NoError(t, err)
+	require.Len(t, locked, 1)
+	assert.Equal(t, UsernameKey("alice"), locked[0].Key)
+
+	clock.Advance(15 * time.Minute)
+	assert.NoError(t, limiter.Attempt("alice", "198.51.100.1"))
+}
+
+// 測試來源 IP 達到上限時鎖定所有使用者名稱，被拒絕的嘗試也會歸還使用者名稱上的計數
+func TestLimiterIPLockout(t *testing.T) {
+	limiter, store, clock := newTestLimiter(0, 2)
+
+	require.NoError(t, limiter.Attempt("alice", "203.0.113.7"))
+	clock.Advance(time.Minute)
+	require.NoError(t, limiter.Attempt("bob", "203.0.113.7"))
+	clock.Advance(time.Minute)
+
+	throttled := requireThrottled(t, limiter.Attempt("carol", "203.0.113.7"))
+	assert.True(t, throttled.Locked)
+	assert.Equal(t, 0, failures(t, store, UsernameKey("carol")))
+}
+
+// 測試最後一次失敗超過計數區間後重新從 1 開始計數
+func TestLimiterWindowReset(t *testing.T) {
+	limiter, store, clock := newTestLimiter(3, 0)
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, limiter.Attempt("alice", ""))
+		clock.Advance(time.Minute)
+	}
+	assert.Equal(t, 2, failures(t, store, UsernameKey("alice")))
+
+	clock.Advance(time.Hour)
+	require.NoError(t, limiter.Attempt("alice", ""))
+	assert.Equal(t, 1, failures(t, store, UsernameKey("alice")))
+
+	// 重新計數後需要再失敗兩次才會鎖定
+	clock.Advance(time.Minute)
+	require.NoError(t, limiter.Attempt("alice", ""))
+	clock.Advance(time.Minute)
+	require.NoError(t, limiter.Attempt("alice", ""))
+	assert.True(t, requireThrottled(t, limiter.Check("alice", "")).Locked)
+}
+
+// 測試登入成功清除使用者名稱的紀錄，但來源 IP 之前的失敗紀錄保持不變
+func TestLimiterRecordSuccessKeepsIPFailures(t *testing.T) {
+	limiter, store, clock := newTestLimiter(5, 5)
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, limiter.Attempt("victim", "203.0.113.7"))
+		clock.Advance(time.Minute)
+	}
+
+	// 攻擊者穿插登入自己的帳號
+	require.NoError(t, limiter.Attempt("attacker", "203.0.113.7"))
+	require.NoError(t, limiter.RecordSuccess("attacker", "203.0.113.7"))
+
+	assert.Equal(t, 0, failures(t, store, UsernameKey("attacker")))
+	assert.Equal(t, 2, failures(t, store, IPKey("203.0.113.7")))
+	assert.Equal(t, 2, failures(t, store, UsernameKey("victim")))
+
+	// 沒有經過 Attempt 的登入不會變更來源 IP 的紀錄
+	require.NoError(t, limiter.RecordSuccess("victim", ""))
+	assert.Equal(t, 0, failures(t, store, UsernameKey("victim")))
+	assert.Equal(t, 2, failures(t, store, IPKey("203.0.113.7")))
+}
+
+// 測試 Release 只歸還這次嘗試，使用者名稱與來源 IP 之前的失敗紀錄都保持不變
+func TestLimiterReleaseKeepsFailures(t *testing.T) {
+	limiter, store, clock := newTestLimiter(5, 5)
+
+	require.NoError(t, limiter.Attempt("alice", "203.0.113.7"))
+	clock.Advance(time.Minute)
+	require.NoError(t, limiter.Attempt("alice", "203.0.113.7"))
+	require.NoError(t, limiter.Release("alice", "203.0.113.7"))
+
+	assert.Equal(t, 1, failures(t, store, UsernameKey("alice")))
+	assert.Equal(t, 1, failures(t, store, IPKey("203.0.113.7")))
+}
+
+// 測試同時送出的嘗試依序計數，不會在任何一次失敗被記錄之前全部通過
+func TestLimiterConcurrentAttempts(t *testing.T) {
+	limiter, store, _ := newTestLimiter(3, 0)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if limiter.Attempt("alice", "203.0.113.7") == nil {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 時間沒有前進，第一次嘗試之後都在退避時間內
+	assert.Equal(t, 1, allowed)
+	assert.Equal(t, 1, failures(t, store, UsernameKey("alice")))
+	assert.Equal(t, 1, failures(t, store, IPKey("203.0.113.7")))
+}
@@ -0,0 +1,272 @@
+package user
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"go-template/internal/models"
+	"go-template/internal/repository"
+	"go-template/internal/services/throttle"
+	"go-template/internal/utils/logger"
+	"gorm.io/gorm"
+)
+
+// ListUsers 依照篩選、排序與分頁條件取得使用者列表
+// @param query query ListUsersQuery true "查詢條件"
+// @return page 使用者列表與分頁資訊
+// @return error 錯誤訊息
+func (svc *ServiceDefault) ListUsers(query ListUsersQuery) (*UserPage, error) {
+	repoQuery := query.UserListQuery
+	if query.Cursor != "" {
+		after, err := decodeUserCursor(query.Cursor, repoQuery)
+		if err != nil {
+			logger.Logger.Debugf("Invalid user list cursor: %v", err) // 記錄錯誤
+			return nil, ErrInvalidListQuery
+		}
+		repoQuery.After = after
+	}
+
+	// 多取一筆資料來判斷是否還有下一頁
+	limit := repoQuery.Limit
+	if limit > 0 {
+		repoQuery.Limit = limit + 1
+	}
+
+	users, total, err := svc.userRepo.List(repoQuery)
+	if errors.Is(err, repository.ErrInvalidSortField) || errors.Is(err, repository.ErrKeysetNotSupported) {
+		return nil, ErrInvalidListQuery
+	}
+	if err != nil {
+		logger.Logger.Errorf("Error listing users in repository: %v", err) // 記錄錯誤
+		return nil, err
+	}
+
+	page := &UserPage{Users: users, Total: total}
+	if limit > 0 && len(users) > limit {
+		page.Users, page.HasMore = users[:limit], true
+		if repoQuery.SupportsKeyset() {
+			last := page.Users[limit-1]
+			if page.NextCursor, err = encodeUserCursor(last, repoQuery); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return page, nil
+}
+
+// AdminGetUser 取得任意使用者的資訊 (包含已刪除的使用者與使用者的角色)
+// @param id path uint true "使用者 ID"
+// @return user 使用者資訊
+// @return error 錯誤訊息
+func (svc *ServiceDefault) AdminGetUser(id uint) (*models.User, error) {
+	user, err := svc.userRepo.GetByIDUnscoped(id)
+	if err != nil {
+		return nil, translateNotFound(err)
+	}
+	return user, nil
+}
+
+// AdminUpdateUser 更新任意使用者的帳號名稱、電子郵件與角色
+// 角色有變動時會登出該使用者所有的裝置，讓新的角色立即生效；修改的帳號名稱或電子郵件不符合規則時回傳 validators.ErrValidation
+// @param id path uint true "使用者 ID"
+// @param update body AdminUserUpdate true "要更新的欄位"
+// @return user 更新後的使用者資訊
+// @return error 錯誤訊息
+func (svc *ServiceDefault) AdminUpdateUser(id uint, update AdminUserUpdate) (*models.User, error) {
+	if _, err := svc.userRepo.GetByID(id); err != nil {
+		return nil, translateNotFound(err)
+	}
+
+	// 只驗證有修改的欄位
+	candidate := &models.User{Model: gorm.Model{ID: id}}
+	var fields []string
+	if update.Username != nil {
+		candidate.Username = *update.Username
+		fields = append(fields, "Username")
+	}
+	if update.Email != nil {
+		candidate.Email = *update.Email
+		fields = append(fields, "Email")
+	}
+	if len(fields) > 0 {
+		if err := svc.userValidator.Validate(candidate, fields...); err != nil {
+			return nil, err
+		}
+	}
+
+	columns := make(map[string]interface{})
+	if update.Username != nil {
+		columns["username"] = candidate.Username
+	}
+	if update.Email != nil {
+		columns["email"] = candidate.Email
+	}
+	if len(columns) > 0 {
+		if err := svc.userRepo.UpdateColumns(id, columns); err != nil {
+			logger.Logger.Errorf("Error updating user in repository: %v", err) // 記錄錯誤
+			return nil, translateNotFound(err)
+		}
+	}
+
+	if update.Roles != nil {
+		if err := svc.rbacService.ReplaceRoles(id, uniqueRoles(update.Roles)...); err != nil {
+			if errors.Is(err, repository.ErrUnknownRole) {
+				return nil, ErrUnknownRole
+			}
+			logger.Logger.Errorf("Error replacing roles of user: %v", err) // 記錄錯誤
+			return nil, err
+		}
+		// 已發行的 access token 仍帶有舊的角色，必須讓使用者重新登入
+		if err := svc.LogoutAll(id); err != nil {
+			return nil, err
+		}
+	}
+
+	logger.Logger.Infof("User updated by admin: %d", id) // 記錄使用者已更新
+	return svc.userRepo.GetByIDUnscoped(id)
+}
+
+// SuspendUser 停權使用者，並登出該使用者所有的裝置
+// @param id path uint true "使用者 ID"
+// @param actorID body uint false "執行停權的管理者 ID"
+// @param reason body string false "停權原因"
+// @return error 錯誤訊息
+func (svc *ServiceDefault) SuspendUser(id uint, actorID *uint, reason string) error {
+	return svc.ChangeUserStatus(id, models.UserStatusSuspended, actorID, reason)
+}
+
+// RestoreUser 還原被刪除、停權、鎖定或停用的使用者，還原後帳號狀態為 active
+// @param id path uint true "使用者 ID"
+// @param actorID body uint false "執行還原的管理者 ID"
+// @param reason body string false "還原原因"
+// @return error 錯誤訊息
+func (svc *ServiceDefault) RestoreUser(id uint, actorID *uint, reason string) error {
+	user, err := svc.userRepo.GetByIDUnscoped(id)
+	if err != nil {
+		return translateNotFound(err)
+	}
+	if user.DeletedAt.Valid {
+		if err := svc.userRepo.Restore(id); err != nil {
+			return translateNotFound(err)
+		}
+	}
+	if user.Status != models.UserStatusActive {
+		if err := svc.ChangeUserStatus(id, models.UserStatusActive, actorID, reason); err != nil {
+			return err
+		}
+	}
+	logger.Logger.Infof("User restored: %d", id) // 記錄使用者已還原
+	return nil
+}
+
+// HardDeleteUser 永久刪除使用者，無法還原
+// @param id path uint true "使用者 ID"
+// @return error 錯誤訊息
+func (svc *ServiceDefault) HardDeleteUser(id uint) error {
+	// 先撤銷尚未過期的 token，避免永久刪除後仍能使用
+	if err := svc.LogoutAll(id); err != nil {
+		return err
+	}
+	if err := svc.userRepo.HardDelete(id); err != nil {
+		return translateNotFound(err)
+	}
+	logger.Logger.Infof("User permanently deleted: %d", id) // 記錄使用者已永久刪除
+	return nil
+}
+
+// ForcePasswordReset 要求使用者重設密碼，並登出該使用者所有的裝置
+// 重設密碼之前，使用者無法再登入
+// @param id path uint true "使用者 ID"
+// @return error 錯誤訊息
+func (svc *ServiceDefault) ForcePasswordReset(id uint) error {
+	if err := svc.userRepo.UpdateColumns(id, map[string]interface{}{"password_reset_required": true}); err != nil {
+		return translateNotFound(err)
+	}
+	if err := svc.LogoutAll(id); err != nil {
+		return err
+	}
+	logger.Logger.Infof("Password reset required for user: %d", id) // 記錄已要求重設密碼
+	return nil
+}
+
+// ListLoginLockouts 列出目前因為登入失敗次數過多而被暫時鎖定的使用者名稱與來源 IP
+// @return records 被鎖定的計數對象
+// @return error 錯誤訊息
+func (svc *ServiceDefault) ListLoginLockouts() ([]throttle.Record, error) {
+	return svc.loginLimiter.Locked()
+}
+
+// ClearLoginLockout 解除使用者因為登入失敗次數過多造成的暫時鎖定
+// @param id path uint true "使用者 ID"
+// @return error 錯誤訊息
+func (svc *ServiceDefault) ClearLoginLockout(id uint) error {
+	user, err := svc.userRepo.GetByIDUnscoped(id)
+	if err != nil {
+		return translateNotFound(err)
+	}
+	return svc.loginLimiter.Unlock(user.Username)
+}
+
+// translateNotFound 將資料庫找不到資料的錯誤轉換成 ErrUserNotFound
+func translateNotFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrUserNotFound
+	}
+	return err
+}
+
+// uniqueRoles 移除重複的角色名稱
+func uniqueRoles(roles []string) []string {
+	seen := make(map[string]bool, len(roles))
+	unique := make([]string, 0, len(roles))
+	for _, role := range roles {
+		if !seen[role] {
+			seen[role] = true
+			unique = append(unique, role)
+		}
+	}
+	return unique
+}
+
+// userCursor 分頁游標的內容，記錄上一頁最後一筆資料的排序鍵以及產生游標時的排序方式
+type userCursor struct {
+	ID        uint      `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	SortBy    string    `json:"sort"`
+	Desc      bool      `json:"desc"`
+}
+
+// encodeUserCursor 將使用者的排序鍵編碼成不透明的游標字串
+func encodeUserCursor(user models.User, query repository.UserListQuery) (string, error) {
+	data, err := json.Marshal(userCursor{
+		ID:        user.ID,
+		CreatedAt: user.CreatedAt,
+		SortBy:    query.SortBy,
+		Desc:      query.Desc,
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeUserCursor 解析游標字串，排序方式必須與產生游標時相同
+func decodeUserCursor(cursor string, query repository.UserListQuery) (*repository.UserKeyset, error) {
+	if !query.SupportsKeyset() {
+		return nil, repository.ErrKeysetNotSupported
+	}
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+	var decoded userCursor
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+	if decoded.SortBy != query.SortBy || decoded.Desc != query.Desc {
+		return nil, errors.New("cursor was created with a different sort order")
+	}
+	return &repository.UserKeyset{ID: decoded.ID, CreatedAt: decoded.CreatedAt}, nil
+}
@@ -0,0 +1,239 @@
+package user
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-template/internal/models"
+	"go-template/internal/repository"
+	"go-template/internal/validators"
+)
+
+// expectUserByID 預期以 ID 查詢使用者，found 為 false 時回傳空的結果
+func expectUserByID(mock sqlmock.Sqlmock, id uint, found bool) {
+	rows := sqlmock.NewRows([]string{"id", "username", "email"})
+	if found {
+		rows.AddRow(id, "alice", "alice@example.com")
+	}
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"."id" = \$1 AND "users"."deleted_at" IS NULL`).WithArgs(id, 1).WillReturnRows(rows)
+}
+
+// expectLogoutAll 預期撤銷使用者所有的 refresh token
+func expectLogoutAll(mock sqlmock.Sqlmock, id uint) {
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "refresh_tokens" SET "revoked_at"=\$1,"updated_at"=\$2 WHERE \(user_id = \$3 AND revoked_at IS NULL\)`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), id).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "sessions" SET "revoked_at"=\$1 WHERE user_id = \$2 AND revoked_at IS NULL`).
+		WithArgs(sqlmock.AnyArg(), id).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+// expectNotTaken 預期檢查 column 的值沒有被其他使用者使用
+func expectNotTaken(mock sqlmock.Sqlmock, column, value string, excludeID uint) {
+	condition := `LOWER\(` + column + `\) = LOWER\(\$1\)`
+	if column == "username" {
+		condition, value = `username_key = \$1`, models.UsernameKey(value)
+	}
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "users" WHERE `+condition+` AND id <> \$2`).
+		WithArgs(value, excludeID).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+}
+
+// expectUserUnscoped 預期查詢包含已刪除的使用者與使用者的角色
+func expectUserUnscoped(mock sqlmock.Sqlmock, id uint, roles ...string) {
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"."id" = \$1`).WithArgs(id, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email"}).AddRow(id, "alice", "alice@example.com"))
+	userRoles := sqlmock.NewRows([]string{"user_id", "role_id"})
+	roleRows := sqlmock.NewRows([]string{"id", "name"})
+	for i, role := range roles {
+		userRoles.AddRow(id, i+1)
+		roleRows.AddRow(i+1, role)
+	}
+	mock.ExpectQuery(`SELECT \* FROM "user_roles" WHERE "user_roles"."user_id" = \$1`).WithArgs(id).WillReturnRows(userRoles)
+	if len(roles) > 0 {
+		mock.ExpectQuery(`SELECT \* FROM "roles" WHERE "roles"."id"`).WillReturnRows(roleRows)
+	}
+}
+
+// loggedOut 檢查使用者在 at 之前發行的 token 是否已經全部失效
+func loggedOut(t *testing.T, svc *ServiceDefault, id uint) bool {
+	t.Helper()
+	validAfter, err := svc.revocationStore.TokensValidAfter(id)
+	require.NoError(t, err)
+	return !validAfter.IsZero()
+}
+
+// 測試管理者變更使用者的角色時會登出該使用者所有的裝置，讓新的角色立即生效
+func TestAdminUpdateUserRoles(t *testing.T) {
+	svc, mock := newTestService(t)
+
+	username := "alice2"
+	expectUserByID(mock, 7, true)
+	expectNotTaken(mock, "username", username, 7)
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "users" SET "username"=\$1,"username_key"=\$2,"updated_at"=\$3 WHERE id = \$4`).
+		WithArgs(username, username, sqlmock.AnyArg(), 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`SELECT \* FROM "roles" WHERE name IN \(\$1,\$2\)`).WithArgs("admin", "user").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "admin").AddRow(2, "user"))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "users" SET "updated_at"=\$1 WHERE "users"."deleted_at" IS NULL AND "id" = \$2`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO "roles"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	mock.ExpectExec(`INSERT INTO "user_roles"`).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "user_roles" WHERE "user_roles"."user_id" = \$1 AND "user_roles"."role_id" NOT IN`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	expectLogoutAll(mock, 7)
+	expectUserUnscoped(mock, 7, "admin", "user")
+
+	user, err := svc.AdminUpdateUser(7, AdminUserUpdate{Username: &username, Roles: []string{"admin", "user", "admin"}})
+	require.NoError(t, err)
+	assert.Len(t, user.Roles, 2)
+	assert.True(t, loggedOut(t, svc, 7), "tokens with the old roles are revoked")
+}
+
+// 測試只更新帳號資料、沒有變更角色時不會登出使用者
+func TestAdminUpdateUserWithoutRoles(t *testing.T) {
+	svc, mock := newTestService(t)
+
+	email := "alice@example.org"
+	expectUserByID(mock, 7, true)
+	expectNotTaken(mock, "email", email, 7)
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "users" SET "email"=\$1,"updated_at"=\$2 WHERE id = \$3`).
+		WithArgs(email, sqlmock.AnyArg(), 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectUserUnscoped(mock, 7)
+
+	_, err := svc.AdminUpdateUser(7, AdminUserUpdate{Email: &email})
+	require.NoError(t, err)
+	assert.False(t, loggedOut(t, svc, 7))
+}
+
+// 測試指定不存在的角色、不存在的使用者或重複的帳號名稱時回傳對應的錯誤，且不會登出使用者
+func TestAdminUpdateUserErrors(t *testing.T) {
+	svc, mock := newTestService(t)
+
+	expectUserByID(mock, 7, true)
+	mock.ExpectQuery(`SELECT \* FROM "roles" WHERE name IN \(\$1,\$2\)`).WithArgs("user", "owner").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "user"))
+	_, err := svc.AdminUpdateUser(7, AdminUserUpdate{Roles: []string{"user", "owner"}})
+	assert.ErrorIs(t, err, ErrUnknownRole)
+	assert.False(t, loggedOut(t, svc, 7))
+
+	expectUserByID(mock, 8, false)
+	_, err = svc.AdminUpdateUser(8, AdminUserUpdate{Roles: []string{"user"}})
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	// 帳號名稱正規化之後與其他使用者重複
+	username := " ｂｏｂｂｙ "
+	expectUserByID(mock, 7, true)
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "users" WHERE username_key = \$1 AND id <> \$2`).
+		WithArgs("bobby", 7).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	_, err = svc.AdminUpdateUser(7, AdminUserUpdate{Username: &username})
+	assert.ErrorIs(t, err, validators.ErrValidation)
+}
+
+// 測試停權使用者時寫入狀態變更紀錄並登出該使用者所有的裝置，目前的狀態不允許停權時回傳 ErrInvalidStatusTransition
+func TestSuspendUser(t *testing.T) {
+	svc, mock := newTestService(t)
+	actorID := uint(1)
+
+	expectUserStatus(mock, 7, models.UserStatusActive, false)
+	expectChangeStatus(mock, 7, models.UserStatusActive, models.UserStatusSuspended, true)
+	expectLogoutAll(mock, 7)
+	require.NoError(t, svc.SuspendUser(7, &actorID, "spam"))
+	assert.True(t, loggedOut(t, svc, 7))
+
+	expectUserStatus(mock, 8, models.UserStatusDeactivated, false)
+	assert.ErrorIs(t, svc.SuspendUser(8, &actorID, ""), ErrInvalidStatusTransition)
+	assert.False(t, loggedOut(t, svc, 8))
+}
+
+// 測試還原使用者時清除刪除時間並恢復為 active 狀態
+func TestRestoreUser(t *testing.T) {
+	svc, mock := newTestService(t)
+
+	expectUserStatus(mock, 7, models.UserStatusSuspended, true)
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "users" SET "deleted_at"=\$1,"updated_at"=\$2 WHERE id = \$3`).
+		WithArgs(nil, sqlmock.AnyArg(), 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectUserStatus(mock, 7, models.UserStatusSuspended, false)
+	expectChangeStatus(mock, 7, models.UserStatusSuspended, models.UserStatusActive, true)
+	require.NoError(t, svc.RestoreUser(7, nil, ""))
+
+	// 已經是 active 且沒有被刪除的使用者不需要變更
+	expectUserStatus(mock, 8, models.UserStatusActive, false)
+	require.NoError(t, svc.RestoreUser(8, nil, ""))
+
+	mock.ExpectQuery(`SELECT \* FROM "users"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	assert.ErrorIs(t, svc.RestoreUser(9, nil, ""), ErrUserNotFound)
+}
+
+// 測試篩選條件與 keyset 分頁：多取一筆判斷是否有下一頁，下一頁以游標接續上一頁最後一筆資料
+func TestListUsersKeysetPagination(t *testing.T) {
+	svc, mock := newTestService(t)
+
+	status := models.UserStatusActive
+	query := ListUsersQuery{UserListQuery: repository.UserListQuery{
+		Status: &status, EmailDomain: "Example.COM", SortBy: repository.UserSortCreatedAt, Desc: true, Limit: 2,
+	}}
+	createdAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "users" WHERE status = \$1 AND LOWER\(email\) LIKE \$2`).
+		WithArgs(status, "%@example.com").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE status = \$1 AND LOWER\(email\) LIKE \$2 AND "users"."deleted_at" IS NULL ORDER BY created_at DESC,id DESC LIMIT \$3`).
+		WithArgs(status, "%@example.com", 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "created_at"}).
+			AddRow(9, "carol", createdAt.Add(2*time.Hour)).AddRow(8, "bob", createdAt).AddRow(7, "alice", createdAt))
+	mock.ExpectQuery(`SELECT \* FROM "user_roles" WHERE "user_roles"."user_id" IN \(\$1,\$2,\$3\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "role_id"}))
+
+	page, err := svc.ListUsers(query)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), page.Total)
+	assert.Len(t, page.Users, 2)
+	assert.True(t, page.HasMore)
+	require.NotEmpty(t, page.NextCursor)
+
+	// 下一頁從 bob (created_at 相同時再比較 id) 之後開始
+	query.Cursor = page.NextCursor
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "users"`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+	mock.ExpectQuery(`ORDER BY created_at DESC,id DESC LIMIT \$6`).
+		WithArgs(status, "%@example.com", createdAt, createdAt, 8, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "created_at"}).AddRow(7, "alice", createdAt))
+	mock.ExpectQuery(`SELECT \* FROM "user_roles"`).WillReturnRows(sqlmock.NewRows([]string{"user_id", "role_id"}))
+
+	page, err = svc.ListUsers(query)
+	require.NoError(t, err)
+	assert.Len(t, page.Users, 1)
+	assert.False(t, page.HasMore)
+	assert.Empty(t, page.NextCursor)
+}
+
+// 測試不支援的排序欄位、無法解析的游標以及排序方式與游標不同時回傳 ErrInvalidListQuery
+func TestListUsersInvalidQuery(t *testing.T) {
+	svc, mock := newTestService(t)
+
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "users"`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	_, err := svc.ListUsers(ListUsersQuery{UserListQuery: repository.UserListQuery{SortBy: "password"}})
+	assert.ErrorIs(t, err, ErrInvalidListQuery)
+
+	_, err = svc.ListUsers(ListUsersQuery{Cursor: "not a cursor"})
+	assert.ErrorIs(t, err, ErrInvalidListQuery)
+
+	cursor, err := encodeUserCursor(models.User{}, repository.UserListQuery{SortBy: repository.UserSortCreatedAt})
+	require.NoError(t, err)
+	_, err = svc.ListUsers(ListUsersQuery{UserListQuery: repository.UserListQuery{SortBy: repository.UserSortID}, Cursor: cursor})
+	assert.ErrorIs(t, err, ErrInvalidListQuery)
+	_, err = svc.ListUsers(ListUsersQuery{UserListQuery: repository.UserListQuery{SortBy: repository.UserSortUsername}, Cursor: cursor})
+	assert.ErrorIs(t, err, ErrInvalidListQuery)
+}
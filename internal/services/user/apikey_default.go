@@ -0,0 +1,159 @@
+package user
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"time"
+
+	"go-template/internal/models"
+	"go-template/internal/utils/jwt"
+	"go-template/internal/utils/logger"
+
+	"gorm.io/gorm"
+)
+
+// apiKeyPrefix 所有 API key 共用的開頭，方便在程式碼或日誌中辨識 (以及讓 secret scanning 工具比對)
+const apiKeyPrefix = "gtk_"
+
+// apiKeyPrefixBytes API key 公開前綴的隨機位元組長度
+const apiKeyPrefixBytes = 6
+
+// apiKeyTouchInterval 最後使用時間的更新間隔，避免每個請求都寫入資料庫
+const apiKeyTouchInterval = time.Minute
+
+// CreateAPIKey 為使用者建立新的 API key
+// key 的格式為 gtk_<公開前綴>_<secret>，資料庫只儲存完整 key 的雜湊值與公開前綴
+// @param userID path uint true "使用者 ID"
+// @param input body NewAPIKey true "API key 的名稱、權限與過期時間"
+// @return key 新的 API key，明文 key 只會回傳這一次
+// @return error 錯誤訊息
+func (svc *ServiceDefault) CreateAPIKey(userID uint, input NewAPIKey) (*CreatedAPIKey, error) {
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidAPIKeyExpiry
+	}
+	scopes, err := svc.validateAPIKeyScopes(userID, input.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix, err := generateAPIKeyPrefix()
+	if err != nil {
+		return nil, err
+	}
+	secret, _, err := jwt.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	key := prefix + "_" + secret
+
+	apiKey := &models.APIKey{
+		UserID:    userID,
+		Name:      input.Name,
+		Prefix:    prefix,
+		KeyHash:   jwt.HashOpaqueToken(key),
+		Scopes:    scopes,
+		ExpiresAt: input.ExpiresAt,
+	}
+	if err := svc.apiKeyRepo.Create(apiKey); err != nil {
+		return nil, err
+	}
+
+	logger.Logger.Infof("API key %s created for user %d with scopes %v", prefix, userID, scopes) // 記錄 API key 已建立
+	return &CreatedAPIKey{APIKey: apiKey, Key: key}, nil
+}
+
+// ListAPIKeys 列出使用者尚未撤銷的 API key (不包含明文 key)
+// @param userID path uint true "使用者 ID"
+// @return keys API key 列表
+// @return error 錯誤訊息
+func (svc *ServiceDefault) ListAPIKeys(userID uint) ([]models.APIKey, error) {
+	return svc.apiKeyRepo.ListByUser(userID)
+}
+
+// RevokeAPIKey 撤銷使用者的 API key，撤銷後立即失效
+// @param userID path uint true "使用者 ID"
+// @param keyID path uint true "API key ID"
+// @return error 錯誤訊息
+func (svc *ServiceDefault) RevokeAPIKey(userID, keyID uint) error {
+	revoked, err := svc.apiKeyRepo.Revoke(userID, keyID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrAPIKeyNotFound
+	}
+	logger.Logger.Infof("API key %d of user %d revoked", keyID, userID) // 記錄 API key 已撤銷
+	return nil
+}
+
+// AuthenticateAPIKey 驗證 API key，並更新最後使用時間
+// 只檢查 key 本身是否有效，擁有者的帳號狀態與權限由呼叫端 (Auth 中介軟體) 檢查
+// @param key header string true "API key"
+// @return apiKey 有效的 API key
+// @return error 錯誤訊息，key 無效時為 ErrInvalidAPIKey
+func (svc *ServiceDefault) AuthenticateAPIKey(key string) (*models.APIKey, error) {
+	apiKey, err := svc.apiKeyRepo.GetByHash(jwt.HashOpaqueToken(key))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if !apiKey.Active(now) {
+		logger.Logger.Debugf("Inactive API key %s used", apiKey.Prefix) // 記錄錯誤
+		return nil, ErrInvalidAPIKey
+	}
+
+	// 最後使用時間只是參考資訊，更新失敗不影響驗證結果
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyTouchInterval {
+		if err := svc.apiKeyRepo.TouchLastUsed(apiKey.ID, now, now.Add(-apiKeyTouchInterval)); err != nil {
+			logger.Logger.Warnf("Error updating last used time of API key %s: %v", apiKey.Prefix, err) // 記錄錯誤
+		}
+	}
+	return apiKey, nil
+}
+
+// validateAPIKeyScopes 檢查 API key 的權限都是擁有者目前擁有的權限，回傳排序並移除重複後的權限
+// 避免使用者透過 API key 取得自己沒有的權限
+func (svc *ServiceDefault) validateAPIKeyScopes(userID uint, scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, ErrInvalidAPIKeyScope
+	}
+
+	roles, err := svc.rbacService.RolesForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	granted, err := svc.rbacService.PermissionsForRoles(roles)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(scopes))
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !granted[scope] {
+			logger.Logger.Debugf("User %d requested API key scope %q without the permission", userID, scope) // 記錄錯誤
+			return nil, ErrInvalidAPIKeyScope
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// generateAPIKeyPrefix 產生 API key 的公開前綴，例如 gtk_3f9a0c1b2d4e
+func generateAPIKeyPrefix() (string, error) {
+	buf := make([]byte, apiKeyPrefixBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,144 @@
+package user
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"go-template/internal/models"
+	"go-template/internal/utils/jwt"
+	"go-template/internal/utils/logger"
+	"go-template/internal/utils/mailer"
+)
+
+// RequestMagicLink 寄送不需要密碼的登入連結，使用者之前的登入連結會失效
+// 為了避免洩漏帳號是否存在，找不到使用者、帳號無法登入、登入被暫時鎖定、寄送過於頻繁或寄送失敗時都不會回傳錯誤；
+// 找到使用者之後的檢查與寄送都在背景執行，回應時間與找不到使用者時相同
+// @param email body string true "電子郵件"
+// @param client header ClientInfo false "來源 IP 與 User-Agent，IP 用於檢查登入鎖定"
+// @return error 錯誤訊息，沒有開啟登入連結功能時為 ErrMagicLinkDisabled
+func (svc *ServiceDefault) RequestMagicLink(email string, client ClientInfo) error {
+	if !svc.cfg.MagicLinkEnabled {
+		return ErrMagicLinkDisabled
+	}
+
+	user, err := svc.userRepo.GetByEmail(email)
+	if err != nil {
+		logger.Logger.Debugf("Magic link not sent: no user with email %s", email) // 記錄略過
+		return nil
+	}
+
+	svc.inBackground(func() { svc.deliverMagicLink(user, client) })
+	return nil
+}
+
+// LoginMagicLink 使用登入連結中的 token 登入
+// 與 Login 一樣檢查登入鎖定與帳號狀態，回傳相同的登入結果；啟用兩步驟驗證的使用者一樣必須再呼叫 LoginMFA
+// 每個 token 只能使用一次，被鎖定時 token 不會被使用，鎖定解除後仍然可以使用
+// @param token body string true "登入連結中的 token"
+// @param client header ClientInfo false "來源 IP 與 User-Agent"
+// @return result 登入結果
+// @return error 錯誤訊息，失敗次數過多時為 *throttle.ThrottledError
+func (svc *ServiceDefault) LoginMagicLink(token string, client ClientInfo) (*LoginResult, error) {
+	if !svc.cfg.MagicLinkEnabled {
+		return nil, ErrMagicLinkDisabled
+	}
+
+	stored, err := svc.lookupUserToken(models.UserTokenPurposeMagicLink, token)
+	if err == errUserTokenInvalid {
+		return nil, ErrInvalidMagicLink
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := svc.userRepo.GetByID(stored.UserID)
+	if err != nil {
+		logger.Logger.Debugf("Error getting user of magic link: %v", err) // 記錄錯誤
+		return nil, ErrInvalidMagicLink
+	}
+
+	// 與密碼登入相同的鎖定檢查，使用者名稱或來源 IP 被鎖定時不能以登入連結繞過
+	if err := svc.loginLimiter.Check(user.Username, client.IP); err != nil {
+		logger.Logger.Debugf("Magic link login throttled for user %s from %s: %v", user.Username, client.IP, err) // 記錄錯誤
+		return nil, err
+	}
+
+	if err := svc.markUserTokenUsed(stored); err == errUserTokenInvalid {
+		return nil, ErrInvalidMagicLink
+	} else if err != nil {
+		return nil, err
+	}
+	if user.ServiceAccount {
+		return nil, ErrInvalidMagicLink
+	}
+
+	// 寄出連結之後帳號狀態可能已經改變，重新檢查
+	if err := checkLoginAllowed(user); err != nil {
+		logger.Logger.Debugf("Login refused for user %s: %v", user.Username, err) // 記錄錯誤
+		return nil, err
+	}
+
+	result, err := svc.continueLogin(user, client, []string{jwt.AuthMethodMagicLink})
+	if err != nil {
+		return nil, err
+	}
+
+	// 完成登入之後才清除失敗紀錄，被拒絕或還需要兩步驟驗證的登入不會重設使用者名稱的計數
+	if !result.MFARequired {
+		if err := svc.loginLimiter.RecordSuccess(user.Username, ""); err != nil {
+			logger.Logger.Warnf("Error clearing failed login attempts: %v", err) // 記錄錯誤
+		}
+	}
+	return result, nil
+}
+
+// deliverMagicLink 檢查使用者是否可以使用登入連結登入，可以時寄送登入信，錯誤只會記錄
+func (svc *ServiceDefault) deliverMagicLink(user *models.User, client ClientInfo) {
+	// 與密碼登入相同，服務帳號與不能登入的帳號不寄送
+	if user.ServiceAccount {
+		logger.Logger.Debugf("Magic link not sent: user %d is a service account", user.ID) // 記錄略過
+		return
+	}
+	if err := checkLoginAllowed(user); err != nil {
+		logger.Logger.Debugf("Magic link not sent: user %d: %v", user.ID, err) // 記錄略過
+		return
+	}
+	if err := svc.loginLimiter.Check(user.Username, client.IP); err != nil {
+		logger.Logger.Debugf("Magic link not sent: user %d: %v", user.ID, err) // 記錄略過
+		return
+	}
+
+	throttled, err := svc.userTokenThrottled(user.ID, models.UserTokenPurposeMagicLink, svc.cfg.MagicLinkResendInterval)
+	if err != nil {
+		logger.Logger.Errorf("Error checking magic link throttle: %v", err) // 記錄錯誤
+		return
+	}
+	if throttled {
+		logger.Logger.Debugf("Magic link email to user %d throttled", user.ID) // 記錄略過
+		return
+	}
+
+	if err := svc.sendMagicLinkEmail(user); err != nil {
+		logger.Logger.Errorf("Error sending magic link email to user %d: %v", user.ID, err) // 記錄錯誤
+	}
+}
+
+// sendMagicLinkEmail 產生新的登入連結 token 並寄送登入信
+func (svc *ServiceDefault) sendMagicLinkEmail(user *models.User) error {
+	token, expiresAt, err := svc.issueUserToken(user.ID, models.UserTokenPurposeMagicLink, svc.cfg.MagicLinkExpiresIn)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/login/magic-link?token=%s", svc.cfg.AppBaseURL, url.QueryEscape(token))
+	return svc.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Your sign-in link",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Open the link below to sign in:\n\n%s\n\n"+
+			"The link expires at %s and can only be used once. "+
+			"If you did not request a sign-in link, you can ignore this email.\n",
+			user.Username, link, expiresAt.UTC().Format(time.RFC1123)),
+	})
+}
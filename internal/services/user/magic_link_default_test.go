@@ -0,0 +1,140 @@
+package user
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-template/internal/models"
+	"go-template/internal/services/throttle"
+	"go-template/internal/utils/jwt"
+)
+
+// newMagicLinkTestService 建立開啟登入連結功能的測試服務
+func newMagicLinkTestService(t *testing.T) (*ServiceDefault, sqlmock.Sqlmock) {
+	svc, mock := newTestService(t)
+	svc.cfg.MagicLinkEnabled = true
+	svc.cfg.MagicLinkExpiresIn = 15 * time.Minute
+	svc.cfg.MagicLinkResendInterval = time.Minute
+	return svc, mock
+}
+
+// expectCompleteLogin 預期沒有啟用兩步驟驗證的使用者完成登入：更新最後登入時間並建立工作階段
+func expectCompleteLogin(mock sqlmock.Sqlmock, userID uint, roles ...string) {
+	mock.ExpectQuery(`SELECT \* FROM "user_mfa" WHERE user_id = \$1`).WithArgs(userID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	expectFinishLogin(mock, userID, roles...)
+}
+
+// expectFinishLogin 預期所有驗證都通過之後更新最後登入時間並建立工作階段
+func expectFinishLogin(mock sqlmock.Sqlmock, userID uint, roles ...string) {
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "users" SET "last_login"=\$1,"updated_at"=\$2 WHERE id = \$3`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), userID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectStartSession(mock, userID, roles...)
+}
+
+// 測試 RequestMagicLink 寄送包含登入連結的信件
+func TestRequestMagicLink(t *testing.T) {
+	svc, mock := newMagicLinkTestService(t)
+
+	expectUserByEmail(mock, testUser(7, "alice", models.UserStatusActive))
+	expectUserTokenThrottle(mock, 7, models.UserTokenPurposeMagicLink, time.Time{})
+	expectIssueUserToken(mock, 7, models.UserTokenPurposeMagicLink)
+
+	require.NoError(t, svc.RequestMagicLink("alice@example.com", ClientInfo{IP: "203.0.113.7"}))
+	svc.background.Wait()
+	messages := sentMessages(svc)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "alice@example.com", messages[0].To)
+	assert.Contains(t, messages[0].Body, "https://app.example.com/login/magic-link?token=")
+}
+
+// 測試 RequestMagicLink 不等待登入信寄出就返回，信件在背景寄送
+func TestRequestMagicLinkSendsInBackground(t *testing.T) {
+	svc, mock := newMagicLinkTestService(t)
+	mail := newBlockingMailer(t)
+	svc.mailer = mail
+
+	expectUserByEmail(mock, testUser(7, "alice", models.UserStatusActive))
+	expectUserTokenThrottle(mock, 7, models.UserTokenPurposeMagicLink, time.Time{})
+	expectIssueUserToken(mock, 7, models.UserTokenPurposeMagicLink)
+
+	returned := make(chan error, 1)
+	go func() { returned <- svc.RequestMagicLink("alice@example.com", ClientInfo{IP: "203.0.113.7"}) }()
+	select {
+	case err := <-returned:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("RequestMagicLink waited for the email to be sent")
+	}
+
+	close(mail.release)
+	svc.background.Wait()
+	assert.Len(t, mail.outbox.Messages(), 1)
+}
+
+// 測試不能登入的帳號或沒有開啟功能時不寄送登入信，帳號不存在或無法登入時請求一樣不回傳錯誤
+func TestRequestMagicLinkNotSent(t *testing.T) {
+	svc, mock := newMagicLinkTestService(t)
+
+	expectUserByEmail(mock, nil)
+	require.NoError(t, svc.RequestMagicLink("nobody@example.com", ClientInfo{}))
+	svc.background.Wait()
+
+	expectUserByEmail(mock, testUser(7, "alice", models.UserStatusSuspended))
+	require.NoError(t, svc.RequestMagicLink("alice@example.com", ClientInfo{}))
+	svc.background.Wait()
+
+	svc.cfg.MagicLinkEnabled = false
+	assert.ErrorIs(t, svc.RequestMagicLink("alice@example.com", ClientInfo{}), ErrMagicLinkDisabled)
+
+	assert.Empty(t, sentMessages(svc))
+}
+
+// 測試使用登入連結登入，token 只能使用一次
+func TestLoginMagicLink(t *testing.T) {
+	svc, mock := newMagicLinkTestService(t)
+
+	expectLookupUserToken(mock, models.UserTokenPurposeMagicLink, "magic-token", 7, nil)
+	expectUserByID(mock, 7, true)
+	expectMarkUserTokenUsed(mock, true)
+	expectCompleteLogin(mock, 7, "user")
+
+	result, err := svc.LoginMagicLink("magic-token", ClientInfo{IP: "203.0.113.7"})
+	require.NoError(t, err)
+	require.False(t, result.MFARequired)
+	claims, err := svc.jwtService.ValidateToken(result.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, uint(7), claims.UserID)
+	assert.Equal(t, []string{jwt.AuthMethodMagicLink}, claims.AuthMethods)
+
+	usedAt := time.Now()
+	expectLookupUserToken(mock, models.UserTokenPurposeMagicLink, "magic-token", 7, &usedAt)
+	_, err = svc.LoginMagicLink("magic-token", ClientInfo{})
+	assert.ErrorIs(t, err, ErrInvalidMagicLink)
+}
+
+// 測試帳號狀態不允許登入時，使用登入連結不會清除使用者名稱的失敗紀錄
+func TestLoginMagicLinkRefusedKeepsFailures(t *testing.T) {
+	svc, mock := newMagicLinkTestService(t)
+	store := throttle.NewMemoryStore()
+	svc.loginLimiter = throttle.NewLimiter(svc.cfg, store)
+	require.NoError(t, svc.loginLimiter.Attempt("alice", ""))
+
+	expectLookupUserToken(mock, models.UserTokenPurposeMagicLink, "magic-token", 7, nil)
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"."id" = \$1`).WithArgs(7, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "status"}).AddRow(7, "alice", models.UserStatusSuspended))
+	expectMarkUserTokenUsed(mock, true)
+
+	_, err := svc.LoginMagicLink("magic-token", ClientInfo{})
+	require.Error(t, err)
+
+	record, err := store.Get(throttle.UsernameKey("alice"))
+	require.NoError(t, err)
+	require.NotNil(t, record)
+	assert.Equal(t, 1, record.Failures)
+}
@@ -0,0 +1,284 @@
+package user
+
+import (
+	"errors"
+	"time"
+
+	"go-template/internal/models"
+	"go-template/internal/utils/jwt"
+	"go-template/internal/utils/logger"
+	"go-template/internal/utils/totp"
+
+	"gorm.io/gorm"
+)
+
+// recoveryCodeCount 每次產生的復原碼數量
+const recoveryCodeCount = 10
+
+// totpSkew 驗證 TOTP 時允許前後誤差的 time step 數量，用來容忍使用者裝置的時間誤差
+const totpSkew = 1
+
+// LoginMFA 使用 Login 或 LoginMagicLink 回傳的 MFA token 與 TOTP 驗證碼 (或復原碼) 完成登入
+// MFA token 只能使用一次，驗證碼錯誤時也會失效，必須重新登入，避免驗證碼被暴力猜測
+// @param mfaToken body string true "Login 或 LoginMagicLink 回傳的 MFA token"
+// @param code body string true "TOTP 驗證碼或復原碼"
+// @param client header ClientInfo false "來源 IP 與 User-Agent"
+// @return tokens access token 與 refresh token
+// @return error 錯誤訊息，失敗次數過多時為 *throttle.ThrottledError
+func (svc *ServiceDefault) LoginMFA(mfaToken, code string, client ClientInfo) (*TokenPair, error) {
+	stored, err := svc.consumeUserToken(models.UserTokenPurposeMFAPending, mfaToken)
+	if err == errUserTokenInvalid {
+		return nil, ErrInvalidMFAToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 密碼驗證之後帳號狀態可能已經改變，重新檢查
+	user, err := svc.userRepo.GetByID(stored.UserID)
+	if err != nil {
+		logger.Logger.Debugf("Error getting user of MFA token: %v", err) // 記錄錯誤
+		return nil, ErrInvalidMFAToken
+	}
+	if err := checkLoginAllowed(user); err != nil {
+		logger.Logger.Debugf("Login refused for user %d: %v", user.ID, err) // 記錄錯誤
+		return nil, err
+	}
+
+	// 與密碼相同，驗證碼 (包含復原碼) 的嘗試也以 throttle.Limiter 計數，先記為一次失敗再驗證
+	if err := svc.loginLimiter.Attempt(user.Username, client.IP); err != nil {
+		logger.Logger.Debugf("MFA login throttled for user %s from %s: %v", user.Username, client.IP, err) // 記錄錯誤
+		return nil, err
+	}
+
+	if err := svc.verifyMFACode(user.ID, code); err != nil {
+		switch err {
+		case ErrMFANotEnabled:
+			// 兩步驟驗證在登入期間被停用，要求重新登入；沒有驗證任何驗證碼，歸還這次嘗試
+			if releaseErr := svc.loginLimiter.Release(user.Username, client.IP); releaseErr != nil {
+				logger.Logger.Warnf("Error releasing MFA login attempt: %v", releaseErr) // 記錄錯誤
+			}
+			return nil, ErrInvalidMFAToken
+		case ErrInvalidMFACode:
+			return nil, ErrMFALoginFailed
+		}
+		return nil, err
+	}
+
+	// 沿用第一個步驟通過的驗證方式，此功能之前發行的 MFA token 沒有記錄，視為密碼登入
+	authMethods := stored.AuthMethods
+	if len(authMethods) == 0 {
+		authMethods = []string{jwt.AuthMethodPassword}
+	}
+	tokens, err := svc.completeLogin(user, client, append(authMethods, jwt.AuthMethodMFA))
+	if err != nil {
+		return nil, err
+	}
+
+	// 所有驗證都通過，完成登入之後才清除失敗紀錄
+	if err := svc.loginLimiter.RecordSuccess(user.Username, client.IP); err != nil {
+		logger.Logger.Warnf("Error clearing failed login attempts: %v", err) // 記錄錯誤
+	}
+	return tokens, nil
+}
+
+// EnrollTOTP 開始設定 TOTP 兩步驟驗證，產生新的共享密鑰
+// 必須再使用 ConfirmTOTP 確認之後才會啟用；重複呼叫會取代尚未確認的設定
+// @param userID path uint true "使用者 ID"
+// @return enrollment 共享密鑰與 provisioning URI
+// @return error 錯誤訊息
+func (svc *ServiceDefault) EnrollTOTP(userID uint) (*TOTPEnrollment, error) {
+	user, err := svc.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, translateNotFound(err)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	created, err := svc.mfaRepo.ReplacePending(&models.UserMFA{UserID: userID, Secret: secret})
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	uri := totp.ProvisioningURI(svc.cfg.MFAIssuer, user.Username, secret)
+	logger.Logger.Infof("TOTP enrollment started for user: %d", userID) // 記錄開始設定
+	return &TOTPEnrollment{Secret: secret, ProvisioningURI: uri, QRPayload: uri}, nil
+}
+
+// ConfirmTOTP 使用驗證器 App 產生的驗證碼確認設定並啟用兩步驟驗證
+// 啟用後回傳復原碼，復原碼只會回傳這一次
+// @param userID path uint true "使用者 ID"
+// @param code body string true "TOTP 驗證碼"
+// @return recoveryCodes 復原碼
+// @return error 錯誤訊息
+func (svc *ServiceDefault) ConfirmTOTP(userID uint, code string) ([]string, error) {
+	mfa, err := svc.mfaRepo.GetByUserID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMFANotEnabled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if mfa.Enabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	step, ok := totp.Validate(mfa.Secret, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	confirmed, err := svc.mfaRepo.Confirm(userID, step, hashes)
+	if err != nil {
+		return nil, err
+	}
+	if !confirmed {
+		// 同時有另一個請求完成確認
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	logger.Logger.Infof("MFA enabled for user: %d", userID) // 記錄已啟用兩步驟驗證
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes 產生新的復原碼，之前的復原碼全部失效
+// @param userID path uint true "使用者 ID"
+// @param code body string true "TOTP 驗證碼"
+// @return recoveryCodes 新的復原碼
+// @return error 錯誤訊息
+func (svc *ServiceDefault) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	if err := svc.verifyTOTP(userID, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.mfaRepo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+
+	logger.Logger.Infof("Recovery codes regenerated for user: %d", userID) // 記錄已產生新的復原碼
+	return codes, nil
+}
+
+// DisableMFA 停用兩步驟驗證，必須提供目前的密碼
+// @param userID path uint true "使用者 ID"
+// @param password body string true "目前的密碼"
+// @return error 錯誤訊息
+func (svc *ServiceDefault) DisableMFA(userID uint, password string) error {
+	user, err := svc.userRepo.GetByID(userID)
+	if err != nil {
+		return translateNotFound(err)
+	}
+	if !svc.verifyPassword(user, password) {
+		logger.Logger.Debugf("Invalid password when disabling MFA for user: %d", userID) // 記錄錯誤
+		return ErrInvalidCurrentPassword
+	}
+
+	if _, err := svc.mfaRepo.GetByUserID(userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrMFANotEnabled
+		}
+		return err
+	}
+	if err := svc.mfaRepo.DeleteByUser(userID); err != nil {
+		return err
+	}
+
+	logger.Logger.Infof("MFA disabled for user: %d", userID) // 記錄已停用兩步驟驗證
+	return nil
+}
+
+// verifyMFACode 驗證 TOTP 驗證碼或復原碼，6 位數字視為 TOTP 驗證碼，其他格式視為復原碼
+func (svc *ServiceDefault) verifyMFACode(userID uint, code string) error {
+	if isTOTPCode(code) {
+		return svc.verifyTOTP(userID, code)
+	}
+
+	mfa, err := svc.mfaRepo.GetByUserID(userID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if !mfa.Enabled() {
+		return ErrMFANotEnabled
+	}
+
+	used, err := svc.mfaRepo.UseRecoveryCode(userID, jwt.HashOpaqueToken(totp.NormalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		logger.Logger.Debugf("Invalid recovery code for user: %d", userID) // 記錄錯誤
+		return ErrInvalidMFACode
+	}
+
+	remaining, err := svc.mfaRepo.CountRemainingRecoveryCodes(userID)
+	if err == nil {
+		logger.Logger.Infof("Recovery code used for user %d, %d remaining", userID, remaining) // 記錄已使用復原碼
+	}
+	return nil
+}
+
+// verifyTOTP 驗證已啟用的 TOTP 驗證碼，同一個 time step 的驗證碼只能使用一次
+func (svc *ServiceDefault) verifyTOTP(userID uint, code string) error {
+	mfa, err := svc.mfaRepo.GetByUserID(userID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if !mfa.Enabled() {
+		return ErrMFANotEnabled
+	}
+
+	step, ok := totp.Validate(mfa.Secret, code, time.Now(), totpSkew)
+	if !ok {
+		logger.Logger.Debugf("Invalid TOTP code for user: %d", userID) // 記錄錯誤
+		return ErrInvalidMFACode
+	}
+	used, err := svc.mfaRepo.UseStep(userID, step)
+	if err != nil {
+		return err
+	}
+	if !used {
+		logger.Logger.Debugf("TOTP code replayed for user: %d", userID) // 記錄錯誤
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// isTOTPCode 判斷輸入是否為 TOTP 驗證碼的格式
+func isTOTPCode(code string) bool {
+	if len(code) != totp.Digits {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// generateRecoveryCodes 產生新的復原碼，回傳要交給使用者的明文復原碼以及要儲存的雜湊值
+// 復原碼具備足夠的隨機性，與其他不透明 token 一樣使用 SHA-256 雜湊
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes, err := totp.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, jwt.HashOpaqueToken(totp.NormalizeRecoveryCode(code)))
+	}
+	return codes, hashes, nil
+}
@@ -0,0 +1,234 @@
+package user
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-template/internal/models"
+	"go-template/internal/services/throttle"
+	"go-template/internal/utils/jwt"
+	"go-template/internal/utils/totp"
+)
+
+// expectMFA 預期查詢使用者的兩步驟驗證設定，confirmed 為 false 代表尚未確認
+func expectMFA(mock sqlmock.Sqlmock, userID uint, secret string, confirmed bool) {
+	var confirmedAt *time.Time
+	if confirmed {
+		now := time.Now()
+		confirmedAt = &now
+	}
+	mock.ExpectQuery(`SELECT \* FROM "user_mfa" WHERE user_id = \$1`).WithArgs(userID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "secret", "confirmed_at", "last_used_step"}).
+			AddRow(1, userID, secret, confirmedAt, 0))
+}
+
+// expectConsumeMFAToken 預期使用 Login 回傳的 MFA token 並查詢 token 所屬的使用者
+func expectConsumeMFAToken(mock sqlmock.Sqlmock, token string, userID uint) {
+	expectLookupUserToken(mock, models.UserTokenPurposeMFAPending, token, userID, nil)
+	expectMarkUserTokenUsed(mock, true)
+	expectUserByID(mock, userID, true)
+}
+
+// expectUseStep 預期記錄使用過的 time step，used 為 false 代表這個 time step 已經使用過
+func expectUseStep(mock sqlmock.Sqlmock, userID uint, used bool) {
+	rowsAffected := int64(0)
+	if used {
+		rowsAffected = 1
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "user_mfa" SET "last_used_step"=\$1,"updated_at"=\$2 WHERE user_id = \$3 AND confirmed_at IS NOT NULL AND last_used_step < \$4`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), userID, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, rowsAffected))
+	mock.ExpectCommit()
+}
+
+// expectUseRecoveryCode 預期使用一組復原碼，used 為 false 代表復原碼不存在或已經使用過
+func expectUseRecoveryCode(mock sqlmock.Sqlmock, userID uint, code string, used bool) {
+	rowsAffected := int64(0)
+	if used {
+		rowsAffected = 1
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "mfa_recovery_codes" SET "used_at"=\$1 WHERE user_id = \$2 AND code_hash = \$3 AND used_at IS NULL`).
+		WithArgs(sqlmock.AnyArg(), userID, jwt.HashOpaqueToken(totp.NormalizeRecoveryCode(code))).
+		WillReturnResult(sqlmock.NewResult(0, rowsAffected))
+	mock.ExpectCommit()
+	if used {
+		mock.ExpectQuery(`SELECT count\(\*\) FROM "mfa_recovery_codes" WHERE user_id = \$1 AND used_at IS NULL`).
+			WithArgs(userID).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(9))
+	}
+}
+
+// newTOTPSecret 產生新的共享密鑰與目前的驗證碼
+func newTOTPSecret(t *testing.T) (string, string) {
+	t.Helper()
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	code, err := totp.GenerateCode(secret, time.Now())
+	require.NoError(t, err)
+	return secret, code
+}
+
+// 測試以驗證器 App 的驗證碼確認設定後啟用兩步驟驗證，並回傳復原碼
+func TestConfirmTOTP(t *testing.T) {
+	svc, mock := newTestService(t)
+	secret, code := newTOTPSecret(t)
+
+	expectMFA(mock, 7, secret, false)
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "user_mfa" SET "confirmed_at"=\$1,"last_used_step"=\$2,"updated_at"=\$3 WHERE user_id = \$4 AND confirmed_at IS NULL`).
+		WithArgs(sqlmock.AnyArg(), totp.Step(time.Now()), sqlmock.AnyArg(), 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM "mfa_recovery_codes" WHERE user_id = \$1`).WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	rows := sqlmock.NewRows([]string{"id"})
+	for i := 1; i <= recoveryCodeCount; i++ {
+		rows.AddRow(i)
+	}
+	mock.ExpectQuery(`INSERT INTO "mfa_recovery_codes"`).WillReturnRows(rows)
+	mock.ExpectCommit()
+
+	codes, err := svc.ConfirmTOTP(7, code)
+	require.NoError(t, err)
+	assert.Len(t, codes, recoveryCodeCount)
+}
+
+// 測試驗證碼錯誤、已經啟用或尚未開始設定時不啟用兩步驟驗證
+func TestConfirmTOTPRejected(t *testing.T) {
+	svc, mock := newTestService(t)
+	secret, code := newTOTPSecret(t)
+
+	expectMFA(mock, 7, secret, false)
+	_, err := svc.ConfirmTOTP(7, "000000")
+	assert.ErrorIs(t, err, ErrInvalidMFACode)
+
+	expectMFA(mock, 7, secret, true)
+	_, err = svc.ConfirmTOTP(7, code)
+	assert.ErrorIs(t, err, ErrMFAAlreadyEnabled)
+
+	mock.ExpectQuery(`SELECT \* FROM "user_mfa" WHERE user_id = \$1`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	_, err = svc.ConfirmTOTP(7, code)
+	assert.ErrorIs(t, err, ErrMFANotEnabled)
+}
+
+// 測試使用 TOTP 驗證碼完成兩步驟驗證登入，MFA token 只能使用一次
+func TestLoginMFA(t *testing.T) {
+	svc, mock := newTestService(t)
+	secret, code := newTOTPSecret(t)
+
+	expectConsumeMFAToken(mock, "mfa-token", 7)
+	expectMFA(mock, 7, secret, true)
+	expectUseStep(mock, 7, true)
+	expectFinishLogin(mock, 7, "user")
+
+	tokens, err := svc.LoginMFA("mfa-token", code, ClientInfo{IP: "203.0.113.7"})
+	require.NoError(t, err)
+	claims, err := svc.jwtService.ValidateToken(tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, uint(7), claims.UserID)
+	assert.Equal(t, []string{jwt.AuthMethodPassword, jwt.AuthMethodMFA}, claims.AuthMethods)
+
+	usedAt := time.Now()
+	expectLookupUserToken(mock, models.UserTokenPurposeMFAPending, "mfa-token", 7, &usedAt)
+	_, err = svc.LoginMFA("mfa-token", code, ClientInfo{})
+	assert.ErrorIs(t, err, ErrInvalidMFAToken)
+}
+
+// 測試同一個 time step 的 TOTP 驗證碼不能再次用來登入
+func TestLoginMFAReplayedStep(t *testing.T) {
+	svc, mock := newTestService(t)
+	secret, code := newTOTPSecret(t)
+
+	expectConsumeMFAToken(mock, "mfa-token", 7)
+	expectMFA(mock, 7, secret, true)
+	expectUseStep(mock, 7, false)
+
+	_, err := svc.LoginMFA("mfa-token", code, ClientInfo{})
+	assert.ErrorIs(t, err, ErrMFALoginFailed)
+}
+
+// 測試錯誤的 TOTP 驗證碼不能登入
+func TestLoginMFAInvalidCode(t *testing.T) {
+	svc, mock := newTestService(t)
+	secret, code := newTOTPSecret(t)
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+
+	expectConsumeMFAToken(mock, "mfa-token", 7)
+	expectMFA(mock, 7, secret, true)
+
+	_, err := svc.LoginMFA("mfa-token", wrong, ClientInfo{})
+	assert.ErrorIs(t, err, ErrMFALoginFailed)
+}
+
+// 測試使用復原碼完成兩步驟驗證登入，每組復原碼只能使用一次
+func TestLoginMFARecoveryCode(t *testing.T) {
+	svc, mock := newTestService(t)
+	secret, _ := newTOTPSecret(t)
+
+	expectConsumeMFAToken(mock, "mfa-token", 7)
+	expectMFA(mock, 7, secret, true)
+	expectUseRecoveryCode(mock, 7, "abcde-12345", true)
+	expectFinishLogin(mock, 7, "user")
+
+	_, err := svc.LoginMFA("mfa-token", "ABCDE-12345", ClientInfo{})
+	require.NoError(t, err)
+
+	expectConsumeMFAToken(mock, "mfa-token-2", 7)
+	expectMFA(mock, 7, secret, true)
+	expectUseRecoveryCode(mock, 7, "abcde-12345", false)
+
+	_, err = svc.LoginMFA("mfa-token-2", "abcde-12345", ClientInfo{})
+	assert.ErrorIs(t, err, ErrMFALoginFailed)
+}
+
+// 測試需要兩步驟驗證的密碼登入不會清除失敗紀錄，錯誤的驗證碼計入失敗次數，完成兩步驟驗證之後才清除
+func TestLoginMFAThrottled(t *testing.T) {
+	svc, mock := newTestService(t)
+	svc.cfg.LoginAttemptWindow = time.Hour
+	store := throttle.NewMemoryStore()
+	svc.loginLimiter = throttle.NewLimiter(svc.cfg, store)
+	secret, code := newTOTPSecret(t)
+	hashedPassword, err := svc.hashPassword("password")
+	require.NoError(t, err)
+	usernameFailures := func() int {
+		record, err := store.Get(throttle.UsernameKey("alice"))
+		require.NoError(t, err)
+		if record == nil {
+			return 0
+		}
+		return record.Failures
+	}
+
+	// 之前失敗過一次
+	require.NoError(t, svc.loginLimiter.Attempt("alice", ""))
+
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE username_key = \$1`).WithArgs("alice", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password"}).AddRow(7, "alice", hashedPassword))
+	expectMFA(mock, 7, secret, true)
+	expectIssueUserToken(mock, 7, models.UserTokenPurposeMFAPending)
+	result, err := svc.Login("alice", "password", ClientInfo{IP: "203.0.113.7"})
+	require.NoError(t, err)
+	require.True(t, result.MFARequired)
+	assert.Equal(t, 1, usernameFailures(), "a correct password alone does not clear earlier failures")
+
+	expectConsumeMFAToken(mock, "mfa-token", 7)
+	expectMFA(mock, 7, secret, true)
+	expectUseRecoveryCode(mock, 7, "wrong-code", false)
+	_, err = svc.LoginMFA("mfa-token", "wrong-code", ClientInfo{IP: "203.0.113.7"})
+	assert.ErrorIs(t, err, ErrMFALoginFailed)
+	assert.Equal(t, 2, usernameFailures(), "a wrong MFA code counts as a failed login")
+
+	expectConsumeMFAToken(mock, "mfa-token-2", 7)
+	expectMFA(mock, 7, secret, true)
+	expectUseStep(mock, 7, true)
+	expectFinishLogin(mock, 7, "user")
+	_, err = svc.LoginMFA("mfa-token-2", code, ClientInfo{IP: "203.0.113.7"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, usernameFailures())
+}
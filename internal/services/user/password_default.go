@@ -0,0 +1,178 @@
+package user
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"go-template/internal/models"
+	"go-template/internal/utils/jwt"
+	"go-template/internal/utils/logger"
+	"go-template/internal/utils/mailer"
+)
+
+// ForgotPassword 寄送重設密碼信，使用者之前的重設密碼 token 會失效
+// 為了避免洩漏帳號是否存在，找不到使用者、帳號無法重設密碼、寄送過於頻繁或寄送失敗時都不會回傳錯誤；
+// 找到使用者之後的檢查與寄送都在背景執行，回應時間與找不到使用者時相同
+// @param email body string true "電子郵件"
+// @return error 錯誤訊息
+func (svc *ServiceDefault) ForgotPassword(email string) error {
+	user, err := svc.userRepo.GetByEmail(email)
+	if err != nil {
+		logger.Logger.Debugf("Password reset not sent: no user with email %s", email) // 記錄略過
+		return nil
+	}
+
+	svc.inBackground(func() { svc.deliverPasswordReset(user) })
+	return nil
+}
+
+// ResetPassword 使用重設密碼信中的 token 設定新的密碼
+// 每個 token 只能使用一次；重設成功後會登出該使用者所有的裝置
+// 新密碼不符合密碼規則時回傳 *validators.PasswordPolicyError，token 不會被使用，使用者可以換一個密碼再試
+// @param token body string true "重設密碼 token"
+// @param newPassword body string true "新的密碼"
+// @return error 錯誤訊息
+func (svc *ServiceDefault) ResetPassword(token, newPassword string) error {
+	stored, err := svc.lookupUserToken(models.UserTokenPurposePasswordReset, token)
+	if err == errUserTokenInvalid {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+
+	// 檢查密碼規則需要使用者的帳號名稱與電子郵件
+	user, err := svc.userRepo.GetByID(stored.UserID)
+	if err != nil {
+		return translateNotFound(err)
+	}
+	if err := svc.passwordPolicy.Validate(newPassword, user.Username, user.Email); err != nil {
+		return err
+	}
+
+	if err := svc.markUserTokenUsed(stored); err == errUserTokenInvalid {
+		return ErrInvalidResetToken
+	} else if err != nil {
+		return err
+	}
+
+	hashedPassword, err := svc.hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	if err := svc.userRepo.UpdateColumns(stored.UserID, map[string]interface{}{
+		"password":                hashedPassword,
+		"password_reset_required": false,
+	}); err != nil {
+		return translateNotFound(err)
+	}
+
+	// 密碼可能已經外洩，讓所有已發行的 token 失效
+	if err := svc.LogoutAll(stored.UserID); err != nil {
+		return err
+	}
+
+	logger.Logger.Infof("Password reset for user: %d", stored.UserID) // 記錄密碼已重設
+	return nil
+}
+
+// ChangePassword 已登入的使用者變更自己的密碼，必須提供目前的密碼
+// 變更成功後其他裝置都會被登出，並為目前的裝置發行新的 token 組合
+// 新密碼不符合密碼規則時回傳 *validators.PasswordPolicyError
+// @param userID path uint true "使用者 ID"
+// @param currentPassword body string true "目前的密碼"
+// @param newPassword body string true "新的密碼"
+// @param client header ClientInfo false "目前裝置的來源 IP 與 User-Agent"
+// @return tokens 目前裝置使用的新 access token 與 refresh token
+// @return error 錯誤訊息
+func (svc *ServiceDefault) ChangePassword(userID uint, currentPassword, newPassword string, client ClientInfo) (*TokenPair, error) {
+	user, err := svc.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, translateNotFound(err)
+	}
+
+	// 驗證目前的密碼是否正確
+	if !svc.verifyPassword(user, currentPassword) {
+		logger.Logger.Debugf("Invalid current password for user: %d", userID) // 記錄錯誤
+		return nil, ErrInvalidCurrentPassword
+	}
+	if currentPassword == newPassword {
+		return nil, ErrPasswordUnchanged
+	}
+	if err := svc.passwordPolicy.Validate(newPassword, user.Username, user.Email); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := svc.hashPassword(newPassword)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.userRepo.UpdateColumns(userID, map[string]interface{}{
+		"password":                hashedPassword,
+		"password_reset_required": false,
+	}); err != nil {
+		return nil, translateNotFound(err)
+	}
+
+	// 讓所有已發行的 token 與工作階段失效，再為目前的裝置建立新的工作階段
+	// 撤銷時間精確到毫秒 (與 iat_ms 相同的精度)，LogoutAll 返回時已經過了撤銷的那一毫秒，接著發行的 access token 不會被一併視為無效
+	if err := svc.LogoutAll(userID); err != nil {
+		return nil, err
+	}
+
+	// 變更密碼時已經重新輸入目前的密碼，新的工作階段視為以密碼登入
+	tokens, err := svc.startSession(userID, client, []string{jwt.AuthMethodPassword})
+	if err != nil {
+		logger.Logger.Errorf("Error generating token: %v", err) // 記錄錯誤
+		return nil, err
+	}
+
+	logger.Logger.Infof("Password changed for user: %d", userID) // 記錄密碼已變更
+	return tokens, nil
+}
+
+// sendPasswordResetEmail 產生新的重設密碼 token 並寄送重設密碼信
+func (svc *ServiceDefault) sendPasswordResetEmail(user *models.User) error {
+	token, expiresAt, err := svc.issueUserToken(user.ID, models.UserTokenPurposePasswordReset,
+		svc.cfg.PasswordResetExpiresIn)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", svc.cfg.AppBaseURL, url.QueryEscape(token))
+	return svc.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"We received a request to reset your password. Open the link below to choose a new password:\n\n%s\n\n"+
+			"Or submit this reset code: %s\n\n"+
+			"The link expires at %s and can only be used once. "+
+			"If you did not request a password reset, you can ignore this email.\n",
+			user.Username, link, token, expiresAt.UTC().Format(time.RFC1123)),
+	})
+}
+
+// deliverPasswordReset 檢查使用者是否可以重設密碼，可以時寄送重設密碼信，錯誤只會記錄
+func (svc *ServiceDefault) deliverPasswordReset(user *models.User) {
+	// 停權或停用的帳號不能透過重設密碼重新取得存取權，服務帳號不使用密碼
+	if user.Status == models.UserStatusSuspended || user.Status == models.UserStatusDeactivated || user.ServiceAccount {
+		logger.Logger.Debugf("Password reset not sent: user %d is %s", user.ID, user.Status) // 記錄略過
+		return
+	}
+
+	throttled, err := svc.userTokenThrottled(user.ID, models.UserTokenPurposePasswordReset,
+		svc.cfg.PasswordResetResendInterval)
+	if err != nil {
+		logger.Logger.Errorf("Error checking password reset throttle: %v", err) // 記錄錯誤
+		return
+	}
+	if throttled {
+		logger.Logger.Debugf("Password reset email to user %d throttled", user.ID) // 記錄略過
+		return
+	}
+
+	if err := svc.sendPasswordResetEmail(user); err != nil {
+		logger.Logger.Errorf("Error sending password reset email to user %d: %v", user.ID, err) // 記錄錯誤
+	}
+}
@@ -0,0 +1,235 @@
+package user
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-template/internal/configs"
+	"go-template/internal/models"
+	"go-template/internal/services/revocation"
+	"go-template/internal/utils/jwt"
+	"go-template/internal/utils/mailer"
+	"go-template/internal/validators"
+)
+
+// blockingMailer 寄送信件時等待 release 被關閉，用來確認請求不會等待寄送完成
+type blockingMailer struct {
+	release chan struct{}
+	outbox  *mailer.Outbox
+}
+
+func newBlockingMailer(t *testing.T) *blockingMailer {
+	outbox, err := mailer.NewOutbox("", "noreply@example.com")
+	require.NoError(t, err)
+	return &blockingMailer{release: make(chan struct{}), outbox: outbox}
+}
+
+func (m *blockingMailer) Send(msg mailer.Message) error {
+	<-m.release
+	return m.outbox.Send(msg)
+}
+
+// expectUserByEmail 預期以電子郵件查詢使用者，user 為 nil 時代表找不到
+func expectUserByEmail(mock sqlmock.Sqlmock, user *models.User) {
+	rows := sqlmock.NewRows([]string{"id", "username", "email", "status"})
+	if user != nil {
+		rows.AddRow(user.ID, user.Username, user.Email, user.Status)
+	}
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE LOWER\(email\) = LOWER\(\$1\)`).WillReturnRows(rows)
+}
+
+// expectUserTokenThrottle 預期查詢使用者最近一次寄送 purpose 用途 token 的時間，latest 為零值代表沒有寄送過
+func expectUserTokenThrottle(mock sqlmock.Sqlmock, userID uint, purpose string, latest time.Time) {
+	rows := sqlmock.NewRows([]string{"id", "created_at"})
+	if !latest.IsZero() {
+		rows.AddRow(1, latest)
+	}
+	mock.ExpectQuery(`SELECT \* FROM "user_tokens" WHERE user_id = \$1 AND purpose = \$2 ORDER BY created_at DESC LIMIT \$3`).
+		WithArgs(userID, purpose, 1).WillReturnRows(rows)
+}
+
+// expectIssueUserToken 預期讓使用者相同用途的舊 token 失效並儲存新的 token
+func expectIssueUserToken(mock sqlmock.Sqlmock, userID uint, purpose string) {
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "user_tokens" SET "used_at"=\$1 WHERE user_id = \$2 AND purpose = \$3 AND used_at IS NULL`).
+		WithArgs(sqlmock.AnyArg(), userID, purpose).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "user_tokens"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+}
+
+// expectLookupUserToken 預期查詢一次性 token，usedAt 不為 nil 代表已被使用
+func expectLookupUserToken(mock sqlmock.Sqlmock, purpose, token string, userID uint, usedAt *time.Time) {
+	mock.ExpectQuery(`SELECT \* FROM "user_tokens" WHERE purpose = \$1 AND token_hash = \$2`).
+		WithArgs(purpose, jwt.HashOpaqueToken(token), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "purpose", "expires_at", "used_at"}).
+			AddRow(3, userID, purpose, time.Now().Add(time.Hour), usedAt))
+}
+
+// expectMarkUserTokenUsed 預期標記一次性 token 為已使用，marked 為 false 代表被其他請求搶先使用
+func expectMarkUserTokenUsed(mock sqlmock.Sqlmock, marked bool) {
+	rowsAffected := int64(0)
+	if marked {
+		rowsAffected = 1
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "user_tokens" SET "used_at"=\$1 WHERE id = \$2 AND used_at IS NULL AND expires_at > \$3`).
+		WithArgs(sqlmock.AnyArg(), 3, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, rowsAffected))
+	mock.ExpectCommit()
+}
+
+// 測試 ForgotPassword 寄送包含重設密碼連結的信件
+func TestForgotPassword(t *testing.T) {
+	svc, mock := newTestService(t)
+
+	expectUserByEmail(mock, testUser(7, "alice", models.UserStatusActive))
+	expectUserTokenThrottle(mock, 7, models.UserTokenPurposePasswordReset, time.Time{})
+	expectIssueUserToken(mock, 7, models.UserTokenPurposePasswordReset)
+
+	require.NoError(t, svc.ForgotPassword("Alice@example.com"))
+	svc.background.Wait()
+	messages := sentMessages(svc)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "alice@example.com", messages[0].To)
+	assert.Contains(t, messages[0].Body, "https://app.example.com/reset-password?token=")
+}
+
+// 測試 ForgotPassword 不等待重設密碼信寄出就返回，信件在背景寄送
+func TestForgotPasswordSendsInBackground(t *testing.T) {
+	svc, mock := newTestService(t)
+	mail := newBlockingMailer(t)
+	svc.mailer = mail
+
+	expectUserByEmail(mock, testUser(7, "alice", models.UserStatusActive))
+	expectUserTokenThrottle(mock, 7, models.UserTokenPurposePasswordReset, time.Time{})
+	expectIssueUserToken(mock, 7, models.UserTokenPurposePasswordReset)
+
+	returned := make(chan error, 1)
+	go func() { returned <- svc.ForgotPassword("alice@example.com") }()
+	select {
+	case err := <-returned:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("ForgotPassword waited for the email to be sent")
+	}
+
+	close(mail.release)
+	svc.background.Wait()
+	assert.Len(t, mail.outbox.Messages(), 1)
+}
+
+// 測試找不到使用者、帳號被停權或寄送過於頻繁時不寄送信件，也不回傳錯誤
+func TestForgotPasswordNotSent(t *testing.T) {
+	svc, mock := newTestService(t)
+
+	expectUserByEmail(mock, nil)
+	require.NoError(t, svc.ForgotPassword("nobody@example.com"))
+	svc.background.Wait()
+
+	expectUserByEmail(mock, testUser(7, "alice", models.UserStatusSuspended))
+	require.NoError(t, svc.ForgotPassword("alice@example.com"))
+	svc.background.Wait()
+
+	expectUserByEmail(mock, testUser(8, "bob", models.UserStatusActive))
+	expectUserTokenThrottle(mock, 8, models.UserTokenPurposePasswordReset, time.Now().Add(-10*time.Second))
+	require.NoError(t, svc.ForgotPassword("bob@example.com"))
+	svc.background.Wait()
+
+	assert.Empty(t, sentMessages(svc))
+}
+
+// 測試重設密碼後登出該使用者所有的裝置，已使用或已過期的 token 回傳 ErrInvalidResetToken
+func TestResetPassword(t *testing.T) {
+	svc, mock := newTestService(t)
+
+	expectLookupUserToken(mock, models.UserTokenPurposePasswordReset, "reset-token", 7, nil)
+	expectUserByID(mock, 7, true)
+	expectMarkUserTokenUsed(mock, true)
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "users" SET "password"=\$1,"password_reset_required"=\$2,"updated_at"=\$3 WHERE id = \$4`).
+		WithArgs(sqlmock.AnyArg(), false, sqlmock.AnyArg(), 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectLogoutAll(mock, 7)
+	require.NoError(t, svc.ResetPassword("reset-token", "new-password"))
+	assert.True(t, loggedOut(t, svc, 7))
+
+	usedAt := time.Now()
+	expectLookupUserToken(mock, models.UserTokenPurposePasswordReset, "reset-token", 7, &usedAt)
+	assert.ErrorIs(t, svc.ResetPassword("reset-token", "new-password"), ErrInvalidResetToken)
+
+	// 驗證密碼規則之後才被其他請求搶先使用
+	expectLookupUserToken(mock, models.UserTokenPurposePasswordReset, "reset-token", 7, nil)
+	expectUserByID(mock, 7, true)
+	expectMarkUserTokenUsed(mock, false)
+	assert.ErrorIs(t, svc.ResetPassword("reset-token", "new-password"), ErrInvalidResetToken)
+
+	mock.ExpectQuery(`SELECT \* FROM "user_tokens"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	assert.ErrorIs(t, svc.ResetPassword("unknown", "new-password"), ErrInvalidResetToken)
+}
+
+// 測試新密碼不符合密碼規則時不使用重設密碼 token，使用者可以換一個密碼再試
+func TestResetPasswordPolicyViolation(t *testing.T) {
+	svc, mock := newTestService(t)
+	policy, err := validators.NewPasswordPolicy(&configs.Config{PasswordMinLength: 12})
+	require.NoError(t, err)
+	svc.passwordPolicy = policy
+
+	expectLookupUserToken(mock, models.UserTokenPurposePasswordReset, "reset-token", 7, nil)
+	expectUserByID(mock, 7, true)
+	var policyErr *validators.PasswordPolicyError
+	assert.ErrorAs(t, svc.ResetPassword("reset-token", "short"), &policyErr)
+}
+
+// expectUserWithPassword 預期以 ID 查詢使用者，回傳 password 的雜湊值
+func expectUserWithPassword(t *testing.T, svc *ServiceDefault, mock sqlmock.Sqlmock, id uint, plain string) {
+	t.Helper()
+	hashedPassword, err := svc.hashPassword(plain)
+	require.NoError(t, err)
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"."id" = \$1`).WithArgs(id, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password"}).AddRow(id, "alice", hashedPassword))
+}
+
+// 測試變更密碼後登出所有的裝置，並為目前的裝置發行新的 token 組合
+func TestChangePassword(t *testing.T) {
+	svc, mock := newTestService(t)
+
+	expectUserWithPassword(t, svc, mock, 7, "old-password")
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "users" SET "password"=\$1,"password_reset_required"=\$2,"updated_at"=\$3 WHERE id = \$4`).
+		WithArgs(sqlmock.AnyArg(), false, sqlmock.AnyArg(), 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectLogoutAll(mock, 7)
+	expectStartSession(mock, 7, "user")
+
+	tokens, err := svc.ChangePassword(7, "old-password", "new-password", ClientInfo{})
+	require.NoError(t, err)
+	assert.True(t, loggedOut(t, svc, 7), "other devices are logged out")
+	claims, err := svc.jwtService.ValidateToken(tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, uint(7), claims.UserID)
+	assert.NotEmpty(t, claims.SessionID, "the current device gets a new session")
+	assert.NotEmpty(t, tokens.RefreshToken)
+
+	// 新的 token 即使與撤銷時間在同一秒內發行也仍然有效
+	validAfter, err := svc.revocationStore.TokensValidAfter(7)
+	require.NoError(t, err)
+	assert.False(t, revocation.IssuedBefore(claims.IssuedAt, validAfter), "iat %s, revoked at %s", claims.IssuedAt, validAfter)
+}
+
+// 測試目前的密碼錯誤或新的密碼與目前的密碼相同時不變更密碼
+func TestChangePasswordRejected(t *testing.T) {
+	svc, mock := newTestService(t)
+
+	expectUserWithPassword(t, svc, mock, 7, "old-password")
+	_, err := svc.ChangePassword(7, "wrong-password", "new-password", ClientInfo{})
+	assert.ErrorIs(t, err, ErrInvalidCurrentPassword)
+
+	expectUserWithPassword(t, svc, mock, 7, "old-password")
+	_, err = svc.ChangePassword(7, "old-password", "old-password", ClientInfo{})
+	assert.ErrorIs(t, err, ErrPasswordUnchanged)
+	assert.False(t, loggedOut(t, svc, 7))
+}
@@ -0,0 +1,103 @@
+package user
+
+import (
+	"time"
+
+	"go-template/internal/models"
+	"go-template/internal/services/revocation"
+	"go-template/internal/utils/jwt"
+	"go-template/internal/utils/logger"
+)
+
+// maxUserAgentLength 儲存的 User-Agent 最大長度，超過的部分會被截斷
+const maxUserAgentLength = 512
+
+// ListSessions 取得使用者目前有效的登入工作階段 (每個裝置一筆)
+// @param userID path uint true "使用者 ID"
+// @return []models.Session "工作階段列表"
+// @return error 錯誤訊息
+func (svc *ServiceDefault) ListSessions(userID uint) ([]models.Session, error) {
+	sessions, err := svc.sessionRepo.ListActiveByUser(userID)
+	if err != nil {
+		logger.Logger.Errorf("Error listing sessions: %v", err) // 記錄錯誤
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// RevokeSession 撤銷使用者的登入工作階段，該裝置的 access token 與 refresh token 立即失效
+// @param userID path uint true "使用者 ID"
+// @param sessionID path string true "工作階段 ID"
+// @return error 錯誤訊息，工作階段不存在、已撤銷或屬於其他使用者時為 ErrSessionNotFound
+func (svc *ServiceDefault) RevokeSession(userID uint, sessionID string) error {
+	// 先確認工作階段屬於該使用者，才撤銷對應的 token
+	revoked, err := svc.sessionRepo.Revoke(userID, sessionID)
+	if err != nil {
+		logger.Logger.Errorf("Error revoking session: %v", err) // 記錄錯誤
+		return err
+	}
+	if !revoked {
+		return ErrSessionNotFound
+	}
+	if err := svc.revokeSessionTokens(userID, sessionID); err != nil {
+		return err
+	}
+
+	logger.Logger.Infof("Session %s revoked for user: %d", sessionID, userID) // 記錄工作階段已撤銷
+	return nil
+}
+
+// startSession 建立新的登入工作階段並發行第一組 token
+// authMethods 為登入時使用的驗證方式，寫入之後換發的每一個 access token 的 amr claim
+func (svc *ServiceDefault) startSession(userID uint, client ClientInfo, authMethods []string) (*TokenPair, error) {
+	sessionID, err := jwt.NewTokenID()
+	if err != nil {
+		return nil, err
+	}
+
+	userAgent := client.UserAgent
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	now := time.Now()
+	session := &models.Session{
+		ID:          sessionID,
+		UserID:      userID,
+		UserAgent:   userAgent,
+		IP:          client.IP,
+		AuthMethods: authMethods,
+		CreatedAt:   now,
+		LastSeenAt:  now,
+		ExpiresAt:   now.Add(svc.jwtService.RefreshTokenExpiresIn()),
+	}
+	if err := svc.sessionRepo.Create(session); err != nil {
+		return nil, err
+	}
+
+	return svc.issueTokens(session)
+}
+
+// endSession 結束已知屬於該使用者的工作階段 (sid 來自已驗證的 token 或 refresh token)
+// 工作階段已撤銷或不存在 (此功能之前發行的 token) 時一樣會撤銷 refresh token 與 sid
+func (svc *ServiceDefault) endSession(userID uint, sessionID string) error {
+	if _, err := svc.sessionRepo.Revoke(userID, sessionID); err != nil {
+		logger.Logger.Errorf("Error revoking session: %v", err) // 記錄錯誤
+		return err
+	}
+	return svc.revokeSessionTokens(userID, sessionID)
+}
+
+// revokeSessionTokens 撤銷同一次登入的 refresh token，並將 sid 加入撤銷清單讓已發行的 access token 立即失效
+func (svc *ServiceDefault) revokeSessionTokens(userID uint, sessionID string) error {
+	if err := svc.refreshTokenRepo.RevokeFamily(sessionID); err != nil {
+		logger.Logger.Errorf("Error revoking refresh token family: %v", err) // 記錄錯誤
+		return err
+	}
+	// access token 最長只會存活 AccessTokenExpiresIn，之後就可以從撤銷清單中移除
+	expiresAt := time.Now().Add(svc.jwtService.AccessTokenExpiresIn())
+	if err := svc.revocationStore.Revoke(userID, revocation.SessionKey(sessionID), expiresAt); err != nil {
+		logger.Logger.Errorf("Error revoking access tokens of session: %v", err) // 記錄錯誤
+		return err
+	}
+	return nil
+}
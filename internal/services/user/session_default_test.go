@@ -0,0 +1,70 @@
+package user
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-template/internal/services/revocation"
+)
+
+// expectRevokeSession 預期撤銷使用者的工作階段，revoked 為 false 代表工作階段不存在、已撤銷或不屬於該使用者
+func expectRevokeSession(mock sqlmock.Sqlmock, userID uint, sessionID string, revoked bool) {
+	rowsAffected := int64(0)
+	if revoked {
+		rowsAffected = 1
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "sessions" SET "revoked_at"=\$1 WHERE id = \$2 AND user_id = \$3 AND revoked_at IS NULL`).
+		WithArgs(sqlmock.AnyArg(), sessionID, userID).WillReturnResult(sqlmock.NewResult(0, rowsAffected))
+	mock.ExpectCommit()
+}
+
+// expectStartSession 預期建立新的登入工作階段並發行第一組 token
+func expectStartSession(mock sqlmock.Sqlmock, userID uint, roles ...string) {
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "sessions"`).WithArgs(sqlmock.AnyArg(), userID, sqlmock.AnyArg(), sqlmock.AnyArg(),
+		sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), nil).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	roleRows := sqlmock.NewRows([]string{"name"})
+	for _, role := range roles {
+		roleRows.AddRow(role)
+	}
+	mock.ExpectQuery(`SELECT "roles"."name" FROM "roles" JOIN user_roles`).WithArgs(userID).WillReturnRows(roleRows)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "refresh_tokens"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+}
+
+// sessionRevoked 檢查工作階段的 access token 是否已被撤銷
+func sessionRevoked(t *testing.T, svc *ServiceDefault, sessionID string) bool {
+	t.Helper()
+	revoked, err := svc.revocationStore.IsRevoked(revocation.SessionKey(sessionID))
+	require.NoError(t, err)
+	return revoked
+}
+
+// 測試撤銷工作階段時一併撤銷同一次登入的 refresh token 與 access token
+func TestRevokeSession(t *testing.T) {
+	svc, mock := newTestService(t)
+
+	expectRevokeSession(mock, 7, "session-1", true)
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "refresh_tokens" SET "revoked_at"=\$1,"updated_at"=\$2 WHERE \(family_id = \$3 AND revoked_at IS NULL\)`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "session-1").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	require.NoError(t, svc.RevokeSession(7, "session-1"))
+	assert.True(t, sessionRevoked(t, svc, "session-1"))
+}
+
+// 測試不存在、已撤銷或屬於其他使用者的工作階段回傳 ErrSessionNotFound，不會撤銷任何 token
+func TestRevokeSessionNotOwned(t *testing.T) {
+	svc, mock := newTestService(t)
+
+	expectRevokeSession(mock, 8, "session-1", false)
+
+	assert.ErrorIs(t, svc.RevokeSession(8, "session-1"), ErrSessionNotFound)
+	assert.False(t, sessionRevoked(t, svc, "session-1"))
+}
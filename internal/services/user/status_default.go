@@ -0,0 +1,106 @@
+package user
+
+import (
+	"go-template/internal/models"
+	"go-template/internal/utils/logger"
+)
+
+// userStatusTransitions 允許的帳號狀態轉換，沒有列出的轉換一律拒絕
+var userStatusTransitions = map[models.UserStatus][]models.UserStatus{
+	models.UserStatusPendingVerification: {models.UserStatusActive, models.UserStatusSuspended, models.UserStatusDeactivated},
+	models.UserStatusActive:              {models.UserStatusSuspended, models.UserStatusLocked, models.UserStatusDeactivated},
+	models.UserStatusSuspended:           {models.UserStatusActive, models.UserStatusDeactivated},
+	models.UserStatusLocked:              {models.UserStatusActive, models.UserStatusSuspended, models.UserStatusDeactivated},
+	models.UserStatusDeactivated:         {models.UserStatusActive},
+}
+
+// canTransition 判斷帳號狀態是否可以從 from 轉換成 to
+func canTransition(from, to models.UserStatus) bool {
+	for _, allowed := range userStatusTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ChangeUserStatus 依照允許的轉換變更帳號狀態，並寫入狀態變更紀錄
+// 變更成 active 以外的狀態時，會登出該使用者所有的裝置
+// @param id path uint true "使用者 ID"
+// @param status body models.UserStatus true "新的狀態"
+// @param actorID body uint false "執行變更的使用者 ID，nil 代表由系統自動變更"
+// @param reason body string false "變更原因"
+// @return error 錯誤訊息
+func (svc *ServiceDefault) ChangeUserStatus(id uint, status models.UserStatus, actorID *uint, reason string) error {
+	user, err := svc.userRepo.GetByIDUnscoped(id)
+	if err != nil {
+		return translateNotFound(err)
+	}
+	if !canTransition(user.Status, status) {
+		logger.Logger.Debugf("Invalid status transition for user %d: %s -> %s", id, user.Status, status) // 記錄錯誤
+		return ErrInvalidStatusTransition
+	}
+
+	changed, err := svc.userRepo.ChangeStatus(&models.UserStatusChange{
+		UserID:     id,
+		FromStatus: user.Status,
+		ToStatus:   status,
+		ActorID:    actorID,
+		Reason:     reason,
+	})
+	if err != nil {
+		return err
+	}
+	// 狀態在讀取之後被其他請求變更，轉換的前提已經不成立
+	if !changed {
+		return ErrInvalidStatusTransition
+	}
+
+	if status != models.UserStatusActive {
+		if err := svc.LogoutAll(id); err != nil {
+			return err
+		}
+	}
+
+	logger.Logger.Infof("User %d status changed: %s -> %s", id, user.Status, status) // 記錄狀態已變更
+	return nil
+}
+
+// UserStatusHistory 取得使用者的帳號狀態變更紀錄，最新的在前面
+// @param id path uint true "使用者 ID"
+// @return changes 狀態變更紀錄
+// @return error 錯誤訊息
+func (svc *ServiceDefault) UserStatusHistory(id uint) ([]models.UserStatusChange, error) {
+	if _, err := svc.userRepo.GetByIDUnscoped(id); err != nil {
+		return nil, translateNotFound(err)
+	}
+	return svc.userRepo.ListStatusChanges(id)
+}
+
+// CheckAccountActive 檢查使用者目前是否可以使用已發行的 token
+// 使用者不存在時回傳 ErrUserNotFound，帳號不是 active 時回傳對應狀態的錯誤
+// @param id path uint true "使用者 ID"
+// @return error 錯誤訊息
+func (svc *ServiceDefault) CheckAccountActive(id uint) error {
+	user, err := svc.userRepo.GetByID(id)
+	if err != nil {
+		return translateNotFound(err)
+	}
+	return accountStatusError(user.Status)
+}
+
+// accountStatusError 將不是 active 的帳號狀態轉換成對應的錯誤
+func accountStatusError(status models.UserStatus) error {
+	switch status {
+	case models.UserStatusActive:
+		return nil
+	case models.UserStatusPendingVerification:
+		return ErrUserPendingVerification
+	case models.UserStatusSuspended:
+		return ErrUserSuspended
+	case models.UserStatusLocked:
+		return ErrUserLocked
+	default:
+		return ErrUserDeactivated
+	}
+}
@@ -0,0 +1,109 @@
+package user
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-template/internal/models"
+)
+
+// expectUserStatus 預期以 GetByIDUnscoped 查詢使用者，回傳目前的帳號狀態，deleted 代表使用者已被軟刪除
+func expectUserStatus(mock sqlmock.Sqlmock, id uint, status models.UserStatus, deleted bool) {
+	var deletedAt interface{}
+	if deleted {
+		deletedAt = time.Now()
+	}
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"."id" = \$1`).WithArgs(id, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "status", "deleted_at"}).AddRow(id, "alice", status, deletedAt))
+	mock.ExpectQuery(`SELECT \* FROM "user_roles"`).WillReturnRows(sqlmock.NewRows([]string{"user_id", "role_id"}))
+}
+
+// expectChangeStatus 預期在交易中以目前的狀態為條件更新狀態並寫入變更紀錄，changed 為 false 代表狀態已被其他請求變更
+func expectChangeStatus(mock sqlmock.Sqlmock, id uint, from, to models.UserStatus, changed bool) {
+	mock.ExpectBegin()
+	if !changed {
+		mock.ExpectExec(`UPDATE "users" SET "status"=\$1,"updated_at"=\$2 WHERE id = \$3 AND status = \$4`).
+			WithArgs(to, sqlmock.AnyArg(), id, from).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		return
+	}
+	mock.ExpectExec(`UPDATE "users" SET "status"=\$1,"updated_at"=\$2 WHERE id = \$3 AND status = \$4`).
+		WithArgs(to, sqlmock.AnyArg(), id, from).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO "user_status_changes"`).
+		WithArgs(id, from, to, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+}
+
+// 測試帳號狀態轉換的完整矩陣，只有列出的轉換是允許的
+func TestCanTransition(t *testing.T) {
+	const (
+		active     = models.UserStatusActive
+		pending    = models.UserStatusPendingVerification
+		suspended  = models.UserStatusSuspended
+		locked     = models.UserStatusLocked
+		deactivate = models.UserStatusDeactivated
+	)
+	statuses := []models.UserStatus{active, pending, suspended, locked, deactivate}
+	allowed := map[models.UserStatus]map[models.UserStatus]bool{
+		pending:    {active: true, suspended: true, deactivate: true},
+		active:     {suspended: true, locked: true, deactivate: true},
+		suspended:  {active: true, deactivate: true},
+		locked:     {active: true, suspended: true, deactivate: true},
+		deactivate: {active: true},
+	}
+
+	for _, from := range statuses {
+		for _, to := range statuses {
+			t.Run(from.String()+"->"+to.String(), func(t *testing.T) {
+				assert.Equal(t, allowed[from][to], canTransition(from, to))
+			})
+		}
+	}
+}
+
+// 測試允許的轉換會寫入變更紀錄，變更成 active 以外的狀態時登出使用者所有的裝置
+func TestChangeUserStatus(t *testing.T) {
+	svc, mock := newTestService(t)
+	actorID := uint(1)
+
+	expectUserStatus(mock, 7, models.UserStatusActive, false)
+	expectChangeStatus(mock, 7, models.UserStatusActive, models.UserStatusLocked, true)
+	expectLogoutAll(mock, 7)
+	require.NoError(t, svc.ChangeUserStatus(7, models.UserStatusLocked, &actorID, "too many failed logins"))
+	assert.True(t, loggedOut(t, svc, 7))
+
+	// 變更回 active 時不需要登出
+	expectUserStatus(mock, 8, models.UserStatusLocked, false)
+	expectChangeStatus(mock, 8, models.UserStatusLocked, models.UserStatusActive, true)
+	require.NoError(t, svc.ChangeUserStatus(8, models.UserStatusActive, &actorID, ""))
+	assert.False(t, loggedOut(t, svc, 8))
+}
+
+// 測試不允許的轉換與讀取之後被其他請求變更的狀態回傳 ErrInvalidStatusTransition，且不會登出使用者
+func TestChangeUserStatusRejected(t *testing.T) {
+	svc, mock := newTestService(t)
+
+	expectUserStatus(mock, 7, models.UserStatusSuspended, false)
+	assert.ErrorIs(t, svc.ChangeUserStatus(7, models.UserStatusLocked, nil, ""), ErrInvalidStatusTransition)
+
+	expectUserStatus(mock, 7, models.UserStatusActive, false)
+	expectChangeStatus(mock, 7, models.UserStatusActive, models.UserStatusSuspended, false)
+	assert.ErrorIs(t, svc.ChangeUserStatus(7, models.UserStatusSuspended, nil, ""), ErrInvalidStatusTransition)
+	assert.False(t, loggedOut(t, svc, 7))
+
+	mock.ExpectQuery(`SELECT \* FROM "users"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	assert.ErrorIs(t, svc.ChangeUserStatus(8, models.UserStatusActive, nil, ""), ErrUserNotFound)
+}
+
+// 測試帳號不是 active 時回傳對應狀態的錯誤
+func TestAccountStatusError(t *testing.T) {
+	assert.NoError(t, accountStatusError(models.UserStatusActive))
+	assert.ErrorIs(t, accountStatusError(models.UserStatusPendingVerification), ErrUserPendingVerification)
+	assert.ErrorIs(t, accountStatusError(models.UserStatusSuspended), ErrUserSuspended)
+	assert.ErrorIs(t, accountStatusError(models.UserStatusLocked), ErrUserLocked)
+	assert.ErrorIs(t, accountStatusError(models.UserStatusDeactivated), ErrUserDeactivated)
+}
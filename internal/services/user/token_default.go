@@ -0,0 +1,203 @@
+package user
+
+import (
+	"errors"
+	"time"
+
+	"go-template/internal/models"
+	"go-template/internal/services/revocation"
+	"go-template/internal/utils/jwt"
+	"go-template/internal/utils/logger"
+	"gorm.io/gorm"
+)
+
+// RefreshToken 使用 refresh token 換發新的 token 組合
+// 每個 refresh token 只能使用一次，使用後會輪換成同一個 family 的新 token；
+// 若已經輪換過的 token 再次被使用，代表 token 可能外洩，整個 family 都會被撤銷
+// @param refreshToken body string true "refresh token"
+// @return tokens 新的 access token 與 refresh token
+// @return error 錯誤訊息
+func (svc *ServiceDefault) RefreshToken(refreshToken string) (*TokenPair, error) {
+	stored, err := svc.refreshTokenRepo.GetByHash(svc.jwtService.HashRefreshToken(refreshToken))
+	if err != nil {
+		logger.Logger.Debugf("Refresh token not found: %v", err) // 記錄錯誤
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// 已使用或已撤銷的 token 被重新提交，視為重放攻擊
+	if stored.UsedAt != nil || stored.RevokedAt != nil {
+		return nil, svc.handleRefreshTokenReuse(stored)
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		logger.Logger.Debugf("Refresh token expired for user: %d", stored.UserID) // 記錄錯誤
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// 標記為已使用；如果沒有成功標記，代表同一個 token 已經被另一個請求搶先使用
+	marked, err := svc.refreshTokenRepo.MarkUsed(stored.ID)
+	if err != nil {
+		logger.Logger.Errorf("Error marking refresh token as used: %v", err) // 記錄錯誤
+		return nil, err
+	}
+	if !marked {
+		return nil, svc.handleRefreshTokenReuse(stored)
+	}
+
+	// 確認使用者仍然存在，且帳號狀態允許換發 token
+	user, err := svc.userRepo.GetByID(stored.UserID)
+	if err != nil {
+		logger.Logger.Debugf("Error getting user of refresh token: %v", err) // 記錄錯誤
+		return nil, ErrInvalidRefreshToken
+	}
+	if err := checkLoginAllowed(user); err != nil {
+		logger.Logger.Debugf("Refresh refused for user %d: %v", stored.UserID, err) // 記錄錯誤
+		return nil, err
+	}
+
+	// 新的 access token 沿用工作階段的登入時間與驗證方式
+	session, err := svc.sessionRepo.GetByID(stored.FamilyID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// 工作階段功能之前發行的 refresh token 沒有對應的工作階段，只保留 sid
+		session = &models.Session{ID: stored.FamilyID, UserID: stored.UserID}
+	} else if err != nil {
+		logger.Logger.Errorf("Error getting session of refresh token: %v", err) // 記錄錯誤
+		return nil, err
+	}
+
+	tokens, err := svc.issueTokens(session)
+	if err != nil {
+		logger.Logger.Errorf("Error generating token: %v", err) // 記錄錯誤
+		return nil, err
+	}
+
+	// 換發 token 代表工作階段仍在使用中，延長到新的 refresh token 過期為止
+	now := time.Now()
+	extended, err := svc.sessionRepo.Extend(stored.FamilyID, now, now.Add(svc.jwtService.RefreshTokenExpiresIn()))
+	if err != nil {
+		logger.Logger.Warnf("Error extending session: %v", err) // 記錄錯誤
+	} else if !extended {
+		logger.Logger.Debugf("No active session for refresh token family %s", stored.FamilyID) // 記錄錯誤
+	}
+
+	logger.Logger.Debugf("Refresh token rotated for user: %d", stored.UserID) // 記錄 token 已輪換
+	return tokens, nil
+}
+
+// Logout 登出目前的裝置
+// 撤銷目前使用的 access token 與所屬的工作階段 (包含同一次登入的 refresh token)；
+// 如果有提供 refresh token，該 token 所屬的 refresh token family 也會一併撤銷
+// @param userID path uint true "使用者 ID"
+// @param sessionID path string false "access token 的 sid"
+// @param tokenID path string true "access token 的 jti"
+// @param tokenExpiresAt path time.Time true "access token 的過期時間"
+// @param refreshToken body string false "refresh token"
+// @return error 錯誤訊息
+func (svc *ServiceDefault) Logout(userID uint, sessionID, tokenID string, tokenExpiresAt time.Time, refreshToken string) error {
+	if sessionID != "" {
+		if err := svc.endSession(userID, sessionID); err != nil {
+			return err
+		}
+	}
+
+	if tokenID != "" {
+		if err := svc.revocationStore.Revoke(userID, tokenID, tokenExpiresAt); err != nil {
+			logger.Logger.Errorf("Error revoking access token: %v", err) // 記錄錯誤
+			return err
+		}
+	}
+
+	if refreshToken != "" {
+		stored, err := svc.refreshTokenRepo.GetByHash(svc.jwtService.HashRefreshToken(refreshToken))
+		// 只能撤銷自己的 refresh token，找不到時不視為錯誤，避免洩漏 token 是否存在
+		if err == nil && stored.UserID == userID {
+			if err := svc.refreshTokenRepo.RevokeFamily(stored.FamilyID); err != nil {
+				logger.Logger.Errorf("Error revoking refresh token family: %v", err) // 記錄錯誤
+				return err
+			}
+		}
+	}
+
+	logger.Logger.Infof("User logged out: %d", userID) // 記錄使用者登出
+	return nil
+}
+
+// LogoutAll 登出所有裝置
+// 讓使用者目前所有的 access token、refresh token 與工作階段失效
+// 與撤銷時間同一毫秒內發行的 token 也會被拒絕，因此等到下一毫秒才返回，之後發行的新 token 不會被一併視為無效
+// @param userID path uint true "使用者 ID"
+// @return error 錯誤訊息
+func (svc *ServiceDefault) LogoutAll(userID uint) error {
+	revokedAt := time.Now()
+	if err := svc.revocationStore.RevokeAllForUser(userID, revokedAt); err != nil {
+		logger.Logger.Errorf("Error revoking access tokens of user: %v", err) // 記錄錯誤
+		return err
+	}
+	if err := svc.refreshTokenRepo.RevokeByUser(userID); err != nil {
+		logger.Logger.Errorf("Error revoking refresh tokens of user: %v", err) // 記錄錯誤
+		return err
+	}
+	if err := svc.sessionRepo.RevokeByUser(userID); err != nil {
+		logger.Logger.Errorf("Error revoking sessions of user: %v", err) // 記錄錯誤
+		return err
+	}
+	time.Sleep(time.Until(revocation.ValidFrom(revokedAt)))
+
+	logger.Logger.Infof("User logged out from all sessions: %d", userID) // 記錄使用者登出所有裝置
+	return nil
+}
+
+// handleRefreshTokenReuse 處理 refresh token 重複使用的情況，撤銷整個 token family 與所屬的工作階段
+// 工作階段一併撤銷，讓同一次登入已發行的 access token 也立即失效
+func (svc *ServiceDefault) handleRefreshTokenReuse(stored *models.RefreshToken) error {
+	logger.Logger.Warnf("Refresh token reuse detected for user %d, revoking family %s", stored.UserID, stored.FamilyID)
+	if err := svc.endSession(stored.UserID, stored.FamilyID); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// issueTokens 為登入工作階段產生 access token 與 refresh token，並將 refresh token 的雜湊值存入資料庫
+// 工作階段 ID 同時是 access token 的 sid 與 refresh token 的 family，登入時間與驗證方式寫入 auth_time 與 amr；
+// 新的登入使用 startSession 建立工作階段
+func (svc *ServiceDefault) issueTokens(session *models.Session) (*TokenPair, error) {
+	userID := session.UserID
+	// 每次發行都重新查詢角色，讓角色異動在下一次刷新 token 時生效
+	roles, err := svc.rbacService.RolesForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := svc.jwtService.GenerateToken(jwt.UserToken{
+		UserID:      userID,
+		Roles:       roles,
+		SessionID:   session.ID,
+		AuthTime:    session.CreatedAt,
+		AuthMethods: session.AuthMethods,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, refreshTokenHash, err := svc.jwtService.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.refreshTokenRepo.Create(&models.RefreshToken{
+		UserID:    userID,
+		FamilyID:  session.ID,
+		TokenHash: refreshTokenHash,
+		ExpiresAt: time.Now().Add(svc.jwtService.RefreshTokenExpiresIn()),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(svc.jwtService.AccessTokenExpiresIn().Seconds()),
+	}, nil
+}
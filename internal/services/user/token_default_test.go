@@ -0,0 +1,103 @@
+package user
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-template/internal/utils/jwt"
+)
+
+// refreshTokenColumns refresh_tokens 查詢回傳的欄位
+var refreshTokenColumns = []string{"id", "user_id", "family_id", "token_hash", "expires_at", "used_at", "revoked_at"}
+
+// 測試已經輪換過的 refresh token 被重新提交時撤銷同一次登入 (family) 的所有 refresh token，
+// 輪換後取得的新 refresh token 也無法再使用
+func TestRotatedRefreshTokenReplayRevokesFamily(t *testing.T) {
+	svc, mock := newTestService(t)
+	jwtService := svc.jwtService
+
+	original, originalHash, err := jwtService.GenerateRefreshToken()
+	require.NoError(t, err)
+	expiresAt := time.Now().Add(time.Hour)
+	usedAt := time.Now()
+	authTime := time.Now().Add(-time.Minute).Truncate(time.Second)
+
+	// 第一次使用：輪換成同一個 family 的新 refresh token
+	mock.ExpectQuery(`SELECT \* FROM "refresh_tokens" WHERE token_hash = \$1`).WithArgs(originalHash, 1).
+		WillReturnRows(sqlmock.NewRows(refreshTokenColumns).AddRow(1, 7, "family-1", originalHash, expiresAt, nil, nil))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "refresh_tokens" SET "used_at"=\$1,"updated_at"=\$2 WHERE \(id = \$3 AND used_at IS NULL AND revoked_at IS NULL\)`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"."id" = \$1`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(7, "alice"))
+	mock.ExpectQuery(`SELECT \* FROM "sessions" WHERE id = \$1`).WithArgs("family-1", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "auth_methods", "created_at"}).AddRow("family-1", 7, `["pwd"]`, authTime))
+	mock.ExpectQuery(`SELECT "roles"."name" FROM "roles" JOIN user_roles`).WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("user"))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "refresh_tokens"`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, 7, "family-1", sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "sessions" SET "expires_at"=\$1,"last_seen_at"=\$2 WHERE id = \$3 AND revoked_at IS NULL`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "family-1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	rotated, err := svc.RefreshToken(original)
+	require.NoError(t, err)
+	claims, err := jwtService.ValidateToken(rotated.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, uint(7), claims.UserID)
+	assert.Equal(t, []string{"user"}, claims.Roles)
+	assert.Equal(t, "family-1", claims.SessionID)
+	assert.True(t, authTime.Equal(claims.AuthTime), "auth_time is kept from the session")
+	assert.Equal(t, []string{jwt.AuthMethodPassword}, claims.AuthMethods)
+	assert.NotEqual(t, original, rotated.RefreshToken)
+
+	// 重新提交已經輪換過的 token：撤銷整個 family 與所屬的工作階段
+	mock.ExpectQuery(`SELECT \* FROM "refresh_tokens" WHERE token_hash = \$1`).WithArgs(originalHash, 1).
+		WillReturnRows(sqlmock.NewRows(refreshTokenColumns).AddRow(1, 7, "family-1", originalHash, expiresAt, usedAt, nil))
+	expectRevokeSession(mock, 7, "family-1", true)
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "refresh_tokens" SET "revoked_at"=\$1,"updated_at"=\$2 WHERE \(family_id = \$3 AND revoked_at IS NULL\)`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "family-1").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	_, err = svc.RefreshToken(original)
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+	assert.True(t, sessionRevoked(t, svc, "family-1"), "access tokens of the session are revoked")
+
+	// 輪換後取得的 refresh token 已經隨著 family 一起被撤銷
+	rotatedHash := jwtService.HashRefreshToken(rotated.RefreshToken)
+	mock.ExpectQuery(`SELECT \* FROM "refresh_tokens" WHERE token_hash = \$1`).WithArgs(rotatedHash, 1).
+		WillReturnRows(sqlmock.NewRows(refreshTokenColumns).AddRow(2, 7, "family-1", rotatedHash, expiresAt, nil, time.Now()))
+	expectRevokeSession(mock, 7, "family-1", false)
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "refresh_tokens" SET "revoked_at"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	_, err = svc.RefreshToken(rotated.RefreshToken)
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+}
+
+// 測試不存在或已過期的 refresh token 回傳 ErrInvalidRefreshToken，且不會被標記為已使用
+func TestRefreshTokenInvalid(t *testing.T) {
+	svc, mock := newTestService(t)
+	jwtService := svc.jwtService
+
+	mock.ExpectQuery(`SELECT \* FROM "refresh_tokens" WHERE token_hash = \$1`).
+		WillReturnRows(sqlmock.NewRows(refreshTokenColumns))
+	_, err := svc.RefreshToken("unknown")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	expired := jwtService.HashRefreshToken("expired")
+	mock.ExpectQuery(`SELECT \* FROM "refresh_tokens" WHERE token_hash = \$1`).WithArgs(expired, 1).
+		WillReturnRows(sqlmock.NewRows(refreshTokenColumns).AddRow(3, 7, "family-2", expired, time.Now().Add(-time.Second), nil, nil))
+	_, err = svc.RefreshToken("expired")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
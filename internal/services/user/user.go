@@ -1,16 +1,150 @@
 package user
 
 import (
-	"errors"
+	"time"
+
+	"go-template/internal/apperror"
 	"go-template/internal/models"
+	"go-template/internal/repository"
+	"go-template/internal/services/throttle"
 )
 
-// 可能的錯誤代碼
+// 可能的錯誤代碼，Kind 決定 ErrorHandler 中介軟體回應的 HTTP 狀態碼，Code 為回應給用戶端的字串代碼
 var (
-	ErrUserNotFound       = errors.New("user not found")
-	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrUserNotFound       = apperror.New(apperror.KindNotFound, "user_not_found", "user not found")
+	ErrInvalidCredentials = apperror.New(apperror.KindUnauthorized, "invalid_credentials", "invalid credentials")
+	// ErrLoginUserNotFound 登入時使用者名稱不存在，與 ErrUserNotFound 的字串代碼相同，但視為驗證失敗
+	ErrLoginUserNotFound = apperror.New(apperror.KindUnauthorized, "user_not_found", "user not found")
+	// ErrInvalidCurrentPassword 變更密碼或停用兩步驟驗證時目前的密碼錯誤
+	// 屬於 KindInvalid 而不是 KindUnauthorized，避免用戶端誤以為 access token 失效
+	ErrInvalidCurrentPassword = apperror.New(apperror.KindInvalid, "invalid_current_password", "current password is incorrect")
+	// ErrInvalidRefreshToken refresh token 不存在、已過期或已被撤銷
+	ErrInvalidRefreshToken = apperror.New(apperror.KindUnauthorized, "invalid_refresh_token", "invalid refresh token")
+	// ErrRefreshTokenReused 已經輪換過的 refresh token 被重複使用，整個 token family 會被撤銷
+	ErrRefreshTokenReused = apperror.New(apperror.KindUnauthorized, "refresh_token_reused", "refresh token reused, all sessions of this login have been revoked")
+	// ErrUserPendingVerification 帳號尚未完成電子郵件驗證
+	ErrUserPendingVerification = apperror.New(apperror.KindForbidden, "user_pending_verification", "email address has not been verified")
+	// ErrUserSuspended 帳號已被管理者停權
+	ErrUserSuspended = apperror.New(apperror.KindForbidden, "user_suspended", "user has been suspended")
+	// ErrUserLocked 帳號因安全因素被鎖定
+	ErrUserLocked = apperror.New(apperror.KindForbidden, "user_locked", "user has been locked")
+	// ErrUserDeactivated 帳號已停用
+	ErrUserDeactivated = apperror.New(apperror.KindForbidden, "user_deactivated", "user has been deactivated")
+	// ErrInvalidVerificationToken 電子郵件驗證 token 不存在、已過期或已被使用
+	ErrInvalidVerificationToken = apperror.New(apperror.KindInvalid, "invalid_verification_token", "invalid or expired verification token")
+	// ErrInvalidResetToken 重設密碼 token 不存在、已過期或已被使用
+	ErrInvalidResetToken = apperror.New(apperror.KindInvalid, "invalid_reset_token", "invalid or expired password reset token")
+	// ErrPasswordUnchanged 新的密碼與目前的密碼相同
+	ErrPasswordUnchanged = apperror.New(apperror.KindInvalid, "password_unchanged", "new password must be different from the current password")
+	// ErrInvalidMFAToken 兩步驟驗證的登入 token 不存在、已過期或已被使用，必須重新登入
+	ErrInvalidMFAToken = apperror.New(apperror.KindUnauthorized, "invalid_mfa_token", "invalid or expired MFA token, please log in again")
+	// ErrInvalidMFACode TOTP 驗證碼或復原碼錯誤
+	ErrInvalidMFACode = apperror.New(apperror.KindInvalid, "invalid_mfa_code", "invalid MFA code")
+	// ErrMFALoginFailed 完成兩步驟驗證登入時驗證碼錯誤，與 ErrInvalidMFACode 的字串代碼相同，但視為驗證失敗
+	ErrMFALoginFailed = apperror.New(apperror.KindUnauthorized, "invalid_mfa_code", "invalid MFA code")
+	// ErrMFAAlreadyEnabled 已經啟用兩步驟驗證
+	ErrMFAAlreadyEnabled = apperror.New(apperror.KindConflict, "mfa_already_enabled", "two-factor authentication is already enabled")
+	// ErrMFANotEnabled 尚未啟用兩步驟驗證，或尚未開始設定
+	ErrMFANotEnabled = apperror.New(apperror.KindInvalid, "mfa_not_enabled", "two-factor authentication is not enabled")
+	// ErrInvalidStatusTransition 不允許從目前的帳號狀態轉換成指定的狀態
+	ErrInvalidStatusTransition = apperror.New(apperror.KindConflict, "invalid_status_transition", "status transition not allowed")
+	// ErrPasswordResetRequired 管理者要求使用者重設密碼，重設之前無法登入
+	ErrPasswordResetRequired = apperror.New(apperror.KindForbidden, "password_reset_required", "password reset required")
+	// ErrUnknownRole 指定的角色不存在
+	ErrUnknownRole = apperror.New(apperror.KindInvalid, "unknown_role", "unknown role")
+	// ErrInvalidListQuery 使用者列表的排序欄位或分頁游標無效
+	ErrInvalidListQuery = apperror.New(apperror.KindInvalid, "invalid_list_query", "invalid sort field or pagination cursor")
+	// ErrInvalidAPIKey API key 不存在、已過期或已被撤銷
+	ErrInvalidAPIKey = apperror.New(apperror.KindUnauthorized, "invalid_api_key", "invalid, expired or revoked API key")
+	// ErrAPIKeyNotFound 要撤銷的 API key 不存在或不屬於該使用者
+	ErrAPIKeyNotFound = apperror.New(apperror.KindNotFound, "api_key_not_found", "API key not found")
+	// ErrInvalidAPIKeyScope API key 沒有指定權限，或指定了擁有者沒有的權限
+	ErrInvalidAPIKeyScope = apperror.New(apperror.KindInvalid, "invalid_api_key_scope", "API key scopes must be permissions you currently have")
+	// ErrInvalidAPIKeyExpiry API key 的過期時間不在未來
+	ErrInvalidAPIKeyExpiry = apperror.New(apperror.KindInvalid, "invalid_api_key_expiry", "API key expiry must be in the future")
+	// ErrNotServiceAccount 指定的使用者不是服務帳號
+	ErrNotServiceAccount = apperror.New(apperror.KindInvalid, "not_service_account", "user is not a service account")
+	// ErrSessionNotFound 要撤銷的登入工作階段不存在、已撤銷或不屬於該使用者
+	ErrSessionNotFound = apperror.New(apperror.KindNotFound, "session_not_found", "session not found")
+	// ErrMagicLinkDisabled 沒有開啟登入連結功能
+	ErrMagicLinkDisabled = apperror.New(apperror.KindForbidden, "magic_link_disabled", "magic link login is disabled")
+	// ErrInvalidMagicLink 登入連結無效、已使用或已過期
+	ErrInvalidMagicLink = apperror.New(apperror.KindUnauthorized, "invalid_magic_link", "invalid or expired magic link")
+	// ErrCannotImpersonate 指定的使用者不能被代替操作 (自己、服務帳號或同樣可以代替他人操作的管理者)
+	ErrCannotImpersonate = apperror.New(apperror.KindForbidden, "cannot_impersonate", "this user cannot be impersonated")
 )
 
+// TokenPair 登入或刷新 token 後回傳的 token 組合
+type TokenPair struct {
+	AccessToken  string `json:"access_token,omitempty"`  // 短效期的 access token (JWT)，以 cookie 回傳時為空
+	RefreshToken string `json:"refresh_token,omitempty"` // 不透明的 refresh token，用來換發新的 token 組合，以 cookie 回傳時為空
+	TokenType    string `json:"token_type"`              // Token 類型，固定為 Bearer
+	ExpiresIn    int64  `json:"expires_in"`              // Access token 的有效秒數
+}
+
+// ClientInfo 登入時的用戶端資訊，記錄在登入工作階段中讓使用者辨識自己的裝置
+type ClientInfo struct {
+	IP        string // 來源 IP，同時用於計算登入失敗次數
+	UserAgent string // User-Agent
+}
+
+// LoginResult 登入的結果
+// 沒有啟用兩步驟驗證時直接回傳 token 組合；啟用時只回傳 MFAToken，必須再使用驗證碼換發 token 組合
+type LoginResult struct {
+	*TokenPair
+	MFARequired  bool   `json:"mfa_required,omitempty"`   // 是否需要完成兩步驟驗證
+	MFAToken     string `json:"mfa_token,omitempty"`      // 只能用來完成兩步驟驗證的短效期 token
+	MFAExpiresIn int64  `json:"mfa_expires_in,omitempty"` // MFAToken 的有效秒數
+}
+
+// TOTPEnrollment 開始設定 TOTP 時回傳給使用者的資料
+type TOTPEnrollment struct {
+	Secret          string `json:"secret"`           // base32 編碼的共享密鑰，無法掃描 QR code 時手動輸入
+	ProvisioningURI string `json:"provisioning_uri"` // otpauth:// URI
+	QRPayload       string `json:"qr_payload"`       // 要編碼成 QR code 的內容 (與 ProvisioningURI 相同)
+}
+
+// NewAPIKey 建立 API key 時指定的內容
+type NewAPIKey struct {
+	Name      string     // 用途說明
+	Scopes    []string   // 允許使用的權限，必須是擁有者目前擁有的權限
+	ExpiresAt *time.Time // 過期時間，nil 代表不會過期
+}
+
+// CreatedAPIKey 建立 API key 的結果，明文 Key 只會在此時回傳一次
+type CreatedAPIKey struct {
+	*models.APIKey
+	Key string `json:"key"` // 完整的 API key，請妥善保存
+}
+
+// NewServiceAccount 管理者建立服務帳號時指定的內容
+type NewServiceAccount struct {
+	Username string   // 帳號名稱
+	Email    string   // 電子郵件，空值時使用不會收到信件的 <username>@service-accounts.invalid
+	Roles    []string // 服務帳號的角色，決定 API key 可以使用的權限上限
+}
+
+// AdminUserUpdate 管理者更新使用者時可以修改的欄位，nil 代表不修改
+type AdminUserUpdate struct {
+	Username *string  `json:"username"` // 帳號名稱
+	Email    *string  `json:"email"`    // 電子郵件
+	Roles    []string `json:"roles"`    // 使用者的所有角色，會取代目前的角色
+}
+
+// ListUsersQuery 使用者列表的查詢條件
+type ListUsersQuery struct {
+	repository.UserListQuery
+	Cursor string // 上一頁回傳的 NextCursor，設定後改用 keyset 分頁並忽略 Offset
+}
+
+// UserPage 使用者列表的查詢結果
+type UserPage struct {
+	Users      []models.User // 使用者列表
+	Total      int64         // 符合篩選條件的使用者總數
+	HasMore    bool          // 是否還有下一頁
+	NextCursor string        // 下一頁的游標，只有排序欄位支援 keyset 分頁時才會有值
+}
+
 // Service 介面，定義使用者服務的方法
 type Service interface {
 	CreateUser(user *models.User) error
@@ -18,5 +152,47 @@ type Service interface {
 	GetUserByUsername(username string) (*models.User, error)
 	UpdateUser(user *models.User) error
 	DeleteUser(id uint) error
-	Login(username, password string) (authToken string, err error)
+	Login(username, password string, client ClientInfo) (result *LoginResult, err error)
+	LoginMFA(mfaToken, code string, client ClientInfo) (tokens *TokenPair, err error)
+	RequestMagicLink(email string, client ClientInfo) error
+	LoginMagicLink(token string, client ClientInfo) (result *LoginResult, err error)
+	RefreshToken(refreshToken string) (tokens *TokenPair, err error)
+	Logout(userID uint, sessionID, tokenID string, tokenExpiresAt time.Time, refreshToken string) error
+	LogoutAll(userID uint) error
+	ListSessions(userID uint) ([]models.Session, error)
+	RevokeSession(userID uint, sessionID string) error
+	CheckAccountActive(userID uint) error
+	VerifyEmail(token string) error
+	ResendVerificationEmail(email string) error
+	ForgotPassword(email string) error
+	ResetPassword(token, newPassword string) error
+	ChangePassword(userID uint, currentPassword, newPassword string, client ClientInfo) (*TokenPair, error)
+	EnrollTOTP(userID uint) (*TOTPEnrollment, error)
+	ConfirmTOTP(userID uint, code string) (recoveryCodes []string, err error)
+	RegenerateRecoveryCodes(userID uint, code string) (recoveryCodes []string, err error)
+	DisableMFA(userID uint, password string) error
+	CreateAPIKey(userID uint, input NewAPIKey) (*CreatedAPIKey, error)
+	ListAPIKeys(userID uint) ([]models.APIKey, error)
+	RevokeAPIKey(userID, keyID uint) error
+	AuthenticateAPIKey(key string) (*models.APIKey, error)
+
+	// 以下為管理者使用的方法，可以操作任意使用者
+	ListUsers(query ListUsersQuery) (*UserPage, error)
+	AdminGetUser(id uint) (*models.User, error)
+	AdminUpdateUser(id uint, update AdminUserUpdate) (*models.User, error)
+	ChangeUserStatus(id uint, status models.UserStatus, actorID *uint, reason string) error
+	UserStatusHistory(id uint) ([]models.UserStatusChange, error)
+	SuspendUser(id uint, actorID *uint, reason string) error
+	RestoreUser(id uint, actorID *uint, reason string) error
+	HardDeleteUser(id uint) error
+	ForcePasswordReset(id uint) error
+	ListLoginLockouts() ([]throttle.Record, error)
+	ClearLoginLockout(id uint) error
+	CreateServiceAccount(input NewServiceAccount) (*models.User, error)
+	CreateServiceAccountAPIKey(id uint, input NewAPIKey) (*CreatedAPIKey, error)
+	ListServiceAccountAPIKeys(id uint) ([]models.APIKey, error)
+	RevokeServiceAccountAPIKey(id, keyID uint) error
+	Impersonate(actorID, id uint, reason, ip string) (*TokenPair, error)
+	ListImpersonations(id uint) ([]models.ImpersonationLog, error)
+	RecordImpersonatedRequest(log *models.ImpersonationLog) error
 }
@@ -1,46 +1,152 @@
 package user
 
 import (
+	"errors"
+	"sync"
 	"time"
 
+	"go-template/internal/configs"
 	"go-template/internal/models"
 	"go-template/internal/repository"
+	"go-template/internal/services/rbac"
+	"go-template/internal/services/revocation"
+	"go-template/internal/services/throttle"
 	"go-template/internal/utils/jwt"
 	"go-template/internal/utils/logger"
+	"go-template/internal/utils/mailer"
+	"go-template/internal/utils/password"
+	"go-template/internal/validators"
 
-	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 // ServiceDefault Struct，實作 UserService 介面
 type ServiceDefault struct {
-	userRepo   *repository.UserRepository
-	jwtService *jwt.Service
+	cfg               *configs.Config
+	userRepo          *repository.UserRepository
+	refreshTokenRepo  *repository.RefreshTokenRepository
+	userTokenRepo     *repository.UserTokenRepository
+	mfaRepo           *repository.UserMFARepository
+	apiKeyRepo        *repository.APIKeyRepository
+	sessionRepo       *repository.SessionRepository
+	impersonationRepo *repository.ImpersonationRepository
+	loginLimiter      *throttle.Limiter
+	revocationStore   revocation.Store
+	rbacService       rbac.Service
+	jwtService        *jwt.Service
+	mailer            mailer.Mailer
+	passwordPolicy    *validators.PasswordPolicy
+	userValidator     *validators.UserValidator
+	passwordHasher    password.Hasher
+
+	background sync.WaitGroup // 背景執行中的工作 (例如寄送信件)，關閉時等待工作完成
 }
 
+// backgroundShutdownTimeout 關閉時等待背景工作完成的時間上限
+const backgroundShutdownTimeout = 10 * time.Second
+
 // NewUserService 建立一個新的 user 實例
-func NewUserService(userRepo *repository.UserRepository, jwtService *jwt.Service) Service {
-	return &ServiceDefault{userRepo: userRepo, jwtService: jwtService}
+// 回傳的函數用來在關閉 server 時等待背景執行中的工作 (例如寄送信件) 完成，見 Close
+func NewUserService(
+	cfg *configs.Config,
+	userRepo *repository.UserRepository,
+	refreshTokenRepo *repository.RefreshTokenRepository,
+	userTokenRepo *repository.UserTokenRepository,
+	mfaRepo *repository.UserMFARepository,
+	apiKeyRepo *repository.APIKeyRepository,
+	sessionRepo *repository.SessionRepository,
+	impersonationRepo *repository.ImpersonationRepository,
+	loginLimiter *throttle.Limiter,
+	revocationStore revocation.Store,
+	rbacService rbac.Service,
+	jwtService *jwt.Service,
+	mailer mailer.Mailer,
+	passwordPolicy *validators.PasswordPolicy,
+	userValidator *validators.UserValidator,
+	passwordHasher password.Hasher,
+) (Service, func()) {
+	svc := &ServiceDefault{
+		cfg:               cfg,
+		userRepo:          userRepo,
+		refreshTokenRepo:  refreshTokenRepo,
+		userTokenRepo:     userTokenRepo,
+		mfaRepo:           mfaRepo,
+		apiKeyRepo:        apiKeyRepo,
+		sessionRepo:       sessionRepo,
+		impersonationRepo: impersonationRepo,
+		loginLimiter:      loginLimiter,
+		revocationStore:   revocationStore,
+		rbacService:       rbacService,
+		jwtService:        jwtService,
+		mailer:            mailer,
+		passwordPolicy:    passwordPolicy,
+		userValidator:     userValidator,
+		passwordHasher:    passwordHasher,
+	}
+	return svc, svc.Close
+}
+
+// Close 等待背景執行中的工作 (例如寄送驗證信、重設密碼信與登入連結) 完成，最多等待 backgroundShutdownTimeout
+// 必須在 server 停止接受請求之後呼叫；超過時間還沒完成的工作會被放棄並記錄警告
+func (svc *ServiceDefault) Close() {
+	if !svc.waitBackground(backgroundShutdownTimeout) {
+		logger.Logger.Warnf("Background tasks still running after %s, giving up", backgroundShutdownTimeout) // 記錄警告
+	}
+}
+
+// waitBackground 等待背景執行中的工作完成，超過 timeout 時回傳 false
+func (svc *ServiceDefault) waitBackground(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		svc.background.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
 }
 
 // CreateUser 建立一個新的使用者
+// 新的使用者處於等待驗證電子郵件的狀態，驗證信寄送失敗時不影響註冊結果，使用者可以要求重新寄送
+// 使用者資料不符合 validate tag、帳號名稱是保留的名稱或與其他使用者重複時回傳 validators.ErrValidation (Fields 列出所有違反的規則)；
+// 密碼不符合密碼規則時回傳 *validators.PasswordPolicyError
 // @param user body models.User true "使用者資訊"
 // @return error 錯誤訊息
 func (svc *ServiceDefault) CreateUser(user *models.User) error {
+	// 檢查使用者資料，帳號名稱會被正規化
+	if err := svc.userValidator.Validate(user); err != nil {
+		return err
+	}
+
+	// 檢查密碼規則
+	if err := svc.passwordPolicy.Validate(user.Password, user.Username, user.Email); err != nil {
+		return err
+	}
+
+	user.Status = models.UserStatusPendingVerification
+
 	// 將密碼加密
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	hashedPassword, err := svc.hashPassword(user.Password)
 	if err != nil {
-		logger.Logger.Errorf("Error hashing password: %v", err) // 記錄密碼加密錯誤
 		return err
 	}
-	user.Password = string(hashedPassword)
+	user.Password = hashedPassword
 
-	// 將使用者資料存入資料庫
-	err = svc.userRepo.Create(user)
+	// 在同一個交易中將使用者資料存入資料庫並指派預設角色，指派失敗時不會留下沒有角色的使用者
+	err = svc.userRepo.CreateWithRoles(user, models.RoleUser)
 	if err != nil {
 		logger.Logger.Errorf("Error creating user in repository: %v", err) // 記錄資料庫錯誤
 		return err
 	}
 
+	// 寄送驗證信
+	if err := svc.sendVerificationEmail(user); err != nil {
+		logger.Logger.Warnf("Error sending verification email to user %s: %v", user.Username, err) // 記錄錯誤
+	}
+
 	logger.Logger.Infof("User created successfully: %s", user.Username) // 記錄使用者建立成功
 	return nil
 }
@@ -74,14 +180,30 @@ func (svc *ServiceDefault) GetUserByUsername(username string) (*models.User, err
 }
 
 // UpdateUser 更新使用者資訊
+// 帳號名稱或電子郵件不符合規則、或與其他使用者重複時回傳 validators.ErrValidation
 // @param user body models.User true "使用者資訊"
 // @return error 錯誤訊息
 func (svc *ServiceDefault) UpdateUser(user *models.User) error {
-	err := svc.userRepo.Update(user)
+	if err := svc.userValidator.Validate(user, "Username", "Email"); err != nil {
+		return err
+	}
+
+	// 只更新個人資料欄位，密碼、狀態等欄位必須透過各自的流程變更，避免被請求內容覆寫
+	err := svc.userRepo.UpdateColumns(user.ID, map[string]interface{}{
+		"username": user.Username,
+		"email":    user.Email,
+	})
 	if err != nil {
 		logger.Logger.Errorf("Error updating user in repository: %v", err) // 記錄錯誤
-		return err
+		return translateNotFound(err)
 	}
+
+	// 重新讀取完整的使用者資料回傳給呼叫端
+	updated, err := svc.userRepo.GetByID(user.ID)
+	if err != nil {
+		return translateNotFound(err)
+	}
+	*user = *updated
 	logger.Logger.Debugf("User updated: %s", user.Username) // 記錄使用者已更新
 	return nil
 }
@@ -90,6 +212,11 @@ func (svc *ServiceDefault) UpdateUser(user *models.User) error {
 // @param id path uint true "使用者 ID"
 // @return error 錯誤訊息
 func (svc *ServiceDefault) DeleteUser(id uint) error {
+	// 將帳號停用並記錄由使用者本人刪除；停用時會讓使用者所有的 token 失效
+	if err := svc.ChangeUserStatus(id, models.UserStatusDeactivated, &id, "deleted by user"); err != nil {
+		return err
+	}
+
 	err := svc.userRepo.Delete(id)
 	if err != nil {
 		logger.Logger.Errorf("Error deleting user in repository: %v", err) // 記錄錯誤
@@ -100,39 +227,159 @@ func (svc *ServiceDefault) DeleteUser(id uint) error {
 }
 
 // Login 使用者登入
+// 沒有啟用兩步驟驗證時直接回傳 token 組合；啟用時只回傳短效期的 MFA token，必須再呼叫 LoginMFA 完成登入
 // @param username body string true "使用者名稱"
 // @param password body string true "密碼"
-// @return token JWT token
-// @return error 錯誤訊息
-func (svc *ServiceDefault) Login(username, password string) (string, error) {
+// @param client header ClientInfo false "來源 IP 與 User-Agent，IP 用於計算登入失敗次數"
+// @return result 登入結果
+// @return error 錯誤訊息，失敗次數過多時為 *throttle.ThrottledError
+func (svc *ServiceDefault) Login(username, password string, client ClientInfo) (*LoginResult, error) {
+	// 在查詢資料庫與驗證密碼之前檢查失敗次數並先把這次嘗試記為失敗，避免暴力破解以及大量的密碼雜湊運算
+	// 同時送出的請求也會依序計數，不會在任何一次失敗被記錄之前全部通過檢查
+	if err := svc.loginLimiter.Attempt(username, client.IP); err != nil {
+		logger.Logger.Debugf("Login throttled for user %s from %s: %v", username, client.IP, err) // 記錄錯誤
+		return nil, err
+	}
+
 	// 根據使用者名稱取得使用者資訊
 	user, err := svc.userRepo.GetByUsername(username)
 	if err != nil {
 		logger.Logger.Debugf("Error getting user by username: %v", err) // 記錄錯誤
-		return "", ErrUserNotFound
+		return nil, ErrLoginUserNotFound
 	}
 
-	// 驗證密碼是否正確
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
-	if err != nil {
+	// 驗證密碼是否正確，服務帳號只能使用 API key
+	if user.ServiceAccount || !svc.verifyPassword(user, password) {
 		logger.Logger.Debugf("Invalid credentials for user: %s", username) // 記錄錯誤
-		return "", ErrInvalidCredentials
+		return nil, ErrInvalidCredentials
+	}
+
+	// 只有在這個時候拿得到明文密碼，使用舊的演算法或參數的雜湊值在此升級
+	svc.rehashPasswordIfNeeded(user, password)
+
+	// 檢查帳號是否可以登入
+	if err := checkLoginAllowed(user); err != nil {
+		logger.Logger.Debugf("Login refused for user %s: %v", username, err) // 記錄錯誤
+		return nil, err
 	}
 
+	result, err := svc.continueLogin(user, client, []string{jwt.AuthMethodPassword})
+	if err != nil {
+		return nil, err
+	}
+
+	// 完成登入之後才清除失敗紀錄；還需要兩步驟驗證時只歸還這次嘗試，
+	// 驗證碼錯誤時 LoginMFA 會再記為失敗，重新登入不會重設計數，驗證碼無法被暴力猜測
+	if result.MFARequired {
+		err = svc.loginLimiter.Release(username, client.IP)
+	} else {
+		err = svc.loginLimiter.RecordSuccess(username, client.IP)
+	}
+	if err != nil {
+		logger.Logger.Warnf("Error clearing failed login attempts: %v", err) // 記錄錯誤
+	}
+	return result, nil
+}
+
+// continueLogin 第一個驗證方式 (密碼或登入連結) 通過後繼續登入
+// 啟用兩步驟驗證的使用者只回傳 MFA token，必須再提供驗證碼；其他使用者直接完成登入
+func (svc *ServiceDefault) continueLogin(user *models.User, client ClientInfo, authMethods []string) (*LoginResult, error) {
+	mfa, err := svc.mfaRepo.GetByUserID(user.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if mfa.Enabled() {
+		mfaToken, _, err := svc.createUserToken(&models.UserToken{
+			UserID:      user.ID,
+			Purpose:     models.UserTokenPurposeMFAPending,
+			AuthMethods: authMethods,
+		}, svc.cfg.MFAPendingExpiresIn)
+		if err != nil {
+			logger.Logger.Errorf("Error generating MFA token: %v", err) // 記錄錯誤
+			return nil, err
+		}
+		logger.Logger.Infof("User passed %v check, MFA required: %s", authMethods, user.Username) // 記錄等待兩步驟驗證
+		return &LoginResult{
+			MFARequired:  true,
+			MFAToken:     mfaToken,
+			MFAExpiresIn: int64(svc.cfg.MFAPendingExpiresIn.Seconds()),
+		}, nil
+	}
+
+	tokens, err := svc.completeLogin(user, client, authMethods)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResult{TokenPair: tokens}, nil
+}
+
+// completeLogin 所有驗證都通過後完成登入，更新最後登入時間，建立新的登入工作階段並發行 token 組合
+// authMethods 為這次登入通過的驗證方式
+func (svc *ServiceDefault) completeLogin(user *models.User, client ClientInfo, authMethods []string) (*TokenPair, error) {
 	// 更新最後登入時間
 	user.LastLogin = time.Now()
-	updateErr := svc.userRepo.Update(user)
+	updateErr := svc.userRepo.UpdateColumns(user.ID, map[string]interface{}{"last_login": user.LastLogin})
 	if updateErr != nil {
 		logger.Logger.Warnf("Error updating last login time: %v", updateErr) // 記錄錯誤
 	}
 
-	// 產生 JWT token
-	token, err := svc.jwtService.GenerateToken(user.ID)
+	// 建立工作階段，並產生 access token 與新的 refresh token family
+	tokens, err := svc.startSession(user.ID, client, authMethods)
 	if err != nil {
 		logger.Logger.Errorf("Error generating token: %v", err) // 記錄錯誤
+		return nil, err
+	}
+
+	logger.Logger.Infof("User logged in: %s", user.Username) // 記錄使用者登入
+	return tokens, nil
+}
+
+// hashPassword 使用設定的演算法將密碼加密，註冊、變更與重設密碼共用
+func (svc *ServiceDefault) hashPassword(plain string) (string, error) {
+	hashedPassword, err := svc.passwordHasher.Hash(plain)
+	if err != nil {
+		logger.Logger.Errorf("Error hashing password: %v", err) // 記錄密碼加密錯誤
 		return "", err
 	}
+	return hashedPassword, nil
+}
+
+// verifyPassword 驗證密碼是否與使用者儲存的雜湊值相符，雜湊值無法解析時視為不相符
+func (svc *ServiceDefault) verifyPassword(user *models.User, plain string) bool {
+	ok, err := svc.passwordHasher.Verify(plain, user.Password)
+	if err != nil {
+		logger.Logger.Errorf("Error verifying password hash of user %d: %v", user.ID, err) // 記錄錯誤
+		return false
+	}
+	return ok
+}
+
+// rehashPasswordIfNeeded 驗證密碼成功後，如果雜湊值使用舊的演算法或參數就重新雜湊並儲存
+// 升級失敗不影響登入，下次登入時會再試一次
+func (svc *ServiceDefault) rehashPasswordIfNeeded(user *models.User, plain string) {
+	if !svc.passwordHasher.NeedsRehash(user.Password) {
+		return
+	}
+	hashedPassword, err := svc.hashPassword(plain)
+	if err != nil {
+		logger.Logger.Warnf("Error rehashing password of user %d: %v", user.ID, err) // 記錄錯誤
+		return
+	}
+	if err := svc.userRepo.UpdateColumns(user.ID, map[string]interface{}{"password": hashedPassword}); err != nil {
+		logger.Logger.Warnf("Error saving rehashed password of user %d: %v", user.ID, err) // 記錄錯誤
+		return
+	}
+	user.Password = hashedPassword
+	logger.Logger.Infof("Password hash of user %d upgraded to %s", user.ID, svc.passwordHasher.Algorithm()) // 記錄已升級
+}
 
-	logger.Logger.Infof("User logged in: %s", username) // 記錄使用者登入
-	return token, nil
+// checkLoginAllowed 檢查帳號目前的狀態是否允許登入或換發 token
+func checkLoginAllowed(user *models.User) error {
+	if err := accountStatusError(user.Status); err != nil {
+		return err
+	}
+	if user.PasswordResetRequired {
+		return ErrPasswordResetRequired
+	}
+	return nil
 }
@@ -0,0 +1,125 @@
+package user
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-template/internal/configs"
+	"go-template/internal/models"
+	"go-template/internal/repository"
+	"go-template/internal/services/rbac"
+	"go-template/internal/services/revocation"
+	"go-template/internal/services/throttle"
+	"go-template/internal/utils/jwt"
+	"go-template/internal/utils/logger"
+	"go-template/internal/utils/mailer"
+	"go-template/internal/utils/password"
+	"go-template/internal/validators"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormLog "gorm.io/gorm/logger"
+)
+
+func TestMain(m *testing.M) {
+	_ = logger.Init(&logger.Config{Level: "error", ConsoleOut: true, ServiceName: "user-service-test"})
+	os.Exit(m.Run())
+}
+
+// newMockDB 建立使用 sqlmock 的 *gorm.DB，測試結束時檢查所有預期的語句都已執行
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	conn, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{Logger: gormLog.Default.LogMode(gormLog.Silent)})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	return db, mock
+}
+
+// newTestService 建立使用 sqlmock 資料庫、記憶體撤銷清單與只保存在記憶體的 outbox 的使用者服務
+func newTestService(t *testing.T) (*ServiceDefault, sqlmock.Sqlmock) {
+	t.Helper()
+	cfg := &configs.Config{
+		JWTSecret:                       "secret",
+		TokenExpiresIn:                  time.Minute,
+		RefreshTokenExpiresIn:           time.Hour,
+		AppBaseURL:                      "https://app.example.com",
+		EmailVerificationExpiresIn:      time.Hour,
+		EmailVerificationResendInterval: time.Minute,
+		PasswordResetExpiresIn:          time.Hour,
+		PasswordResetResendInterval:     time.Minute,
+		PasswordHashAlgorithm:           password.AlgorithmBcrypt,
+		PasswordBcryptCost:              bcrypt.MinCost,
+		PasswordArgon2Memory:            1024,
+		PasswordArgon2Iterations:        1,
+		PasswordArgon2Parallelism:       1,
+	}
+	jwtService, _, err := jwt.NewService(cfg)
+	require.NoError(t, err)
+	outbox, err := mailer.NewOutbox("", "noreply@example.com")
+	require.NoError(t, err)
+	passwordPolicy, err := validators.NewPasswordPolicy(cfg)
+	require.NoError(t, err)
+	passwordHasher, err := password.New(cfg)
+	require.NoError(t, err)
+	db, mock := newMockDB(t)
+	svc, _ := NewUserService(cfg, repository.NewUserRepository(db), repository.NewRefreshTokenRepository(db),
+		repository.NewUserTokenRepository(db), repository.NewUserMFARepository(db), repository.NewAPIKeyRepository(db),
+		repository.NewSessionRepository(db), repository.NewImpersonationRepository(db),
+		throttle.NewLimiter(cfg, throttle.NewMemoryStore()), revocation.NewMemoryStore(func(uint) (bool, error) { return true, nil }),
+		rbac.NewService(repository.NewRoleRepository(db)), jwtService, outbox, passwordPolicy,
+		validators.NewUserValidator(repository.NewUserRepository(db)), passwordHasher)
+	return svc.(*ServiceDefault), mock
+}
+
+// testUser 建立測試使用的使用者
+func testUser(id uint, username string, status models.UserStatus) *models.User {
+	user := &models.User{Username: username, Email: username + "@example.com", Status: status}
+	user.ID = id
+	return user
+}
+
+// sentMessages 取得測試服務寄出的信件
+func sentMessages(svc *ServiceDefault) []mailer.Message {
+	return svc.mailer.(*mailer.Outbox).Messages()
+}
+
+// 測試登入時帳號名稱以 models.UsernameKey 比較，全形與大小寫不同的寫法登入同一個帳號並共用失敗計數
+func TestLoginNormalisedUsername(t *testing.T) {
+	svc, mock := newTestService(t)
+	hashedPassword, err := svc.hashPassword("password")
+	require.NoError(t, err)
+
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE username_key = \$1`).WithArgs("alice", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password"}).AddRow(7, "Alice", hashedPassword))
+	mock.ExpectQuery(`SELECT \* FROM "user_mfa" WHERE user_id = \$1`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	expectFinishLogin(mock, 7, "user")
+
+	result, err := svc.Login(" ＡＬＩＣＥ ", "password", ClientInfo{})
+	require.NoError(t, err)
+	assert.False(t, result.MFARequired)
+	assert.Equal(t, throttle.UsernameKey("alice"), throttle.UsernameKey(" ＡＬＩＣＥ "))
+}
+
+// 測試關閉時等待背景寄送的信件寄出，超過等待時間時放棄等待
+func TestCloseWaitsForBackground(t *testing.T) {
+	svc, _ := newTestService(t)
+	mail := newBlockingMailer(t)
+	svc.mailer = mail
+	svc.inBackground(func() {
+		_ = svc.mailer.Send(mailer.Message{To: "alice@example.com", Subject: "hello"})
+	})
+
+	assert.False(t, svc.waitBackground(10*time.Millisecond), "the email is still being sent")
+
+	close(mail.release)
+	svc.Close()
+	assert.Len(t, mail.outbox.Messages(), 1)
+}
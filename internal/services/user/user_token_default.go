@@ -0,0 +1,96 @@
+package user
+
+import (
+	"errors"
+	"time"
+
+	"go-template/internal/models"
+	"go-template/internal/utils/jwt"
+	"go-template/internal/utils/logger"
+)
+
+// errUserTokenInvalid 一次性 token 不存在、已過期或已被使用，由呼叫端轉換成對應用途的錯誤
+var errUserTokenInvalid = errors.New("invalid user token")
+
+// issueUserToken 產生新的一次性 token 並儲存雜湊值，使用者相同用途的舊 token 會失效
+// 回傳要寄給使用者的明文 token 與過期時間
+func (svc *ServiceDefault) issueUserToken(userID uint, purpose string, ttl time.Duration) (string, time.Time, error) {
+	return svc.createUserToken(&models.UserToken{UserID: userID, Purpose: purpose}, ttl)
+}
+
+// createUserToken 與 issueUserToken 相同，但可以在 token 上附帶其他資料 (例如已通過的驗證方式)
+func (svc *ServiceDefault) createUserToken(userToken *models.UserToken, ttl time.Duration) (string, time.Time, error) {
+	if err := svc.userTokenRepo.InvalidateByUser(userToken.UserID, userToken.Purpose); err != nil {
+		return "", time.Time{}, err
+	}
+
+	token, tokenHash, err := jwt.GenerateOpaqueToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	userToken.TokenHash = tokenHash
+	userToken.ExpiresAt = time.Now().Add(ttl)
+	if err := svc.userTokenRepo.Create(userToken); err != nil {
+		return "", time.Time{}, err
+	}
+	return token, userToken.ExpiresAt, nil
+}
+
+// consumeUserToken 驗證並使用一次性 token，每個 token 只能成功使用一次
+func (svc *ServiceDefault) consumeUserToken(purpose, token string) (*models.UserToken, error) {
+	stored, err := svc.lookupUserToken(purpose, token)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.markUserTokenUsed(stored); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// lookupUserToken 取得尚未使用且尚未過期的一次性 token，但不標記為已使用
+// 用於使用 token 前還需要檢查其他條件 (例如新密碼是否符合規則) 的情況，檢查通過後再呼叫 markUserTokenUsed
+func (svc *ServiceDefault) lookupUserToken(purpose, token string) (*models.UserToken, error) {
+	stored, err := svc.userTokenRepo.GetByHash(purpose, jwt.HashOpaqueToken(token))
+	if err != nil {
+		return nil, errUserTokenInvalid
+	}
+	if stored.UsedAt != nil || !time.Now().Before(stored.ExpiresAt) {
+		logger.Logger.Debugf("User token (%s) of user %d already used or expired", purpose, stored.UserID) // 記錄錯誤
+		return nil, errUserTokenInvalid
+	}
+	return stored, nil
+}
+
+// markUserTokenUsed 將一次性 token 標記為已使用
+// 已使用、已過期或被其他請求搶先使用的 token 都無法標記，此時回傳 errUserTokenInvalid
+func (svc *ServiceDefault) markUserTokenUsed(stored *models.UserToken) error {
+	marked, err := svc.userTokenRepo.MarkUsed(stored.ID)
+	if err != nil {
+		return err
+	}
+	if !marked {
+		logger.Logger.Debugf("User token (%s) of user %d already used or expired", stored.Purpose, stored.UserID) // 記錄錯誤
+		return errUserTokenInvalid
+	}
+	return nil
+}
+
+// userTokenThrottled 判斷使用者相同用途的 token 是否在 interval 內已經寄送過
+func (svc *ServiceDefault) userTokenThrottled(userID uint, purpose string, interval time.Duration) (bool, error) {
+	latest, err := svc.userTokenRepo.LatestCreatedAt(userID, purpose)
+	if err != nil {
+		return false, err
+	}
+	return time.Since(latest) < interval, nil
+}
+
+// inBackground 在背景執行與帳號是否存在有關的工作 (檢查、產生 token 與寄送信件)
+// 請求在查詢使用者之後立即返回，回應時間不會因為帳號存在而變長，避免洩漏帳號是否存在
+func (svc *ServiceDefault) inBackground(task func()) {
+	svc.background.Add(1)
+	go func() {
+		defer svc.background.Done()
+		task()
+	}()
+}
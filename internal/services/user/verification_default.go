@@ -0,0 +1,96 @@
+package user
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"go-template/internal/models"
+	"go-template/internal/utils/logger"
+	"go-template/internal/utils/mailer"
+)
+
+// VerifyEmail 使用驗證信中的 token 驗證電子郵件，驗證成功後帳號狀態變更成 active
+// 每個 token 只能使用一次
+// @param token body string true "驗證 token"
+// @return error 錯誤訊息
+func (svc *ServiceDefault) VerifyEmail(token string) error {
+	stored, err := svc.consumeUserToken(models.UserTokenPurposeEmailVerification, token)
+	if err == errUserTokenInvalid {
+		return ErrInvalidVerificationToken
+	}
+	if err != nil {
+		return err
+	}
+
+	err = svc.ChangeUserStatus(stored.UserID, models.UserStatusActive, &stored.UserID, "email verified")
+	if err == ErrInvalidStatusTransition || err == ErrUserNotFound {
+		// 帳號已經不是等待驗證的狀態 (例如已被停權)，驗證 token 不能用來啟用帳號
+		return ErrInvalidVerificationToken
+	}
+	if err != nil {
+		return err
+	}
+
+	logger.Logger.Infof("Email verified for user: %d", stored.UserID) // 記錄電子郵件已驗證
+	return nil
+}
+
+// ResendVerificationEmail 重新寄送驗證信，舊的驗證 token 會失效
+// 為了避免洩漏帳號是否存在，找不到使用者、帳號不需要驗證、寄送過於頻繁或寄送失敗時都不會回傳錯誤，只是不寄送信件；
+// 與 ForgotPassword 相同，找到使用者之後的檢查與寄送都在背景執行
+// @param email body string true "電子郵件"
+// @return error 錯誤訊息
+func (svc *ServiceDefault) ResendVerificationEmail(email string) error {
+	user, err := svc.userRepo.GetByEmail(email)
+	if err != nil {
+		logger.Logger.Debugf("Verification email not resent: no user with email %s", email) // 記錄略過
+		return nil
+	}
+
+	svc.inBackground(func() { svc.deliverVerification(user) })
+	return nil
+}
+
+// deliverVerification 檢查使用者是否在等待驗證，是的話重新寄送驗證信，錯誤只會記錄
+func (svc *ServiceDefault) deliverVerification(user *models.User) {
+	if user.Status != models.UserStatusPendingVerification {
+		logger.Logger.Debugf("Verification email not resent: user %d is %s", user.ID, user.Status) // 記錄略過
+		return
+	}
+
+	throttled, err := svc.userTokenThrottled(user.ID, models.UserTokenPurposeEmailVerification,
+		svc.cfg.EmailVerificationResendInterval)
+	if err != nil {
+		logger.Logger.Errorf("Error checking verification email throttle: %v", err) // 記錄錯誤
+		return
+	}
+	if throttled {
+		logger.Logger.Debugf("Verification email to user %d throttled", user.ID) // 記錄略過
+		return
+	}
+
+	if err := svc.sendVerificationEmail(user); err != nil {
+		logger.Logger.Errorf("Error resending verification email to user %d: %v", user.ID, err) // 記錄錯誤
+	}
+}
+
+// sendVerificationEmail 產生新的驗證 token 並寄送驗證信，使用者之前的驗證 token 會失效
+func (svc *ServiceDefault) sendVerificationEmail(user *models.User) error {
+	token, expiresAt, err := svc.issueUserToken(user.ID, models.UserTokenPurposeEmailVerification,
+		svc.cfg.EmailVerificationExpiresIn)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/verify-email?token=%s", svc.cfg.AppBaseURL, url.QueryEscape(token))
+	return svc.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Please verify your email address by opening the link below:\n\n%s\n\n"+
+			"Or submit this verification code: %s\n\n"+
+			"The link expires at %s. If you did not create an account, you can ignore this email.\n",
+			user.Username, link, token, expiresAt.UTC().Format(time.RFC1123)),
+	})
+}
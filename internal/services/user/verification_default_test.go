@@ -0,0 +1,39 @@
+package user
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-template/internal/models"
+)
+
+// 測試檢查寄送頻率時的資料庫錯誤與 ForgotPassword 一樣只會記錄，不會回傳給呼叫者
+func TestResendVerificationEmailThrottleErrorNotReturned(t *testing.T) {
+	svc, mock := newTestService(t)
+
+	expectUserByEmail(mock, testUser(7, "alice", models.UserStatusPendingVerification))
+	mock.ExpectQuery(`SELECT \* FROM "user_tokens" WHERE user_id = \$1 AND purpose = \$2`).
+		WillReturnError(errors.New("connection reset"))
+
+	require.NoError(t, svc.ResendVerificationEmail("alice@example.com"))
+	svc.background.Wait()
+	assert.Empty(t, sentMessages(svc))
+}
+
+// 測試等待驗證的使用者會在背景收到新的驗證信
+func TestResendVerificationEmailSendsInBackground(t *testing.T) {
+	svc, mock := newTestService(t)
+
+	expectUserByEmail(mock, testUser(7, "alice", models.UserStatusPendingVerification))
+	expectUserTokenThrottle(mock, 7, models.UserTokenPurposeEmailVerification, time.Time{})
+	expectIssueUserToken(mock, 7, models.UserTokenPurposeEmailVerification)
+
+	require.NoError(t, svc.ResendVerificationEmail("alice@example.com"))
+	svc.background.Wait()
+	messages := sentMessages(svc)
+	require.Len(t, messages, 1)
+	assert.Contains(t, messages[0].Body, "https://app.example.com/verify-email?token=")
+}
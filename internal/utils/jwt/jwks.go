@@ -0,0 +1,113 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// JWK JSON Web Key (RFC 7517)，只包含公鑰資訊
+type JWK struct {
+	Kty string `json:"kty"`           // 金鑰類型: RSA、EC、OKP
+	Use string `json:"use,omitempty"` // 用途，固定為 sig
+	Alg string `json:"alg,omitempty"` // 簽署演算法
+	Kid string `json:"kid,omitempty"` // 金鑰 ID
+	N   string `json:"n,omitempty"`   // RSA modulus
+	E   string `json:"e,omitempty"`   // RSA exponent
+	Crv string `json:"crv,omitempty"` // 曲線名稱: P-256、Ed25519
+	X   string `json:"x,omitempty"`   // EC/OKP x 座標
+	Y   string `json:"y,omitempty"`   // EC y 座標
+}
+
+// JWKSet JSON Web Key Set，對應 /.well-known/jwks.json 的回應格式
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS 取得目前所有可公開的驗證金鑰
+// HMAC 共用密鑰不能公開，因此不會出現在結果中；已退役的金鑰也會被移除
+func (s *Service) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range s.keyring.publicKeys(time.Now()) {
+		if jwk, err := publicJWK(key); err == nil {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	// 依照 kid 排序，讓回應內容固定
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
+
+// publicJWK 將金鑰的公鑰部分轉換成 JWK
+func publicJWK(key *signingKey) (JWK, error) {
+	jwk := JWK{Use: "sig", Alg: key.method.Alg(), Kid: key.id}
+
+	switch publicKey := key.verifyKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encodeBase64URL(publicKey.N.Bytes())
+		jwk.E = encodeBase64URL(big.NewInt(int64(publicKey.E)).Bytes())
+	case *ecdsa.PublicKey:
+		// P-256 的座標固定為 32 bytes，不足的部分需要補零
+		size := (publicKey.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = publicKey.Curve.Params().Name
+		jwk.X = encodeBase64URL(publicKey.X.FillBytes(make([]byte, size)))
+		jwk.Y = encodeBase64URL(publicKey.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = encodeBase64URL(publicKey)
+	default:
+		return JWK{}, errors.New("unsupported public key type")
+	}
+	return jwk, nil
+}
+
+// thumbprint 計算 RFC 7638 JWK thumbprint
+// 只取必要欄位並依照字母順序排列後做 SHA-256
+func (k JWK) thumbprint() (string, error) {
+	var members interface{}
+	switch k.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{k.E, k.Kty, k.N}
+	case "EC":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{k.Crv, k.Kty, k.X, k.Y}
+	case "OKP":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{k.Crv, k.Kty, k.X}
+	default:
+		return "", errors.New("unsupported key type")
+	}
+
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return encodeBase64URL(sum[:]), nil
+}
+
+// encodeBase64URL 使用不含 padding 的 base64url 編碼
+func encodeBase64URL(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
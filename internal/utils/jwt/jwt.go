@@ -1,95 +1,321 @@
 package jwt
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"go-template/internal/configs"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"go-template/internal/utils/logger"
 )
 
+// 登入時使用的驗證方式，寫入 amr claim (RFC 8176)
+const (
+	AuthMethodPassword  = "pwd"   // 密碼
+	AuthMethodMagicLink = "email" // 寄到電子郵件的登入連結
+	AuthMethodMFA       = "mfa"   // 通過兩步驟驗證
+)
+
+// UserToken 發行使用者 access token 需要的資訊
+type UserToken struct {
+	UserID      uint          // 使用者 ID
+	Roles       []string      // 使用者擁有的角色
+	SessionID   string        // 登入工作階段 ID
+	AuthTime    time.Time     // 使用者實際完成登入的時間，換發 token 時不會改變，零值代表不寫入
+	AuthMethods []string      // 登入時使用的驗證方式
+	ActorID     uint          // 代替使用者操作 (impersonation) 的管理者 ID，寫入 act claim，0 代表不是代替操作
+	ExpiresIn   time.Duration // token 的有效時間，0 代表使用預設的 access token 有效時間
+	TokenID     string        // 指定 token 的 jti，空字串代表自動產生
+}
+
+// Claims 驗證成功後從 token 取出的資訊
+type Claims struct {
+	UserID      uint      // 使用者 ID (sub)，OAuth2 client 的 token 為 0
+	ClientID    string    // OAuth2 client ID (client_id)，只有 client credentials 發行的 token 才有
+	SessionID   string    // 登入工作階段 ID (sid)，同一次登入換發的 token 共用同一個 sid
+	TokenID     string    // Token ID (jti)，用於撤銷單一 token
+	Tenant      string    // 租戶 ID (tid)
+	IssuedAt    time.Time // 發行時間 (iat)
+	ExpiresAt   time.Time // 過期時間 (exp)
+	AuthTime    time.Time // 使用者完成登入的時間 (auth_time)，沒有這個 claim 時為零值
+	AuthMethods []string  // 登入時使用的驗證方式 (amr)
+	Roles       []string  // 使用者擁有的角色 (roles)
+	Scopes      []string  // OAuth2 client 被授與的權限 (scope)
+	ActorID     uint      // 實際操作的管理者 ID (act.sub)，不是代替使用者操作的 token 為 0
+}
+
+// IsClient 判斷 token 的主體是否為 OAuth2 client 而不是使用者
+func (c *Claims) IsClient() bool {
+	return c.ClientID != ""
+}
+
+// IsImpersonated 判斷 token 是否為管理者代替使用者操作 (impersonation) 時發行的 token
+func (c *Claims) IsImpersonated() bool {
+	return c.ActorID != 0
+}
+
+// MFA 判斷使用者登入時是否通過兩步驟驗證
+func (c *Claims) MFA() bool {
+	return slices.Contains(c.AuthMethods, AuthMethodMFA)
+}
+
+// tokenClaims 實際寫入 token 的 claims
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	Roles       []string         `json:"roles,omitempty"`     // 使用者擁有的角色
+	SessionID   string           `json:"sid,omitempty"`       // 登入工作階段 ID
+	Tenant      string           `json:"tid,omitempty"`       // 租戶 ID
+	AuthTime    *jwt.NumericDate `json:"auth_time,omitempty"` // 使用者完成登入的時間 (OpenID Connect)
+	AuthMethods []string         `json:"amr,omitempty"`       // 登入時使用的驗證方式 (RFC 8176)
+	ClientID    string           `json:"client_id,omitempty"` // OAuth2 client ID (RFC 9068)
+	Scope       string           `json:"scope,omitempty"`     // 以空白分隔的權限 (RFC 9068)
+	Actor       *actorClaim      `json:"act,omitempty"`       // 實際操作的一方 (RFC 8693)
+	IssuedAtMs  int64            `json:"iat_ms,omitempty"`    // 精確到毫秒的發行時間 (Unix 毫秒)
+}
+
+// actorClaim act claim 的內容，sub 為實際操作的管理者 ID
+type actorClaim struct {
+	Subject string `json:"sub"`
+}
+
+// clockSkew 驗證 exp、nbf、iat 時容許的伺服器時鐘誤差
+const clockSkew = 30 * time.Second
+
 // Service Struct，用於產生和驗證 JWT token
 type Service struct {
-	secretKey     string
-	oldSecretKeys []string // 新增欄位儲存舊密鑰
-	issuer        string
-	expiration    time.Duration
+	cfg               *configs.Config
+	keyring           *keyring // 以 kid 為索引的簽署與驗證金鑰
+	issuer            string
+	audience          string
+	tenant            string
+	expiration        time.Duration
+	refreshExpiration time.Duration // Refresh token 的過期時間
 }
 
 // NewService 建立一個新的 JWTService 實例
-func NewService(cfg *configs.Config) *Service {
-	logger.Logger.Debugf("Initializing JWTService with secret: %s", cfg.JWTSecret) // 新增日誌
-	return &Service{
-		secretKey:     cfg.JWTSecret,
-		oldSecretKeys: cfg.JWTOldSecrets,  // 初始化舊密鑰列表
-		issuer:        "go-template",      // JWT 的發行者，可以根據你的應用程式修改
-		expiration:    cfg.TokenExpiresIn, // Token 的過期時間
+// 回傳的函數用來停止定期重新載入 keyring 設定檔的 goroutine，並等待它結束
+func NewService(cfg *configs.Config) (*Service, func(), error) {
+	// 載入 keyring，非對稱演算法會從 PEM 檔案讀取私鑰
+	keys, err := loadKeyring(cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error loading JWT keyring: %w", err)
+	}
+
+	// 未設定時預設為 go-template，aud 預設與 iss 相同
+	issuer := cfg.JWTIssuer
+	if issuer == "" {
+		issuer = "go-template"
 	}
+	audience := cfg.JWTAudience
+	if audience == "" {
+		audience = issuer
+	}
+
+	svc := &Service{
+		cfg:               cfg,
+		keyring:           &keyring{keys: keys},
+		issuer:            issuer,                    // JWT 的發行者
+		audience:          audience,                  // JWT 的接收者
+		tenant:            cfg.JWTTenant,             // 租戶 ID，未設定時不寫入也不檢查
+		expiration:        cfg.TokenExpiresIn,        // Token 的過期時間
+		refreshExpiration: cfg.RefreshTokenExpiresIn, // Refresh token 的過期時間
+	}
+
+	// 使用 keyring 設定檔時，啟動一個 goroutine 來定期檢查設定檔是否改變，不需要重新啟動 server 就能輪換金鑰
+	stop := func() {}
+	if cfg.JWTKeyringFile != "" && cfg.JWTKeyringReloadInterval > 0 {
+		lastModified := keyringModTime(cfg.JWTKeyringFile)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			svc.watchKeyringFile(ctx, cfg.JWTKeyringReloadInterval, lastModified)
+		}()
+		stop = func() {
+			cancel()
+			<-done
+		}
+	}
+
+	logger.Logger.Debugf("Initializing JWTService with %d keys", len(keys)) // 新增日誌
+	return svc, stop, nil
 }
 
-// GenerateToken 產生一個 JWT token
-func (s *Service) GenerateToken(userID uint) (string, error) {
-	// 建立 JWT claims (有效負載)
-	claims := &jwt.RegisteredClaims{
-		Issuer:    s.issuer,                                         // 設定發行者
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.expiration)), // 設定過期時間
-		IssuedAt:  jwt.NewNumericDate(time.Now()),                   // 設定發行時間
-		Subject:   strconv.FormatUint(uint64(userID), 10),           // 將使用者 ID 轉換成字串並設定為 Subject
+// GenerateToken 為使用者產生一個 JWT token
+// 角色寫入 roles claim，工作階段寫入 sid claim，登入時間與驗證方式寫入 auth_time 與 amr claim
+func (s *Service) GenerateToken(user UserToken) (string, error) {
+	claims := &tokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: strconv.FormatUint(uint64(user.UserID), 10), // 將使用者 ID 轉換成字串並設定為 Subject
+			ID:      user.TokenID,                                // 未指定時由 sign 產生
+		},
+		Roles:       user.Roles,       // 設定角色
+		SessionID:   user.SessionID,   // 設定登入工作階段
+		AuthMethods: user.AuthMethods, // 設定驗證方式
+	}
+	if !user.AuthTime.IsZero() {
+		claims.AuthTime = jwt.NewNumericDate(user.AuthTime)
 	}
+	if user.ActorID != 0 {
+		claims.Actor = &actorClaim{Subject: strconv.FormatUint(uint64(user.ActorID), 10)}
+	}
+	expiresIn := user.ExpiresIn
+	if expiresIn <= 0 {
+		expiresIn = s.expiration
+	}
+	return s.sign(claims, expiresIn)
+}
 
-	// 使用 HMAC SHA256 演算法和 secretKey 簽署 token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.secretKey))
+// GenerateClientToken 為 OAuth2 client 產生 JWT token (client credentials grant)
+// sub 與 client_id 都是 client ID，scopes 以空白分隔寫入 scope claim，不包含 roles
+func (s *Service) GenerateClientToken(clientID string, scopes []string) (string, error) {
+	return s.sign(&tokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{Subject: clientID},
+		ClientID:         clientID,
+		Scope:            strings.Join(scopes, " "),
+	}, s.expiration)
 }
 
-// ValidateToken 驗證 JWT token
-func (s *Service) ValidateToken(tokenString string) (uint, error) {
-	// 先嘗試使用當前密鑰解析 token
-	userID, err := s.validateTokenWithSecret(tokenString, s.secretKey)
-	if err == nil {
-		return userID, nil
-	}
-
-	// 嘗試使用舊密鑰解析 token
-	for _, oldSecret := range s.oldSecretKeys {
-		userID, err := s.validateTokenWithSecret(tokenString, oldSecret)
-		if err == nil {
-			logger.Logger.Warnf("Token validated with old secret key")
-			return userID, nil
+// sign 補上 jti、發行者、接收者、租戶、發行、生效與過期時間，並使用目前啟用的金鑰簽署 token
+// token 的有效時間為 expiresIn
+func (s *Service) sign(claims *tokenClaims, expiresIn time.Duration) (string, error) {
+	now := time.Now().Truncate(time.Millisecond)
+	key, err := s.keyring.signingKeyAt(now)
+	if err != nil {
+		return "", err
+	}
+
+	// 每個 token 都有唯一的 jti，撤銷時以 jti 為單位；呼叫者需要事先知道 jti 時可以自行指定
+	if claims.ID == "" {
+		tokenID, err := NewTokenID()
+		if err != nil {
+			return "", err
 		}
+		claims.ID = tokenID // 設定 token ID
 	}
 
-	logger.Logger.Debugf("Invalid token: %v", err)
-	return 0, errors.New("invalid token")
+	claims.Issuer = s.issuer                                  // 設定發行者
+	claims.Audience = jwt.ClaimStrings{s.audience}            // 設定接收者
+	claims.Tenant = s.tenant                                  // 設定租戶
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(expiresIn)) // 設定過期時間
+	claims.IssuedAt = jwt.NewNumericDate(now)                 // 設定發行時間
+	claims.IssuedAtMs = now.UnixMilli()                       // 設定精確到毫秒的發行時間
+	claims.NotBefore = jwt.NewNumericDate(now)                // 設定生效時間
+
+	// 使用目前啟用的金鑰簽署 token，並在 header 中帶上 kid
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.id
+	return token.SignedString(key.signKey)
 }
 
-// validateTokenWithSecret 使用指定的 secret 驗證 token
-func (s *Service) validateTokenWithSecret(tokenString, secret string) (uint, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// 檢查簽名演算法是否符合預期
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		// 返回 secretKey 用於驗證簽名
-		return []byte(secret), nil
-	})
+// AccessTokenExpiresIn 取得 access token 的有效時間
+func (s *Service) AccessTokenExpiresIn() time.Duration {
+	return s.expiration
+}
+
+// Issuer 取得 token 的發行者 (iss)
+func (s *Service) Issuer() string {
+	return s.issuer
+}
+
+// Audience 取得 token 的接收者 (aud)
+func (s *Service) Audience() string {
+	return s.audience
+}
+
+// ValidateToken 驗證 JWT token
+// 根據 token header 的 kid 直接挑選驗證金鑰；沒有 kid 的舊 token 只會用目前的簽署金鑰驗證
+// iss、aud 必須與設定相符，exp、nbf 必須存在且在有效期間內；設定租戶時 tid 也必須相符
+func (s *Service) ValidateToken(tokenString string) (*Claims, error) {
+	claims := &tokenClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, s.keyFunc,
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuer(s.issuer),
+		jwt.WithAudience(s.audience),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+	)
 
 	// 如果解析失敗，返回錯誤
 	if err != nil {
-		return 0, err
+		logger.Logger.Debugf("Invalid token: %v", err)
+		return nil, errors.New("invalid token")
 	}
 
 	// 檢查 token 是否有效
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		userID, err := strconv.ParseUint(claims["sub"].(string), 10, 32)
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	// 函式庫只在 nbf 存在時檢查，這裡要求每個 token 都必須帶有 nbf
+	if claims.NotBefore == nil {
+		return nil, errors.New("missing not before in token")
+	}
+	if s.tenant != "" && claims.Tenant != s.tenant {
+		return nil, errors.New("invalid tenant in token")
+	}
+
+	result := &Claims{TokenID: claims.ID, Tenant: claims.Tenant, ExpiresAt: claims.ExpiresAt.Time}
+	if claims.ClientID != "" {
+		// OAuth2 client 的 token，sub 必須與 client_id 相同，避免 client 的 token 被當成使用者的 token
+		if claims.Subject != claims.ClientID {
+			return nil, errors.New("invalid client ID in token")
+		}
+		result.ClientID = claims.ClientID
+		result.Scopes = strings.Fields(claims.Scope)
+	} else {
+		userID, err := strconv.ParseUint(claims.Subject, 10, 32)
 		if err != nil {
-			return 0, errors.New("invalid user ID in token")
+			return nil, errors.New("invalid user ID in token")
+		}
+		result.UserID = uint(userID)
+		result.Roles = claims.Roles
+		result.SessionID = claims.SessionID
+		result.AuthMethods = claims.AuthMethods
+		if claims.AuthTime != nil {
+			result.AuthTime = claims.AuthTime.Time
 		}
-		return uint(userID), nil
+		if claims.Actor != nil {
+			actorID, err := strconv.ParseUint(claims.Actor.Subject, 10, 32)
+			if err != nil || actorID == 0 {
+				return nil, errors.New("invalid actor in token")
+			}
+			result.ActorID = uint(actorID)
+		}
+	}
+	// iat 只精確到秒，優先使用 iat_ms，撤銷使用者所有 token 之後同一秒內發行的新 token 才不會被一併拒絕
+	if claims.IssuedAtMs != 0 {
+		result.IssuedAt = time.UnixMilli(claims.IssuedAtMs)
+	} else if claims.IssuedAt != nil {
+		result.IssuedAt = claims.IssuedAt.Time
 	}
+	return result, nil
+}
+
+// keyFunc 根據 token header 取得驗證用的金鑰
+func (s *Service) keyFunc(token *jwt.Token) (interface{}, error) {
+	now := time.Now()
 
-	return 0, errors.New("invalid token")
+	var key *signingKey
+	var err error
+	if kid, ok := token.Header["kid"].(string); ok {
+		key, err = s.keyring.verificationKey(kid, now)
+	} else {
+		key, err = s.keyring.signingKeyAt(now)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 檢查簽名演算法是否符合金鑰，避免演算法混淆攻擊 (例如拿公鑰當 HMAC 密鑰)
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	// 返回驗證用的金鑰
+	return key.verifyKey, nil
 }
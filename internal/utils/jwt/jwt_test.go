@@ -0,0 +1,410 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-template/internal/configs"
+	"go-template/internal/utils/logger"
+)
+
+func TestMain(m *testing.M) {
+	_ = logger.Init(&logger.Config{Level: "error", ConsoleOut: true, ServiceName: "jwt-test"})
+	os.Exit(m.Run())
+}
+
+// 測試 HS256 的產生與驗證，以及舊密鑰的相容性
+func TestHMACToken(t *testing.T) {
+	oldSvc, _, err := NewService(&configs.Config{JWTSecret: "old-secret", TokenExpiresIn: time.Minute})
+	require.NoError(t, err)
+	oldToken, err := oldSvc.GenerateToken(UserToken{UserID: 7})
+	require.NoError(t, err)
+
+	svc, _, err := NewService(&configs.Config{
+		JWTSecret:      "new-secret",
+		JWTOldSecrets:  []string{"old-secret"},
+		TokenExpiresIn: time.Minute,
+	})
+	require.NoError(t, err)
+
+	token, err := svc.GenerateToken(UserToken{UserID: 42, Roles: []string{"admin"}, SessionID: "session-1"})
+	require.NoError(t, err)
+	claims, err := svc.ValidateToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, uint(42), claims.UserID)
+	assert.Equal(t, []string{"admin"}, claims.Roles)
+	assert.Equal(t, "session-1", claims.SessionID)
+	assert.NotEmpty(t, claims.TokenID)
+
+	claims, err = svc.ValidateToken(oldToken)
+	require.NoError(t, err, "token signed with an old secret should still be valid")
+	assert.Equal(t, uint(7), claims.UserID)
+
+	assert.Empty(t, svc.JWKS().Keys, "HMAC secrets must never be published")
+}
+
+// 測試 iat、nbf、exp 維持整數秒，iat_ms 保留到毫秒，撤銷時間同一秒內之後發行的 token 才能與之前發行的 token 區分
+func TestIssuedAtMillisecondPrecision(t *testing.T) {
+	svc, _, err := NewService(&configs.Config{JWTSecret: "secret", TokenExpiresIn: time.Minute})
+	require.NoError(t, err)
+
+	before := time.Now().Truncate(time.Millisecond)
+	token, err := svc.GenerateToken(UserToken{UserID: 42})
+	require.NoError(t, err)
+	after := time.Now()
+
+	claims, err := svc.ValidateToken(token)
+	require.NoError(t, err)
+	assert.False(t, claims.IssuedAt.Before(before), "iat %s is truncated below %s", claims.IssuedAt, before)
+	assert.False(t, claims.IssuedAt.After(after))
+
+	raw := gojwt.MapClaims{}
+	_, _, err = gojwt.NewParser().ParseUnverified(token, raw)
+	require.NoError(t, err)
+	for _, name := range []string{"iat", "nbf", "exp"} {
+		value, ok := raw[name].(float64)
+		require.True(t, ok, name)
+		assert.Equal(t, math.Trunc(value), value, "%s must be whole seconds", name)
+	}
+	assert.Equal(t, float64(claims.IssuedAt.UnixMilli()), raw["iat_ms"])
+	assert.Equal(t, claims.IssuedAt.Unix(), int64(raw["iat"].(float64)))
+}
+
+// 測試 OAuth2 client 的 token 與使用者的 token 不會混淆
+func TestClientToken(t *testing.T) {
+	svc, _, err := NewService(&configs.Config{JWTSecret: "secret", TokenExpiresIn: time.Minute})
+	require.NoError(t, err)
+
+	token, err := svc.GenerateClientToken("gtc_0123456789ab", []string{"users:read", "users:update"})
+	require.NoError(t, err)
+	claims, err := svc.ValidateToken(token)
+	require.NoError(t, err)
+	assert.True(t, claims.IsClient())
+	assert.Equal(t, "gtc_0123456789ab", claims.ClientID)
+	assert.Equal(t, uint(0), claims.UserID)
+	assert.Equal(t, []string{"users:read", "users:update"}, claims.Scopes)
+	assert.Empty(t, claims.Roles)
+
+	userToken, err := svc.GenerateToken(UserToken{UserID: 42, Roles: []string{"user"}})
+	require.NoError(t, err)
+	claims, err = svc.ValidateToken(userToken)
+	require.NoError(t, err)
+	assert.False(t, claims.IsClient())
+	assert.Equal(t, uint(42), claims.UserID)
+
+	// sub 與 client_id 不同的 token 不能通過驗證
+	forged, err := svc.sign(&tokenClaims{RegisteredClaims: gojwt.RegisteredClaims{Subject: "1"}, ClientID: "gtc_0123456789ab"}, time.Minute)
+	require.NoError(t, err)
+	_, err = svc.ValidateToken(forged)
+	assert.Error(t, err)
+}
+
+// 測試登入資訊的 claims，以及 iss、aud、nbf、tid 的驗證
+func TestUserClaimsValidation(t *testing.T) {
+	cfg := &configs.Config{JWTSecret: "secret", TokenExpiresIn: time.Minute, JWTAudience: "api", JWTTenant: "acme"}
+	svc, _, err := NewService(cfg)
+	require.NoError(t, err)
+
+	authTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	token, err := svc.GenerateToken(UserToken{
+		UserID:      42,
+		SessionID:   "session-1",
+		AuthTime:    authTime,
+		AuthMethods: []string{AuthMethodPassword, AuthMethodMFA},
+	})
+	require.NoError(t, err)
+	claims, err := svc.ValidateToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, "acme", claims.Tenant)
+	assert.True(t, claims.AuthTime.Equal(authTime))
+	assert.True(t, claims.MFA())
+
+	// 相同金鑰但 iss、aud 或 tid 不同的服務，不接受這個 token
+	for _, other := range []*configs.Config{
+		{JWTSecret: "secret", JWTIssuer: "other", JWTAudience: "api", JWTTenant: "acme"},
+		{JWTSecret: "secret", JWTAudience: "other", JWTTenant: "acme"},
+		{JWTSecret: "secret", JWTAudience: "api", JWTTenant: "other"},
+	} {
+		otherSvc, _, err := NewService(other)
+		require.NoError(t, err)
+		_, err = otherSvc.ValidateToken(token)
+		assert.Error(t, err)
+	}
+
+	// 沒有 nbf 或尚未生效的 token 不能通過驗證
+	sign := func(nbf *gojwt.NumericDate) string {
+		now := time.Now()
+		key, err := svc.keyring.signingKeyAt(now)
+		require.NoError(t, err)
+		raw := gojwt.NewWithClaims(key.method, &tokenClaims{RegisteredClaims: gojwt.RegisteredClaims{
+			Subject:   "42",
+			Issuer:    svc.Issuer(),
+			Audience:  gojwt.ClaimStrings{svc.Audience()},
+			ExpiresAt: gojwt.NewNumericDate(now.Add(time.Minute)),
+			NotBefore: nbf,
+		}, Tenant: "acme"})
+		raw.Header["kid"] = key.id
+		signed, err := raw.SignedString(key.signKey)
+		require.NoError(t, err)
+		return signed
+	}
+	_, err = svc.ValidateToken(sign(nil))
+	assert.Error(t, err)
+	_, err = svc.ValidateToken(sign(gojwt.NewNumericDate(time.Now().Add(time.Hour))))
+	assert.Error(t, err)
+	_, err = svc.ValidateToken(sign(gojwt.NewNumericDate(time.Now())))
+	assert.NoError(t, err)
+}
+
+// 測試代替使用者操作的 token 會帶有 act claim 與較短的有效時間
+func TestImpersonationToken(t *testing.T) {
+	svc, _, err := NewService(&configs.Config{JWTSecret: "secret", TokenExpiresIn: time.Hour})
+	require.NoError(t, err)
+
+	token, err := svc.GenerateToken(UserToken{UserID: 42, Roles: []string{"user"}, ActorID: 1, ExpiresIn: 5 * time.Minute})
+	require.NoError(t, err)
+	claims, err := svc.ValidateToken(token)
+	require.NoError(t, err)
+	assert.True(t, claims.IsImpersonated())
+	assert.Equal(t, uint(42), claims.UserID)
+	assert.Equal(t, uint(1), claims.ActorID)
+	assert.WithinDuration(t, time.Now().Add(5*time.Minute), claims.ExpiresAt, 2*time.Second)
+
+	token, err = svc.GenerateToken(UserToken{UserID: 42})
+	require.NoError(t, err)
+	claims, err = svc.ValidateToken(token)
+	require.NoError(t, err)
+	assert.False(t, claims.IsImpersonated())
+	assert.WithinDuration(t, time.Now().Add(time.Hour), claims.ExpiresAt, 2*time.Second)
+}
+
+// 測試非對稱演算法的產生、驗證與 JWKS
+func TestAsymmetricToken(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		algorithm string
+		key       interface{}
+		kty       string
+	}{
+		{AlgorithmRS256, rsaKey, "RSA"},
+		{AlgorithmES256, ecKey, "EC"},
+		{AlgorithmEdDSA, edKey, "OKP"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.algorithm, func(t *testing.T) {
+			svc, _, err := NewService(&configs.Config{
+				JWTAlgorithm:      tc.algorithm,
+				JWTPrivateKeyFile: writePrivateKey(t, tc.key),
+				TokenExpiresIn:    time.Minute,
+			})
+			require.NoError(t, err)
+
+			token, err := svc.GenerateToken(UserToken{UserID: 1})
+			require.NoError(t, err)
+			claims, err := svc.ValidateToken(token)
+			require.NoError(t, err)
+			assert.Equal(t, uint(1), claims.UserID)
+
+			jwks := svc.JWKS()
+			require.Len(t, jwks.Keys, 1)
+			assert.Equal(t, tc.kty, jwks.Keys[0].Kty)
+			key, err := svc.keyring.signingKeyAt(time.Now())
+			require.NoError(t, err)
+			assert.Equal(t, key.id, jwks.Keys[0].Kid)
+			assert.Equal(t, tc.algorithm, jwks.Keys[0].Alg)
+		})
+	}
+}
+
+// 測試其他金鑰簽署的 token 不能通過驗證
+func TestRejectForeignToken(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	svc, _, err := NewService(&configs.Config{
+		JWTAlgorithm: "es256", JWTPrivateKeyFile: writePrivateKey(t, ecKey), TokenExpiresIn: time.Minute,
+	})
+	require.NoError(t, err)
+	other, _, err := NewService(&configs.Config{
+		JWTAlgorithm: "ES256", JWTPrivateKeyFile: writePrivateKey(t, otherKey), TokenExpiresIn: time.Minute,
+	})
+	require.NoError(t, err)
+
+	token, err := other.GenerateToken(UserToken{UserID: 1})
+	require.NoError(t, err)
+	_, err = svc.ValidateToken(token)
+	assert.Error(t, err)
+}
+
+// 測試 keyring 依照 kid 挑選金鑰，以及啟用、退役與重新載入
+func TestKeyring(t *testing.T) {
+	now := time.Now()
+	keyringPath := filepath.Join(t.TempDir(), "keyring.json")
+	writeKeyring := func(content string) {
+		require.NoError(t, os.WriteFile(keyringPath, []byte(content), 0600))
+	}
+
+	writeKeyring(`{"keys": [
+		{"kid": "k1", "algorithm": "HS256", "secret": "secret-1"},
+		{"kid": "k2", "algorithm": "HS256", "secret": "secret-2", "activates_at": "` +
+		now.Add(time.Hour).Format(time.RFC3339) + `"}
+	]}`)
+	svc, _, err := NewService(&configs.Config{JWTKeyringFile: keyringPath, TokenExpiresIn: time.Minute})
+	require.NoError(t, err)
+
+	// k2 尚未啟用，因此使用 k1 簽署
+	key, err := svc.keyring.signingKeyAt(now)
+	require.NoError(t, err)
+	assert.Equal(t, "k1", key.id)
+	k1Token, err := svc.GenerateToken(UserToken{UserID: 1})
+	require.NoError(t, err)
+
+	// k2 啟用後改用 k2 簽署
+	key, err = svc.keyring.signingKeyAt(now.Add(2 * time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, "k2", key.id)
+
+	// 將 k1 退役並重新載入後，k1 簽署的 token 不再有效
+	writeKeyring(`{"keys": [
+		{"kid": "k1", "algorithm": "HS256", "secret": "secret-1", "retire_after": "` +
+		now.Add(-time.Minute).Format(time.RFC3339) + `"},
+		{"kid": "k2", "algorithm": "HS256", "secret": "secret-2"}
+	]}`)
+	require.NoError(t, svc.ReloadKeys())
+	_, err = svc.ValidateToken(k1Token)
+	assert.Error(t, err)
+
+	k2Token, err := svc.GenerateToken(UserToken{UserID: 2})
+	require.NoError(t, err)
+	claims, err := svc.ValidateToken(k2Token)
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), claims.UserID)
+
+	// 設定檔有誤時保留原本的 keyring
+	writeKeyring(`{"keys": [{"algorithm": "HS256", "secret": "no-kid"}]}`)
+	assert.Error(t, svc.ReloadKeys())
+	_, err = svc.ValidateToken(k2Token)
+	assert.NoError(t, err)
+}
+
+// 測試使用尚未啟用的金鑰簽署的 token 會被拒絕，金鑰只出現在 JWKS 中供其他服務預先快取
+func TestKeyringRejectsKeyBeforeActivation(t *testing.T) {
+	now := time.Now()
+	dir := t.TempDir()
+	writeKeyring := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+		return path
+	}
+
+	svc, _, err := NewService(&configs.Config{TokenExpiresIn: time.Minute, JWTKeyringFile: writeKeyring("keyring.json", `{"keys": [
+		{"kid": "k1", "algorithm": "HS256", "secret": "secret-1"},
+		{"kid": "k2", "algorithm": "HS256", "secret": "secret-2", "activates_at": "`+now.Add(time.Hour).Format(time.RFC3339)+`"}
+	]}`)})
+	require.NoError(t, err)
+
+	// 另一個 keyring 已經啟用 k2 (例如設定錯誤或被竊取的金鑰)
+	early, _, err := NewService(&configs.Config{TokenExpiresIn: time.Minute, JWTKeyringFile: writeKeyring("early.json", `{"keys": [
+		{"kid": "k2", "algorithm": "HS256", "secret": "secret-2"}
+	]}`)})
+	require.NoError(t, err)
+	token, err := early.GenerateToken(UserToken{UserID: 1})
+	require.NoError(t, err)
+
+	_, err = svc.ValidateToken(token)
+	assert.Error(t, err)
+	_, err = svc.keyring.verificationKey("k2", now)
+	assert.ErrorIs(t, err, ErrUnknownKeyID)
+
+	_, err = svc.keyring.verificationKey("k2", now.Add(time.Hour))
+	assert.NoError(t, err, "the key is accepted once it is active")
+	_, err = svc.keyring.verificationKey("k2", now.Add(time.Hour-clockSkew/2))
+	assert.NoError(t, err, "clock skew between instances is tolerated")
+}
+
+// 測試定期重新載入 keyring 設定檔，並且可以停止檢查的 goroutine
+func TestKeyringWatch(t *testing.T) {
+	keyringPath := filepath.Join(t.TempDir(), "keyring.json")
+	require.NoError(t, os.WriteFile(keyringPath, []byte(`{"keys": [{"kid": "k1", "algorithm": "HS256", "secret": "secret-1"}]}`), 0600))
+
+	svc, stop, err := NewService(&configs.Config{
+		JWTKeyringFile:           keyringPath,
+		JWTKeyringReloadInterval: 10 * time.Millisecond,
+		TokenExpiresIn:           time.Minute,
+	})
+	require.NoError(t, err)
+
+	// 修改時間必須不同才會被視為變動
+	require.NoError(t, os.WriteFile(keyringPath, []byte(`{"keys": [{"kid": "k2", "algorithm": "HS256", "secret": "secret-2"}]}`), 0600))
+	require.NoError(t, os.Chtimes(keyringPath, time.Now(), time.Now().Add(time.Second)))
+	assert.Eventually(t, func() bool {
+		key, err := svc.keyring.signingKeyAt(time.Now())
+		return err == nil && key.id == "k2"
+	}, 2*time.Second, 10*time.Millisecond)
+
+	stopped := make(chan struct{})
+	go func() {
+		stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("stop did not return after the watcher exited")
+	}
+}
+
+// 測試 refresh token 的產生與雜湊
+func TestRefreshToken(t *testing.T) {
+	svc, _, err := NewService(&configs.Config{JWTSecret: "secret", RefreshTokenExpiresIn: time.Hour})
+	require.NoError(t, err)
+
+	token, hash, err := svc.GenerateRefreshToken()
+	require.NoError(t, err)
+	assert.NotEqual(t, token, hash)
+	assert.Equal(t, hash, svc.HashRefreshToken(token))
+
+	another, _, err := svc.GenerateRefreshToken()
+	require.NoError(t, err)
+	assert.NotEqual(t, token, another)
+}
+
+// 測試不透明 token 的產生與雜湊
+func TestOpaqueToken(t *testing.T) {
+	token, hash, err := GenerateOpaqueToken()
+	require.NoError(t, err)
+	assert.Len(t, hash, 64)
+	assert.Equal(t, hash, HashOpaqueToken(token))
+	assert.NotEqual(t, hash, HashOpaqueToken(token+"x"))
+}
+
+// 工具函數：將私鑰寫成 PKCS#8 PEM 檔案
+func writePrivateKey(t *testing.T, key interface{}) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "key.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+	return path
+}
@@ -0,0 +1,123 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 支援的簽署演算法
+const (
+	AlgorithmHS256 = "HS256" // HMAC SHA-256，使用共用密鑰 (JWT_SECRET)
+	AlgorithmRS256 = "RS256" // RSA PKCS#1 v1.5 SHA-256
+	AlgorithmES256 = "ES256" // ECDSA P-256 SHA-256
+	AlgorithmEdDSA = "EdDSA" // Ed25519
+)
+
+// signingKey 簽署及驗證 token 使用的金鑰
+type signingKey struct {
+	id        string            // 金鑰 ID，會放在 token header 的 kid
+	method    jwt.SigningMethod // 簽署演算法
+	signKey   interface{}       // 簽署用的金鑰 (HMAC 為 []byte，非對稱演算法為私鑰)
+	verifyKey interface{}       // 驗證用的金鑰 (HMAC 為 []byte，非對稱演算法為公鑰)
+}
+
+// newHMACKey 使用共用密鑰建立 HMAC 金鑰
+// 如果沒有指定 kid，會使用密鑰雜湊值的前 16 個字元作為 kid，不會洩漏密鑰本身
+func newHMACKey(secret, kid string) *signingKey {
+	if kid == "" {
+		sum := sha256.Sum256([]byte(secret))
+		kid = "hs-" + hex.EncodeToString(sum[:])[:16]
+	}
+	return &signingKey{
+		id:        kid,
+		method:    jwt.SigningMethodHS256,
+		signKey:   []byte(secret),
+		verifyKey: []byte(secret),
+	}
+}
+
+// loadSigningKey 根據演算法建立簽署金鑰
+// 非對稱演算法會從 PEM 檔案載入私鑰；沒有指定 kid 時使用 RFC 7638 的公鑰 thumbprint 作為 kid
+func loadSigningKey(algorithm, privateKeyFile, secret, kid string) (*signingKey, error) {
+	if algorithm == "" || algorithm == AlgorithmHS256 {
+		return newHMACKey(secret, kid), nil
+	}
+
+	if privateKeyFile == "" {
+		return nil, fmt.Errorf("private key file is required for algorithm %s", algorithm)
+	}
+	pemBytes, err := os.ReadFile(privateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading private key file: %w", err)
+	}
+
+	return parseSigningKey(algorithm, pemBytes, kid)
+}
+
+// parseSigningKey 解析 PEM 格式的私鑰並建立簽署金鑰
+func parseSigningKey(algorithm string, pemBytes []byte, kid string) (*signingKey, error) {
+	key := &signingKey{id: kid}
+
+	switch algorithm {
+	case AlgorithmRS256:
+		privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing RSA private key: %w", err)
+		}
+		key.method, key.signKey, key.verifyKey = jwt.SigningMethodRS256, privateKey, &privateKey.PublicKey
+	case AlgorithmES256:
+		privateKey, err := jwt.ParseECPrivateKeyFromPEM(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing EC private key: %w", err)
+		}
+		if privateKey.Curve != elliptic.P256() {
+			return nil, errors.New("ES256 requires a P-256 private key")
+		}
+		key.method, key.signKey, key.verifyKey = jwt.SigningMethodES256, privateKey, &privateKey.PublicKey
+	case AlgorithmEdDSA:
+		privateKey, err := jwt.ParseEdPrivateKeyFromPEM(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing Ed25519 private key: %w", err)
+		}
+		edKey, ok := privateKey.(ed25519.PrivateKey)
+		if !ok {
+			return nil, errors.New("EdDSA requires an Ed25519 private key")
+		}
+		key.method, key.signKey, key.verifyKey = jwt.SigningMethodEdDSA, edKey, edKey.Public()
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", algorithm)
+	}
+
+	if key.id == "" {
+		jwk, err := publicJWK(key)
+		if err != nil {
+			return nil, err
+		}
+		if key.id, err = jwk.thumbprint(); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// isAsymmetric 判斷金鑰是否為非對稱金鑰 (只有非對稱金鑰的公鑰可以公開)
+func (k *signingKey) isAsymmetric() bool {
+	_, isHMAC := k.verifyKey.([]byte)
+	return !isHMAC
+}
+
+// normalizeAlgorithm 將設定中的演算法名稱正規化，例如 rs256 -> RS256、eddsa -> EdDSA
+func normalizeAlgorithm(algorithm string) string {
+	if strings.EqualFold(algorithm, AlgorithmEdDSA) {
+		return AlgorithmEdDSA
+	}
+	return strings.ToUpper(algorithm)
+}
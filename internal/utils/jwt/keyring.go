@@ -0,0 +1,214 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go-template/internal/configs"
+	"go-template/internal/utils/logger"
+)
+
+var (
+	// ErrNoSigningKey 目前沒有任何已啟用且未退役的簽署金鑰
+	ErrNoSigningKey = errors.New("no active signing key")
+	// ErrUnknownKeyID token 的 kid 不存在於 keyring，或該金鑰已經退役
+	ErrUnknownKeyID = errors.New("unknown or retired key ID")
+)
+
+// keyringKey keyring 中的一把金鑰
+type keyringKey struct {
+	*signingKey
+	activatesAt time.Time // 開始用來簽署 token 的時間，在這之前只會出現在 JWKS 中讓其他服務預先快取
+	retiresAt   time.Time // 退役時間，之後使用此金鑰簽署的 token 一律拒絕；零值代表不退役
+	verifyOnly  bool      // 只用於驗證，不會被選為簽署金鑰 (例如 JWT_OLD_SECRETS)
+}
+
+// usableAt 判斷金鑰在指定時間是否仍可用於驗證
+func (k *keyringKey) usableAt(now time.Time) bool {
+	return k.retiresAt.IsZero() || now.Before(k.retiresAt)
+}
+
+// keyring 以 kid 為索引的金鑰集合，可以在執行期間重新載入
+type keyring struct {
+	mu   sync.RWMutex
+	keys map[string]*keyringKey
+}
+
+// keyringFile keyring 設定檔的格式 (JWT_KEYRING_FILE)
+type keyringFile struct {
+	Keys []keyringFileEntry `json:"keys"`
+}
+
+// keyringFileEntry keyring 設定檔中的一把金鑰
+type keyringFileEntry struct {
+	Kid            string    `json:"kid"`              // 金鑰 ID (必填)
+	Algorithm      string    `json:"algorithm"`        // 簽署演算法: HS256、RS256、ES256、EdDSA
+	PrivateKeyFile string    `json:"private_key_file"` // 非對稱演算法的 PEM 私鑰檔案
+	Secret         string    `json:"secret"`           // HS256 的共用密鑰
+	ActivatesAt    time.Time `json:"activates_at"`     // 開始簽署的時間 (RFC 3339)，未設定代表立即啟用
+	RetireAfter    time.Time `json:"retire_after"`     // 退役時間 (RFC 3339)，未設定代表不退役
+	VerifyOnly     bool      `json:"verify_only"`      // 只用於驗證
+}
+
+// loadKeyring 根據設定載入 keyring
+// 有設定 JWT_KEYRING_FILE 時從設定檔載入，否則使用 JWT_SECRET / JWT_PRIVATE_KEY_FILE 與 JWT_OLD_SECRETS
+func loadKeyring(cfg *configs.Config) (map[string]*keyringKey, error) {
+	if cfg.JWTKeyringFile != "" {
+		return loadKeyringFile(cfg.JWTKeyringFile)
+	}
+
+	keys := make(map[string]*keyringKey)
+	key, err := loadSigningKey(normalizeAlgorithm(cfg.JWTAlgorithm), cfg.JWTPrivateKeyFile, cfg.JWTSecret, cfg.JWTKeyID)
+	if err != nil {
+		return nil, err
+	}
+	keys[key.id] = &keyringKey{signingKey: key}
+
+	// 舊的 HMAC 密鑰只用於驗證尚未過期的 token
+	for _, oldSecret := range cfg.JWTOldSecrets {
+		oldKey := newHMACKey(oldSecret, "")
+		keys[oldKey.id] = &keyringKey{signingKey: oldKey, verifyOnly: true}
+	}
+	return keys, nil
+}
+
+// loadKeyringFile 從 JSON 設定檔載入 keyring
+func loadKeyringFile(path string) (map[string]*keyringKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading keyring file: %w", err)
+	}
+
+	var file keyringFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("error parsing keyring file: %w", err)
+	}
+
+	keys := make(map[string]*keyringKey, len(file.Keys))
+	for _, entry := range file.Keys {
+		if entry.Kid == "" {
+			return nil, errors.New("every key in the keyring file must have a kid")
+		}
+		if _, exists := keys[entry.Kid]; exists {
+			return nil, fmt.Errorf("duplicate kid in keyring file: %s", entry.Kid)
+		}
+
+		key, err := loadSigningKey(normalizeAlgorithm(entry.Algorithm), entry.PrivateKeyFile, entry.Secret, entry.Kid)
+		if err != nil {
+			return nil, fmt.Errorf("error loading key %s: %w", entry.Kid, err)
+		}
+		keys[entry.Kid] = &keyringKey{
+			signingKey:  key,
+			activatesAt: entry.ActivatesAt,
+			retiresAt:   entry.RetireAfter,
+			verifyOnly:  entry.VerifyOnly,
+		}
+	}
+	return keys, nil
+}
+
+// replace 以新的金鑰集合取代目前的 keyring
+func (r *keyring) replace(keys map[string]*keyringKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys = keys
+}
+
+// signingKeyAt 取得指定時間應該用來簽署的金鑰
+// 在已啟用且未退役的金鑰中，選擇最晚啟用的那一把
+func (r *keyring) signingKeyAt(now time.Time) (*signingKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var selected *keyringKey
+	for _, key := range r.keys {
+		if key.verifyOnly || now.Before(key.activatesAt) || !key.usableAt(now) {
+			continue
+		}
+		// 啟用時間相同時以 kid 排序，確保結果固定
+		if selected == nil || key.activatesAt.After(selected.activatesAt) ||
+			(key.activatesAt.Equal(selected.activatesAt) && key.id > selected.id) {
+			selected = key
+		}
+	}
+	if selected == nil {
+		return nil, ErrNoSigningKey
+	}
+	return selected.signingKey, nil
+}
+
+// verificationKey 根據 kid 直接取得驗證用的金鑰
+// 尚未啟用的金鑰只會出現在 JWKS 中，還不能用來簽署，使用它簽署的 token 一律拒絕 (容許 clockSkew 的時鐘誤差)
+func (r *keyring) verificationKey(kid string, now time.Time) (*signingKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, ok := r.keys[kid]
+	if !ok || !key.usableAt(now) || now.Add(clockSkew).Before(key.activatesAt) {
+		return nil, ErrUnknownKeyID
+	}
+	return key.signingKey, nil
+}
+
+// publicKeys 取得所有尚未退役的非對稱金鑰 (包含尚未啟用的金鑰，讓其他服務可以預先快取)
+func (r *keyring) publicKeys(now time.Time) []*signingKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]*signingKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		if key.isAsymmetric() && key.usableAt(now) {
+			keys = append(keys, key.signingKey)
+		}
+	}
+	return keys
+}
+
+// ReloadKeys 重新載入 keyring，載入失敗時保留原本的金鑰
+func (s *Service) ReloadKeys() error {
+	keys, err := loadKeyring(s.cfg)
+	if err != nil {
+		logger.Logger.Errorf("Error reloading JWT keyring: %v", err)
+		return err
+	}
+	s.keyring.replace(keys)
+	logger.Logger.Infof("JWT keyring reloaded with %d keys", len(keys))
+	return nil
+}
+
+// watchKeyringFile 定期檢查 keyring 設定檔是否有變動，有變動時重新載入，直到 ctx 被取消
+// lastModified 為目前載入的設定檔的修改時間
+func (s *Service) watchKeyringFile(ctx context.Context, interval time.Duration, lastModified time.Time) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		modified := keyringModTime(s.cfg.JWTKeyringFile)
+		if modified.Equal(lastModified) {
+			continue
+		}
+		if err := s.ReloadKeys(); err == nil {
+			lastModified = modified
+		}
+	}
+}
+
+// keyringModTime 取得 keyring 設定檔的最後修改時間
+func keyringModTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
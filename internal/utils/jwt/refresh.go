@@ -0,0 +1,58 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+)
+
+// opaqueTokenBytes 不透明 token 的隨機位元組長度 (256 bits)
+const opaqueTokenBytes = 32
+
+// tokenIDBytes token ID 的隨機位元組長度 (128 bits)
+const tokenIDBytes = 16
+
+// GenerateRefreshToken 產生一個不透明 (opaque) 的 refresh token
+// 回傳要交給使用者端的明文 token，以及要儲存在伺服器端的雜湊值
+func (s *Service) GenerateRefreshToken() (token string, tokenHash string, err error) {
+	return GenerateOpaqueToken()
+}
+
+// HashRefreshToken 計算 refresh token 的雜湊值
+func (s *Service) HashRefreshToken(token string) string {
+	return HashOpaqueToken(token)
+}
+
+// GenerateOpaqueToken 產生一個不透明 (opaque) 的隨機 token，例如 refresh token、電子郵件驗證 token
+// 回傳要交給使用者端的明文 token，以及要儲存在伺服器端的雜湊值
+func GenerateOpaqueToken() (token string, tokenHash string, err error) {
+	buf := make([]byte, opaqueTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(buf)
+	return token, HashOpaqueToken(token), nil
+}
+
+// HashOpaqueToken 計算不透明 token 的雜湊值
+// token 本身已具備足夠的隨機性，因此使用 SHA-256 即可，不需要 bcrypt 這類慢速雜湊
+func HashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RefreshTokenExpiresIn 取得 refresh token 的有效時間
+func (s *Service) RefreshTokenExpiresIn() time.Duration {
+	return s.refreshExpiration
+}
+
+// NewTokenID 產生一個隨機的識別碼，可用於 token family 等需要唯一 ID 的地方
+func NewTokenID() (string, error) {
+	buf := make([]byte, tokenIDBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
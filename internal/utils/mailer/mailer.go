@@ -0,0 +1,65 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+
+	"go-template/internal/configs"
+)
+
+// 支援的寄信方式 (MAILER)
+const (
+	DriverSMTP   = "smtp"   // 透過 SMTP 伺服器寄送
+	DriverOutbox = "outbox" // 不實際寄送，只保存在記憶體 (以及 MAIL_OUTBOX_DIR 目錄)，用於本機開發與測試
+)
+
+// Message 要寄送的信件內容 (純文字)
+type Message struct {
+	To      string // 收件者
+	Subject string // 主旨
+	Body    string // 內容
+}
+
+// Mailer 介面，定義寄送信件的方法
+type Mailer interface {
+	// Send 寄送信件
+	Send(msg Message) error
+}
+
+// New 根據設定建立 Mailer
+// @param cfg body configs.Config true "設定"
+// @return Mailer 寄信實作
+// @return error 錯誤訊息
+func New(cfg *configs.Config) (Mailer, error) {
+	switch strings.ToLower(cfg.MailerDriver) {
+	case DriverSMTP:
+		return NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom), nil
+	case DriverOutbox, "":
+		return NewOutbox(cfg.MailOutboxDir, cfg.MailFrom)
+	default:
+		return nil, fmt.Errorf("unsupported mailer: %s", cfg.MailerDriver)
+	}
+}
+
+// formatMessage 將信件轉換成 RFC 5322 格式
+func formatMessage(from string, msg Message, date time.Time) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("From: " + sanitizeHeader(from) + "\r\n")
+	buf.WriteString("To: " + sanitizeHeader(msg.To) + "\r\n")
+	buf.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", sanitizeHeader(msg.Subject)) + "\r\n")
+	buf.WriteString("Date: " + date.Format(time.RFC1123Z) + "\r\n")
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return buf.Bytes()
+}
+
+// sanitizeHeader 移除換行字元，避免 header injection
+func sanitizeHeader(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
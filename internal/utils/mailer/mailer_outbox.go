@@ -0,0 +1,56 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go-template/internal/utils/logger"
+)
+
+// Outbox Struct，不實際寄送信件，實作 Mailer 介面
+// 信件會保存在記憶體中，有設定目錄時也會寫成 .eml 檔案，方便本機開發時查看信件內容以及在測試中檢查
+type Outbox struct {
+	mu       sync.Mutex
+	dir      string
+	from     string
+	messages []Message
+}
+
+// NewOutbox 建立一個新的 Outbox 實例，dir 為空字串時只保存在記憶體
+func NewOutbox(dir, from string) (*Outbox, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			return nil, fmt.Errorf("error creating mail outbox directory: %w", err)
+		}
+	}
+	return &Outbox{dir: dir, from: from}, nil
+}
+
+// Send 將信件保存到 outbox
+func (o *Outbox) Send(msg Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.messages = append(o.messages, msg)
+	if o.dir != "" {
+		now := time.Now()
+		name := fmt.Sprintf("%s-%04d.eml", now.Format("20060102T150405.000000000"), len(o.messages))
+		if err := os.WriteFile(filepath.Join(o.dir, name), formatMessage(o.from, msg, now), 0o600); err != nil {
+			logger.Logger.Errorf("Error writing mail to outbox: %v", err) // 記錄錯誤
+			return err
+		}
+	}
+
+	logger.Logger.Infof("Mail to %s stored in outbox: %s", msg.To, msg.Subject) // 記錄信件已保存
+	return nil
+}
+
+// Messages 取得目前 outbox 中所有的信件
+func (o *Outbox) Messages() []Message {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]Message(nil), o.messages...)
+}
@@ -0,0 +1,47 @@
+package mailer
+
+import (
+	"fmt"
+	"net/smtp"
+	"strconv"
+	"time"
+
+	"go-template/internal/utils/logger"
+)
+
+// SMTPMailer Struct，透過 SMTP 伺服器寄送信件，實作 Mailer 介面
+// 伺服器支援 STARTTLS 時會自動使用加密連線
+type SMTPMailer struct {
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+}
+
+// NewSMTPMailer 建立一個新的 SMTPMailer 實例
+func NewSMTPMailer(host string, port int, username, password, from string) *SMTPMailer {
+	return &SMTPMailer{
+		addr:     host + ":" + strconv.Itoa(port),
+		host:     host,
+		username: username,
+		password: password,
+		from:     from,
+	}
+}
+
+// Send 寄送信件
+func (m *SMTPMailer) Send(msg Message) error {
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+
+	err := smtp.SendMail(m.addr, auth, m.from, []string{sanitizeHeader(msg.To)}, formatMessage(m.from, msg, time.Now()))
+	if err != nil {
+		logger.Logger.Errorf("Error sending mail via SMTP: %v", err) // 記錄錯誤
+		return fmt.Errorf("error sending mail: %w", err)
+	}
+	logger.Logger.Debugf("Mail sent via SMTP to %s: %s", msg.To, msg.Subject) // 記錄信件已寄出
+	return nil
+}
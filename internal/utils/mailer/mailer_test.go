@@ -0,0 +1,51 @@
+package mailer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-template/internal/utils/logger"
+)
+
+func TestMain(m *testing.M) {
+	_ = logger.Init(&logger.Config{Level: "error", ConsoleOut: true, ServiceName: "mailer-test"})
+	os.Exit(m.Run())
+}
+
+// 測試 outbox 會保存信件，並在有設定目錄時寫成檔案
+func TestOutbox(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "outbox")
+	outbox, err := NewOutbox(dir, "no-reply@example.com")
+	require.NoError(t, err)
+
+	msg := Message{To: "john@example.com", Subject: "Verify your email", Body: "token: abc"}
+	require.NoError(t, outbox.Send(msg))
+	assert.Equal(t, []Message{msg}, outbox.Messages())
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	content, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "To: john@example.com\r\n")
+	assert.Contains(t, string(content), "token: abc")
+}
+
+// 測試信件格式會移除 header 中的換行字元
+func TestFormatMessageSanitizesHeaders(t *testing.T) {
+	raw := string(formatMessage("no-reply@example.com", Message{
+		To:      "john@example.com\r\nBcc: attacker@example.com",
+		Subject: "Hello",
+		Body:    "line 1\nline 2",
+	}, time.Now()))
+
+	headers, body, found := strings.Cut(raw, "\r\n\r\n")
+	require.True(t, found)
+	assert.NotContains(t, headers, "\r\nBcc:")
+	assert.Equal(t, "line 1\r\nline 2", body)
+}
@@ -0,0 +1,69 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// rangePrefixLength k-anonymity 分組使用的 SHA-1 前綴長度，與 Have I Been Pwned 的 range API 相同
+const rangePrefixLength = 5
+
+// BreachedList 存放在本機的外洩密碼雜湊清單
+// 清單依照 SHA-1 前 5 碼分成多個檔案 (<dir>/<PREFIX>.txt)，每一行為 "<剩餘 35 碼>:<出現次數>"，
+// 也就是 Have I Been Pwned range API 回應的格式；檢查時只需要讀取一個檔案，不需要載入整份清單
+type BreachedList struct {
+	dir string
+}
+
+// NewBreachedList 建立一個新的 BreachedList 實例，dir 必須是存在的目錄
+func NewBreachedList(dir string) (*BreachedList, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("breached password list: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, errors.New("breached password list: " + dir + " is not a directory")
+	}
+	return &BreachedList{dir: dir}, nil
+}
+
+// RangeKey 計算密碼的 SHA-1 雜湊 (大寫十六進位)，回傳用來分組的前綴與剩餘的後綴
+func RangeKey(password string) (prefix, suffix string) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	return hash[:rangePrefixLength], hash[rangePrefixLength:]
+}
+
+// Contains 檢查密碼是否出現在外洩密碼清單中，回傳出現的次數 (0 代表沒有出現)
+func (b *BreachedList) Contains(password string) (int, error) {
+	prefix, suffix := RangeKey(password)
+	file, err := os.Open(filepath.Join(b.dir, prefix+".txt"))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		hashSuffix, countStr, _ := strings.Cut(line, ":")
+		if !strings.EqualFold(hashSuffix, suffix) {
+			continue
+		}
+		count := 1
+		if _, err := fmt.Sscanf(countStr, "%d", &count); err != nil || count < 1 {
+			count = 1
+		}
+		return count, nil
+	}
+	return 0, scanner.Err()
+}
@@ -0,0 +1,123 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go-template/internal/configs"
+)
+
+// 支援的密碼雜湊演算法 (PASSWORD_HASH_ALGORITHM)
+const (
+	AlgorithmBcrypt   = "bcrypt"   // bcrypt，雜湊值為 $2a$ / $2b$ / $2y$ 開頭的 modular crypt 格式
+	AlgorithmArgon2id = "argon2id" // Argon2id (RFC 9106)，雜湊值為 PHC 字串格式
+)
+
+var (
+	// ErrPasswordTooLong 密碼超過演算法可以處理的長度 (bcrypt 只會使用前 72 個位元組)
+	ErrPasswordTooLong = errors.New("password is too long for the hash algorithm")
+	// ErrUnsupportedHash 無法辨識的雜湊值格式或參數
+	ErrUnsupportedHash = errors.New("unsupported password hash")
+)
+
+// Hasher 介面，定義密碼雜湊與驗證的方法
+type Hasher interface {
+	// Algorithm 產生新雜湊值時使用的演算法
+	Algorithm() string
+	// Hash 產生密碼的雜湊值 (包含演算法、參數與 salt)
+	Hash(password string) (string, error)
+	// Verify 驗證密碼是否與雜湊值相符；密碼不符時回傳 false 與 nil，雜湊值格式錯誤時回傳錯誤
+	Verify(password, encoded string) (bool, error)
+	// NeedsRehash 判斷雜湊值是否使用了與目前設定不同的演算法或參數，需要在下次驗證成功時重新雜湊
+	NeedsRehash(encoded string) bool
+}
+
+// Identify 根據雜湊值的前綴判斷使用的演算法，無法辨識時回傳空字串
+func Identify(encoded string) string {
+	switch {
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		return AlgorithmBcrypt
+	case strings.HasPrefix(encoded, "$"+AlgorithmArgon2id+"$"):
+		return AlgorithmArgon2id
+	default:
+		return ""
+	}
+}
+
+// New 根據設定建立 Hasher
+// 新的雜湊值使用 PASSWORD_HASH_ALGORITHM 指定的演算法，其他支援的演算法產生的舊雜湊值仍然可以驗證
+// @param cfg body configs.Config true "設定"
+// @return Hasher 密碼雜湊實作
+// @return error 錯誤訊息
+func New(cfg *configs.Config) (Hasher, error) {
+	bcryptHasher, err := NewBcryptHasher(cfg.PasswordBcryptCost)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.PasswordArgon2Memory < 1 || cfg.PasswordArgon2Memory > maxArgon2Memory ||
+		cfg.PasswordArgon2Iterations < 1 || cfg.PasswordArgon2Parallelism < 1 || cfg.PasswordArgon2Parallelism > 255 {
+		return nil, errors.New("invalid argon2id parameters")
+	}
+	argon2Hasher, err := NewArgon2idHasher(Argon2idParams{
+		Memory:      uint32(cfg.PasswordArgon2Memory),
+		Iterations:  uint32(cfg.PasswordArgon2Iterations),
+		Parallelism: uint8(cfg.PasswordArgon2Parallelism),
+		SaltLength:  argon2SaltLength,
+		KeyLength:   argon2KeyLength,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	hashers := map[string]Hasher{
+		AlgorithmBcrypt:   bcryptHasher,
+		AlgorithmArgon2id: argon2Hasher,
+	}
+	primary, ok := hashers[strings.ToLower(cfg.PasswordHashAlgorithm)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported password hash algorithm: %s", cfg.PasswordHashAlgorithm)
+	}
+	return NewMultiHasher(primary, hashers), nil
+}
+
+// MultiHasher 使用主要的 Hasher 產生新的雜湊值，並依照雜湊值的格式選擇對應的 Hasher 驗證
+// 用於更換演算法或參數之後，舊的雜湊值仍然可以登入，並在登入成功時升級
+type MultiHasher struct {
+	primary Hasher
+	hashers map[string]Hasher
+}
+
+// NewMultiHasher 建立一個新的 MultiHasher 實例
+// @param primary body Hasher true "產生新雜湊值使用的 Hasher"
+// @param hashers body map[string]Hasher true "以演算法名稱對應的 Hasher，用於驗證舊的雜湊值"
+func NewMultiHasher(primary Hasher, hashers map[string]Hasher) *MultiHasher {
+	return &MultiHasher{primary: primary, hashers: hashers}
+}
+
+// Algorithm 實作 Hasher 介面，回傳主要的演算法
+func (m *MultiHasher) Algorithm() string {
+	return m.primary.Algorithm()
+}
+
+// Hash 實作 Hasher 介面，使用主要的演算法產生雜湊值
+func (m *MultiHasher) Hash(password string) (string, error) {
+	return m.primary.Hash(password)
+}
+
+// Verify 實作 Hasher 介面，依照雜湊值的格式選擇驗證的演算法
+func (m *MultiHasher) Verify(password, encoded string) (bool, error) {
+	hasher, ok := m.hashers[Identify(encoded)]
+	if !ok {
+		return false, ErrUnsupportedHash
+	}
+	return hasher.Verify(password, encoded)
+}
+
+// NeedsRehash 實作 Hasher 介面，演算法與主要的演算法不同，或參數與目前的設定不同時需要重新雜湊
+func (m *MultiHasher) NeedsRehash(encoded string) bool {
+	if Identify(encoded) != m.primary.Algorithm() {
+		return true
+	}
+	return m.primary.NeedsRehash(encoded)
+}
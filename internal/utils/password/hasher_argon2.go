@@ -0,0 +1,140 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2id 固定的 salt 與輸出長度 (位元組)，可以調整的參數為記憶體、迭代次數與平行度
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+	maxArgon2Memory  = 1 << 20 // 驗證時接受的最大記憶體參數 (KiB)，也就是 1 GiB
+)
+
+// Argon2idParams Argon2id 的參數
+type Argon2idParams struct {
+	Memory      uint32 // 使用的記憶體 (KiB)
+	Iterations  uint32 // 迭代次數
+	Parallelism uint8  // 平行度
+	SaltLength  uint32 // salt 長度 (位元組)
+	KeyLength   uint32 // 輸出長度 (位元組)
+}
+
+// Argon2idHasher 使用 Argon2id 的 Hasher
+// 雜湊值為 PHC 字串格式：$argon2id$v=19$m=<記憶體>,t=<迭代次數>,p=<平行度>$<salt>$<hash>，
+// salt 與 hash 使用不含 padding 的 base64，參數都記錄在雜湊值中，調整設定後舊的雜湊值仍然可以驗證
+type Argon2idHasher struct {
+	params Argon2idParams
+}
+
+// NewArgon2idHasher 建立一個新的 Argon2idHasher 實例
+// @param params body Argon2idParams true "Argon2id 的參數"
+func NewArgon2idHasher(params Argon2idParams) (*Argon2idHasher, error) {
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+	return &Argon2idHasher{params: params}, nil
+}
+
+// Algorithm 實作 Hasher 介面
+func (h *Argon2idHasher) Algorithm() string {
+	return AlgorithmArgon2id
+}
+
+// Hash 實作 Hasher 介面
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.params.Iterations, h.params.Memory, h.params.Parallelism,
+		h.params.KeyLength)
+	return encodeArgon2id(h.params, salt, key), nil
+}
+
+// Verify 實作 Hasher 介面，使用雜湊值中記錄的參數計算
+func (h *Argon2idHasher) Verify(password, encoded string) (bool, error) {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+	computed := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism,
+		params.KeyLength)
+	return subtle.ConstantTimeCompare(key, computed) == 1, nil
+}
+
+// NeedsRehash 實作 Hasher 介面，不是 Argon2id 或任何參數與目前的設定不同時需要重新雜湊
+func (h *Argon2idHasher) NeedsRehash(encoded string) bool {
+	params, _, _, err := decodeArgon2id(encoded)
+	return err != nil || params != h.params
+}
+
+// validate 檢查參數是否在 Argon2 允許的範圍內
+func (p Argon2idParams) validate() error {
+	switch {
+	case p.Iterations < 1:
+		return errors.New("argon2id iterations must be at least 1")
+	case p.Parallelism < 1:
+		return errors.New("argon2id parallelism must be at least 1")
+	case p.Memory < 8*uint32(p.Parallelism):
+		return errors.New("argon2id memory must be at least 8 KiB per lane")
+	case p.SaltLength < 8:
+		return errors.New("argon2id salt must be at least 8 bytes")
+	case p.KeyLength < 4:
+		return errors.New("argon2id key must be at least 4 bytes")
+	}
+	return nil
+}
+
+// encodeArgon2id 將參數、salt 與雜湊結果編碼成 PHC 字串
+func encodeArgon2id(params Argon2idParams, salt, key []byte) string {
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s", AlgorithmArgon2id, argon2.Version,
+		params.Memory, params.Iterations, params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+}
+
+// decodeArgon2id 解析 PHC 字串，回傳參數、salt 與雜湊結果
+func decodeArgon2id(encoded string) (Argon2idParams, []byte, []byte, error) {
+	var params Argon2idParams
+	// 格式為 "" / argon2id / v=19 / m=...,t=...,p=... / salt / hash
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != AlgorithmArgon2id {
+		return params, nil, nil, ErrUnsupportedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("%w: argon2 version %q", ErrUnsupportedHash, parts[2])
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations,
+		&params.Parallelism); err != nil {
+		return params, nil, nil, fmt.Errorf("%w: argon2 parameters %q", ErrUnsupportedHash, parts[3])
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("%w: argon2 salt: %v", ErrUnsupportedHash, err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("%w: argon2 hash: %v", ErrUnsupportedHash, err)
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+
+	// 避免竄改過的雜湊值使用不合理的參數 (例如 0 或極大的記憶體) 造成錯誤或耗盡資源
+	if err := params.validate(); err != nil {
+		return params, nil, nil, fmt.Errorf("%w: %v", ErrUnsupportedHash, err)
+	}
+	if params.Memory > maxArgon2Memory {
+		return params, nil, nil, fmt.Errorf("%w: argon2 memory %d KiB exceeds the limit", ErrUnsupportedHash, params.Memory)
+	}
+	return params, salt, key, nil
+}
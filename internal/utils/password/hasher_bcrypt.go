@@ -0,0 +1,64 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// BcryptMaxBytes bcrypt 只會使用密碼的前 72 個位元組，超過時拒絕而不是默默截斷
+const BcryptMaxBytes = 72
+
+// BcryptHasher 使用 bcrypt 的 Hasher
+type BcryptHasher struct {
+	cost int
+}
+
+// NewBcryptHasher 建立一個新的 BcryptHasher 實例
+// @param cost body int true "bcrypt 的 cost (4 ~ 31)"
+func NewBcryptHasher(cost int) (*BcryptHasher, error) {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	return &BcryptHasher{cost: cost}, nil
+}
+
+// Algorithm 實作 Hasher 介面
+func (h *BcryptHasher) Algorithm() string {
+	return AlgorithmBcrypt
+}
+
+// Hash 實作 Hasher 介面
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	if len(password) > BcryptMaxBytes {
+		return "", ErrPasswordTooLong
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// Verify 實作 Hasher 介面
+// 超過 72 個位元組的密碼不可能產生過雜湊值，直接視為不相符，避免前 72 個位元組相同的密碼被接受
+func (h *BcryptHasher) Verify(password, encoded string) (bool, error) {
+	if len(password) > BcryptMaxBytes {
+		return false, nil
+	}
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrUnsupportedHash, err)
+	}
+	return true, nil
+}
+
+// NeedsRehash 實作 Hasher 介面，不是 bcrypt 或 cost 與目前的設定不同時需要重新雜湊
+func (h *BcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != h.cost
+}
@@ -0,0 +1,138 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-template/internal/configs"
+)
+
+// testArgon2Params 測試用的 Argon2id 參數，使用較少的記憶體加快測試
+var testArgon2Params = Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+// 測試 Argon2id 的 PHC 格式與驗證
+func TestArgon2idHasher(t *testing.T) {
+	h, err := NewArgon2idHasher(testArgon2Params)
+	require.NoError(t, err)
+
+	encoded, err := h.Hash("correct horse")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encoded, "$argon2id$v=19$m=64,t=1,p=1$"))
+	assert.Equal(t, AlgorithmArgon2id, Identify(encoded))
+
+	ok, err := h.Verify("correct horse", encoded)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = h.Verify("wrong horse", encoded)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// 每次雜湊使用不同的 salt
+	other, err := h.Hash("correct horse")
+	require.NoError(t, err)
+	assert.NotEqual(t, encoded, other)
+
+	assert.False(t, h.NeedsRehash(encoded))
+	stronger, err := NewArgon2idHasher(Argon2idParams{Memory: 128, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32})
+	require.NoError(t, err)
+	assert.True(t, stronger.NeedsRehash(encoded))
+
+	// 參數改變後舊的雜湊值仍然可以驗證
+	ok, err = stronger.Verify("correct horse", encoded)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+// 測試格式錯誤或參數不合理的雜湊值會被拒絕
+func TestArgon2idMalformedHash(t *testing.T) {
+	h, err := NewArgon2idHasher(testArgon2Params)
+	require.NoError(t, err)
+
+	for _, bad := range []string{
+		"$argon2id$v=19$m=64,t=1,p=1$c29tZXNhbHRzb21lc2FsdA",               // 缺少 hash
+		"$argon2id$v=16$m=64,t=1,p=1$c29tZXNhbHRzb21lc2FsdA$AAAAAAAA",      // 不支援的版本
+		"$argon2id$v=19$m=0,t=1,p=1$c29tZXNhbHRzb21lc2FsdA$AAAAAAAA",       // 記憶體為 0
+		"$argon2id$v=19$m=4194304,t=1,p=1$c29tZXNhbHRzb21lc2FsdA$AAAAAAAA", // 記憶體超過上限
+		"$argon2id$v=19$m=64,t=1,p=1$c29tZXNhbHRzb21lc2FsdA$not base64!",   // hash 不是 base64
+		"$argon2i$v=19$m=64,t=1,p=1$c29tZXNhbHRzb21lc2FsdA$AAAAAAAA",       // 其他 Argon2 變體
+	} {
+		_, err := h.Verify("password", bad)
+		assert.ErrorIs(t, err, ErrUnsupportedHash, bad)
+		assert.True(t, h.NeedsRehash(bad), bad)
+	}
+}
+
+// 測試 bcrypt 的 cost 與 72 位元組限制
+func TestBcryptHasher(t *testing.T) {
+	h, err := NewBcryptHasher(4)
+	require.NoError(t, err)
+
+	encoded, err := h.Hash("correct horse")
+	require.NoError(t, err)
+	assert.Equal(t, AlgorithmBcrypt, Identify(encoded))
+
+	ok, err := h.Verify("correct horse", encoded)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = h.Verify("wrong horse", encoded)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.False(t, h.NeedsRehash(encoded))
+	higher, err := NewBcryptHasher(5)
+	require.NoError(t, err)
+	assert.True(t, higher.NeedsRehash(encoded))
+
+	long := strings.Repeat("a", BcryptMaxBytes+1)
+	_, err = h.Hash(long)
+	assert.ErrorIs(t, err, ErrPasswordTooLong)
+
+	// 前 72 個位元組相同的密碼不能通過驗證
+	exact, err := h.Hash(long[:BcryptMaxBytes])
+	require.NoError(t, err)
+	ok, err = h.Verify(long, exact)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = NewBcryptHasher(3)
+	assert.Error(t, err)
+}
+
+// 測試依照設定建立的 Hasher 可以驗證舊演算法的雜湊值並要求升級
+func TestNewHasherUpgrades(t *testing.T) {
+	cfg := &configs.Config{
+		PasswordHashAlgorithm:     AlgorithmArgon2id,
+		PasswordBcryptCost:        4,
+		PasswordArgon2Memory:      64,
+		PasswordArgon2Iterations:  1,
+		PasswordArgon2Parallelism: 1,
+	}
+	h, err := New(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, AlgorithmArgon2id, h.Algorithm())
+
+	legacy, err := NewBcryptHasher(4)
+	require.NoError(t, err)
+	old, err := legacy.Hash("correct horse")
+	require.NoError(t, err)
+
+	ok, err := h.Verify("correct horse", old)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, h.NeedsRehash(old))
+
+	upgraded, err := h.Hash("correct horse")
+	require.NoError(t, err)
+	assert.False(t, h.NeedsRehash(upgraded))
+
+	_, err = h.Verify("correct horse", "plaintext")
+	assert.ErrorIs(t, err, ErrUnsupportedHash)
+
+	cfg.PasswordHashAlgorithm = "md5"
+	_, err = New(cfg)
+	assert.Error(t, err)
+}
@@ -0,0 +1,59 @@
+package password
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 測試常見的弱密碼與強密碼的分數
+func TestEstimateStrength(t *testing.T) {
+	weak := []string{"password", "123456", "qwerty123", "P@ssw0rd", "aaaaaaaa", "abcdefgh", "zxcvbnm1"}
+	for _, pw := range weak {
+		assert.LessOrEqual(t, EstimateStrength(pw).Score, 1, pw)
+	}
+
+	strong := []string{"correct horse battery staple", "xK9#mQ2$vL7!pR4", "tangerine-violin-orbit-87"}
+	for _, pw := range strong {
+		assert.GreaterOrEqual(t, EstimateStrength(pw).Score, 3, pw)
+	}
+
+	assert.Equal(t, 0, EstimateStrength("").Score)
+}
+
+// 測試使用者資料出現在密碼中時會降低分數
+func TestEstimateStrengthUserInputs(t *testing.T) {
+	pw := "Zebrafinch1987"
+	without := EstimateStrength(pw)
+	with := EstimateStrength(pw, "zebrafinch", "zebrafinch@example.com")
+	assert.Less(t, with.GuessesLog10, without.GuessesLog10)
+}
+
+// 測試外洩密碼清單的查詢
+func TestBreachedList(t *testing.T) {
+	dir := t.TempDir()
+	prefix, suffix := RangeKey("password")
+	assert.Equal(t, "5BAA6", prefix)
+	assert.Equal(t, "1E4C9B93F3F0682250B6CF8331B7EE68FD8", suffix)
+
+	content := "0018A45C4D1DEF81644B54AB7F969B88D65:1\n" + suffix + ":3861493\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, prefix+".txt"), []byte(content), 0o644))
+
+	list, err := NewBreachedList(dir)
+	require.NoError(t, err)
+
+	count, err := list.Contains("password")
+	require.NoError(t, err)
+	assert.Equal(t, 3861493, count)
+
+	// 同一個前綴但不在清單中
+	count, err = list.Contains("not-in-the-list-" + prefix)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	_, err = NewBreachedList(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
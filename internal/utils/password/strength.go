@@ -0,0 +1,280 @@
+package password
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// 強度分數的門檻 (log10 猜測次數)，與 zxcvbn 相同
+var scoreThresholds = []float64{3, 6, 8, 10}
+
+// 最小的猜測次數，避免單一字元的片段被低估 (與 zxcvbn 的 MIN_SUBMATCH_GUESSES 相同)
+const (
+	minGuessesSingleChar = 10
+	minGuessesMultiChar  = 50
+	// bruteforceCardinality 沒有符合任何模式的字元，每個字元的猜測次數
+	bruteforceCardinality = 10
+	// minPatternLength 重複、序列與字典比對的最短長度
+	minPatternLength = 3
+)
+
+// sequences 視為連續序列的字元順序，包含字母、數字與鍵盤上的排列
+var sequences = []string{
+	"abcdefghijklmnopqrstuvwxyz",
+	"0123456789",
+	"qwertyuiop",
+	"asdfghjkl",
+	"zxcvbnm",
+	"1qaz2wsx3edc4rfv5tgb6yhn7ujm8ik9ol0p",
+}
+
+// leetSubstitutions 常見的 leet 替換字元，'1' 同時可能代表 i 或 l
+var leetSubstitutions = map[rune][]rune{
+	'4': {'a'}, '@': {'a'}, '8': {'b'}, '(': {'c'}, '3': {'e'}, '6': {'g'}, '9': {'g'},
+	'1': {'i', 'l'}, '!': {'i'}, '|': {'i', 'l'}, '0': {'o'}, '$': {'s'}, '5': {'s'},
+	'7': {'t'}, '+': {'t'}, '2': {'z'}, '%': {'x'},
+}
+
+// Strength 密碼強度的估算結果
+type Strength struct {
+	Score        int     // 0 (非常弱) ~ 4 (非常強)
+	GuessesLog10 float64 // 估算的猜測次數 (log10)
+}
+
+// EstimateStrength 以類似 zxcvbn 的方式估算密碼強度
+// 將密碼拆成常見密碼、使用者資料、重複字元、連續序列、年份與其他字元等片段，
+// 找出猜測次數最少的拆法，再依照猜測次數換算成 0 ~ 4 的分數
+// @param password 密碼
+// @param userInputs 使用者相關的資料 (例如帳號名稱、電子郵件)，出現在密碼中時會被視為很容易猜到
+func EstimateStrength(password string, userInputs ...string) Strength {
+	runes := []rune(password)
+	if len(runes) == 0 {
+		return Strength{}
+	}
+	lower := []rune(strings.ToLower(password))
+	dictionary := buildDictionary(userInputs)
+
+	// best[i] 為前 i 個字元最少的猜測次數 (log10)
+	n := len(runes)
+	best := make([]float64, n+1)
+	for i := 1; i <= n; i++ {
+		best[i] = math.Inf(1)
+	}
+	for end := 1; end <= n; end++ {
+		for start := 0; start < end; start++ {
+			guesses := segmentGuesses(runes[start:end], lower[start:end], dictionary)
+			if guesses <= 0 {
+				continue
+			}
+			minimum := float64(minGuessesMultiChar)
+			if end-start == 1 {
+				minimum = minGuessesSingleChar
+			}
+			total := best[start] + math.Log10(math.Max(guesses, minimum))
+			if total < best[end] {
+				best[end] = total
+			}
+		}
+	}
+
+	guessesLog10 := best[n]
+	score := len(scoreThresholds)
+	for i, threshold := range scoreThresholds {
+		if guessesLog10 < threshold {
+			score = i
+			break
+		}
+	}
+	return Strength{Score: score, GuessesLog10: guessesLog10}
+}
+
+// segmentGuesses 估算單一片段的猜測次數，片段不符合任何模式且長度大於 1 時回傳 0 (由更短的片段組成)
+func segmentGuesses(original, lower []rune, dictionary map[string]int) float64 {
+	length := len(lower)
+	if length == 1 {
+		return bruteforceCardinality
+	}
+	if length < minPatternLength {
+		return 0
+	}
+
+	guesses := math.Inf(1)
+	if rank, leet := dictionaryRank(lower, dictionary); rank > 0 {
+		guesses = math.Min(guesses, float64(rank)*caseVariations(original)*leetVariations(leet))
+	}
+	if isRepeat(lower) {
+		guesses = math.Min(guesses, float64(charCardinality(lower[0])*length))
+	}
+	if reversed, ok := isSequence(lower); ok {
+		base := float64(26 * length)
+		if reversed {
+			base *= 2
+		}
+		guesses = math.Min(guesses, base)
+	}
+	if isYear(lower) {
+		guesses = math.Min(guesses, 120)
+	}
+	if math.IsInf(guesses, 1) {
+		return 0
+	}
+	return guesses
+}
+
+// buildDictionary 合併常見密碼與使用者資料，使用者資料的排名在最前面
+func buildDictionary(userInputs []string) map[string]int {
+	dictionary := make(map[string]int, len(commonPasswords)+len(userInputs))
+	rank := 1
+	for _, input := range userInputs {
+		for _, part := range strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			if len([]rune(part)) >= minPatternLength {
+				if _, ok := dictionary[part]; !ok {
+					dictionary[part] = rank
+					rank++
+				}
+			}
+		}
+	}
+	for i, word := range commonPasswords {
+		if _, ok := dictionary[word]; !ok {
+			dictionary[word] = rank + i
+		}
+	}
+	return dictionary
+}
+
+// dictionaryRank 查詢片段在字典中的排名，會嘗試將 leet 字元還原；找不到時回傳 0
+func dictionaryRank(lower []rune, dictionary map[string]int) (int, bool) {
+	if rank, ok := dictionary[string(lower)]; ok {
+		return rank, false
+	}
+	best := 0
+	for _, candidate := range unleet(lower) {
+		if rank, ok := dictionary[candidate]; ok && (best == 0 || rank < best) {
+			best = rank
+		}
+	}
+	return best, best > 0
+}
+
+// unleet 產生將 leet 字元還原後可能的字串 (每個替換字元最多兩種可能，因此數量有上限)
+func unleet(lower []rune) []string {
+	candidates := [][]rune{make([]rune, 0, len(lower))}
+	changed := false
+	for _, r := range lower {
+		subs, ok := leetSubstitutions[r]
+		if !ok {
+			for i := range candidates {
+				candidates[i] = append(candidates[i], r)
+			}
+			continue
+		}
+		changed = true
+		next := make([][]rune, 0, len(candidates)*len(subs))
+		for _, candidate := range candidates {
+			for _, sub := range subs {
+				extended := append(append(make([]rune, 0, len(lower)), candidate...), sub)
+				next = append(next, extended)
+			}
+		}
+		if len(next) > 16 {
+			next = next[:16]
+		}
+		candidates = next
+	}
+	if !changed {
+		return nil
+	}
+	result := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		result = append(result, string(candidate))
+	}
+	return result
+}
+
+// caseVariations 大小寫變化造成的額外猜測次數：全小寫為 1，只有開頭或全部大寫為 2，其他混合為 4
+func caseVariations(original []rune) float64 {
+	upper, lower := 0, 0
+	for _, r := range original {
+		if unicode.IsUpper(r) {
+			upper++
+		} else if unicode.IsLower(r) {
+			lower++
+		}
+	}
+	switch {
+	case upper == 0:
+		return 1
+	case lower == 0 || (upper == 1 && unicode.IsUpper(original[0])):
+		return 2
+	default:
+		return 4
+	}
+}
+
+// leetVariations leet 替換造成的額外猜測次數
+func leetVariations(leet bool) float64 {
+	if leet {
+		return 2
+	}
+	return 1
+}
+
+// isRepeat 判斷片段是否由同一個字元重複組成
+func isRepeat(lower []rune) bool {
+	for _, r := range lower[1:] {
+		if r != lower[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// isSequence 判斷片段是否為連續序列 (例如 abc、321、qwerty)，回傳是否為反向
+func isSequence(lower []rune) (reversed bool, ok bool) {
+	s := string(lower)
+	for _, seq := range sequences {
+		if strings.Contains(seq, s) {
+			return false, true
+		}
+		if strings.Contains(reverse(seq), s) {
+			return true, true
+		}
+	}
+	return false, false
+}
+
+// isYear 判斷片段是否為 1900 ~ 2099 的年份
+func isYear(lower []rune) bool {
+	if len(lower) != 4 {
+		return false
+	}
+	s := string(lower)
+	return (strings.HasPrefix(s, "19") || strings.HasPrefix(s, "20")) && strings.IndexFunc(s, func(r rune) bool {
+		return r < '0' || r > '9'
+	}) < 0
+}
+
+// charCardinality 字元所屬類別的字元數，用於估算重複字元的猜測次數
+func charCardinality(r rune) int {
+	switch {
+	case r >= '0' && r <= '9':
+		return 10
+	case r >= 'a' && r <= 'z':
+		return 26
+	default:
+		return 33
+	}
+}
+
+// reverse 反轉字串
+func reverse(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
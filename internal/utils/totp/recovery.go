@@ -0,0 +1,39 @@
+package totp
+
+import (
+	"crypto/rand"
+	"strings"
+)
+
+// recoveryCodeAlphabet 復原碼使用的字元，去除容易混淆的 0/O、1/I/L
+const recoveryCodeAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+
+// recoveryCodeLength 復原碼的字元數 (不含分隔線)，約 50 bits 的隨機性
+const recoveryCodeLength = 10
+
+// GenerateRecoveryCodes 產生 n 組復原碼，格式為 XXXXX-XXXXX
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, 0, n)
+	buf := make([]byte, recoveryCodeLength)
+	for len(codes) < n {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		var sb strings.Builder
+		for i, b := range buf {
+			if i == recoveryCodeLength/2 {
+				sb.WriteByte('-')
+			}
+			// 256 不是字元數的倍數，會有極小的分布偏差，對復原碼而言可以接受
+			sb.WriteByte(recoveryCodeAlphabet[int(b)%len(recoveryCodeAlphabet)])
+		}
+		codes = append(codes, sb.String())
+	}
+	return codes, nil
+}
+
+// NormalizeRecoveryCode 將使用者輸入的復原碼正規化 (去除空白與分隔線並轉成大寫)，計算雜湊值前必須先正規化
+func NormalizeRecoveryCode(code string) string {
+	replacer := strings.NewReplacer("-", "", " ", "")
+	return strings.ToUpper(replacer.Replace(code))
+}
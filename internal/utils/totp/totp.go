@@ -0,0 +1,120 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RFC 6238 建議的預設值，也是大部分驗證器 App 唯一支援的設定
+const (
+	Digits    = 6                // 驗證碼位數
+	Period    = 30 * time.Second // 每個驗證碼的有效時間 (time step)
+	Algorithm = "SHA1"           // HMAC 演算法
+	// secretBytes 共享密鑰的隨機位元組長度 (160 bits，與 HMAC-SHA1 的輸出長度相同)
+	secretBytes = 20
+)
+
+// ErrInvalidSecret 共享密鑰不是有效的 base32 字串
+var ErrInvalidSecret = errors.New("invalid totp secret")
+
+// secretEncoding 驗證器 App 使用不含 padding 的 base32 編碼
+var secretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret 產生一個新的共享密鑰 (base32 編碼)
+func GenerateSecret() (string, error) {
+	buf := make([]byte, secretBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return secretEncoding.EncodeToString(buf), nil
+}
+
+// ProvisioningURI 產生驗證器 App 使用的 otpauth:// URI，也就是 QR code 的內容
+// @param issuer 服務名稱，會顯示在驗證器 App 中
+// @param account 帳號名稱，通常是使用者名稱或電子郵件
+// @param secret base32 編碼的共享密鑰
+func ProvisioningURI(issuer, account, secret string) string {
+	values := url.Values{}
+	values.Set("secret", secret)
+	values.Set("issuer", issuer)
+	values.Set("algorithm", Algorithm)
+	values.Set("digits", fmt.Sprint(Digits))
+	values.Set("period", fmt.Sprint(int(Period.Seconds())))
+
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	return "otpauth://totp/" + label + "?" + values.Encode()
+}
+
+// Step 取得指定時間所在的 time step (RFC 6238 的 T)
+func Step(t time.Time) int64 {
+	return t.Unix() / int64(Period.Seconds())
+}
+
+// GenerateCode 產生指定時間的驗證碼
+func GenerateCode(secret string, t time.Time) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return hotp(key, uint64(Step(t)), Digits), nil
+}
+
+// Validate 驗證驗證碼，允許前後 skew 個 time step 的時間誤差
+// 驗證成功時回傳符合的 time step，呼叫端應記錄最後使用的 time step，拒絕相同或更早的 step 以防止驗證碼被重複使用
+func Validate(secret, code string, t time.Time, skew int) (int64, bool) {
+	key, err := decodeSecret(secret)
+	if err != nil || len(code) != Digits {
+		return 0, false
+	}
+
+	current := Step(t)
+	for i := -skew; i <= skew; i++ {
+		step := current + int64(i)
+		if step < 0 {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(hotp(key, uint64(step), Digits)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// decodeSecret 解碼 base32 共享密鑰，允許小寫、空白與 padding
+func decodeSecret(secret string) ([]byte, error) {
+	normalized := strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	key, err := secretEncoding.DecodeString(strings.TrimRight(normalized, "="))
+	if err != nil || len(key) == 0 {
+		return nil, ErrInvalidSecret
+	}
+	return key, nil
+}
+
+// hotp 依照 RFC 4226 計算 HOTP 驗證碼
+func hotp(key []byte, counter uint64, digits int) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// dynamic truncation
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, value%mod)
+}
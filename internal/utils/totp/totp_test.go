@@ -0,0 +1,103 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RFC 4226 附錄 D 的測試向量
+func TestHOTPVectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+	expected := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	for counter, code := range expected {
+		assert.Equal(t, code, hotp(key, uint64(counter), 6))
+	}
+}
+
+// RFC 6238 附錄 B 的 SHA1 測試向量
+func TestTOTPVectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+	vectors := map[int64]string{
+		59:          "94287082",
+		1111111109:  "07081804",
+		1111111111:  "14050471",
+		1234567890:  "89005924",
+		2000000000:  "69279037",
+		20000000000: "65353130",
+	}
+	for unix, code := range vectors {
+		assert.Equal(t, code, hotp(key, uint64(Step(time.Unix(unix, 0))), 8))
+	}
+}
+
+// 測試驗證碼產生與驗證，以及允許的時間誤差
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+
+	now := time.Unix(1700000000, 0)
+	code, err := GenerateCode(secret, now)
+	require.NoError(t, err)
+
+	step, ok := Validate(secret, code, now, 1)
+	assert.True(t, ok)
+	assert.Equal(t, Step(now), step)
+
+	// 前一個 time step 的驗證碼在允許的誤差內
+	step, ok = Validate(secret, code, now.Add(Period), 1)
+	assert.True(t, ok)
+	assert.Equal(t, Step(now), step)
+
+	// 超出允許的誤差
+	_, ok = Validate(secret, code, now.Add(2*Period), 1)
+	assert.False(t, ok)
+
+	_, ok = Validate(secret, "12345", now, 1)
+	assert.False(t, ok)
+	_, ok = Validate("not base32!", code, now, 1)
+	assert.False(t, ok)
+
+	// 小寫與空白的密鑰也可以使用
+	lower := strings.ToLower(secret[:4]) + " " + secret[4:]
+	_, ok = Validate(lower, code, now, 0)
+	assert.True(t, ok)
+}
+
+// 測試 provisioning URI 的格式
+func TestProvisioningURI(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	uri := ProvisioningURI("Go Template", "john@example.com", secret)
+
+	parsed, err := url.Parse(uri)
+	require.NoError(t, err)
+	assert.Equal(t, "otpauth", parsed.Scheme)
+	assert.Equal(t, "totp", parsed.Host)
+	assert.Equal(t, "/Go Template:john@example.com", parsed.Path)
+	assert.Equal(t, secret, parsed.Query().Get("secret"))
+	assert.Equal(t, "Go Template", parsed.Query().Get("issuer"))
+	assert.Equal(t, "6", parsed.Query().Get("digits"))
+	assert.Equal(t, "30", parsed.Query().Get("period"))
+}
+
+// 測試復原碼的格式與正規化
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	require.NoError(t, err)
+	require.Len(t, codes, 10)
+
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		assert.Len(t, code, recoveryCodeLength+1)
+		assert.Equal(t, byte('-'), code[recoveryCodeLength/2])
+		assert.False(t, seen[code])
+		seen[code] = true
+	}
+
+	assert.Equal(t, "ABCDEFGHJK", NormalizeRecoveryCode(" abcde-fghjk "))
+}
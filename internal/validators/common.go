@@ -1 +1,31 @@
 package validators
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// RegisterJSONFieldNames 讓 gin 的 binding 驗證錯誤使用 JSON 欄位名稱 (或 form 欄位名稱)，而不是 Go 的欄位名稱
+// 回應給用戶端的欄位路徑才能直接對應到請求內容，在啟動 server 時呼叫一次
+func RegisterJSONFieldNames() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(fieldName)
+	}
+}
+
+// fieldName 取得欄位在請求中的名稱：json tag、form tag，都沒有時使用 Go 的欄位名稱
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form"} {
+		name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
@@ -0,0 +1,197 @@
+package validators
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go-template/internal/apperror"
+	"go-template/internal/configs"
+	"go-template/internal/utils/logger"
+	"go-template/internal/utils/password"
+)
+
+// 密碼規則的名稱，用於回報違反了哪些規則
+const (
+	PasswordRuleMinLength    = "min_length"
+	PasswordRuleMaxLength    = "max_length"
+	PasswordRuleUpper        = "require_upper"
+	PasswordRuleLower        = "require_lower"
+	PasswordRuleDigit        = "require_digit"
+	PasswordRuleSymbol       = "require_symbol"
+	PasswordRulePersonalInfo = "personal_info"
+	PasswordRuleStrength     = "strength"
+	PasswordRuleBreached     = "breached"
+)
+
+const (
+	minPersonalInfoLength    = 3 // 帳號名稱或電子郵件少於這個長度時不檢查，避免誤判
+	maxPasswordStrengthScore = 4 // 強度分數的最大值
+)
+
+// ErrPasswordPolicy 密碼不符合密碼規則，實際回傳的錯誤為 *PasswordPolicyError
+var ErrPasswordPolicy = apperror.New(apperror.KindInvalid, "password_policy", "password does not satisfy the password policy")
+
+// PasswordViolation 違反的密碼規則
+type PasswordViolation struct {
+	Rule    string `json:"rule"`    // 規則名稱
+	Message string `json:"message"` // 說明
+}
+
+// PasswordPolicyError 密碼不符合密碼規則時回傳的錯誤，包含所有違反的規則
+type PasswordPolicyError struct {
+	Violations []PasswordViolation
+}
+
+// Error 實作 error 介面
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, v.Message)
+	}
+	return ErrPasswordPolicy.Error() + ": " + strings.Join(messages, "; ")
+}
+
+// Unwrap 讓 errors.Is(err, ErrPasswordPolicy) 成立，並讓 ErrorHandler 回應 400 以及所有違反的規則
+func (e *PasswordPolicyError) Unwrap() error {
+	return ErrPasswordPolicy.WithDetails(e.Violations)
+}
+
+// PasswordPolicy 密碼規則，在註冊、變更密碼與重設密碼時檢查
+type PasswordPolicy struct {
+	MinLength            int  // 最短長度 (以字元計算，不是位元組)
+	MaxLength            int  // 最長長度 (以字元計算)，0 代表不限制
+	RequireUpper         bool // 必須包含大寫字母
+	RequireLower         bool // 必須包含小寫字母
+	RequireDigit         bool // 必須包含數字
+	RequireSymbol        bool // 必須包含符號
+	DisallowPersonalInfo bool // 不允許包含帳號名稱或電子郵件
+	MinStrength          int  // 最低的強度分數 (0 ~ 4)，0 代表不檢查
+	MaxBytes             int  // 最長的位元組數，0 代表不限制；使用 bcrypt 時為 72，避免密碼被截斷
+	breached             *password.BreachedList
+}
+
+// NewPasswordPolicy 根據設定建立密碼規則，設定外洩密碼清單時目錄必須存在
+func NewPasswordPolicy(cfg *configs.Config) (*PasswordPolicy, error) {
+	if cfg.PasswordMinStrength < 0 || cfg.PasswordMinStrength > maxPasswordStrengthScore {
+		return nil, fmt.Errorf("password min strength must be between 0 and %d", maxPasswordStrengthScore)
+	}
+	if cfg.PasswordMaxLength > 0 && cfg.PasswordMaxLength < cfg.PasswordMinLength {
+		return nil, errors.New("password max length must not be less than min length")
+	}
+
+	policy := &PasswordPolicy{
+		MinLength:            cfg.PasswordMinLength,
+		MaxLength:            cfg.PasswordMaxLength,
+		RequireUpper:         cfg.PasswordRequireUpper,
+		RequireLower:         cfg.PasswordRequireLower,
+		RequireDigit:         cfg.PasswordRequireDigit,
+		RequireSymbol:        cfg.PasswordRequireSymbol,
+		DisallowPersonalInfo: cfg.PasswordDisallowPersonalInfo,
+		MinStrength:          cfg.PasswordMinStrength,
+	}
+	if strings.EqualFold(cfg.PasswordHashAlgorithm, password.AlgorithmBcrypt) {
+		policy.MaxBytes = password.BcryptMaxBytes
+	}
+	if cfg.PasswordBreachedListDir != "" {
+		list, err := password.NewBreachedList(cfg.PasswordBreachedListDir)
+		if err != nil {
+			return nil, err
+		}
+		policy.breached = list
+		logger.Logger.Infof("Using breached password list in %s", cfg.PasswordBreachedListDir)
+	}
+	return policy, nil
+}
+
+// Validate 檢查密碼是否符合規則，不符合時回傳包含所有違反規則的 *PasswordPolicyError
+// @param pw 密碼
+// @param username 帳號名稱
+// @param email 電子郵件
+func (p *PasswordPolicy) Validate(pw, username, email string) error {
+	var violations []PasswordViolation
+	add := func(rule, format string, args ...interface{}) {
+		violations = append(violations, PasswordViolation{Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	length := utf8.RuneCountInString(pw)
+	if length < p.MinLength {
+		add(PasswordRuleMinLength, "password must be at least %d characters long", p.MinLength)
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		add(PasswordRuleMaxLength, "password must be at most %d characters long", p.MaxLength)
+	} else if p.MaxBytes > 0 && len(pw) > p.MaxBytes {
+		// 多位元組字元 (例如中文或 emoji) 可能在字元數以內仍然超過位元組上限
+		add(PasswordRuleMaxLength, "password must be at most %d bytes long", p.MaxBytes)
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range pw {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if p.RequireUpper && !hasUpper {
+		add(PasswordRuleUpper, "password must contain an uppercase letter")
+	}
+	if p.RequireLower && !hasLower {
+		add(PasswordRuleLower, "password must contain a lowercase letter")
+	}
+	if p.RequireDigit && !hasDigit {
+		add(PasswordRuleDigit, "password must contain a digit")
+	}
+	if p.RequireSymbol && !hasSymbol {
+		add(PasswordRuleSymbol, "password must contain a symbol")
+	}
+
+	if p.DisallowPersonalInfo && containsPersonalInfo(pw, username, email) {
+		add(PasswordRulePersonalInfo, "password must not contain the username or email address")
+	}
+
+	if p.MinStrength > 0 {
+		if strength := password.EstimateStrength(pw, username, email); strength.Score < p.MinStrength {
+			add(PasswordRuleStrength, "password is too easy to guess (strength %d of %d, at least %d required)",
+				strength.Score, maxPasswordStrengthScore, p.MinStrength)
+		}
+	}
+
+	if p.breached != nil {
+		count, err := p.breached.Contains(pw)
+		if err != nil {
+			// 清單無法讀取時不阻擋使用者，只記錄錯誤
+			logger.Logger.Errorf("Error checking breached password list: %v", err)
+		} else if count > 0 {
+			add(PasswordRuleBreached, "password has appeared in a data breach and must not be used")
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// containsPersonalInfo 檢查密碼是否包含帳號名稱、電子郵件或電子郵件的帳號部分 (不區分大小寫)
+func containsPersonalInfo(pw, username, email string) bool {
+	lower := strings.ToLower(pw)
+	candidates := []string{username, email}
+	if local, _, ok := strings.Cut(email, "@"); ok {
+		candidates = append(candidates, local)
+	}
+	for _, candidate := range candidates {
+		candidate = strings.ToLower(strings.TrimSpace(candidate))
+		if utf8.RuneCountInString(candidate) >= minPersonalInfoLength && strings.Contains(lower, candidate) {
+			return true
+		}
+	}
+	return false
+}
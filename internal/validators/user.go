@@ -1,41 +1,109 @@
 package validators
 
 import (
-	"errors"
+	"slices"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"go-template/internal/apperror"
 	"go-template/internal/models"
-	"net/mail"
+	"go-template/internal/repository"
+	"golang.org/x/text/unicode/norm"
 )
 
-// ValidateUser 驗證使用者資料
-func ValidateUser(user *models.User) error {
-	// 檢查使用者名稱長度
-	if len(user.Username) < 4 {
-		return errors.New("username must be at least 4 characters long")
+// reservedUsernames 保留的帳號名稱，避免使用者冒充系統或管理者，比較時使用 models.UsernameKey
+var reservedUsernames = map[string]bool{
+	"admin":         true,
+	"administrator": true,
+	"root":          true,
+	"system":        true,
+	"support":       true,
+	"security":      true,
+	"api":           true,
+	"me":            true,
+	"null":          true,
+	"undefined":     true,
+	"anonymous":     true,
+}
+
+// NormalizeUsername 將帳號名稱轉換成 NFKC 正規化的形式並去掉前後的空白
+// 全形與半形、合成與分解的字元會變成相同的字串，存入資料庫的帳號名稱因此一致
+func NormalizeUsername(username string) string {
+	return strings.TrimSpace(norm.NFKC.String(username))
+}
+
+// notReserved 自訂的 not_reserved 規則：帳號名稱不可以是保留的名稱
+func notReserved(fl validator.FieldLevel) bool {
+	return !reservedUsernames[models.UsernameKey(fl.Field().String())]
+}
+
+// UserValidator 驗證使用者資料：models.User 的 validate tag，以及帳號名稱與電子郵件的唯一性
+type UserValidator struct {
+	userRepo *repository.UserRepository
+}
+
+// NewUserValidator 建立一個新的 UserValidator 實例
+func NewUserValidator(userRepo *repository.UserRepository) *UserValidator {
+	return &UserValidator{userRepo: userRepo}
+}
+
+// Validate 驗證使用者資料，回傳包含所有違反規則的 ErrValidation (Fields 的 field 為 JSON 欄位名稱、rule 為規則名稱)
+// 驗證前會以 NormalizeUsername 正規化 user.Username 與去掉 user.Email 前後的空白；
+// fields 指定只驗證部分欄位 (Go 的欄位名稱，例如 Username、Email)，沒有指定時驗證所有欄位。
+// 帳號名稱以 NFKC 正規化並忽略大小寫後比較，電子郵件忽略大小寫後比較，與 user.ID 以外的使用者 (包含已刪除的使用者) 重複時違反 unique 規則
+// @param user body models.User true "使用者資料，更新時 ID 為要更新的使用者"
+// @param fields query []string false "要驗證的欄位"
+// @return error 錯誤訊息
+func (v *UserValidator) Validate(user *models.User, fields ...string) error {
+	user.Username = NormalizeUsername(user.Username)
+	user.Email = strings.TrimSpace(user.Email)
+
+	var violations []apperror.FieldError
+	if err := Struct(user, fields...); err != nil {
+		appErr, ok := apperror.As(err)
+		if !ok {
+			return err
+		}
+		violations = append(violations, appErr.Fields...)
 	}
-	// 檢查密碼長度
-	if len(user.Password) < 6 {
-		return errors.New("password must be at least 6 characters long")
+
+	// 格式正確的欄位才檢查是否重複，避免同一個欄位回報多個錯誤
+	if validates(fields, "Username") && !violated(violations, "username") {
+		taken, err := v.userRepo.UsernameTaken(user.Username, user.ID)
+		if err != nil {
+			return err
+		}
+		if taken {
+			violations = append(violations, apperror.FieldError{Field: "username", Rule: "unique", Message: "is already taken"})
+		}
 	}
-	// 檢查 email 格式
-	if _, err := mail.ParseAddress(user.Email); err != nil {
-		return errors.New("invalid email format")
+	if validates(fields, "Email") && !violated(violations, "email") {
+		taken, err := v.userRepo.EmailTaken(user.Email, user.ID)
+		if err != nil {
+			return err
+		}
+		if taken {
+			violations = append(violations, apperror.FieldError{Field: "email", Rule: "unique", Message: "is already taken"})
+		}
+	}
+
+	if len(violations) > 0 {
+		return ErrValidation.WithFields(violations)
 	}
 	return nil
 }
 
-// ValidateNewUser 驗證新增使用者資料
-func ValidateNewUser(username string, email string, password string) error {
-	// 檢查使用者名稱長度
-	if len(username) < 4 {
-		return errors.New("username must be at least 4 characters long")
-	}
-	// 檢查密碼長度
-	if len(password) < 6 {
-		return errors.New("password must be at least 6 characters long")
-	}
-	// 檢查 email 格式
-	if _, err := mail.ParseAddress(email); err != nil {
-		return errors.New("invalid email format")
+// validates 判斷 field 是否在要驗證的欄位中，沒有指定欄位代表全部驗證
+func validates(fields []string, field string) bool {
+	return len(fields) == 0 || slices.Contains(fields, field)
+}
+
+// violated 判斷欄位是否已經有違反的規則
+func violated(violations []apperror.FieldError, field string) bool {
+	for _, violation := range violations {
+		if violation.Field == field {
+			return true
+		}
 	}
-	return nil
+	return false
 }
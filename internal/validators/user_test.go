@@ -0,0 +1,118 @@
+package validators
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-template/internal/apperror"
+	"go-template/internal/models"
+	"go-template/internal/repository"
+	"go-template/internal/utils/logger"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormLog "gorm.io/gorm/logger"
+)
+
+func TestMain(m *testing.M) {
+	_ = logger.Init(&logger.Config{Level: "error", ConsoleOut: true, ServiceName: "validators-test"})
+	os.Exit(m.Run())
+}
+
+// 檢查帳號名稱與電子郵件是否重複的查詢，包含已刪除的使用者
+const (
+	usernameTakenQuery = `SELECT count\(\*\) FROM "users" WHERE username_key = \$1 AND id <> \$2$`
+	emailTakenQuery    = `SELECT count\(\*\) FROM "users" WHERE LOWER\(email\) = LOWER\(\$1\) AND id <> \$2$`
+)
+
+// newTestValidator 建立使用 sqlmock 資料庫的 UserValidator，測試結束時檢查所有預期的查詢都已執行
+func newTestValidator(t *testing.T) (*UserValidator, sqlmock.Sqlmock) {
+	conn, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, mock.ExpectationsWereMet())
+		_ = conn.Close()
+	})
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{Logger: gormLog.Default.LogMode(gormLog.Silent)})
+	require.NoError(t, err)
+	return NewUserValidator(repository.NewUserRepository(db)), mock
+}
+
+// expectTaken 預期檢查是否重複的查詢，value 為正規化後的值
+func expectTaken(mock sqlmock.Sqlmock, query, value string, excludeID uint, taken bool) {
+	count := 0
+	if taken {
+		count = 1
+	}
+	mock.ExpectQuery(query).WithArgs(value, excludeID).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(count))
+}
+
+// fieldRules 取得驗證錯誤中每個欄位違反的規則
+func fieldRules(t *testing.T, err error) map[string]string {
+	t.Helper()
+	require.ErrorIs(t, err, ErrValidation)
+	appErr, ok := apperror.As(err)
+	require.True(t, ok)
+	rules := make(map[string]string, len(appErr.Fields))
+	for _, field := range appErr.Fields {
+		rules[field.Field] = field.Rule
+	}
+	return rules
+}
+
+// 測試保留的帳號名稱在正規化並忽略大小寫之後比較，違反時不再查詢是否重複
+func TestUserValidatorReservedUsername(t *testing.T) {
+	for _, username := range []string{"admin", "Admin", " ROOT ", "ａｄｍｉｎ", "Ｓｙｓｔｅｍ"} {
+		t.Run(username, func(t *testing.T) {
+			validator, mock := newTestValidator(t)
+			expectTaken(mock, emailTakenQuery, "alice@example.com", 0, false)
+
+			err := validator.Validate(&models.User{Username: username, Email: "alice@example.com", Password: "hashed"})
+			assert.Equal(t, map[string]string{"username": "not_reserved"}, fieldRules(t, err))
+		})
+	}
+}
+
+// 測試帳號名稱以 NFKC 正規化並 case folding 後檢查是否重複，電子郵件去掉前後空白後檢查
+func TestUserValidatorNormalisedUniqueness(t *testing.T) {
+	validator, mock := newTestValidator(t)
+
+	user := &models.User{Username: " Ａｌｉｃｅ ", Email: " Alice@Example.com ", Password: "hashed"}
+	user.ID = 7
+	expectTaken(mock, usernameTakenQuery, "alice", 7, true)
+	expectTaken(mock, emailTakenQuery, "Alice@Example.com", 7, true)
+
+	err := validator.Validate(user)
+	assert.Equal(t, map[string]string{"username": "unique", "email": "unique"}, fieldRules(t, err))
+	assert.Equal(t, "Alice", user.Username, "the username keeps its case, only the comparison folds it")
+	assert.Equal(t, "Alice@Example.com", user.Email)
+}
+
+// 測試沒有重複時通過驗證，指定欄位時只驗證與查詢指定的欄位
+func TestUserValidatorFields(t *testing.T) {
+	validator, mock := newTestValidator(t)
+
+	expectTaken(mock, usernameTakenQuery, "alice", 0, false)
+	expectTaken(mock, emailTakenQuery, "alice@example.com", 0, false)
+	require.NoError(t, validator.Validate(&models.User{Username: "alice", Email: "alice@example.com", Password: "hashed"}))
+
+	// 只更新電子郵件時不檢查帳號名稱
+	expectTaken(mock, emailTakenQuery, "bob@example.com", 0, false)
+	require.NoError(t, validator.Validate(&models.User{Username: "admin", Email: "bob@example.com", Password: "hashed"}, "Email"))
+}
+
+// 測試格式錯誤的欄位不再查詢是否重複，資料庫錯誤原樣回傳
+func TestUserValidatorInvalidFormatAndDatabaseError(t *testing.T) {
+	validator, mock := newTestValidator(t)
+
+	expectTaken(mock, usernameTakenQuery, "alice", 0, false)
+	err := validator.Validate(&models.User{Username: "alice", Email: "not-an-email", Password: "hashed"})
+	assert.Equal(t, map[string]string{"email": "email"}, fieldRules(t, err))
+
+	dbErr := errors.New("connection reset")
+	mock.ExpectQuery(usernameTakenQuery).WillReturnError(dbErr)
+	assert.ErrorIs(t, validator.Validate(&models.User{Username: "alice", Email: "alice@example.com", Password: "hashed"}), dbErr)
+}
@@ -0,0 +1,112 @@
+package validators
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"go-template/internal/apperror"
+)
+
+// ErrValidation 資料沒有通過驗證，Fields 列出所有違反的規則
+var ErrValidation = apperror.New(apperror.KindInvalid, "invalid_request", "invalid request")
+
+// validate 驗證 validate tag 的 validator，欄位路徑使用 JSON 欄位名稱
+// 與 gin 的 binding tag 分開：binding tag 驗證請求的格式，validate tag 驗證 model 的資料
+var validate = newValidate()
+
+// newValidate 建立 validator 並註冊自訂的規則
+func newValidate() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(fieldName)
+	if err := v.RegisterValidation("not_reserved", notReserved); err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Struct 驗證 s 的 validate tag，回傳包含所有違反規則的 ErrValidation；fields 指定只驗證部分欄位 (Go 的欄位名稱)
+func Struct(s interface{}, fields ...string) error {
+	var err error
+	if len(fields) > 0 {
+		err = validate.StructPartial(s, fields...)
+	} else {
+		err = validate.Struct(s)
+	}
+	if err == nil {
+		return nil
+	}
+	if violations := FieldErrors(err); len(violations) > 0 {
+		return ErrValidation.WithFields(violations)
+	}
+	return err
+}
+
+// FieldErrors 將 validator 的驗證錯誤或 JSON 的型別錯誤轉換成每個欄位的錯誤，其他錯誤回傳 nil
+func FieldErrors(err error) []apperror.FieldError {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]apperror.FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields = append(fields, apperror.FieldError{
+				Field:   fieldPath(fe.Namespace()),
+				Rule:    fe.Tag(),
+				Param:   fe.Param(),
+				Message: fieldMessage(fe),
+			})
+		}
+		return fields
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return []apperror.FieldError{{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Param:   typeErr.Type.String(),
+			Message: fmt.Sprintf("must be of type %s", typeErr.Type.String()),
+		}}
+	}
+	return nil
+}
+
+// fieldPath 去掉 validator 命名空間最前面的結構名稱，例如 registerRequest.email 轉換成 email
+func fieldPath(namespace string) string {
+	if _, path, found := strings.Cut(namespace, "."); found {
+		return path
+	}
+	return namespace
+}
+
+// fieldMessage 產生常用規則的錯誤訊息，其他規則使用通用的訊息
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "min":
+		return fmt.Sprintf("must be at least %s%s", fe.Param(), unit(fe.Kind()))
+	case "max":
+		return fmt.Sprintf("must be at most %s%s", fe.Param(), unit(fe.Kind()))
+	case "not_reserved":
+		return "is reserved"
+	default:
+		return fmt.Sprintf("failed on the %s rule", fe.Tag())
+	}
+}
+
+// unit min、max 規則的單位：字串為字元數，陣列與 map 為項目數
+func unit(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return " characters long"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return " items"
+	default:
+		return ""
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"go.uber.org/zap"
 	"log"
 	"reflect"
+	"strings"
 
 	"go-template/internal/models"
+	"go-template/internal/repository"
 	"go-template/internal/utils/database"
 	"go-template/internal/utils/logger"
 )
@@ -37,6 +39,19 @@ func main() {
 	// 定義需要遷移的 Model
 	autoMigrateLists := []interface{}{
 		&models.User{}, // 使用 models.User
+		&models.RefreshToken{},
+		&models.RevokedToken{},
+		&models.Permission{},
+		&models.Role{},
+		&models.UserStatusChange{},
+		&models.UserToken{},
+		&models.UserMFA{},
+		&models.MFARecoveryCode{},
+		&models.LoginAttempt{},
+		&models.APIKey{},
+		&models.OAuthClient{},
+		&models.Session{},
+		&models.ImpersonationLog{},
 	}
 
 	// 執行 AutoMigrate
@@ -49,5 +64,35 @@ func main() {
 		}
 	}
 
+	// 為新增 username_key 之前建立的使用者寫入比較用的帳號名稱
+	userRepo := repository.NewUserRepository(db)
+	backfilled, err := userRepo.BackfillUsernameKeys()
+	if err != nil {
+		logger.Logger.Fatalf("backfilling username keys error: %v", err)
+	}
+	if backfilled > 0 {
+		logger.Logger.Infof("Username keys written for %d users", backfilled)
+	}
+
+	// 寫入預設的角色與權限
+	logger.Logger.Info("Seeding default roles and permissions...")
+	if err := repository.NewRoleRepository(db).SeedDefaults(); err != nil {
+		logger.Logger.Fatalf("seeding roles error: %v", err)
+	}
+
+	// 將 admin 角色指派給 ADMIN_USERNAMES 指定的使用者，讓第一位管理者可以登入管理 API
+	roleRepo := repository.NewRoleRepository(db)
+	for _, username := range cfg.AdminUsernames {
+		user, err := userRepo.GetByUsername(strings.TrimSpace(username))
+		if err != nil {
+			logger.Logger.Warnf("Admin user %s not found, skipping", username)
+			continue
+		}
+		if err := roleRepo.AssignRoles(user.ID, models.RoleAdmin); err != nil {
+			logger.Logger.Fatalf("assigning admin role error: %v", err)
+		}
+		logger.Logger.Infof("Admin role assigned to user: %s", user.Username)
+	}
+
 	logger.Logger.Info("Auto migration successfully completed.")
 }